@@ -0,0 +1,569 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+	"go.uber.org/zap"
+)
+
+const adminUsage = `Usage: leadhub-api admin [-db-dsn=...] [-json] <command> [flags]
+
+Commands:
+  tenant create -name -email [-description] [-parent]
+  user create -tenant -name -email (-password | -password-stdin) [-activate]
+  user activate -email
+  user reset-password -email (-password | -password-stdin)
+  user set-locale -email [-locale]
+  permission list -email
+  permission grant -email <code>...
+  permission revoke -email <code>...
+  token revoke -email [-scope authentication|activation|password-reset|all]
+  rates import [-format csv|json] <file>
+  rates list [-date YYYY-MM-DD]
+  stats [-currency USD] [-date YYYY-MM-DD]`
+
+var (
+	errUnknownAdminCommand = errors.New("unknown admin command")
+)
+
+// tokenScopes lists every token scope, "token revoke -scope=all" revokes all of them.
+var tokenScopes = []string{
+	data.ScopeActivation,
+	data.ScopeAuthentication,
+	data.ScopePasswordReset,
+	data.ScopeMFALogin,
+	data.ScopeRecovery,
+}
+
+// adminCLI runs the operator commands of "leadhub-api admin" against the data models, so
+// the first admin can be bootstrapped without SQL scripts.
+type adminCLI struct {
+	models data.Models
+	input  io.Reader
+	output io.Writer
+	json   bool
+}
+
+// runAdminCommand runs "leadhub-api admin" against the configured database. Nothing is
+// logged, stdout only carries the command output so -json can be piped.
+func runAdminCommand(args []string, input io.Reader, output io.Writer) error {
+	cli := &adminCLI{input: input, output: output}
+	fs := cli.flagSet("admin")
+	dsn := fs.String("db-dsn", os.Getenv("LEADHUB_DB_DSN"), "PostgreSQL DSN")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(output, adminUsage)
+		return flag.ErrHelp
+	}
+	if *dsn == "" {
+		*dsn = buildDSN(zap.NewNop())
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := sql.Open("postgres", *dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := db.PingContext(ctx); err != nil {
+		return err
+	}
+	cli.models = data.NewModels(db, data.Timeouts{})
+	return cli.run(ctx, fs.Args())
+}
+
+// run() dispatches a command, args start with the command name, e.g. "user create".
+func (cli *adminCLI) run(ctx context.Context, args []string) error {
+	if len(args) > 0 && args[0] == "stats" {
+		return cli.stats(ctx, args[1:])
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("%w %q\n%s", errUnknownAdminCommand, strings.Join(args, " "), adminUsage)
+	}
+	commands := map[string]func(context.Context, []string) error{
+		"tenant create":       cli.createTenant,
+		"user create":         cli.createUser,
+		"user activate":       cli.activateUser,
+		"user reset-password": cli.resetPassword,
+		"user set-locale":     cli.setLocale,
+		"permission list":     cli.listPermissions,
+		"permission grant":    cli.grantPermissions,
+		"permission revoke":   cli.revokePermissions,
+		"token revoke":        cli.revokeTokens,
+		"rates import":        cli.importExchangeRates,
+		"rates list":          cli.listExchangeRates,
+	}
+	command, ok := commands[args[0]+" "+args[1]]
+	if !ok {
+		return fmt.Errorf("%w %q\n%s", errUnknownAdminCommand, args[0]+" "+args[1], adminUsage)
+	}
+	return command(ctx, args[2:])
+}
+
+// flagSet() returns a flag set for a command, every command accepts -json.
+func (cli *adminCLI) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(cli.output)
+	fs.BoolVar(&cli.json, "json", cli.json, "Write the result as JSON")
+	return fs
+}
+
+// print() writes the result of a command, as JSON when -json is set and as the
+// formatted text otherwise.
+func (cli *adminCLI) print(result envelope, format string, args ...any) error {
+	if cli.json {
+		enc := json.NewEncoder(cli.output)
+		enc.SetIndent("", "\t")
+		return enc.Encode(result)
+	}
+	_, err := fmt.Fprintf(cli.output, format+"\n", args...)
+	return err
+}
+
+// readPassword() returns the -password flag, or the first line of the input when
+// -password-stdin is set, so passwords can stay out of the shell history.
+func (cli *adminCLI) readPassword(password string, fromStdin bool) (string, error) {
+	if !fromStdin {
+		return password, nil
+	}
+	line, err := bufio.NewReader(cli.input).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// getUser() looks a user up by email address.
+func (cli *adminCLI) getUser(ctx context.Context, models data.Models, email string) (*data.User, error) {
+	if email == "" {
+		return nil, errors.New("-email must be provided")
+	}
+	user, err := models.Users.GetByEmail(ctx, email)
+	if errors.Is(err, data.ErrGeneralRecordNotFound) {
+		return nil, fmt.Errorf("no user with the email address %q", email)
+	}
+	return user, err
+}
+
+func (cli *adminCLI) createTenant(ctx context.Context, args []string) error {
+	fs := cli.flagSet("tenant create")
+	tenant := &data.Tenant{}
+	fs.StringVar(&tenant.Name, "name", "", "Tenant name")
+	fs.StringVar(&tenant.ContactEmail, "email", "", "Tenant contact email")
+	fs.StringVar(&tenant.Description, "description", "", "Tenant description")
+	parentID := fs.Int64("parent", 0, "ID of the parent tenant")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *parentID != 0 {
+		tenant.ParentID = parentID
+	}
+	v := validator.New()
+	if data.ValidateTenant(v, tenant); !v.Valid() {
+		return validationError(v.Errors)
+	}
+	if err := cli.models.Tenants.CreateTenant(ctx, tenant); err != nil {
+		switch {
+		case errors.Is(err, data.ErrTenantAlreadyExists):
+			return validationError(map[string]string{"name": "tenant with this name already exists"})
+		case errors.Is(err, data.ErrInvalidParentTenant):
+			return validationError(map[string]string{"parent": "the specified parent tenant does not exist"})
+		default:
+			return err
+		}
+	}
+	return cli.print(envelope{"tenant": tenant}, "created tenant %d (%s)", tenant.ID, tenant.Name)
+}
+
+func (cli *adminCLI) createUser(ctx context.Context, args []string) error {
+	fs := cli.flagSet("user create")
+	user := &data.User{}
+	fs.Int64Var(&user.TenantID, "tenant", 0, "ID of the user's tenant")
+	fs.StringVar(&user.Name, "name", "", "User name")
+	fs.StringVar(&user.Email, "email", "", "User email address")
+	fs.StringVar(&user.Locale, "locale", "", "Email locale, e.g. fr (default: the tenant's)")
+	fs.BoolVar(&user.Activated, "activate", false, "Create the user already activated")
+	password := fs.String("password", "", "User password")
+	passwordStdin := fs.Bool("password-stdin", false, "Read the password from stdin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	plaintext, err := cli.readPassword(*password, *passwordStdin)
+	if err != nil {
+		return err
+	}
+	if err := user.Password.Set(plaintext); err != nil {
+		return err
+	}
+	v := validator.New()
+	v.Check(user.TenantID > 0, "tenant", "must be provided")
+	if data.ValidateUser(v, user); !v.Valid() {
+		return validationError(v.Errors)
+	}
+	if err := cli.models.Users.Insert(ctx, user); err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateEmail):
+			return validationError(map[string]string{"email": "a user with this email address already exists"})
+		case errors.Is(err, data.ErrInvalidTenantID):
+			return validationError(map[string]string{"tenant": "the specified tenant does not exist"})
+		default:
+			return err
+		}
+	}
+	return cli.print(envelope{"user": adminUserView(user)}, "created user %d (%s) in tenant %d, activated: %t", user.ID, user.Email, user.TenantID, user.Activated)
+}
+
+func (cli *adminCLI) activateUser(ctx context.Context, args []string) error {
+	fs := cli.flagSet("user activate")
+	email := fs.String("email", "", "User email address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var user *data.User
+	// activate the user and drop its pending activation tokens together
+	err := cli.models.RunInTx(ctx, func(tx data.Models) error {
+		var err error
+		user, err = cli.getUser(ctx, tx, *email)
+		if err != nil || user.Activated {
+			return err
+		}
+		user.Activated = true
+		if err := tx.Users.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		return tx.Tokens.DeleteAllForUser(ctx, data.ScopeActivation, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+	return cli.print(envelope{"user": adminUserView(user)}, "activated user %d (%s)", user.ID, user.Email)
+}
+
+func (cli *adminCLI) resetPassword(ctx context.Context, args []string) error {
+	fs := cli.flagSet("user reset-password")
+	email := fs.String("email", "", "User email address")
+	password := fs.String("password", "", "New password")
+	passwordStdin := fs.Bool("password-stdin", false, "Read the new password from stdin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	plaintext, err := cli.readPassword(*password, *passwordStdin)
+	if err != nil {
+		return err
+	}
+	v := validator.New()
+	if data.ValidatePasswordPlaintext(v, plaintext); !v.Valid() {
+		return validationError(v.Errors)
+	}
+	var user *data.User
+	// the new password also signs the user out everywhere
+	err = cli.models.RunInTx(ctx, func(tx data.Models) error {
+		var err error
+		user, err = cli.getUser(ctx, tx, *email)
+		if err != nil {
+			return err
+		}
+		if err := user.Password.Set(plaintext); err != nil {
+			return err
+		}
+		if err := tx.Users.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		for _, scope := range []string{data.ScopeAuthentication, data.ScopePasswordReset} {
+			if err := tx.Tokens.DeleteAllForUser(ctx, scope, user.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return cli.print(envelope{"user": adminUserView(user)}, "reset the password of user %d (%s)", user.ID, user.Email)
+}
+
+func (cli *adminCLI) setLocale(ctx context.Context, args []string) error {
+	fs := cli.flagSet("user set-locale")
+	email := fs.String("email", "", "User email address")
+	locale := fs.String("locale", "", "Email locale, e.g. fr (default: the tenant's)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	v := validator.New()
+	if data.ValidateLocale(v, *locale); !v.Valid() {
+		return validationError(v.Errors)
+	}
+	user, err := cli.getUser(ctx, cli.models, *email)
+	if err != nil {
+		return err
+	}
+	user.Locale = *locale
+	if err := cli.models.Users.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	return cli.print(envelope{"user": adminUserView(user)}, "set the locale of user %d (%s) to %q", user.ID, user.Email, user.Locale)
+}
+
+func (cli *adminCLI) listPermissions(ctx context.Context, args []string) error {
+	fs := cli.flagSet("permission list")
+	email := fs.String("email", "", "User email address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	user, err := cli.getUser(ctx, cli.models, *email)
+	if err != nil {
+		return err
+	}
+	permissions, err := cli.models.Permissions.GetAllPermissionsForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if permissions == nil {
+		permissions = data.Permissions{}
+	}
+	return cli.print(envelope{"user_id": user.ID, "permissions": permissions}, "%s: %s", user.Email, strings.Join(permissions, " "))
+}
+
+func (cli *adminCLI) grantPermissions(ctx context.Context, args []string) error {
+	fs := cli.flagSet("permission grant")
+	email := fs.String("email", "", "User email address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	codes, err := permissionCodes(fs.Args())
+	if err != nil {
+		return err
+	}
+	user, err := cli.getUser(ctx, cli.models, *email)
+	if err != nil {
+		return err
+	}
+	permission, err := cli.models.Permissions.AddPermissionsForUser(ctx, user.ID, codes...)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicatePermission):
+			return fmt.Errorf("%s already has one of the permissions %s", user.Email, strings.Join(codes, " "))
+		case errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("none of the permissions %s exist", strings.Join(codes, " "))
+		default:
+			return err
+		}
+	}
+	return cli.print(envelope{"permissions": permission}, "granted %s to %s", strings.Join(codes, " "), user.Email)
+}
+
+func (cli *adminCLI) revokePermissions(ctx context.Context, args []string) error {
+	fs := cli.flagSet("permission revoke")
+	email := fs.String("email", "", "User email address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	codes, err := permissionCodes(fs.Args())
+	if err != nil {
+		return err
+	}
+	user, err := cli.getUser(ctx, cli.models, *email)
+	if err != nil {
+		return err
+	}
+	err = cli.models.RunInTx(ctx, func(tx data.Models) error {
+		for _, code := range codes {
+			if _, err := tx.Permissions.DeletePermissionsForUser(ctx, user.ID, code); err != nil {
+				if errors.Is(err, data.ErrPermissionNotFound) {
+					return fmt.Errorf("%s does not have the permission %s", user.Email, code)
+				}
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return cli.print(envelope{"user_id": user.ID, "revoked": codes}, "revoked %s from %s", strings.Join(codes, " "), user.Email)
+}
+
+func (cli *adminCLI) revokeTokens(ctx context.Context, args []string) error {
+	fs := cli.flagSet("token revoke")
+	email := fs.String("email", "", "User email address")
+	scope := fs.String("scope", data.ScopeAuthentication, "Token scope to revoke, or all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	scopes := []string{*scope}
+	if *scope == "all" {
+		scopes = tokenScopes
+	} else if !slices.Contains(tokenScopes, *scope) {
+		return fmt.Errorf("unknown token scope %q", *scope)
+	}
+	user, err := cli.getUser(ctx, cli.models, *email)
+	if err != nil {
+		return err
+	}
+	err = cli.models.RunInTx(ctx, func(tx data.Models) error {
+		for _, scope := range scopes {
+			if err := tx.Tokens.DeleteAllForUser(ctx, scope, user.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return cli.print(envelope{"user_id": user.ID, "scopes": scopes}, "revoked the %s tokens of %s", strings.Join(scopes, ", "), user.Email)
+}
+
+// importExchangeRates() loads the exchange rates of a CSV or JSON file, the format is taken
+// from the file extension unless -format is set.
+func (cli *adminCLI) importExchangeRates(ctx context.Context, args []string) error {
+	fs := cli.flagSet("rates import")
+	format := fs.String("format", "", "File format, csv or json (default from the file extension)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("rates import takes the path of a single file")
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	rates, err := data.ReadExchangeRates(file, *format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	v := validator.New()
+	if data.ValidateExchangeRates(v, rates); !v.Valid() {
+		return validationError(v.Errors)
+	}
+	if err := saveExchangeRates(ctx, cli.models, rates); err != nil {
+		return err
+	}
+	return cli.print(envelope{"imported": len(rates)}, "imported %d exchange rates from %s", len(rates), path)
+}
+
+func (cli *adminCLI) listExchangeRates(ctx context.Context, args []string) error {
+	fs := cli.flagSet("rates list")
+	date := fs.String("date", "", "List the rates in effect on this date (default today)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	on, err := parseAdminDate(*date)
+	if err != nil {
+		return err
+	}
+	rates, err := cli.models.ExchangeRates.GetExchangeRatesOnDate(ctx, on)
+	if err != nil {
+		return err
+	}
+	lines := make([]string, 0, len(rates))
+	for _, rate := range rates {
+		lines = append(lines, fmt.Sprintf("%s %s/%s %s", rate.RateDate.Format(time.DateOnly), rate.BaseCurrency, rate.QuoteCurrency, rate.Rate))
+	}
+	return cli.print(envelope{"exchange_rates": rates}, "%s", strings.Join(lines, "\n"))
+}
+
+func (cli *adminCLI) stats(ctx context.Context, args []string) error {
+	fs := cli.flagSet("stats")
+	currency := fs.String("currency", data.DefaultTenantCurrency, "Currency the values are converted to")
+	date := fs.String("date", "", "Convert at the exchange rates in effect on this date (default today)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !data.IsCurrency(*currency) {
+		return fmt.Errorf("%q is not an ISO 4217 currency code", *currency)
+	}
+	on, err := parseAdminDate(*date)
+	if err != nil {
+		return err
+	}
+	converter, err := newCurrencyConverter(ctx, cli.models, *currency, on)
+	if err != nil {
+		return err
+	}
+	stats, err := cli.models.TradeLeads.AdminGetTradeLeadStats(ctx, converter)
+	if err != nil {
+		return err
+	}
+	return cli.print(envelope{"stats": stats, "currency": converter.Currency}, "total leads: %s\nverified leads: %s\ntotal verified value: %s %s",
+		stats.TotalLeads, stats.VerifiedLeads, stats.TotalVerifiedValue, converter.Currency)
+}
+
+// parseAdminDate() parses a YYYY-MM-DD date flag, the empty value is today.
+func parseAdminDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now().UTC(), nil
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, want YYYY-MM-DD", value)
+	}
+	return date, nil
+}
+
+// adminUserView() returns the user fields shown to operators, User hides the
+// activation state from API clients.
+func adminUserView(user *data.User) envelope {
+	return envelope{
+		"id":         user.ID,
+		"tenant_id":  user.TenantID,
+		"name":       user.Name,
+		"email":      user.Email,
+		"activated":  user.Activated,
+		"locale":     user.Locale,
+		"created_at": user.CreatedAt,
+	}
+}
+
+// permissionCodes() validates the permission codes given as arguments.
+func permissionCodes(codes []string) ([]string, error) {
+	v := validator.New()
+	v.Check(len(codes) != 0, "codes", "must be provided")
+	for _, code := range codes {
+		data.ValidatePermission(v, code)
+	}
+	if !v.Valid() {
+		return nil, validationError(v.Errors)
+	}
+	return codes, nil
+}
+
+// validationError() turns the errors of a validator into a single error, ordered by key.
+func validationError(errs map[string]string) error {
+	keys := make([]string, 0, len(errs))
+	for key := range errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	messages := make([]string, 0, len(keys))
+	for _, key := range keys {
+		messages = append(messages, key+" "+errs[key])
+	}
+	return fmt.Errorf("invalid input: %s", strings.Join(messages, "; "))
+}
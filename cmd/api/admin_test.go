@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+)
+
+// runAdmin() runs an admin command against models and returns its output.
+func runAdmin(t *testing.T, models data.Models, input string, args ...string) (string, error) {
+	t.Helper()
+	var output bytes.Buffer
+	cli := &adminCLI{models: models, input: strings.NewReader(input), output: &output}
+	err := cli.run(context.Background(), args)
+	return output.String(), err
+}
+
+func TestAdminCLI(t *testing.T) {
+	models := data.NewMemoryModels()
+	ctx := context.Background()
+
+	mustRun := func(t *testing.T, input string, args ...string) string {
+		t.Helper()
+		output, err := runAdmin(t, models, input, args...)
+		if err != nil {
+			t.Fatalf("admin %s: %v", strings.Join(args, " "), err)
+		}
+		return output
+	}
+
+	// bootstrap a tenant with an activated admin, the JSON output feeds the next command
+	var created struct {
+		Tenant data.Tenant `json:"tenant"`
+	}
+	output := mustRun(t, "", "tenant", "create", "-json", "-name=Acme", "-email=ops@acme.test")
+	if err := json.Unmarshal([]byte(output), &created); err != nil {
+		t.Fatalf("tenant create -json output %q: %v", output, err)
+	}
+	if created.Tenant.ID == 0 || created.Tenant.Name != "Acme" {
+		t.Fatalf("tenant create -json = %+v", created.Tenant)
+	}
+	tenantID := created.Tenant.ID
+	mustRun(t, "", "user", "create", "-tenant", strconv.FormatInt(tenantID, 10), "-name=Ann", "-email=ann@acme.test", "-password=pa55word!", "-activate")
+	mustRun(t, "", "permission", "grant", "-email=ann@acme.test", data.PermissionAdminRead, data.PermissionAdminWrite)
+
+	user, err := models.Users.GetByEmail(ctx, "ann@acme.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.Activated {
+		t.Error("user create -activate left the user inactive")
+	}
+	if ok, _ := user.Password.Matches("pa55word!"); !ok {
+		t.Error("user create did not set the password")
+	}
+	permissions, err := models.Permissions.GetAllPermissionsForUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !permissions.Include(data.PermissionAdminRead) || !permissions.Include(data.PermissionAdminWrite) {
+		t.Errorf("permissions after grant = %v", permissions)
+	}
+
+	t.Run("Activate", func(t *testing.T) {
+		mustRun(t, "", "user", "create", "-tenant", strconv.FormatInt(tenantID, 10), "-name=Bob", "-email=bob@acme.test", "-password=pa55word!")
+		bob, _ := models.Users.GetByEmail(ctx, "bob@acme.test")
+		if bob.Activated {
+			t.Fatal("user create without -activate activated the user")
+		}
+		output := mustRun(t, "", "user", "activate", "-email=bob@acme.test")
+		if !strings.Contains(output, "activated user") {
+			t.Errorf("user activate output = %q", output)
+		}
+		bob, _ = models.Users.GetByEmail(ctx, "bob@acme.test")
+		if !bob.Activated {
+			t.Error("user activate left the user inactive")
+		}
+	})
+
+	t.Run("Reset password revokes sessions", func(t *testing.T) {
+		token, err := models.Tokens.New(ctx, user.ID, data.DefaultTokenExpiryTime, data.ScopeAuthentication)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustRun(t, "n3w-secret\n", "user", "reset-password", "-email=ann@acme.test", "-password-stdin")
+		updated, _ := models.Users.GetByEmail(ctx, "ann@acme.test")
+		if ok, _ := updated.Password.Matches("n3w-secret"); !ok {
+			t.Error("reset-password did not change the password")
+		}
+		if _, err := models.Users.GetForToken(ctx, data.ScopeAuthentication, token.Plaintext); !errors.Is(err, data.ErrGeneralRecordNotFound) {
+			t.Errorf("GetForToken() after reset-password error = %v, want %v", err, data.ErrGeneralRecordNotFound)
+		}
+	})
+
+	t.Run("Set locale", func(t *testing.T) {
+		output := mustRun(t, "", "user", "set-locale", "-email=ann@acme.test", "-locale=fr-FR")
+		if !strings.Contains(output, `to "fr-FR"`) {
+			t.Errorf("user set-locale output = %q", output)
+		}
+		updated, _ := models.Users.GetByEmail(ctx, "ann@acme.test")
+		if updated.Locale != "fr-FR" {
+			t.Errorf("locale after set-locale = %q, want fr-FR", updated.Locale)
+		}
+		// without -locale the user's emails follow the tenant again
+		mustRun(t, "", "user", "set-locale", "-email=ann@acme.test")
+		updated, _ = models.Users.GetByEmail(ctx, "ann@acme.test")
+		if updated.Locale != "" {
+			t.Errorf("locale after set-locale without -locale = %q, want none", updated.Locale)
+		}
+	})
+
+	t.Run("Revoke tokens", func(t *testing.T) {
+		token, err := models.Tokens.New(ctx, user.ID, data.DefaultTokenExpiryTime, data.ScopeAuthentication)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustRun(t, "", "token", "revoke", "-email=ann@acme.test")
+		if _, err := models.Users.GetForToken(ctx, data.ScopeAuthentication, token.Plaintext); !errors.Is(err, data.ErrGeneralRecordNotFound) {
+			t.Errorf("GetForToken() after token revoke error = %v, want %v", err, data.ErrGeneralRecordNotFound)
+		}
+	})
+
+	t.Run("List and revoke permissions", func(t *testing.T) {
+		mustRun(t, "", "permission", "revoke", "-email=ann@acme.test", data.PermissionAdminWrite)
+		var listed struct {
+			Permissions []string `json:"permissions"`
+		}
+		output := mustRun(t, "", "permission", "list", "-json", "-email=ann@acme.test")
+		if err := json.Unmarshal([]byte(output), &listed); err != nil {
+			t.Fatal(err)
+		}
+		if len(listed.Permissions) != 1 || listed.Permissions[0] != data.PermissionAdminRead {
+			t.Errorf("permission list = %v, want [%s]", listed.Permissions, data.PermissionAdminRead)
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		output := mustRun(t, "", "stats")
+		if !strings.Contains(output, "total leads: 0") {
+			t.Errorf("stats output = %q", output)
+		}
+	})
+
+	t.Run("Exchange rates", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rates.csv")
+		csv := "rate_date,base_currency,quote_currency,rate\n2026-01-30,USD,KES,129.25\n2026-01-31,USD,KES,129.5\n2026-01-31,EUR,USD,1.08\n"
+		if err := os.WriteFile(path, []byte(csv), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if output := mustRun(t, "", "rates", "import", path); !strings.Contains(output, "imported 3 exchange rates") {
+			t.Errorf("rates import output = %q", output)
+		}
+		output := mustRun(t, "", "rates", "list", "-date=2026-01-30")
+		if !strings.Contains(output, "2026-01-30 USD/KES 129.25") || strings.Contains(output, "EUR") {
+			t.Errorf("rates list output = %q, want the USD/KES rate of the day alone", output)
+		}
+		if output := mustRun(t, "", "stats", "-currency=KES", "-date=2026-02-01"); !strings.Contains(output, "total verified value: 0 KES") {
+			t.Errorf("stats -currency output = %q", output)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		tests := []struct {
+			name string
+			args []string
+			want string
+		}{
+			{name: "Unknown command", args: []string{"user", "delete"}, want: "unknown admin command"},
+			{name: "Missing command", args: nil, want: "unknown admin command"},
+			{name: "Duplicate email", args: []string{"user", "create", "-tenant", strconv.FormatInt(tenantID, 10), "-name=Ann", "-email=ann@acme.test", "-password=pa55word!"}, want: "already exists"},
+			{name: "Unknown tenant", args: []string{"user", "create", "-tenant=999", "-name=Cy", "-email=cy@acme.test", "-password=pa55word!"}, want: "tenant does not exist"},
+			{name: "Short password", args: []string{"user", "reset-password", "-email=ann@acme.test", "-password=short"}, want: "password must be at least 8 bytes long"},
+			{name: "Invalid locale", args: []string{"user", "set-locale", "-email=ann@acme.test", "-locale=French"}, want: "locale must be a locale"},
+			{name: "Unknown user", args: []string{"user", "activate", "-email=nobody@acme.test"}, want: "no user with the email address"},
+			{name: "Invalid permission", args: []string{"permission", "grant", "-email=ann@acme.test", "admin"}, want: "permission:code"},
+			{name: "Duplicate permission", args: []string{"permission", "grant", "-email=ann@acme.test", data.PermissionAdminRead}, want: "already has"},
+			{name: "Missing permission", args: []string{"permission", "revoke", "-email=ann@acme.test", data.PermissionTenantAdmin}, want: "does not have"},
+			{name: "Unknown scope", args: []string{"token", "revoke", "-email=ann@acme.test", "-scope=session"}, want: "unknown token scope"},
+			{name: "Unknown rates file", args: []string{"rates", "import", "rates.csv"}, want: "no such file"},
+			{name: "No rates file", args: []string{"rates", "import"}, want: "path of a single file"},
+			{name: "Unknown stats currency", args: []string{"stats", "-currency=usd"}, want: "not an ISO 4217 currency code"},
+			{name: "Invalid stats date", args: []string{"stats", "-date=31/01/2026"}, want: "want YYYY-MM-DD"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := runAdmin(t, models, "", tt.args...)
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("admin %s error = %v, want it to contain %q", strings.Join(tt.args, " "), err, tt.want)
+				}
+			})
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"go.uber.org/zap"
 )
 
 // Define a custom contextKey type, with the underlying type string.
@@ -15,10 +16,28 @@ type contextKey string
 // in the request context.
 const userContextKey = contextKey("user")
 
+// Keys for the request ID and the per-request details collected for the access log.
+const (
+	requestIDContextKey   = contextKey("request_id")
+	requestInfoContextKey = contextKey("request_info")
+)
+
+// requestInfo collects details that are only known further down the middleware chain,
+// such as the authenticated user, so the access log written by logRequest() can include
+// them. It is shared by pointer because every middleware works on a copy of the request.
+type requestInfo struct {
+	userID   int64
+	tenantID int64
+}
+
 // The contextSetUser() method returns a new copy of the request with the provided
 // User struct added to the context. Note that we use our userContextKey constant as the
 // key.
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
+	if info, ok := r.Context().Value(requestInfoContextKey).(*requestInfo); ok && !user.IsAnonymous() {
+		info.userID = user.ID
+		info.tenantID = user.TenantID
+	}
 	ctx := context.WithValue(r.Context(), userContextKey, user)
 	//app.log.PrintInfo("set user in request context", map[string]string{"name": user.Name, "email": user.Email})
 	return r.WithContext(ctx)
@@ -35,3 +54,31 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+// contextSetRequestID() returns a new copy of the request with the request ID added to
+// the context.
+func (app *application) contextSetRequestID(r *http.Request, requestID string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, requestID)
+	return r.WithContext(ctx)
+}
+
+// contextGetRequestID() retrieves the request ID from a context, or "" outside of a request.
+func contextGetRequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
+
+// contextLogger() returns the application logger with the request ID of the context
+// attached, so every line logged while handling a request can be correlated. Background
+// tasks started by a request keep the ID as long as they are passed its context.
+func (app *application) contextLogger(ctx context.Context) *zap.Logger {
+	if requestID := contextGetRequestID(ctx); requestID != "" {
+		return app.logger.With(zap.String("request_id", requestID))
+	}
+	return app.logger
+}
+
+// requestLogger() is a shorthand for contextLogger(r.Context()).
+func (app *application) requestLogger(r *http.Request) *zap.Logger {
+	return app.contextLogger(r.Context())
+}
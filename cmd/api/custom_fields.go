@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+// getCustomFieldsHandler() returns the custom field schema of the user's tenant. Every
+// member of the tenant can read it, as they need it to fill in their leads.
+func (app *application) getCustomFieldsHandler(w http.ResponseWriter, r *http.Request) {
+	fields, err := app.models.CustomFields.GetCustomFieldsByTenantID(r.Context(), app.contextGetUser(r).TenantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"custom_fields": fields}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createCustomFieldHandler() lets a tenant owner add a custom field to their tenant's schema.
+func (app *application) createCustomFieldHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Key      string   `json:"key"`
+		Label    string   `json:"label"`
+		Type     string   `json:"type"`
+		Required bool     `json:"required"`
+		Options  []string `json:"options"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	field := &data.CustomField{
+		TenantID: app.contextGetUser(r).TenantID,
+		Key:      input.Key,
+		Label:    input.Label,
+		Type:     input.Type,
+		Required: input.Required,
+		Options:  input.Options,
+	}
+	v := validator.New()
+	if data.ValidateCustomField(v, field); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if err := app.models.CustomFields.CreateCustomField(r.Context(), field); err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCustomField):
+			v.AddError("key", "a custom field with this key already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err := app.writeJSON(w, http.StatusCreated, envelope{"custom_field": field}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateCustomFieldHandler() updates the label, required flag and options of a custom field.
+func (app *application) updateCustomFieldHandler(w http.ResponseWriter, r *http.Request) {
+	fieldID, err := app.readIDParam(r, "fieldID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	versionID, err := app.readIDParam(r, "versionID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	var input struct {
+		Label    *string  `json:"label"`
+		Required *bool    `json:"required"`
+		Options  []string `json:"options"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	field, err := app.models.CustomFields.GetCustomFieldByID(r.Context(), app.contextGetUser(r).TenantID, fieldID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if input.Label != nil {
+		field.Label = *input.Label
+	}
+	if input.Required != nil {
+		field.Required = *input.Required
+	}
+	if input.Options != nil {
+		field.Options = input.Options
+	}
+	v := validator.New()
+	if data.ValidateCustomField(v, field); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// Validate versionID can be safely converted to int32
+	if versionID > 2147483647 {
+		app.badRequestResponse(w, r, errors.New("version ID out of range"))
+		return
+	}
+	if err := app.models.CustomFields.UpdateCustomField(r.Context(), field, int32(versionID)); err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"custom_field": field}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteCustomFieldHandler() removes a custom field from the tenant's schema.
+func (app *application) deleteCustomFieldHandler(w http.ResponseWriter, r *http.Request) {
+	fieldID, err := app.readIDParam(r, "fieldID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	err = app.models.CustomFields.DeleteCustomField(r.Context(), app.contextGetUser(r).TenantID, fieldID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "custom field successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
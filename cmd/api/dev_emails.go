@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/mailer"
+	"github.com/go-chi/chi"
+)
+
+// devRoutesEnabled() reports whether the development endpoints are served, only in the
+// development environment with the memory mail transport.
+func (app *application) devRoutesEnabled() bool {
+	return app.config.env == "development" && app.mailCapture != nil
+}
+
+// devRoutes() returns the development-only routes for browsing the captured emails.
+func (app *application) devRoutes() chi.Router {
+	devRoutes := chi.NewRouter()
+	devRoutes.Get("/emails", app.listCapturedEmailsHandler)
+	devRoutes.Delete("/emails", app.deleteCapturedEmailsHandler)
+	devRoutes.Get("/emails/{emailID:[0-9]+}", app.renderCapturedEmailHandler)
+	return devRoutes
+}
+
+// listCapturedEmailsHandler() lists the captured emails, newest first, without their
+// bodies.
+func (app *application) listCapturedEmailsHandler(w http.ResponseWriter, r *http.Request) {
+	// the empty body fields shadow those of the embedded email, leaving them out
+	type emailSummary struct {
+		ID int64 `json:"id"`
+		mailer.Email
+		PlainBody string `json:"plain_body,omitempty"`
+		HTMLBody  string `json:"html_body,omitempty"`
+	}
+	captured := app.mailCapture.Emails()
+	emails := make([]emailSummary, 0, len(captured))
+	for _, email := range captured {
+		emails = append(emails, emailSummary{ID: email.ID, Email: email.Email})
+	}
+	err := app.writeJSON(w, http.StatusOK, envelope{"emails": emails}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// renderCapturedEmailHandler() renders a captured email the way a mail client would show
+// it: the HTML body by default, the plain-text body with ?format=text or the whole email
+// as JSON with ?format=json.
+func (app *application) renderCapturedEmailHandler(w http.ResponseWriter, r *http.Request) {
+	emailID, err := app.readIDParam(r, "emailID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	email, ok := app.mailCapture.Email(emailID)
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+	app.writeEmail(w, r, app.readString(r.URL.Query(), "format", "html"), &email.Email, envelope{"email": email})
+}
+
+// deleteCapturedEmailsHandler() drops all captured emails.
+func (app *application) deleteCapturedEmailsHandler(w http.ResponseWriter, r *http.Request) {
+	app.mailCapture.Reset()
+	err := app.writeJSON(w, http.StatusOK, envelope{"message": "captured emails deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
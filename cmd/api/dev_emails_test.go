@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/mailer"
+	"go.uber.org/zap"
+)
+
+func TestDevEmailRoutes(t *testing.T) {
+	capture := mailer.NewMemoryTransport(10)
+	app := &application{
+		config:      config{env: "development"},
+		logger:      zap.NewNop(),
+		mailer:      mailer.New(capture, "no-reply@leadhub.test"),
+		mailCapture: capture,
+	}
+	if !app.devRoutesEnabled() {
+		t.Fatal("dev routes disabled in development with the memory transport")
+	}
+	data := map[string]any{"userName": "Ann", "loginURL": "https://leadhub.test/login"}
+	if _, err := app.mailer.Send(context.Background(), "ann@acme.test", "", "user_succesful_activation.tmpl", data, mailer.Branding{}); err != nil {
+		t.Fatal(err)
+	}
+	router := app.devRoutes()
+	get := func(method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+
+	rr := get(http.MethodGet, "/emails")
+	var listed struct {
+		Emails []map[string]any `json:"emails"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Emails) != 1 || listed.Emails[0]["to"] != "ann@acme.test" {
+		t.Fatalf("listed emails = %v", listed.Emails)
+	}
+	if _, ok := listed.Emails[0]["html_body"]; ok {
+		t.Error("the listing includes the email bodies")
+	}
+	if listed.Emails[0]["id"] != float64(1) {
+		t.Fatalf("email id = %v, want 1", listed.Emails[0]["id"])
+	}
+
+	tests := []struct {
+		name        string
+		target      string
+		status      int
+		contentType string
+		want        string
+	}{
+		{name: "HTML", target: "/emails/1", status: http.StatusOK, contentType: "text/html", want: "<html"},
+		{name: "Text", target: "/emails/1?format=text", status: http.StatusOK, contentType: "text/plain", want: "Hi Ann"},
+		{name: "JSON", target: "/emails/1?format=json", status: http.StatusOK, contentType: "application/json", want: `"plain_body"`},
+		{name: "Unknown format", target: "/emails/1?format=pdf", status: http.StatusBadRequest},
+		{name: "Unknown email", target: "/emails/99", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := get(http.MethodGet, tt.target)
+			if rr.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.status)
+			}
+			if !strings.HasPrefix(rr.Header().Get("Content-Type"), tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", rr.Header().Get("Content-Type"), tt.contentType)
+			}
+			if !strings.Contains(rr.Body.String(), tt.want) {
+				t.Errorf("body does not contain %q", tt.want)
+			}
+		})
+	}
+
+	if rr := get(http.MethodDelete, "/emails"); rr.Code != http.StatusOK {
+		t.Fatalf("DELETE /emails status = %d", rr.Code)
+	}
+	if emails := capture.Emails(); len(emails) != 0 {
+		t.Errorf("emails after DELETE = %v", emails)
+	}
+}
+
+func TestDevRoutesEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		capture *mailer.MemoryTransport
+		want    bool
+	}{
+		{name: "Development with capture", env: "development", capture: mailer.NewMemoryTransport(1), want: true},
+		{name: "Development without capture", env: "development"},
+		{name: "Production with capture", env: "production", capture: mailer.NewMemoryTransport(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &application{config: config{env: tt.env}, mailCapture: tt.capture}
+			if got := app.devRoutesEnabled(); got != tt.want {
+				t.Errorf("devRoutesEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
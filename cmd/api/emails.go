@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/mailer"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+	"github.com/go-chi/chi"
+)
+
+// adminGetAllOutboxEmailsHandler() lists the emails in the outbox, newest first. They can
+// be filtered by tenant_id, status and recipient.
+func (app *application) adminGetAllOutboxEmailsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		TenantID  int64
+		Status    string
+		Recipient string
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.TenantID = int64(app.readInt(qs, "tenant_id", 0, v))
+	input.Status = app.readString(qs, "status", "")
+	input.Recipient = app.readString(qs, "recipient", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	// emails are always listed newest first
+	input.Filters.Sort = ""
+	input.Filters.SortSafelist = []string{""}
+	data.ValidateEmailStatus(v, input.Status)
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	emails, metadata, err := app.models.Emails.AdminGetAllOutboxEmails(r.Context(), input.TenantID, input.Status, input.Recipient, input.Filters)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"emails": emails, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminGetOutboxEmailHandler() returns an email of the outbox with its delivery status.
+func (app *application) adminGetOutboxEmailHandler(w http.ResponseWriter, r *http.Request) {
+	emailID, err := app.readIDParam(r, "emailID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	email, err := app.models.Emails.GetOutboxEmailByID(r.Context(), emailID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"email": email}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminResendOutboxEmailHandler() queues a failed email to be sent again, with the same
+// template data. Emails that are queued or were sent can't be re-sent.
+func (app *application) adminResendOutboxEmailHandler(w http.ResponseWriter, r *http.Request) {
+	emailID, err := app.readIDParam(r, "emailID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	var email *data.OutboxEmail
+	err = app.models.RunInTx(r.Context(), func(tx data.Models) error {
+		err := tx.Emails.RequeueOutboxEmail(r.Context(), emailID)
+		if err != nil {
+			return err
+		}
+		email, err = tx.Emails.GetOutboxEmailByID(r.Context(), emailID)
+		if err != nil {
+			return err
+		}
+		return app.enqueueOutboxEmail(r.Context(), tx, email)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrOutboxEmailNotFailed):
+			// tell a missing email apart from one that didn't fail
+			if _, getErr := app.models.Emails.GetOutboxEmailByID(r.Context(), emailID); errors.Is(getErr, data.ErrGeneralRecordNotFound) {
+				app.notFoundResponse(w, r)
+				return
+			}
+			app.errorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.jobs.Notify()
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"email": email}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listEmailTemplatesHandler() lists the email templates with the locales each of them is
+// translated to.
+func (app *application) listEmailTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	templates, err := mailer.Templates()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"templates": templates, "locales": mailer.SupportedLocales}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// previewEmailTemplateHandler() renders an email template without sending it. A GET renders
+// the sample data and takes the locale, tenant_id and format as query parameters, which
+// allows opening the HTML body in a browser. A POST takes them as JSON together with the
+// data, which replaces the sample data key by key. The tenant's branding and locale are
+// used when a tenant_id is given.
+func (app *application) previewEmailTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Locale   string         `json:"locale"`
+		TenantID int64          `json:"tenant_id"`
+		Format   string         `json:"format"`
+		Data     map[string]any `json:"data"`
+	}
+	v := validator.New()
+	if r.Method == http.MethodPost {
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	} else {
+		qs := r.URL.Query()
+		input.Locale = app.readString(qs, "locale", "")
+		input.TenantID = int64(app.readInt(qs, "tenant_id", 0, v))
+		input.Format = app.readString(qs, "format", "")
+	}
+	if input.Format == "" {
+		input.Format = "json"
+	}
+	v.Check(input.Locale == "" || mailer.IsSupportedLocale(input.Locale), "locale", "must be one of the supported locales")
+	v.Check(input.TenantID >= 0, "tenant_id", "must not be negative")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	templateData := mailer.SampleData()
+	maps.Copy(templateData, input.Data)
+	branding, locale := mailer.DefaultBranding(), input.Locale
+	if input.TenantID > 0 {
+		_, err := app.models.Tenants.GetTenantByID(r.Context(), input.TenantID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrGeneralRecordNotFound):
+				v.AddError("tenant_id", "tenant not found")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		var settings *data.TenantSettings
+		branding, settings = app.tenantBranding(r.Context(), input.TenantID)
+		locale = mailer.ResolveLocale(input.Locale, settings.Locale)
+	}
+	email, err := app.mailer.Preview("", locale, chi.URLParam(r, "templateName"), templateData, branding)
+	if err != nil {
+		switch {
+		case errors.Is(err, mailer.ErrUnknownTemplate):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, mailer.ErrRenderTemplate):
+			// e.g. supplied data of the wrong type
+			app.badRequestResponse(w, r, err)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.writeEmail(w, r, input.Format, email, envelope{"email": email})
+}
+
+// writeEmail() writes a rendered email the way a mail client would show it: the HTML body
+// with format "html", the plain-text body with "text" or the given envelope with "json".
+func (app *application) writeEmail(w http.ResponseWriter, r *http.Request, format string, email *mailer.Email, env envelope) {
+	switch format {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(email.HTMLBody))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(email.PlainBody))
+	case "json":
+		err := app.writeJSON(w, http.StatusOK, env, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+	default:
+		app.badRequestResponse(w, r, errors.New("format must be one of html, text or json"))
+	}
+}
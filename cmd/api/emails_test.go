@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/jobs"
+	"github.com/Blue-Davinci/leadhub-service/internal/mailer"
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+func TestEmailOutbox(t *testing.T) {
+	ctx := context.Background()
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.NewMemoryModels(),
+	}
+	tenant := &data.Tenant{Name: "TradeHub KE", ContactEmail: "admin@tradehub.co.ke"}
+	if err := app.models.Tenants.CreateTenant(ctx, tenant); err != nil {
+		t.Fatal(err)
+	}
+	user := &data.User{TenantID: tenant.ID, Name: "Ann", Email: "ann@tradehub.co.ke", Locale: "fr"}
+	if err := user.Password.Set("pa55word123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Users.Insert(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	claimEmail := func(t *testing.T) (*data.Job, emailJob) {
+		t.Helper()
+		claimed, err := app.models.Jobs.ClaimJobs(ctx, "test-worker", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(claimed) != 1 {
+			t.Fatalf("claimed %d jobs, want 1", len(claimed))
+		}
+		var email emailJob
+		if err := jobs.Decode(claimed[0], &email); err != nil {
+			t.Fatal(err)
+		}
+		return claimed[0], email
+	}
+	outboxEmail := func(t *testing.T, id int64) *data.OutboxEmail {
+		t.Helper()
+		email, err := app.models.Emails.GetOutboxEmailByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return email
+	}
+
+	err := app.enqueueEmail(ctx, app.models, user, "user_welcome.tmpl", map[string]any{"userID": 1234567})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, email := claimEmail(t)
+	if email.OutboxID == 0 || email.Locale != "fr" {
+		t.Fatalf("email job = %+v, want it to reference the outbox", email)
+	}
+	if got := outboxEmail(t, email.OutboxID); got.Status != data.EmailStatusQueued || got.Recipient != user.Email {
+		t.Fatalf("outbox email = %+v", got)
+	}
+
+	// a failed attempt keeps the email queued until the job gives up on it
+	app.recordEmailAttempt(ctx, job, email.OutboxID, nil, errors.New("smtp unavailable"))
+	if got := outboxEmail(t, email.OutboxID); got.Status != data.EmailStatusQueued || got.Attempts != 1 || got.LastError != "smtp unavailable" {
+		t.Errorf("outbox email after a failed attempt = %+v", got)
+	}
+	job.Attempts = job.MaxAttempts
+	app.recordEmailAttempt(ctx, job, email.OutboxID, &mailer.Email{Subject: "Bienvenue"}, errors.New("smtp unavailable"))
+	if got := outboxEmail(t, email.OutboxID); got.Status != data.EmailStatusFailed || got.Subject != "Bienvenue" {
+		t.Errorf("outbox email after the last attempt = %+v", got)
+	}
+
+	router := chi.NewRouter()
+	router.Get("/emails/admin", app.adminGetAllOutboxEmailsHandler)
+	router.Get("/emails/admin/{emailID:[0-9]+}", app.adminGetOutboxEmailHandler)
+	router.Post("/emails/admin/{emailID:[0-9]+}/resend", app.adminResendOutboxEmailHandler)
+	request := func(method, target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+		return rr
+	}
+
+	rr := request(http.MethodGet, "/emails/admin?status=failed&recipient=ANN@tradehub.co.ke")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list status = %d: %s", rr.Code, rr.Body)
+	}
+	if strings.Contains(rr.Body.String(), "1234567") {
+		t.Error("the listing exposes the template data")
+	}
+	var listed struct {
+		Emails []data.OutboxEmail `json:"emails"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Emails) != 1 || listed.Emails[0].ID != email.OutboxID {
+		t.Errorf("failed emails = %+v", listed.Emails)
+	}
+	if rr := request(http.MethodGet, "/emails/admin?status=bounced"); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("unknown status filter: status = %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+
+	// re-sending queues the email again with the same template data
+	resendTarget := fmt.Sprintf("/emails/admin/%d/resend", email.OutboxID)
+	if rr := request(http.MethodPost, resendTarget); rr.Code != http.StatusAccepted {
+		t.Fatalf("resend status = %d: %s", rr.Code, rr.Body)
+	}
+	if got := outboxEmail(t, email.OutboxID); got.Status != data.EmailStatusQueued || got.LastError != "" {
+		t.Errorf("outbox email after the resend = %+v", got)
+	}
+	job, resent := claimEmail(t)
+	if resent.OutboxID != email.OutboxID || fmt.Sprint(resent.Data["userID"]) != "1234567" {
+		t.Errorf("re-sent email job = %+v", resent)
+	}
+	if rr := request(http.MethodPost, resendTarget); rr.Code != http.StatusConflict {
+		t.Errorf("resending a queued email: status = %d, want %d", rr.Code, http.StatusConflict)
+	}
+	if rr := request(http.MethodPost, "/emails/admin/999/resend"); rr.Code != http.StatusNotFound {
+		t.Errorf("resending an unknown email: status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	app.recordEmailAttempt(ctx, job, email.OutboxID, &mailer.Email{Subject: "Bienvenue", MessageID: "<1.abc@leadhub.test>"}, nil)
+	rr = request(http.MethodGet, fmt.Sprintf("/emails/admin/%d", email.OutboxID))
+	var shown struct {
+		Email data.OutboxEmail `json:"email"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &shown); err != nil {
+		t.Fatal(err)
+	}
+	if got := shown.Email; got.Status != data.EmailStatusSent || got.ProviderMessageID != "<1.abc@leadhub.test>" || got.SentAt == nil || got.Attempts != 3 {
+		t.Errorf("sent email = %+v", got)
+	}
+}
+
+func TestEmailTemplatePreview(t *testing.T) {
+	transport := mailer.NewMemoryTransport(1)
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.NewMemoryModels(),
+		mailer: mailer.New(transport, "LeadHub <no-reply@leadhub.test>"),
+	}
+	router := chi.NewRouter()
+	router.Get("/emails/admin/templates", app.listEmailTemplatesHandler)
+	router.Get("/emails/admin/templates/{templateName}/preview", app.previewEmailTemplateHandler)
+	router.Post("/emails/admin/templates/{templateName}/preview", app.previewEmailTemplateHandler)
+	request := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := request(http.MethodGet, "/emails/admin/templates", "")
+	var listed struct {
+		Templates []mailer.TemplateInfo `json:"templates"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if rr.Code != http.StatusOK || len(listed.Templates) == 0 {
+		t.Fatalf("templates = %d %s", rr.Code, rr.Body)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		body        string
+		status      int
+		contentType string
+		want        string
+	}{
+		{name: "Sample data", method: http.MethodGet, target: "/emails/admin/templates/user_welcome.tmpl/preview", status: http.StatusOK, contentType: "application/json", want: `"subject": "Welcome to LeadHub!"`},
+		{name: "HTML in a locale", method: http.MethodGet, target: "/emails/admin/templates/user_welcome.tmpl/preview?locale=fr-FR&format=html", status: http.StatusOK, contentType: "text/html", want: "Bonjour"},
+		{name: "Plain text", method: http.MethodGet, target: "/emails/admin/templates/user_succesful_activation.tmpl/preview?format=text", status: http.StatusOK, contentType: "text/plain", want: "Amina Wanjiru"},
+		{name: "Supplied data", method: http.MethodPost, target: "/emails/admin/templates/user_succesful_activation.tmpl/preview", body: `{"locale": "sw", "data": {"userName": "Baraka"}}`, status: http.StatusOK, contentType: "application/json", want: "Baraka"},
+		{name: "Unknown template", method: http.MethodGet, target: "/emails/admin/templates/missing.tmpl/preview", status: http.StatusNotFound},
+		{name: "Unsupported locale", method: http.MethodGet, target: "/emails/admin/templates/user_welcome.tmpl/preview?locale=de", status: http.StatusUnprocessableEntity},
+		{name: "Unknown tenant", method: http.MethodPost, target: "/emails/admin/templates/user_welcome.tmpl/preview", body: `{"tenant_id": 99}`, status: http.StatusUnprocessableEntity},
+		{name: "Unknown format", method: http.MethodGet, target: "/emails/admin/templates/user_welcome.tmpl/preview?format=pdf", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := request(tt.method, tt.target, tt.body)
+			if rr.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rr.Code, tt.status, rr.Body)
+			}
+			if !strings.HasPrefix(rr.Header().Get("Content-Type"), tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", rr.Header().Get("Content-Type"), tt.contentType)
+			}
+			if !strings.Contains(rr.Body.String(), tt.want) {
+				t.Errorf("body does not contain %q: %s", tt.want, rr.Body)
+			}
+		})
+	}
+	if emails := transport.Emails(); len(emails) != 0 {
+		t.Errorf("previews sent %d emails", len(emails))
+	}
+}
@@ -9,7 +9,7 @@ import (
 func (app *application) logError(r *http.Request, err error) {
 	// Use the PrintError() method to log the error message, and include the current
 	// request method and URL as properties in the log entry.
-	app.logger.Error(err.Error(), zap.String("request_method", r.Method), zap.String("request_url", r.URL.String()))
+	app.requestLogger(r).Error(err.Error(), zap.String("request_method", r.Method), zap.String("request_url", r.URL.String()))
 
 }
 
@@ -17,6 +17,10 @@ func (app *application) logError(r *http.Request, err error) {
 // messages to the client with a given status code.
 func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
 	env := envelope{"error": message}
+	// include the request ID so a client can quote it when reporting a problem
+	if requestID := contextGetRequestID(r.Context()); requestID != "" {
+		env["request_id"] = requestID
+	}
 	// Write the response using the writeJSON() helper. If this happens to return an
 	// error then log it, and fall back to sending the client an empty response with a
 	// 500 Internal Server Error status code.
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+// exchangeRatesMaxBytes caps the size of an uploaded exchange rates file.
+const exchangeRatesMaxBytes = 1_048_576
+
+// exchangeRateFormats maps the content types accepted by the exchange rates upload to the
+// format of the file.
+var exchangeRateFormats = map[string]string{
+	"text/csv":         data.ExchangeRateFormatCSV,
+	"application/json": data.ExchangeRateFormatJSON,
+}
+
+// getExchangeRatesHandler() returns the rate of every currency pair in effect on the date
+// query parameter, which defaults to today.
+func (app *application) getExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	date, _ := app.readTime(r.URL.Query(), "date", time.Now().UTC(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	rates, err := app.models.ExchangeRates.GetExchangeRatesOnDate(r.Context(), date)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"exchange_rates": rates}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// adminImportExchangeRatesHandler() loads the exchange rates of an uploaded CSV (text/csv) or
+// JSON (application/json) file. Rates of a currency pair and date that already exist are
+// replaced, and nothing is saved unless every rate of the file is valid.
+func (app *application) adminImportExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	format, ok := exchangeRateFormats[mediaType]
+	if !ok {
+		app.errorResponse(w, r, http.StatusUnsupportedMediaType, "the exchange rates must be sent as text/csv or application/json")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, exchangeRatesMaxBytes)
+	rates, err := data.ReadExchangeRates(r.Body, format)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			app.errorResponse(w, r, http.StatusRequestEntityTooLarge, "the exchange rates file must not be larger than 1MB")
+			return
+		}
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	if data.ValidateExchangeRates(v, rates); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = saveExchangeRates(r.Context(), app.models, rates)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"imported": len(rates)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// saveExchangeRates() saves validated rates in a single transaction.
+func saveExchangeRates(ctx context.Context, models data.Models, rates []*data.ExchangeRate) error {
+	return models.RunInTx(ctx, func(tx data.Models) error {
+		for _, rate := range rates {
+			if err := tx.ExchangeRates.SaveExchangeRate(ctx, rate); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// newCurrencyConverter() returns a converter to currency at the rates in effect on date.
+func newCurrencyConverter(ctx context.Context, models data.Models, currency string, date time.Time) (*data.CurrencyConverter, error) {
+	rates, err := models.ExchangeRates.GetExchangeRatesOnDate(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	return data.NewCurrencyConverter(currency, date, rates), nil
+}
+
+// readCurrencyConverter() reads the currency and rate_date query parameters of the stats
+// endpoints and loads the exchange rates in effect on rate_date, which defaults to today.
+// The currency defaults to the default currency of the tenant's settings, or to the
+// platform default when tenantID is 0. Invalid parameters are recorded in the Validator
+// and return a nil converter.
+func (app *application) readCurrencyConverter(r *http.Request, tenantID int64, v *validator.Validator) (*data.CurrencyConverter, error) {
+	qs := r.URL.Query()
+	date, _ := app.readTime(qs, "rate_date", time.Now().UTC(), v)
+	currency := app.readString(qs, "currency", "")
+	if currency == "" {
+		currency = data.DefaultTenantCurrency
+		if tenantID > 0 {
+			settings, err := app.models.Settings.GetTenantSettings(r.Context(), tenantID)
+			if err != nil {
+				return nil, err
+			}
+			currency = settings.DefaultCurrency
+		}
+	}
+	v.Check(data.IsCurrency(currency), "currency", "must be an ISO 4217 currency code such as KES")
+	if !v.Valid() {
+		return nil, nil
+	}
+	return newCurrencyConverter(r.Context(), app.models, currency, date)
+}
+
+// currencyConversionErrorResponse() responds to a failure to compute converted values. A
+// missing exchange rate is the caller's to fix by picking another currency or rate date,
+// so it is reported as a failed validation of the currency.
+func (app *application) currencyConversionErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, data.ErrExchangeRateNotFound) {
+		app.failedValidationResponse(w, r, map[string]string{"currency": err.Error()})
+		return
+	}
+	app.serverErrorResponse(w, r, err)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+func TestExchangeRateHandlers(t *testing.T) {
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.NewMemoryModels(),
+	}
+	router := chi.NewRouter()
+	router.Get("/exchange_rates", app.getExchangeRatesHandler)
+	router.Post("/exchange_rates/admin", app.adminImportExchangeRatesHandler)
+	upload := func(contentType, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/exchange_rates/admin", strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, r)
+		return rr
+	}
+
+	// rows padded so the file outgrows the size limit before the limit on rates
+	longRateRow := "2026-01-31,USD,KES,129.25" + strings.Repeat("0", 200) + "\n"
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{name: "CSV", contentType: "text/csv; charset=utf-8", body: "rate_date,base_currency,quote_currency,rate\n2026-01-30,USD,KES,129.25\n2026-01-31,USD,KES,129.5\n", wantStatus: http.StatusOK},
+		{name: "JSON", contentType: "application/json", body: `[{"rate_date": "2026-01-31", "base_currency": "EUR", "quote_currency": "USD", "rate": "1.08"}]`, wantStatus: http.StatusOK},
+		{name: "Unsupported content type", contentType: "application/xml", body: "<rates/>", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "Unreadable file", contentType: "text/csv", body: "date,rate\n", wantStatus: http.StatusBadRequest},
+		{name: "Invalid rate", contentType: "text/csv", body: "rate_date,base_currency,quote_currency,rate\n2026-01-31,USD,KES,-1\n", wantStatus: http.StatusUnprocessableEntity},
+		{name: "Too large", contentType: "text/csv", body: "rate_date,base_currency,quote_currency,rate\n" + strings.Repeat(longRateRow, exchangeRatesMaxBytes/len(longRateRow)+1), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := upload(tt.contentType, tt.body); rr.Code != tt.wantStatus {
+				t.Errorf("status = %d: %s, want %d", rr.Code, rr.Body, tt.wantStatus)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/exchange_rates?date=2026-01-30", nil))
+	var response struct {
+		Rates []data.ExchangeRate `json:"exchange_rates"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	// the rejected files saved nothing and the EUR rate only takes effect the day after
+	if len(response.Rates) != 1 || response.Rates[0].QuoteCurrency != "KES" || response.Rates[0].Rate.String() != "129.25" {
+		t.Errorf("rates on 2026-01-30 = %+v, want the first USD/KES rate alone", response.Rates)
+	}
+}
@@ -1,17 +1,50 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
 )
 
-// healthcheckHandler provides a simple health check endpoint that returns the application status
+// dependencyCheck is a single readiness check. When a critical dependency is down the
+// instance reports itself as not ready.
+type dependencyCheck struct {
+	name     string
+	critical bool
+	check    func(ctx context.Context) error
+}
+
+// dependencyStatus is the outcome of a dependencyCheck as reported by the readiness probe.
+type dependencyStatus struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Define constants for the dependency states.
+const (
+	dependencyUp   = "up"
+	dependencyDown = "down"
+	// defaultHealthCheckTimeout bounds a dependency check when no timeout is configured.
+	defaultHealthCheckTimeout = 2 * time.Second
+)
+
+// healthcheckHandler is the liveness probe. It only reports that the process is up and
+// serving requests, it never touches any dependency. It is served at both /v1/health and
+// /v1/health/live.
 func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
 	// Create health status response
 	health := envelope{
 		"status":      "available",
 		"environment": app.config.env,
 		"version":     version,
-		"database":    "okay",
 	}
 
 	// Write successful health check response
@@ -20,3 +53,102 @@ func (app *application) healthcheckHandler(w http.ResponseWriter, r *http.Reques
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// readinessHandler is the readiness probe. It checks every dependency, reporting the
+// status and latency of each, and responds with a 503 Service Unavailable when a critical
+// dependency is down or the server is shutting down, so no new traffic is routed here.
+func (app *application) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	if app.shuttingDown.Load() {
+		err := app.writeJSON(w, http.StatusServiceUnavailable, envelope{"status": "shutting down"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	statuses, ready := app.runDependencyChecks(r.Context(), app.readinessChecks())
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not ready", http.StatusServiceUnavailable
+	}
+	err := app.writeJSON(w, code, envelope{"status": status, "checks": statuses}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readinessChecks() returns the checks for the configured dependencies. Postgres is
+// critical, the SMTP server is only checked when enabled and never fails readiness
+// as emails are sent in the background and retried.
+func (app *application) readinessChecks() []dependencyCheck {
+	checks := []dependencyCheck{}
+	if app.db != nil {
+		checks = append(checks, dependencyCheck{
+			name:     "database",
+			critical: true,
+			check:    app.db.PingContext,
+		})
+	}
+	if app.config.health.checkSMTP {
+		address := net.JoinHostPort(app.config.smtp.host, fmt.Sprint(app.config.smtp.port))
+		checks = append(checks, dependencyCheck{
+			name:     "smtp",
+			critical: false,
+			check: func(ctx context.Context) error {
+				var dialer net.Dialer
+				conn, err := dialer.DialContext(ctx, "tcp", address)
+				if err != nil {
+					return err
+				}
+				return conn.Close()
+			},
+		})
+	}
+	return checks
+}
+
+// runDependencyChecks() runs the checks concurrently, each bounded by the configured
+// health check timeout. It reports false when a critical check failed.
+func (app *application) runDependencyChecks(ctx context.Context, checks []dependencyCheck) (map[string]dependencyStatus, bool) {
+	timeout := app.config.health.timeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		statuses = make(map[string]dependencyStatus, len(checks))
+		ready    = true
+	)
+	for _, dc := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			err := dc.check(checkCtx)
+			status := dependencyStatus{
+				Status:    dependencyUp,
+				Critical:  dc.critical,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				// the probe is public, keep connection details in the logs
+				app.contextLogger(ctx).Warn("dependency check failed", zap.String("dependency", dc.name), zap.Error(err))
+				status.Status = dependencyDown
+				status.Error = "unavailable"
+				if errors.Is(err, context.DeadlineExceeded) {
+					status.Error = "timed out"
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			statuses[dc.name] = status
+			if err != nil && dc.critical {
+				ready = false
+			}
+		}()
+	}
+	wg.Wait()
+	return statuses, ready
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Blue-Davinci/leadhub-service/internal/data"
 	"go.uber.org/zap"
@@ -56,6 +59,105 @@ func TestHealthcheckHandler(t *testing.T) {
 	}
 }
 
+func TestReadinessHandler(t *testing.T) {
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.Models{},
+	}
+
+	// Without any configured dependency the instance is ready
+	rr := httptest.NewRecorder()
+	app.readinessHandler(rr, httptest.NewRequest("GET", "/v1/health/ready", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected ready instance to return 200, got %d", rr.Code)
+	}
+	if !contains(rr.Body.String(), `"ready"`) {
+		t.Errorf("Expected ready status, got %s", rr.Body.String())
+	}
+
+	// Once graceful shutdown starts readiness fails straight away
+	app.shuttingDown.Store(true)
+	rr = httptest.NewRecorder()
+	app.readinessHandler(rr, httptest.NewRequest("GET", "/v1/health/ready", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected shutting down instance to return 503, got %d", rr.Code)
+	}
+	if !contains(rr.Body.String(), `"shutting down"`) {
+		t.Errorf("Expected shutting down status, got %s", rr.Body.String())
+	}
+}
+
+func TestRunDependencyChecks(t *testing.T) {
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+	}
+	app.config.health.timeout = 50 * time.Millisecond
+
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("dial tcp 10.0.0.5:5432: connection refused") }
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name      string
+		checks    []dependencyCheck
+		wantReady bool
+		wantError map[string]string
+	}{
+		{
+			name:      "All dependencies up",
+			checks:    []dependencyCheck{{name: "database", critical: true, check: up}, {name: "smtp", check: up}},
+			wantReady: true,
+		},
+		{
+			name:      "Critical dependency down",
+			checks:    []dependencyCheck{{name: "database", critical: true, check: down}, {name: "smtp", check: up}},
+			wantReady: false,
+			wantError: map[string]string{"database": "unavailable"},
+		},
+		{
+			name:      "Non critical dependency down",
+			checks:    []dependencyCheck{{name: "database", critical: true, check: up}, {name: "smtp", check: down}},
+			wantReady: true,
+			wantError: map[string]string{"smtp": "unavailable"},
+		},
+		{
+			name:      "Critical dependency times out",
+			checks:    []dependencyCheck{{name: "database", critical: true, check: hang}},
+			wantReady: false,
+			wantError: map[string]string{"database": "timed out"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses, ready := app.runDependencyChecks(context.Background(), tt.checks)
+			if ready != tt.wantReady {
+				t.Errorf("Expected ready to be %v, got %v", tt.wantReady, ready)
+			}
+			if len(statuses) != len(tt.checks) {
+				t.Fatalf("Expected %d statuses, got %d", len(tt.checks), len(statuses))
+			}
+			for _, dc := range tt.checks {
+				status := statuses[dc.name]
+				wantErr, failed := tt.wantError[dc.name]
+				switch {
+				case failed && (status.Status != dependencyDown || status.Error != wantErr):
+					t.Errorf("Expected %s to be down with %q, got %+v", dc.name, wantErr, status)
+				case !failed && status.Status != dependencyUp:
+					t.Errorf("Expected %s to be up, got %+v", dc.name, status)
+				}
+				if status.Critical != dc.critical {
+					t.Errorf("Expected %s critical to be %v", dc.name, dc.critical)
+				}
+			}
+		})
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	// Use strings.Contains for proper substring matching
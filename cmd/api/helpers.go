@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,8 +10,10 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/mailer"
 	"github.com/Blue-Davinci/leadhub-service/internal/validator"
 	"github.com/go-chi/chi"
 )
@@ -88,6 +91,16 @@ func (app *application) readIDParam(r *http.Request, parameterName string) (int6
 	return id, nil
 }
 
+// readVersionParam() retrieves a version URL parameter. Unlike IDs a version can be 0, the
+// version of a record that was never saved.
+func (app *application) readVersionParam(r *http.Request, parameterName string) (int32, error) {
+	version, err := strconv.ParseInt(chi.URLParam(r, parameterName), 10, 32)
+	if err != nil || version < 0 {
+		return 0, errors.New("invalid version parameter")
+	}
+	return int32(version), nil
+}
+
 // jsonReadAndHandleError() is a helper function that takes an error as a parameter and
 // returns a cleaned-up error message. This is used to provide more information in the
 // event of a JSON decoding error.
@@ -139,10 +152,12 @@ func (app *application) jsonReadAndHandleError(err error) error {
 // The done() method of the WaitGroup is called when the goroutine completes.
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
+	app.prometheus.BackgroundStarted()
 	// Launch a background goroutine.
 	go func() {
 		//defer our done()
 		defer app.wg.Done()
+		defer app.prometheus.BackgroundFinished()
 		// Recover any panic.
 		defer func() {
 			if err := recover(); err != nil {
@@ -154,6 +169,14 @@ func (app *application) background(fn func()) {
 	}()
 }
 
+// sendEmail() sends an email in the given locale through the mailer and records the
+// outcome in the Prometheus metrics.
+func (app *application) sendEmail(ctx context.Context, recipient, locale, templateFile string, data any, branding mailer.Branding) (*mailer.Email, error) {
+	email, err := app.mailer.Send(ctx, recipient, locale, templateFile, data, branding)
+	app.prometheus.ObserveEmail(templateFile, err)
+	return email, err
+}
+
 // aunthenticatorHelper() is a helper function for the authentication middleware
 // It takes in a request and returns a user and an error
 func (app *application) aunthenticatorHelper(r *http.Request) (*data.User, error) {
@@ -187,7 +210,7 @@ func (app *application) aunthenticatorHelper(r *http.Request) (*data.User, error
 	// again calling the invalidAuthenticationTokenResponse() helper if no
 	// matching record was found. IMPORTANT: Notice that we are using
 	// ScopeAuthentication as the first parameter here.
-	user, err := app.models.Users.GetForToken(data.ScopeAuthentication, token)
+	user, err := app.models.Users.GetForToken(r.Context(), data.ScopeAuthentication, token)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrGeneralRecordNotFound):
@@ -249,3 +272,63 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	// Otherwise, return the converted integer value.
 	return i
 }
+
+// The readBool() helper reads a boolean value ("true" or "false") from the query string.
+// If no matching key could be found it returns the provided default value. Other values
+// are recorded as an error in the provided Validator instance.
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be true or false")
+		return defaultValue
+	}
+	return b
+}
+
+// The readTime() helper reads a date (YYYY-MM-DD, taken as UTC) or an RFC 3339 timestamp
+// from the query string. It reports whether the value was a plain date, so callers can treat
+// it as a whole day. Values that can't be parsed are recorded in the provided Validator.
+func (app *application) readTime(qs url.Values, key string, defaultValue time.Time, v *validator.Validator) (time.Time, bool) {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue, false
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.AddError(key, "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+		return defaultValue, false
+	}
+	return t, false
+}
+
+// readCustomFieldFilters() collects the "cf.<key>=value" parameters from the query string
+// and converts them to the JSON types of the matching custom field definitions. Unknown
+// keys or badly typed values are recorded in the provided Validator instance.
+func (app *application) readCustomFieldFilters(qs url.Values, definitions []*data.CustomField, v *validator.Validator) map[string]any {
+	filters := map[string]any{}
+	for param := range qs {
+		key, ok := strings.CutPrefix(param, data.CustomFieldQueryPrefix)
+		if !ok {
+			continue
+		}
+		def := data.FindCustomField(definitions, key)
+		if def == nil {
+			v.AddError(param, "is not a defined custom field")
+			continue
+		}
+		value, err := data.ParseCustomFieldValue(def, qs.Get(param))
+		if err != nil {
+			v.AddError(param, err.Error())
+			continue
+		}
+		filters[key] = value
+	}
+	return filters
+}
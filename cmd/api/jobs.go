@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/jobs"
+	"github.com/Blue-Davinci/leadhub-service/internal/mailer"
+	"go.uber.org/zap"
+)
+
+// Define constants for the kinds of background jobs run by the API.
+const (
+	jobKindSendEmail      = "email.send"
+	jobKindDeliverWebhook = "webhook.deliver"
+	jobKindBuildExport    = "export.build"
+)
+
+// The welcome email carries the user's activation token. The token is only created when the
+// email is sent, so its plaintext is never stored with the job or in the email outbox.
+const (
+	activationEmailTemplate = "user_welcome.tmpl"
+	activationTokenTTL      = 3 * 24 * time.Hour
+)
+
+// emailJob is the payload of an email.send job. The template data is stored as JSON, so
+// it should only hold strings and numbers. Jobs enqueued before the email outbox existed
+// have no OutboxID.
+type emailJob struct {
+	OutboxID  int64          `json:"outbox_id,omitempty"`
+	TenantID  int64          `json:"tenant_id"`
+	Recipient string         `json:"recipient"`
+	Locale    string         `json:"locale,omitempty"`
+	Template  string         `json:"template"`
+	Data      map[string]any `json:"data"`
+}
+
+// registerJobHandlers() registers the handler of every job kind on the queue.
+func (app *application) registerJobHandlers() {
+	app.jobs.Register(jobKindSendEmail, app.sendEmailJob)
+	app.jobs.Register(jobKindDeliverWebhook, app.deliverWebhookJob)
+	app.jobs.Register(jobKindBuildExport, app.buildTenantExportJob)
+}
+
+// enqueueEmail() records an email to the user in the outbox and enqueues the job sending
+// it. Passing the models of a transaction records the email only if the transaction
+// commits; call app.jobs.Notify() afterwards to send it without waiting for the next poll.
+func (app *application) enqueueEmail(ctx context.Context, models data.Models, user *data.User, templateFile string, templateData map[string]any) error {
+	email, err := data.NewOutboxEmail(user.TenantID, user.Email, user.Locale, templateFile, templateData)
+	if err != nil {
+		return err
+	}
+	return models.RunInTx(ctx, func(tx data.Models) error {
+		err := tx.Emails.CreateOutboxEmail(ctx, email)
+		if err != nil {
+			return err
+		}
+		return app.enqueueOutboxEmail(ctx, tx, email)
+	})
+}
+
+// enqueueOutboxEmail() enqueues the job sending an email recorded in the outbox.
+func (app *application) enqueueOutboxEmail(ctx context.Context, models data.Models, email *data.OutboxEmail) error {
+	var templateData map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(email.Data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&templateData); err != nil {
+		return err
+	}
+	job, err := data.NewJob(jobKindSendEmail, emailJob{
+		OutboxID:  email.ID,
+		TenantID:  email.TenantID,
+		Recipient: email.Recipient,
+		Locale:    email.Locale,
+		Template:  email.Template,
+		Data:      templateData,
+	})
+	if err != nil {
+		return err
+	}
+	return models.Jobs.EnqueueJob(ctx, job)
+}
+
+// sendEmailJob() sends the email of an email.send job with the tenant's current branding,
+// in the recipient's locale or else the tenant's, and records the attempt in the outbox.
+// An email whose template can't be rendered is not retried.
+func (app *application) sendEmailJob(ctx context.Context, job *data.Job) error {
+	var email emailJob
+	if err := jobs.Decode(job, &email); err != nil {
+		return err
+	}
+	templateData := email.Data
+	if email.Template == activationEmailTemplate {
+		var err error
+		templateData, err = app.activationEmailData(ctx, email.Data)
+		if err != nil {
+			return err
+		}
+	}
+	branding, settings := app.tenantBranding(ctx, email.TenantID)
+	locale := mailer.ResolveLocale(email.Locale, settings.Locale)
+	sent, err := app.sendEmail(ctx, email.Recipient, locale, email.Template, templateData, branding)
+	if errors.Is(err, mailer.ErrRenderTemplate) {
+		err = jobs.Permanent(err)
+	}
+	app.recordEmailAttempt(ctx, job, email.OutboxID, sent, err)
+	return err
+}
+
+// activationEmailData() returns the template data of an activation email with a new
+// activation token for the user in its "userID". Every attempt creates its own token, as
+// the plaintext of an earlier one can't be recovered.
+func (app *application) activationEmailData(ctx context.Context, templateData map[string]any) (map[string]any, error) {
+	userID, err := strconv.ParseInt(fmt.Sprint(templateData["userID"]), 10, 64)
+	if err != nil {
+		return nil, jobs.Permanent(fmt.Errorf("activation email without a user: %w", err))
+	}
+	token, err := app.models.Tokens.New(ctx, userID, activationTokenTTL, data.ScopeActivation)
+	if err != nil {
+		return nil, err
+	}
+	templateData = maps.Clone(templateData)
+	templateData["activationURL"] = app.config.url.activationURL + token.Plaintext
+	templateData["activationToken"] = token.Plaintext
+	return templateData, nil
+}
+
+// recordEmailAttempt() stores the outcome of a delivery attempt in the outbox. A failed
+// attempt leaves the email queued until the job gives up on it. Failing to record the
+// outcome is only logged, retrying the job would send a delivered email twice.
+func (app *application) recordEmailAttempt(ctx context.Context, job *data.Job, outboxID int64, sent *mailer.Email, err error) {
+	if outboxID == 0 {
+		return
+	}
+	attempt := data.EmailAttempt{Status: data.EmailStatusSent}
+	if sent != nil {
+		attempt.Subject = sent.Subject
+	}
+	switch {
+	case err == nil:
+		attempt.ProviderMessageID = sent.MessageID
+	case jobs.IsPermanent(err) || jobs.LastAttempt(job):
+		attempt.Status = data.EmailStatusFailed
+		attempt.LastError = err.Error()
+	default:
+		attempt.Status = data.EmailStatusQueued
+		attempt.LastError = err.Error()
+	}
+	// the job's context may have timed out, which is likely why the delivery failed
+	recordErr := app.models.Emails.RecordOutboxEmailAttempt(context.WithoutCancel(ctx), outboxID, attempt)
+	if recordErr != nil {
+		app.logger.Error("failed to record the email delivery attempt", zap.Int64("outbox_id", outboxID), zap.String("status", attempt.Status), zap.Error(recordErr))
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/jobs"
+	"go.uber.org/zap"
+)
+
+func TestActivationEnqueuesEmail(t *testing.T) {
+	ctx := context.Background()
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.NewMemoryModels(),
+	}
+	app.config.url.authenticationURL = "https://leadhub.test/login"
+
+	tenant := &data.Tenant{Name: "TradeHub KE", ContactEmail: "admin@tradehub.co.ke"}
+	if err := app.models.Tenants.CreateTenant(ctx, tenant); err != nil {
+		t.Fatal(err)
+	}
+	user := &data.User{TenantID: tenant.ID, Name: "Ann", Email: "ann@tradehub.co.ke", Locale: "sw"}
+	if err := user.Password.Set("pa55word123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Users.Insert(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	token, err := app.models.Tokens.New(ctx, user.ID, time.Hour, data.ScopeActivation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := strings.NewReader(`{"token": "` + token.Plaintext + `"}`)
+	rr := httptest.NewRecorder()
+	app.activateUserHandler(rr, httptest.NewRequest(http.MethodPut, "/v1/api/activated", body))
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", rr.Code, http.StatusAccepted, rr.Body)
+	}
+
+	claimed, err := app.models.Jobs.ClaimJobs(ctx, "test-worker", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 1 || claimed[0].Kind != jobKindSendEmail {
+		t.Fatalf("enqueued jobs = %+v, want one %s job", claimed, jobKindSendEmail)
+	}
+	var email emailJob
+	if err := json.Unmarshal(claimed[0].Payload, &email); err != nil {
+		t.Fatal(err)
+	}
+	if email.Recipient != user.Email || email.TenantID != tenant.ID || email.Template != "user_succesful_activation.tmpl" || email.Locale != "sw" {
+		t.Errorf("email job = %+v", email)
+	}
+	if email.Data["loginURL"] != app.config.url.authenticationURL {
+		t.Errorf("loginURL = %v, want %q", email.Data["loginURL"], app.config.url.authenticationURL)
+	}
+}
+
+func TestActivationEmailData(t *testing.T) {
+	ctx := context.Background()
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.NewMemoryModels(),
+	}
+	app.config.url.activationURL = "https://leadhub.test/activate?token="
+	tenant := &data.Tenant{Name: "TradeHub KE", ContactEmail: "admin@tradehub.co.ke"}
+	if err := app.models.Tenants.CreateTenant(ctx, tenant); err != nil {
+		t.Fatal(err)
+	}
+	user := &data.User{TenantID: tenant.ID, Name: "Ann", Email: "ann@tradehub.co.ke"}
+	if err := user.Password.Set("pa55word123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Users.Insert(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	// the job holds the user ID as decoded from its JSON payload
+	stored := map[string]any{"userID": json.Number(strconv.FormatInt(user.ID, 10))}
+	templateData, err := app.activationEmailData(ctx, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stored["activationToken"]; ok {
+		t.Error("the token was added to the stored template data")
+	}
+	token, _ := templateData["activationToken"].(string)
+	if templateData["activationURL"] != app.config.url.activationURL+token {
+		t.Errorf("activationURL = %v, want it to carry the token", templateData["activationURL"])
+	}
+	got, err := app.models.Users.GetForToken(ctx, data.ScopeActivation, token)
+	if err != nil || got.ID != user.ID {
+		t.Errorf("GetForToken() = %+v, %v, want user %d", got, err, user.ID)
+	}
+
+	_, err = app.activationEmailData(ctx, map[string]any{})
+	if !jobs.IsPermanent(err) {
+		t.Errorf("error without a user = %v, want a permanent error", err)
+	}
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"expvar"
 	"flag"
 	"fmt"
@@ -9,13 +13,21 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Blue-Davinci/leadhub-service/internal/data"
-	"github.com/Blue-Davinci/leadhub-service/internal/database"
+	"github.com/Blue-Davinci/leadhub-service/internal/dnsverify"
+	"github.com/Blue-Davinci/leadhub-service/internal/jobs"
 	"github.com/Blue-Davinci/leadhub-service/internal/logger"
 	"github.com/Blue-Davinci/leadhub-service/internal/mailer"
+	"github.com/Blue-Davinci/leadhub-service/internal/metrics"
+	"github.com/Blue-Davinci/leadhub-service/internal/migrations"
+	"github.com/Blue-Davinci/leadhub-service/internal/storage"
+	"github.com/Blue-Davinci/leadhub-service/internal/stream"
+	"github.com/Blue-Davinci/leadhub-service/internal/tracing"
 	"github.com/Blue-Davinci/leadhub-service/internal/vcs"
+	"github.com/Blue-Davinci/leadhub-service/internal/webhooks"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 )
@@ -38,6 +50,10 @@ type config struct {
 		maxOpenConns int
 		maxIdleConns int
 		maxIdleTime  string
+		timeouts     data.Timeouts
+		// migrations
+		autoMigrate        bool
+		migrateLockTimeout time.Duration
 	}
 	cors struct {
 		trustedOrigins []string
@@ -45,6 +61,7 @@ type config struct {
 	url struct {
 		activationURL     string
 		authenticationURL string
+		exportDownloadURL string
 	}
 	smtp struct {
 		host     string
@@ -53,19 +70,67 @@ type config struct {
 		password string
 		sender   string
 	}
+	mail struct {
+		transport string
+		fileDir   string
+	}
 	limiter struct {
 		rps     float64
 		burst   int
 		enabled bool
 	}
+	export struct {
+		storageDir    string
+		signingSecret string
+		linkTTL       time.Duration
+	}
+	domains struct {
+		verifyTimeout time.Duration
+	}
+	health struct {
+		timeout    time.Duration
+		checkSMTP  bool
+		drainDelay time.Duration
+	}
+	tracing struct {
+		exporter     string
+		otlpEndpoint string
+		sampleRatio  float64
+	}
+	jobs struct {
+		workers      int
+		pollInterval time.Duration
+		jobTimeout   time.Duration
+		lockTimeout  time.Duration
+	}
+	webhooks struct {
+		timeout              time.Duration
+		maxAttempts          int
+		disableAfter         int
+		allowPrivateNetworks bool
+	}
+	stream struct {
+		heartbeat           time.Duration
+		bufferSize          int
+		maxStreamsPerTenant int
+	}
 }
 
 type application struct {
-	config config
-	logger *zap.Logger
-	wg     sync.WaitGroup
-	models data.Models
-	mailer mailer.Mailer
+	config       config
+	logger       *zap.Logger
+	wg           sync.WaitGroup
+	db           *sql.DB
+	shuttingDown atomic.Bool
+	models       data.Models
+	mailer       mailer.Mailer
+	mailCapture  *mailer.MemoryTransport
+	storage      storage.Storage
+	domains      *dnsverify.Verifier
+	prometheus   *metrics.Metrics
+	jobs         *jobs.Queue
+	webhooks     *webhooks.Client
+	leadStream   *stream.Broker
 }
 
 func main() {
@@ -75,6 +140,27 @@ func main() {
 		return
 	}
 
+	// "migrate" applies the embedded migrations and exits instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(os.Args[2:], logger, os.Stdout); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			logger.Fatal("Error while running migrations.", zap.Error(err))
+		}
+		return
+	}
+	// "admin" runs an operator command and exits instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := runAdminCommand(os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
 	// config
 	var cfg config
 	// Port & env
@@ -85,6 +171,11 @@ func main() {
 	flag.IntVar(&cfg.db.maxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
 	flag.IntVar(&cfg.db.maxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max idle connections")
 	flag.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", "15m", "PostgreSQL max connection idle time")
+	flag.Func("db-timeouts", "Per model query timeouts, e.g. \"trade_leads=10s,users=2s\" (tenants|users|tokens|permissions|trade_leads|exports|custom_fields|settings|domains|jobs|emails|webhooks|lead_events|exchange_rates|comments)", func(val string) error {
+		return parseModelTimeouts(val, &cfg.db.timeouts)
+	})
+	flag.BoolVar(&cfg.db.autoMigrate, "auto-migrate", os.Getenv("LEADHUB_AUTO_MIGRATE") == "true", "Apply pending database migrations at startup")
+	flag.DurationVar(&cfg.db.migrateLockTimeout, "db-migrate-lock-timeout", migrations.DefaultLockTimeout, "How long to wait for another instance holding the migration lock at startup")
 	// api configuration
 	flag.StringVar(&cfg.api.name, "api-name", "LeadHUb", "API Name")
 	flag.StringVar(&cfg.api.author, "api-author", "Blue-Davinci", "API Author")
@@ -94,6 +185,9 @@ func main() {
 	flag.StringVar(&cfg.smtp.username, "smtp-username", os.Getenv("LEADHUB_SMTP_USERNAME"), "SMTP server username")
 	flag.StringVar(&cfg.smtp.password, "smtp-password", os.Getenv("LEADHUB_SMTP_PASSWORD"), "SMTP server password")
 	flag.StringVar(&cfg.smtp.sender, "smtp-sender", os.Getenv("LEADHUB_SMTP_SENDER"), "SMTP sender email address")
+	// Mail transport, the file, log and memory transports never contact the SMTP server
+	flag.StringVar(&cfg.mail.transport, "mail-transport", getEnvDefault("LEADHUB_MAIL_TRANSPORT", mailer.TransportSMTP), "How emails are delivered (smtp|file|log|memory)")
+	flag.StringVar(&cfg.mail.fileDir, "mail-file-dir", getEnvDefault("LEADHUB_MAIL_FILE_DIR", "./mail"), "Maildir the file mail transport writes .eml files to")
 	// limiter cinfugs
 	flag.Float64Var(&cfg.limiter.rps, "limiter-rps", 5, "Rate limiter maximum requests per second")
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 10, "Rate limiter maximum burst")
@@ -101,6 +195,35 @@ func main() {
 	// URL configuration
 	flag.StringVar(&cfg.url.activationURL, "activation-url", "http://localhost:4000/v1/api/activated/token=", "Activation URL for user registration")
 	flag.StringVar(&cfg.url.authenticationURL, "authentication-url", "http://localhost:4000/v1/api/authentication", "Authentication URL for user login")
+	flag.StringVar(&cfg.url.exportDownloadURL, "export-download-url", "http://localhost:4000/v1/exports", "Base URL for signed tenant export downloads")
+	// Tenant export configuration
+	flag.StringVar(&cfg.export.storageDir, "export-storage-dir", getEnvDefault("LEADHUB_EXPORT_STORAGE_DIR", "./storage"), "Local directory used to store tenant export archives")
+	flag.StringVar(&cfg.export.signingSecret, "export-signing-secret", os.Getenv("LEADHUB_EXPORT_SIGNING_SECRET"), "Secret used to sign tenant export download links")
+	flag.DurationVar(&cfg.export.linkTTL, "export-link-ttl", 24*time.Hour, "How long a tenant export download link stays valid")
+	// Tenant domain verification
+	flag.DurationVar(&cfg.domains.verifyTimeout, "domain-verify-timeout", 10*time.Second, "Timeout for the DNS lookup verifying a tenant domain")
+	// Readiness probe and graceful shutdown
+	flag.DurationVar(&cfg.health.timeout, "health-timeout", 2*time.Second, "Timeout for each dependency check of the readiness probe")
+	flag.BoolVar(&cfg.health.checkSMTP, "health-check-smtp", false, "Report SMTP server reachability in the readiness probe")
+	flag.DurationVar(&cfg.health.drainDelay, "shutdown-drain-delay", 0, "How long to keep serving after readiness starts failing on shutdown")
+	// OpenTelemetry tracing
+	flag.StringVar(&cfg.tracing.exporter, "tracing-exporter", getEnvDefault("LEADHUB_TRACING_EXPORTER", tracing.ExporterNone), "Span exporter (none|stdout|otlp)")
+	flag.StringVar(&cfg.tracing.otlpEndpoint, "tracing-otlp-endpoint", getEnvDefault("LEADHUB_OTLP_ENDPOINT", tracing.DefaultOTLPEndpoint), "OTLP/HTTP traces endpoint of the collector")
+	flag.Float64Var(&cfg.tracing.sampleRatio, "tracing-sample-ratio", 1, "Fraction of new traces that are sampled (0-1)")
+	// Background job queue
+	flag.IntVar(&cfg.jobs.workers, "jobs-workers", jobs.DefaultWorkers, "Number of background jobs run at the same time")
+	flag.DurationVar(&cfg.jobs.pollInterval, "jobs-poll-interval", jobs.DefaultPollInterval, "How often idle job workers look for due jobs")
+	flag.DurationVar(&cfg.jobs.jobTimeout, "jobs-timeout", jobs.DefaultJobTimeout, "Timeout for a single run of a background job")
+	flag.DurationVar(&cfg.jobs.lockTimeout, "jobs-lock-timeout", jobs.DefaultLockTimeout, "How long a job may run before it is handed to another worker")
+	// Outgoing webhooks
+	flag.DurationVar(&cfg.webhooks.timeout, "webhooks-timeout", webhooks.DefaultTimeout, "Timeout for a single webhook delivery attempt")
+	flag.IntVar(&cfg.webhooks.maxAttempts, "webhooks-max-attempts", webhooks.DefaultMaxAttempts, "How often a webhook delivery is attempted before it fails")
+	flag.IntVar(&cfg.webhooks.disableAfter, "webhooks-disable-after", webhooks.DefaultDisableAfter, "Failed webhook deliveries in a row that disable their endpoint (0 never disables)")
+	flag.BoolVar(&cfg.webhooks.allowPrivateNetworks, "webhooks-allow-private-networks", os.Getenv("LEADHUB_WEBHOOKS_ALLOW_PRIVATE_NETWORKS") == "true", "Allow webhook endpoints on loopback and private network addresses")
+	// Live trade lead stream
+	flag.DurationVar(&cfg.stream.heartbeat, "stream-heartbeat", defaultStreamHeartbeat, "How often idle trade lead streams are sent a heartbeat comment")
+	flag.IntVar(&cfg.stream.bufferSize, "stream-buffer-size", stream.DefaultBufferSize, "Recent trade lead events kept per tenant for resuming streams")
+	flag.IntVar(&cfg.stream.maxStreamsPerTenant, "stream-max-per-tenant", stream.DefaultMaxStreamsPerTenant, "Concurrent trade lead streams a tenant can open on one instance")
 	// CORS configuration
 	flag.Func("cors-trusted-origins", "Trusted CORS origins (space separated)", func(val string) error {
 		cfg.cors.trustedOrigins = strings.Fields(val)
@@ -112,20 +235,7 @@ func main() {
 
 	// Construct DSN from individual components if not provided directly
 	if cfg.db.dsn == "" {
-		dbUser := getEnvDefault("DB_USER", "leadhub")
-		dbPassword := getEnvDefault("DB_PASSWORD", "test")
-		dbHost := getEnvDefault("DB_HOST", "localhost")
-		dbPort := getEnvDefault("DB_PORT", "5432")
-		dbName := getEnvDefault("DB_NAME", "leadhub")
-		dbSSLMode := getEnvDefault("DB_SSLMODE", "disable")
-
-		cfg.db.dsn = fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-			dbUser, dbPassword, dbHost, dbPort, dbName, dbSSLMode)
-
-		logger.Info("Constructed DSN from individual components",
-			zap.String("host", dbHost),
-			zap.String("port", dbPort),
-			zap.String("database", dbName))
+		cfg.db.dsn = buildDSN(logger)
 	}
 
 	// Load additional configuration from environment variables
@@ -135,15 +245,87 @@ func main() {
 	if err != nil {
 		logger.Fatal(err.Error(), zap.String("dsn", cfg.db.dsn))
 	}
+	// Apply pending migrations before serving when asked to
+	if cfg.db.autoMigrate {
+		if err := autoMigrate(db, cfg.db.migrateLockTimeout, logger); err != nil {
+			logger.Fatal("Error while migrating the database.", zap.Error(err))
+		}
+	}
+	// export download links must verify on every instance, only local setups sign them with
+	// a random per-process key
+	if cfg.export.signingSecret == "" {
+		cfg.export.signingSecret, err = exportSigningSecret(cfg.env)
+		if err != nil {
+			logger.Fatal(err.Error(), zap.String("env", cfg.env))
+		}
+		logger.Warn("no export signing secret configured, download links will not survive a restart")
+	}
+	// local disk storage for export archives
+	exportStorage, err := storage.NewLocalStorage(cfg.export.storageDir)
+	if err != nil {
+		logger.Fatal(err.Error(), zap.String("dir", cfg.export.storageDir))
+	}
+	// a broken email template stops the service here instead of failing its emails later
+	err = mailer.ValidateTemplates()
+	if err != nil {
+		logger.Fatal("Invalid email templates.", zap.Error(err))
+	}
+	// the transport delivering the rendered emails
+	mailTransport, err := mailer.NewTransport(mailer.TransportConfig{
+		Transport: cfg.mail.transport,
+		Host:      cfg.smtp.host,
+		Port:      cfg.smtp.port,
+		Username:  cfg.smtp.username,
+		Password:  cfg.smtp.password,
+		Dir:       cfg.mail.fileDir,
+	})
+	if err != nil {
+		logger.Fatal(err.Error(), zap.String("transport", cfg.mail.transport))
+	}
 	// Init our exp metrics variables for server metrics.
 	publishMetrics()
+	// set up tracing, with the "none" exporter spans are propagated but not recorded
+	shutdownTracing, err := tracing.Setup(tracing.Config{
+		Exporter:       cfg.tracing.exporter,
+		Endpoint:       cfg.tracing.otlpEndpoint,
+		SampleRatio:    cfg.tracing.sampleRatio,
+		ServiceName:    "leadhub-api",
+		ServiceVersion: version,
+		Environment:    cfg.env,
+	})
+	if err != nil {
+		logger.Fatal(err.Error(), zap.String("exporter", cfg.tracing.exporter))
+	}
 	// instantiate the application struct for dependency injection
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		config:     cfg,
+		logger:     logger,
+		db:         db,
+		models:     data.NewModels(db, cfg.db.timeouts),
+		mailer:     mailer.New(mailTransport, cfg.smtp.sender),
+		storage:    exportStorage,
+		domains:    dnsverify.New(nil),
+		prometheus: metrics.New(db),
+		webhooks: webhooks.NewClient(webhooks.Config{
+			Timeout:              cfg.webhooks.timeout,
+			AllowPrivateNetworks: cfg.webhooks.allowPrivateNetworks,
+		}),
+		leadStream: stream.NewBroker(stream.Config{
+			BufferSize:          cfg.stream.bufferSize,
+			MaxStreamsPerTenant: cfg.stream.maxStreamsPerTenant,
+		}),
 	}
+	// captured emails can be browsed through the development endpoints
+	app.mailCapture, _ = mailTransport.(*mailer.MemoryTransport)
+	// background jobs, e.g. emails, are stored in the database and survive restarts
+	app.jobs = jobs.New(app.models.Jobs, logger, jobs.Config{
+		Workers:      cfg.jobs.workers,
+		PollInterval: cfg.jobs.pollInterval,
+		JobTimeout:   cfg.jobs.jobTimeout,
+		LockTimeout:  cfg.jobs.lockTimeout,
+		Observer:     app.prometheus,
+	})
+	app.registerJobHandlers()
 	// Print the version information
 	logger.Info("Starting LeadHub Service",
 		zap.String("version", version),
@@ -154,11 +336,34 @@ func main() {
 	if err != nil {
 		logger.Fatal("Error while starting server.", zap.String("error", err.Error()))
 	}
+	// flush the spans still buffered by the exporter
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("failed to shut down tracing", zap.Error(err))
+	}
+}
+
+// buildDSN() constructs the PostgreSQL DSN from the DB_* environment variables.
+func buildDSN(logger *zap.Logger) string {
+	dbUser := getEnvDefault("DB_USER", "leadhub")
+	dbPassword := getEnvDefault("DB_PASSWORD", "test")
+	dbHost := getEnvDefault("DB_HOST", "localhost")
+	dbPort := getEnvDefault("DB_PORT", "5432")
+	dbName := getEnvDefault("DB_NAME", "leadhub")
+	dbSSLMode := getEnvDefault("DB_SSLMODE", "disable")
+
+	logger.Info("Constructed DSN from individual components",
+		zap.String("host", dbHost),
+		zap.String("port", dbPort),
+		zap.String("database", dbName))
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
+		dbUser, dbPassword, dbHost, dbPort, dbName, dbSSLMode)
 }
 
 // openDB() opens a new database connection using the provided configuration.
 // It returns a pointer to the sql.DB connection pool and an error value.
-func openDB(cfg config) (*database.Queries, error) {
+func openDB(cfg config) (*sql.DB, error) {
 	db, err := sql.Open("postgres", cfg.db.dsn)
 	if err != nil {
 		return nil, err
@@ -175,8 +380,7 @@ func openDB(cfg config) (*database.Queries, error) {
 	if err != nil {
 		return nil, err
 	}
-	queries := database.New(db)
-	return queries, nil
+	return db, nil
 }
 
 // publishMetrics sets up the expvar variables for the application
@@ -211,6 +415,70 @@ func loadConfig(cfg *config) {
 	}
 }
 
+// parseModelTimeouts parses a comma separated list of model=duration pairs into the
+// per model query timeouts.
+func parseModelTimeouts(val string, timeouts *data.Timeouts) error {
+	targets := map[string]*time.Duration{
+		"tenants":        &timeouts.Tenants,
+		"users":          &timeouts.Users,
+		"tokens":         &timeouts.Tokens,
+		"permissions":    &timeouts.Permissions,
+		"trade_leads":    &timeouts.TradeLeads,
+		"exports":        &timeouts.Exports,
+		"custom_fields":  &timeouts.CustomFields,
+		"settings":       &timeouts.Settings,
+		"domains":        &timeouts.Domains,
+		"jobs":           &timeouts.Jobs,
+		"emails":         &timeouts.Emails,
+		"webhooks":       &timeouts.Webhooks,
+		"lead_events":    &timeouts.LeadEvents,
+		"exchange_rates": &timeouts.ExchangeRates,
+		"comments":       &timeouts.Comments,
+	}
+	for _, pair := range strings.Split(val, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid timeout %q, expected model=duration", pair)
+		}
+		target, ok := targets[strings.TrimSpace(name)]
+		if !ok {
+			return fmt.Errorf("unknown model %q", name)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("timeout for %q must be positive", name)
+		}
+		*target = timeout
+	}
+	return nil
+}
+
+// exportSigningSecret returns the key signing export download links when none is
+// configured. Outside development and testing a secret shared by every instance is required,
+// as links signed with a per-process key fail on other replicas and after a restart.
+func exportSigningSecret(env string) (string, error) {
+	if env != "development" && env != "testing" {
+		return "", errors.New("an export signing secret is required, set LEADHUB_EXPORT_SIGNING_SECRET")
+	}
+	return randomSecret()
+}
+
+// randomSecret generates a random hex encoded 32 byte secret
+func randomSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 // getEnvDefault gets an environment variable with a default fallback
 func getEnvDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/migrations"
+)
+
+func TestParseModelTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		val     string
+		want    data.Timeouts
+		wantErr bool
+	}{
+		{name: "Empty", val: "", want: data.Timeouts{}},
+		{name: "Single", val: "trade_leads=10s", want: data.Timeouts{TradeLeads: 10 * time.Second}},
+		{name: "Several", val: "users=2s, exports=1m ,domains=500ms", want: data.Timeouts{Users: 2 * time.Second, Exports: time.Minute, Domains: 500 * time.Millisecond}},
+		{name: "Missing duration", val: "users", wantErr: true},
+		{name: "Unknown model", val: "leads=5s", wantErr: true},
+		{name: "Invalid duration", val: "users=soon", wantErr: true},
+		{name: "Non positive", val: "users=0s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got data.Timeouts
+			err := parseModelTimeouts(tt.val, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseModelTimeouts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseModelTimeouts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportSigningSecret(t *testing.T) {
+	for _, env := range []string{"development", "testing"} {
+		secret, err := exportSigningSecret(env)
+		if err != nil || len(secret) != 64 {
+			t.Errorf("exportSigningSecret(%q) = %q, %v, want a random key", env, secret, err)
+		}
+	}
+	for _, env := range []string{"staging", "production"} {
+		if _, err := exportSigningSecret(env); err == nil {
+			t.Errorf("exportSigningSecret(%q) error = nil, want an error", env)
+		}
+	}
+}
+
+func TestParseMigrateArgs(t *testing.T) {
+	t.Setenv("LEADHUB_DB_DSN", "postgres://env")
+	tests := []struct {
+		name    string
+		args    []string
+		want    migrateOptions
+		wantErr bool
+	}{
+		{name: "Up", args: []string{"up"}, want: migrateOptions{command: "up", dsn: "postgres://env", lockTimeout: migrations.DefaultLockTimeout}},
+		{name: "Flags before action", args: []string{"-db-dsn=postgres://flag", "status"}, want: migrateOptions{command: "status", dsn: "postgres://flag", lockTimeout: migrations.DefaultLockTimeout}},
+		{name: "Flags after action", args: []string{"redo", "-lock-timeout=30s"}, want: migrateOptions{command: "redo", dsn: "postgres://env", lockTimeout: 30 * time.Second}},
+		{name: "Down", args: []string{"down"}, want: migrateOptions{command: "down", dsn: "postgres://env", lockTimeout: migrations.DefaultLockTimeout}},
+		{name: "Missing action", args: nil, wantErr: true},
+		{name: "Unknown action", args: []string{"sideways"}, wantErr: true},
+		{name: "Extra arguments", args: []string{"up", "now"}, wantErr: true},
+		{name: "Unknown flag", args: []string{"-force", "up"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMigrateArgs(tt.args, io.Discard)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMigrateArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseMigrateArgs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
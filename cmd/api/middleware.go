@@ -1,17 +1,31 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"expvar"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/metrics"
+	"github.com/Blue-Davinci/leadhub-service/internal/tracing"
 	"github.com/felixge/httpsnoop"
+	"github.com/go-chi/chi"
 	"github.com/tomasen/realip"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 	"golang.org/x/time/rate"
 )
 
@@ -163,6 +177,7 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 			// response, just like before.
 			if !clients[ip].limiter.Allow() {
 				mu.Unlock()
+				app.prometheus.RateLimitRejected()
 				app.rateLimitExceededResponse(w, r)
 				return
 			}
@@ -176,6 +191,9 @@ func (app *application) rateLimit(next http.Handler) http.Handler {
 
 // The metrics() middleware will be used to collect and expose various metrics about the
 // API server, such as the total number of requests received, the total number of
+// responses sent and the time spent processing them. Besides the expvar counters it
+// records the Prometheus request duration histogram, labelled by the chi route pattern
+// rather than the raw path to keep the cardinality bounded.
 func (app *application) metrics(next http.Handler) http.Handler {
 	// Initialize the new expvar variables when the middleware chain is first built.
 	totalRequestsReceived := expvar.NewInt("total_requests_received")
@@ -186,9 +204,12 @@ func (app *application) metrics(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Increment the number of requests received by 1.
 		totalRequestsReceived.Add(1)
+		app.prometheus.RequestStarted()
 
 		// Use httpsnoop to capture metrics while passing along the original response writer.
 		metrics := httpsnoop.CaptureMetrics(next, w, r)
+		// The route pattern is only known once chi has routed the request.
+		app.prometheus.ObserveRequest(routePattern(r), r.Method, metrics.Code, metrics.Duration)
 
 		// Increment the total responses sent.
 		totalResponsesSent.Add(1)
@@ -199,6 +220,105 @@ func (app *application) metrics(next http.Handler) http.Handler {
 	})
 }
 
+// The traceRequest() middleware starts a server span for every request. When the caller
+// sends a W3C traceparent header the span joins the caller's trace. Once chi has routed
+// the request the span is renamed after the route pattern, e.g. "GET /v1/trade_leads/".
+func (app *application) traceRequest(next http.Handler) http.Handler {
+	tracer := tracing.Tracer("github.com/Blue-Davinci/leadhub-service/cmd/api")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(realip.FromRequest(r)),
+			),
+		)
+		defer span.End()
+
+		metrics := httpsnoop.CaptureMetrics(next, w, r.WithContext(ctx))
+
+		if route := routePattern(r); route != "" {
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(metrics.Code))
+		if metrics.Code >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(metrics.Code))
+		}
+	})
+}
+
+// requestIDHeader is the header carrying the request ID in both directions.
+const requestIDHeader = "X-Request-ID"
+
+// requestIDRX limits the request IDs accepted from clients to a safe, loggable form.
+var requestIDRX = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// The requestID() middleware accepts the X-Request-ID sent by the client, or a proxy in
+// front of us, and generates one when it is missing or malformed. The ID is stored in the
+// request context and echoed in the response headers.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !requestIDRX.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("http.request.id", requestID))
+		next.ServeHTTP(w, app.contextSetRequestID(r, requestID))
+	})
+}
+
+// newRequestID() generates a random 128 bit request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read never returns an error
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// The logRequest() middleware writes one structured access log line per request once the
+// response has been sent. The user and tenant are filled in by authenticate() through the
+// shared requestInfo, as they are not known yet when this middleware runs.
+func (app *application) logRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := &requestInfo{}
+		r = r.WithContext(context.WithValue(r.Context(), requestInfoContextKey, info))
+
+		captured := httpsnoop.CaptureMetrics(next, w, r)
+
+		route := routePattern(r)
+		if route == "" {
+			route = metrics.UnmatchedRoute
+		}
+		fields := []zap.Field{
+			zap.String("method", r.Method),
+			zap.String("route", route),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", captured.Code),
+			zap.Duration("latency", captured.Duration),
+			zap.Int64("bytes", captured.Written),
+			zap.String("remote_ip", realip.FromRequest(r)),
+		}
+		if info.userID != 0 {
+			fields = append(fields, zap.Int64("user_id", info.userID), zap.Int64("tenant_id", info.tenantID))
+		}
+		app.requestLogger(r).Info("request completed", fields...)
+	})
+}
+
+// routePattern() returns the chi route pattern that matched the request, such as
+// "/v1/trade_leads/admin/{leadID:[0-9]+}/{versionID:[0-9]+}", or "" if none did.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+	return rctx.RoutePattern()
+}
+
 // The requirePermission() middleware will be used to check that the authenticated user
 // has the required permission to access a specific route.
 func (app *application) requirePermission(code string) func(next http.Handler) http.Handler {
@@ -207,7 +327,7 @@ func (app *application) requirePermission(code string) func(next http.Handler) h
 			// Retrieve the user from the request context.
 			user := app.contextGetUser(r)
 			// Get the slice of permissions for the user.
-			permissions, err := app.models.Permissions.GetAllPermissionsForUser(user.ID)
+			permissions, err := app.models.Permissions.GetAllPermissionsForUser(r.Context(), user.ID)
 			if err != nil {
 				app.serverErrorResponse(w, r, err)
 				return
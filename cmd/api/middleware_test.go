@@ -6,7 +6,15 @@ import (
 	"testing"
 
 	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/go-chi/chi"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestRateLimitingMiddleware(t *testing.T) {
@@ -175,3 +183,163 @@ func TestRecoverPanicMiddleware(t *testing.T) {
 
 	t.Log("RELIABILITY: Panic recovery prevents service crashes and sets appropriate headers")
 }
+
+// TestTraceRequestMiddleware tests that every request gets a server span which joins the
+// caller's trace and is named after the matched route
+func TestTraceRequestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.Models{},
+	}
+
+	router := chi.NewRouter()
+	router.Use(app.traceRequest)
+	router.Get("/v1/trade_leads/{leadID:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		// the handler's context must carry the server span
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			t.Error("Expected the request context to carry a span")
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest("GET", "/v1/trade_leads/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /v1/trade_leads/{leadID:[0-9]+}" {
+		t.Errorf("Expected span to be named after the route pattern, got %q", span.Name())
+	}
+	if span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("Expected a server span, got %v", span.SpanKind())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected span to join the caller's trace, got trace ID %s", got)
+	}
+	if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("Expected the caller's span as parent, got %s", got)
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("Expected a 500 response to mark the span as failed, got %v", span.Status().Code)
+	}
+
+	t.Log("OBSERVABILITY: Requests are traced and take part in distributed traces")
+}
+
+// TestRequestIDMiddleware tests that request IDs are accepted, generated and echoed
+func TestRequestIDMiddleware(t *testing.T) {
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.Models{},
+	}
+
+	var seen string
+	handler := app.requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = contextGetRequestID(r.Context())
+		app.notFoundResponse(w, r)
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{name: "Client request ID is kept", header: "client-1234.abc", wantSame: true},
+		{name: "Missing request ID is generated", header: ""},
+		{name: "Malformed request ID is replaced", header: "bad id\nwith newline"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/v1/unknown", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Request-ID", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			got := rr.Header().Get("X-Request-ID")
+			if got == "" || got != seen {
+				t.Fatalf("Expected response header %q to match the context request ID %q", got, seen)
+			}
+			if tt.wantSame && got != tt.header {
+				t.Errorf("Expected client request ID %q to be kept, got %q", tt.header, got)
+			}
+			if !tt.wantSame && (got == tt.header || len(got) != 32) {
+				t.Errorf("Expected a generated request ID, got %q", got)
+			}
+			// the error envelope carries the request ID too
+			if !contains(rr.Body.String(), `"request_id": "`+got+`"`) {
+				t.Errorf("Expected error envelope to contain the request ID, got %s", rr.Body.String())
+			}
+		})
+	}
+
+	t.Log("OBSERVABILITY: Every request can be correlated through its request ID")
+}
+
+// TestLogRequestMiddleware tests that one access log line is written per request
+func TestLogRequestMiddleware(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.New(core),
+		models: data.Models{},
+	}
+
+	router := chi.NewRouter()
+	router.Use(app.requestID, app.logRequest)
+	router.Get("/v1/trade_leads/{leadID:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		// stands in for authenticate()
+		r = app.contextSetUser(r, &data.User{ID: 7, TenantID: 3})
+		app.requestLogger(r).Info("loading lead")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	})
+
+	req := httptest.NewRequest("GET", "/v1/trade_leads/42", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	req.RemoteAddr = "192.168.1.9:5555"
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("Expected a handler log line and an access log line, got %d entries", len(entries))
+	}
+	for _, entry := range entries {
+		if entry.ContextMap()["request_id"] != "req-42" {
+			t.Errorf("Expected %q to carry the request ID, got %v", entry.Message, entry.ContextMap())
+		}
+	}
+	access := entries[1].ContextMap()
+	want := map[string]any{
+		"method":    "GET",
+		"route":     "/v1/trade_leads/{leadID:[0-9]+}",
+		"status":    int64(http.StatusTeapot),
+		"bytes":     int64(len("short and stout")),
+		"user_id":   int64(7),
+		"tenant_id": int64(3),
+		"remote_ip": "192.168.1.9",
+	}
+	for key, value := range want {
+		if access[key] != value {
+			t.Errorf("Expected access log %s to be %v, got %v", key, value, access[key])
+		}
+	}
+	if _, ok := access["latency"]; !ok {
+		t.Error("Expected access log to contain the latency")
+	}
+
+	t.Log("OBSERVABILITY: Every request produces a single structured access log line")
+}
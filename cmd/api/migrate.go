@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/migrations"
+	"github.com/pressly/goose/v3"
+	"go.uber.org/zap"
+)
+
+// migrateCommands lists the actions of the "migrate" subcommand.
+var migrateCommands = []string{"up", "down", "status", "redo"}
+
+// migrateOptions holds the parsed arguments of the "migrate" subcommand.
+type migrateOptions struct {
+	command     string
+	dsn         string
+	lockTimeout time.Duration
+}
+
+// parseMigrateArgs parses the arguments following "migrate". Flags may appear before or
+// after the action, e.g. "migrate -db-dsn=... up" or "migrate status -lock-timeout=1m".
+func parseMigrateArgs(args []string, output io.Writer) (migrateOptions, error) {
+	var opts migrateOptions
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&opts.dsn, "db-dsn", os.Getenv("LEADHUB_DB_DSN"), "PostgreSQL DSN")
+	fs.DurationVar(&opts.lockTimeout, "lock-timeout", migrations.DefaultLockTimeout, "How long to wait for another instance holding the migration lock")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: leadhub-api migrate [flags] up|down|status|redo")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	opts.command = fs.Arg(0)
+	if fs.NArg() > 0 {
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return opts, err
+		}
+	}
+	switch {
+	case opts.command == "":
+		return opts, errors.New("missing migrate action, expected one of up|down|status|redo")
+	case fs.NArg() > 0:
+		return opts, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	for _, command := range migrateCommands {
+		if opts.command == command {
+			return opts, nil
+		}
+	}
+	return opts, fmt.Errorf("unknown migrate action %q, expected one of up|down|status|redo", opts.command)
+}
+
+// runMigrateCommand runs "leadhub-api migrate", applying the embedded migrations and
+// writing the outcome to output.
+func runMigrateCommand(args []string, logger *zap.Logger, output io.Writer) error {
+	opts, err := parseMigrateArgs(args, output)
+	if err != nil {
+		return err
+	}
+	if opts.dsn == "" {
+		opts.dsn = buildDSN(logger)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := sql.Open("postgres", opts.dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := db.PingContext(ctx); err != nil {
+		return err
+	}
+	migrator, err := migrations.New(db, opts.lockTimeout)
+	if err != nil {
+		return err
+	}
+
+	switch opts.command {
+	case "up":
+		results, err := migrator.Up(ctx)
+		printMigrationResults(output, results...)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(output, "no pending migrations, the database is up to date")
+		}
+	case "down":
+		result, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationResults(output, result)
+	case "redo":
+		results, err := migrator.Redo(ctx)
+		printMigrationResults(output, results...)
+		if err != nil {
+			return err
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(output, statuses)
+	}
+	return nil
+}
+
+// autoMigrate applies pending migrations before the server starts. Replicas starting at
+// the same time wait on the migration lock, so only one of them applies each migration.
+func autoMigrate(db *sql.DB, lockTimeout time.Duration, logger *zap.Logger) error {
+	migrator, err := migrations.New(db, lockTimeout)
+	if err != nil {
+		return err
+	}
+	results, err := migrator.Up(context.Background())
+	for _, result := range results {
+		logger.Info("applied database migration",
+			zap.String("migration", filepath.Base(result.Source.Path)),
+			zap.Duration("duration", result.Duration))
+	}
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		logger.Info("database schema is up to date")
+	}
+	return nil
+}
+
+func printMigrationResults(output io.Writer, results ...*goose.MigrationResult) {
+	for _, result := range results {
+		fmt.Fprintln(output, result)
+	}
+}
+
+func printMigrationStatus(output io.Writer, statuses []*goose.MigrationStatus) {
+	tw := tabwriter.NewWriter(output, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "Applied At\tMigration")
+	for _, status := range statuses {
+		appliedAt := "Pending"
+		if status.State == goose.StateApplied {
+			appliedAt = status.AppliedAt.UTC().Format(time.RFC1123)
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", appliedAt, filepath.Base(status.Source.Path))
+	}
+	tw.Flush()
+}
@@ -4,6 +4,7 @@ import (
 	"expvar"
 	"net/http"
 
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/cors"
 	"github.com/justinas/alice"
@@ -14,17 +15,21 @@ func (app *application) routes() http.Handler {
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   app.config.cors.trustedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", requestIDHeader},
+		ExposedHeaders:   []string{"link", requestIDHeader},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
 	//Use alice to make a global middleware chain.
-	globalMiddleware := alice.New(app.metrics, app.recoverPanic, app.rateLimit, app.authenticate).Then
+	globalMiddleware := alice.New(app.traceRequest, app.requestID, app.logRequest, app.metrics, app.recoverPanic, app.rateLimit, app.authenticate).Then
 	// Dynamic Middleware, these will apply to only select routes
 	dynamicMiddleware := alice.New(app.requireAuthenticatedUser, app.requireActivatedUser)
 	// Permission Middleware, this will apply to specific routes that are capped by the permissions
 	adminPermissionMiddleware := alice.New(app.requirePermission("admin:write"))
+	// Tenant Admin Middleware, for tenant owners managing their own tenant
+	tenantAdminPermissionMiddleware := alice.New(app.requirePermission(data.PermissionTenantAdmin))
+	// Group Middleware, for users reading across a tenant and all of its sub-tenants
+	tenantGroupPermissionMiddleware := alice.New(app.requirePermission(data.PermissionTenantGroup))
 
 	// Apply the global middleware to the router
 	router.Use(globalMiddleware)
@@ -33,9 +38,16 @@ func (app *application) routes() http.Handler {
 	v1Router := chi.NewRouter()
 
 	v1Router.Mount("/", app.generalRoutes())
-	v1Router.Mount("/api", app.userRoutes())
-	v1Router.With(dynamicMiddleware.Then).Mount("/tenants", app.tenantRoutes(&adminPermissionMiddleware))
-	v1Router.With(dynamicMiddleware.Then).Mount("/trade_leads", app.tradeLeadsRoutes(&adminPermissionMiddleware))
+	v1Router.Mount("/api", app.userRoutes(&dynamicMiddleware))
+	v1Router.With(dynamicMiddleware.Then).Mount("/tenants", app.tenantRoutes(&adminPermissionMiddleware, &tenantAdminPermissionMiddleware))
+	v1Router.With(dynamicMiddleware.Then).Mount("/trade_leads", app.tradeLeadsRoutes(&adminPermissionMiddleware, &tenantGroupPermissionMiddleware))
+	v1Router.With(dynamicMiddleware.Then).Mount("/emails", app.emailRoutes(&adminPermissionMiddleware))
+	v1Router.With(dynamicMiddleware.Then).Mount("/exchange_rates", app.exchangeRateRoutes(&adminPermissionMiddleware))
+
+	// development-only endpoints for browsing the emails captured by the memory transport
+	if app.devRoutesEnabled() {
+		v1Router.Mount("/dev", app.devRoutes())
+	}
 
 	// Moount the v1Router to the main base router
 	router.Mount("/v1", v1Router)
@@ -51,27 +63,61 @@ func (app *application) generalRoutes() chi.Router {
 	generalRoutes.Get("/debug/vars", func(w http.ResponseWriter, r *http.Request) {
 		expvar.Handler().ServeHTTP(w, r)
 	})
+	// /metrics : Prometheus metrics, scraped by the monitoring stack
+	generalRoutes.Get("/metrics", app.prometheus.Handler().ServeHTTP)
+	// /health and /health/live : liveness, /health/ready : readiness with dependency checks
 	generalRoutes.Get("/health", app.healthcheckHandler)
+	generalRoutes.Get("/health/live", app.healthcheckHandler)
+	generalRoutes.Get("/health/ready", app.readinessHandler)
+	// /exports/{exportID}/download : signed, time limited download of a tenant export
+	generalRoutes.Get("/exports/{exportID:[0-9]+}/download", app.downloadTenantExportHandler)
 	return generalRoutes
 }
 
 // userRoutes() is a method that returns a chi.Router that contains all the routes for the users
-func (app *application) userRoutes() chi.Router {
+func (app *application) userRoutes(dynamicMiddleware *alice.Chain) chi.Router {
 	userRoutes := chi.NewRouter()
 	userRoutes.Post("/", app.registerUserHandler)
 	userRoutes.Post("/authentication", app.createAuthenticationApiKeyHandler)
 	// /activation : for activating accounts
 	userRoutes.Put("/activated", app.activateUserHandler)
+	// the authenticated user's own preferences
+	userRoutes.With(dynamicMiddleware.Then).Put("/me/locale", app.updateUserLocaleHandler)
 	return userRoutes
 }
 
 // tenantRoutes() is a method that returns a chi.Router that contains all the routes for the tenants
 // This is a placeholder for tenant-related routes, which can be expanded as needed.
-func (app *application) tenantRoutes(adminPermissionMiddleware *alice.Chain) chi.Router {
+func (app *application) tenantRoutes(adminPermissionMiddleware, tenantAdminPermissionMiddleware *alice.Chain) chi.Router {
 	tenantRoutes := chi.NewRouter()
 	// /tenants/{id} : for getting a tenant by ID
 	tenantRoutes.Get("/", app.getTenantByIDHandler)
 
+	// tenant owner routes
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Post("/me/exports", app.createTenantExportHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Get("/me/exports/{exportID:[0-9]+}", app.getTenantExportHandler)
+	// /tenants/me/settings : the tenant's settings and email branding
+	tenantRoutes.Get("/me/settings", app.getTenantSettingsHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Patch("/me/settings/{versionID:[0-9]+}", app.updateTenantSettingsHandler)
+	// /tenants/me/domains : verified email domains, users on them join the tenant automatically
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Get("/me/domains", app.getTenantDomainsHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Post("/me/domains", app.createTenantDomainHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Post("/me/domains/{domainID:[0-9]+}/verify", app.verifyTenantDomainHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Delete("/me/domains/{domainID:[0-9]+}", app.deleteTenantDomainHandler)
+	// /tenants/me/custom_fields : the tenant's custom trade lead fields
+	tenantRoutes.Get("/me/custom_fields", app.getCustomFieldsHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Post("/me/custom_fields", app.createCustomFieldHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Patch("/me/custom_fields/{fieldID:[0-9]+}/{versionID:[0-9]+}", app.updateCustomFieldHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Delete("/me/custom_fields/{fieldID:[0-9]+}", app.deleteCustomFieldHandler)
+	// /tenants/me/webhooks : endpoints notified of the tenant's events, with their delivery log
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Get("/me/webhooks", app.getWebhookEndpointsHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Post("/me/webhooks", app.createWebhookEndpointHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Get("/me/webhooks/{webhookID:[0-9]+}", app.getWebhookEndpointHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Patch("/me/webhooks/{webhookID:[0-9]+}/{versionID:[0-9]+}", app.updateWebhookEndpointHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Delete("/me/webhooks/{webhookID:[0-9]+}", app.deleteWebhookEndpointHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Get("/me/webhooks/{webhookID:[0-9]+}/deliveries", app.getWebhookDeliveriesHandler)
+	tenantRoutes.With(tenantAdminPermissionMiddleware.Then).Post("/me/webhooks/{webhookID:[0-9]+}/deliveries/{deliveryID:[0-9]+}/redeliver", app.redeliverWebhookHandler)
+
 	// admin routes
 	tenantRoutes.With(adminPermissionMiddleware.Then).Post("/admin", app.createTenantHandler)
 	tenantRoutes.With(adminPermissionMiddleware.Then).Get("/admin", app.adminGetAllTenantsHandler)
@@ -80,11 +126,27 @@ func (app *application) tenantRoutes(adminPermissionMiddleware *alice.Chain) chi
 }
 
 // tradeLeadsRoutes() is a method that returns a chi.Router that contains all the routes for the trade leads
-func (app *application) tradeLeadsRoutes(adminPermissionMiddleware *alice.Chain) chi.Router {
+func (app *application) tradeLeadsRoutes(adminPermissionMiddleware, tenantGroupPermissionMiddleware *alice.Chain) chi.Router {
 	tradeLeadsRoutes := chi.NewRouter()
 	// /trade_leads : for creating a new trade lead
 	tradeLeadsRoutes.Post("/", app.createTradeLeadHandler)
 	tradeLeadsRoutes.Get("/", app.getAllLeadsByTenantIDHandler)
+	// /trade_leads/stats : analytics of the user's tenant over a date range
+	tradeLeadsRoutes.Get("/stats", app.getTradeLeadStatsHandler)
+	// /trade_leads/stream : live created and updated leads of the user's tenant (SSE)
+	tradeLeadsRoutes.Get("/stream", app.streamTradeLeadsHandler)
+	// /trade_leads/{leadID}/assignee/{versionID} : assign, reassign or unassign a lead
+	tradeLeadsRoutes.Put("/{leadID:[0-9]+}/assignee/{versionID:[0-9]+}", app.assignTradeLeadHandler)
+	tradeLeadsRoutes.Delete("/{leadID:[0-9]+}/assignee/{versionID:[0-9]+}", app.unassignTradeLeadHandler)
+	// /trade_leads/{leadID}/comments : comments on a lead, and internal notes for admins
+	tradeLeadsRoutes.Post("/{leadID:[0-9]+}/comments", app.createTradeLeadCommentHandler)
+	tradeLeadsRoutes.Get("/{leadID:[0-9]+}/comments", app.getTradeLeadCommentsHandler)
+	tradeLeadsRoutes.Patch("/{leadID:[0-9]+}/comments/{commentID:[0-9]+}/{versionID:[0-9]+}", app.updateTradeLeadCommentHandler)
+	tradeLeadsRoutes.Delete("/{leadID:[0-9]+}/comments/{commentID:[0-9]+}", app.deleteTradeLeadCommentHandler)
+
+	// group routes, covering the user's tenant and all of its sub-tenants
+	tradeLeadsRoutes.With(tenantGroupPermissionMiddleware.Then).Get("/group", app.getGroupTradeLeadsHandler)
+	tradeLeadsRoutes.With(tenantGroupPermissionMiddleware.Then).Get("/group/stats", app.getGroupTradeLeadStatsHandler)
 
 	// admin routes
 	tradeLeadsRoutes.With(adminPermissionMiddleware.Then).Get("/admin", app.adminGetAllTradeLeadsHandler)
@@ -94,3 +156,30 @@ func (app *application) tradeLeadsRoutes(adminPermissionMiddleware *alice.Chain)
 	tradeLeadsRoutes.With(adminPermissionMiddleware.Then).Get("/admin/stats", app.adminGetTradeLeadStatsHandler)
 	return tradeLeadsRoutes
 }
+
+// emailRoutes() is a method that returns a chi.Router that contains all the routes for the
+// email outbox and templates
+func (app *application) emailRoutes(adminPermissionMiddleware *alice.Chain) chi.Router {
+	emailRoutes := chi.NewRouter()
+	// admin routes
+	emailRoutes.With(adminPermissionMiddleware.Then).Get("/admin", app.adminGetAllOutboxEmailsHandler)
+	emailRoutes.With(adminPermissionMiddleware.Then).Get("/admin/{emailID:[0-9]+}", app.adminGetOutboxEmailHandler)
+	// re-send an email that failed for good
+	emailRoutes.With(adminPermissionMiddleware.Then).Post("/admin/{emailID:[0-9]+}/resend", app.adminResendOutboxEmailHandler)
+	// email templates, rendered without sending
+	emailRoutes.With(adminPermissionMiddleware.Then).Get("/admin/templates", app.listEmailTemplatesHandler)
+	emailRoutes.With(adminPermissionMiddleware.Then).Get("/admin/templates/{templateName}/preview", app.previewEmailTemplateHandler)
+	emailRoutes.With(adminPermissionMiddleware.Then).Post("/admin/templates/{templateName}/preview", app.previewEmailTemplateHandler)
+	return emailRoutes
+}
+
+// exchangeRateRoutes() is a method that returns a chi.Router that contains all the routes for
+// the exchange rates used to convert trade lead values
+func (app *application) exchangeRateRoutes(adminPermissionMiddleware *alice.Chain) chi.Router {
+	exchangeRateRoutes := chi.NewRouter()
+	// /exchange_rates : the rates in effect on a date
+	exchangeRateRoutes.Get("/", app.getExchangeRatesHandler)
+	// admin routes
+	exchangeRateRoutes.With(adminPermissionMiddleware.Then).Post("/admin", app.adminImportExchangeRatesHandler)
+	return exchangeRateRoutes
+}
@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
@@ -131,3 +135,105 @@ func TestAuthenticationMiddlewareSecurityHeaders(t *testing.T) {
 
 	t.Log("SECURITY PASS: Vary: Authorization header set correctly")
 }
+
+// TestAdminLeadStatusPermissions runs the real authentication and admin handlers against the
+// in-memory stores: only an authenticated admin can verify a lead, and only at its current
+// version.
+func TestAdminLeadStatusPermissions(t *testing.T) {
+	ctx := context.Background()
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.NewMemoryModels(),
+	}
+	router := chi.NewRouter()
+	router.Use(app.authenticate)
+	router.Post("/v1/api/authentication", app.createAuthenticationApiKeyHandler)
+	router.With(app.requireAuthenticatedUser, app.requireActivatedUser, app.requirePermission(data.PermissionAdminWrite)).
+		Patch("/v1/trade_leads/admin/{leadID:[0-9]+}/{versionID:[0-9]+}", app.adminUpdateTradeLeadStatusHandler)
+
+	tenant := &data.Tenant{Name: "TradeHub KE", ContactEmail: "admin@tradehub.co.ke"}
+	if err := app.models.Tenants.CreateTenant(ctx, tenant); err != nil {
+		t.Fatal(err)
+	}
+	admin := &data.User{TenantID: tenant.ID, Name: "Admin", Email: "admin@tradehub.co.ke", Activated: true}
+	if err := admin.Password.Set("pa55word123"); err != nil {
+		t.Fatal(err)
+	}
+	member := &data.User{TenantID: tenant.ID, Name: "Member", Email: "member@tradehub.co.ke", Activated: true}
+	for _, user := range []*data.User{admin, member} {
+		if err := app.models.Users.Insert(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := app.models.Permissions.AddPermissionsForUser(ctx, admin.ID, data.PermissionAdminWrite); err != nil {
+		t.Fatal(err)
+	}
+	memberToken, err := app.models.Tokens.New(ctx, member.ID, time.Hour, data.ScopeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lead := &data.TradeLead{Title: "Arabica beans", Value: decimal.NewFromInt(1000)}
+	if err := app.models.TradeLeads.CreateTradeLead(ctx, tenant.ID, lead); err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, path, token string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// log in as the admin
+	rr := do(http.MethodPost, "/v1/api/authentication", "", map[string]string{"email": admin.Email, "password": "pa55word123"})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("login status = %d, body %s", rr.Code, rr.Body)
+	}
+	var login struct {
+		APIKey data.Token `json:"api_key"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &login); err != nil {
+		t.Fatal(err)
+	}
+	if rr := do(http.MethodPost, "/v1/api/authentication", "", map[string]string{"email": admin.Email, "password": "wr0ngpassword"}); rr.Code != http.StatusUnauthorized {
+		t.Errorf("login with a wrong password status = %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+
+	path := fmt.Sprintf("/v1/trade_leads/admin/%d/%d", lead.ID, lead.Version)
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "Anonymous", token: "", wantStatus: http.StatusUnauthorized},
+		{name: "Unknown token", token: "ABCDEFGHIJKLMNOPQRSTUVWXYZ", wantStatus: http.StatusUnauthorized},
+		{name: "Without permission", token: memberToken.Plaintext, wantStatus: http.StatusForbidden},
+		{name: "Admin", token: login.APIKey.Plaintext, wantStatus: http.StatusOK},
+		{name: "Stale version", token: login.APIKey.Plaintext, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := do(http.MethodPatch, path, tt.token, nil)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body %s", rr.Code, tt.wantStatus, rr.Body)
+			}
+		})
+	}
+
+	verified, err := app.models.TradeLeads.GetTradeLeadByID(ctx, lead.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.Status != "verified" || verified.Version != lead.Version+1 {
+		t.Errorf("lead after the updates = %q at version %d, want verified once", verified.Status, verified.Version)
+	}
+}
@@ -4,16 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/Blue-Davinci/leadhub-service/internal/stream"
 	"go.uber.org/zap"
 )
 
 func (app *application) server() error {
+	// every request context derives from baseCtx, cancelling it aborts the queries of
+	// requests that are still running once the shutdown grace period is over
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
 	// declare our http server
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", app.config.port),
@@ -21,7 +27,20 @@ func (app *application) server() error {
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 60 * time.Second,
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
 	}
+	// start the job workers, they are drained after the HTTP server stops
+	app.jobs.Start()
+	// listen for the trade lead events of every instance and close the open streams as
+	// soon as the shutdown begins, they would otherwise hold it up
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	defer stopListening()
+	go func() {
+		if err := stream.Listen(listenCtx, app.config.db.dsn, app.leadStream, app.logger); err != nil {
+			app.logger.Error("failed to listen for trade lead events", zap.Error(err))
+		}
+	}()
+	srv.RegisterOnShutdown(app.leadStream.Close)
 	// make a channel to listen for shutdown signals
 	shutdownChan := make(chan error)
 	// start a background routine, this will listen to any shutdown signals
@@ -34,16 +53,26 @@ func (app *application) server() error {
 		s := <-quit
 		// printout the signal details
 		app.logger.Info("shutting down server", zap.String("signal", s.String()))
+		// fail the readiness probe first so no new traffic is routed to this instance,
+		// optionally giving the orchestrator time to notice before we stop listening
+		app.shuttingDown.Store(true)
+		time.Sleep(app.config.health.drainDelay)
 		// make a 20sec context
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
 		err := srv.Shutdown(ctx)
+		// any request still running has overstayed the grace period
+		cancelBase()
 		if err != nil {
 			shutdownChan <- err
 		}
 		app.logger.Info("completing background tasks...", zap.String("addr", srv.Addr))
 		// wait for any background tasks to complete
 		app.wg.Wait()
+		// let running jobs finish, those outliving the grace period are retried later
+		if err := app.jobs.Shutdown(ctx); err != nil {
+			app.logger.Warn("background jobs did not finish in time", zap.Error(err))
+		}
 		// Call Shutdown() on our server, passing in the context we just made.
 		shutdownChan <- srv.Shutdown(ctx)
 	}()
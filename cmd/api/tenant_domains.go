@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/dnsverify"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+	"go.uber.org/zap"
+)
+
+// tenantDomainResponse adds the DNS record a tenant owner has to create to a domain.
+type tenantDomainResponse struct {
+	*data.TenantDomain
+	RecordName  string `json:"record_name"`
+	RecordValue string `json:"record_value"`
+}
+
+func newTenantDomainResponse(domain *data.TenantDomain) tenantDomainResponse {
+	return tenantDomainResponse{
+		TenantDomain: domain,
+		RecordName:   dnsverify.RecordName(domain.Domain),
+		RecordValue:  dnsverify.RecordValue(domain.VerificationToken),
+	}
+}
+
+// getTenantDomainsHandler() lists the email domains claimed by the user's tenant.
+func (app *application) getTenantDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	domains, err := app.models.Domains.GetTenantDomainsByTenantID(r.Context(), app.contextGetUser(r).TenantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	response := make([]tenantDomainResponse, 0, len(domains))
+	for _, domain := range domains {
+		response = append(response, newTenantDomainResponse(domain))
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"domains": response}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createTenantDomainHandler() claims an email domain for the tenant. The response holds
+// the TXT record that must be published before the domain can be verified.
+func (app *application) createTenantDomainHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Domain string `json:"domain"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	domain := &data.TenantDomain{
+		TenantID: app.contextGetUser(r).TenantID,
+		Domain:   data.NormalizeDomain(input.Domain),
+	}
+	v := validator.New()
+	if data.ValidateTenantDomain(v, domain.Domain); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if err := app.models.Domains.CreateTenantDomain(r.Context(), domain); err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateTenantDomain):
+			v.AddError("domain", "this domain has already been added")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err := app.writeJSON(w, http.StatusCreated, envelope{"domain": newTenantDomainResponse(domain)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// verifyTenantDomainHandler() looks up the domain's TXT record and marks the domain as
+// verified when it holds the expected token.
+func (app *application) verifyTenantDomainHandler(w http.ResponseWriter, r *http.Request) {
+	domainID, err := app.readIDParam(r, "domainID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	domain, err := app.models.Domains.GetTenantDomainByID(r.Context(), app.contextGetUser(r).TenantID, domainID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !domain.Verified {
+		ctx, cancel := context.WithTimeout(r.Context(), app.config.domains.verifyTimeout)
+		defer cancel()
+		err = app.domains.Verify(ctx, domain.Domain, domain.VerificationToken)
+		if err != nil {
+			v := validator.New()
+			switch {
+			case errors.Is(err, dnsverify.ErrRecordNotFound):
+				v.AddError("domain", "the verification TXT record could not be found")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.requestLogger(r).Warn("domain verification lookup failed", zap.String("domain", domain.Domain), zap.Error(err))
+				v.AddError("domain", "the verification TXT record could not be looked up, please try again later")
+				app.failedValidationResponse(w, r, v.Errors)
+			}
+			return
+		}
+		err = app.models.Domains.MarkTenantDomainVerified(r.Context(), domain)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrGeneralEditConflict):
+				app.editConflictResponse(w, r)
+			case errors.Is(err, data.ErrDomainAlreadyVerified):
+				v := validator.New()
+				v.AddError("domain", "this domain has already been verified by another tenant")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"domain": newTenantDomainResponse(domain)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteTenantDomainHandler() removes a domain from the tenant.
+func (app *application) deleteTenantDomainHandler(w http.ResponseWriter, r *http.Request) {
+	domainID, err := app.readIDParam(r, "domainID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	err = app.models.Domains.DeleteTenantDomain(r.Context(), app.contextGetUser(r).TenantID, domainID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "domain successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// assignRegistrationTenant() decides which tenant a registering user joins. A verified
+// email domain always wins over the tenant ID sent by the client. Otherwise the client's
+// tenant is used, unless that tenant restricts registration to its verified domains.
+// Validation problems are added to v.
+func (app *application) assignRegistrationTenant(ctx context.Context, user *data.User, v *validator.Validator) error {
+	domain, err := app.models.Domains.GetVerifiedTenantDomain(ctx, data.EmailDomain(user.Email))
+	switch {
+	case err == nil:
+		user.TenantID = domain.TenantID
+		return nil
+	case !errors.Is(err, data.ErrGeneralRecordNotFound):
+		return err
+	}
+	if user.TenantID < 1 {
+		v.AddError("tenant_id", "must be provided for email addresses outside a verified tenant domain")
+		return nil
+	}
+	restricted, err := app.models.Domains.TenantHasVerifiedDomains(ctx, user.TenantID)
+	if err != nil {
+		return err
+	}
+	if restricted {
+		v.AddError("email", "this tenant only accepts email addresses from its verified domains")
+	}
+	return nil
+}
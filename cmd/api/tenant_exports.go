@@ -0,0 +1,472 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/jobs"
+	"github.com/Blue-Davinci/leadhub-service/internal/storage"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidExportSignature = errors.New("invalid or expired download link")
+)
+
+// exportJob is the payload of an export.build job.
+type exportJob struct {
+	ExportID int64 `json:"export_id"`
+}
+
+// tenantExportArchive holds everything that goes into a tenant's export archive.
+type tenantExportArchive struct {
+	Tenant     *data.Tenant
+	Users      []*data.User
+	TradeLeads []*data.TradeLead
+	History    []*data.TradeLeadHistory
+	// Converter converts trade lead values to the reporting currency of the export, if any.
+	Converter *data.CurrencyConverter
+}
+
+// exportTradeLead is the exported representation of a trade lead, along with its value in
+// the reporting currency of the export when one was asked for.
+type exportTradeLead struct {
+	*data.TradeLead
+	ReportingCurrency string           `json:"reporting_currency,omitempty"`
+	ReportingValue    *decimal.Decimal `json:"reporting_value,omitempty"`
+}
+
+// exportUser is the exported representation of a user. Password hashes are never included.
+type exportUser struct {
+	ID        int64     `json:"id"`
+	TenantID  int64     `json:"tenant_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Activated bool      `json:"activated"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// createTenantExportHandler() starts an asynchronous export of all of the data belonging
+// to the user's tenant. The job status can be polled through getTenantExportHandler(). With a
+// currency query parameter, trade lead values are also exported converted to that currency
+// at the exchange rates in effect on the day of the export.
+func (app *application) createTenantExportHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	export := &data.TenantExport{
+		TenantID:    user.TenantID,
+		RequestedBy: user.ID,
+		Currency:    app.readString(r.URL.Query(), "currency", ""),
+	}
+	v := validator.New()
+	if v.Check(export.Currency == "" || data.IsCurrency(export.Currency), "currency", "must be an ISO 4217 currency code such as KES"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// record the export and enqueue the job building it together, so no export is left
+	// pending without a job. The user gets an email once the archive is ready.
+	err := app.models.RunInTx(r.Context(), func(tx data.Models) error {
+		err := tx.Exports.CreateTenantExport(r.Context(), export)
+		if err != nil {
+			return err
+		}
+		job, err := data.NewJob(jobKindBuildExport, exportJob{ExportID: export.ID})
+		if err != nil {
+			return err
+		}
+		return tx.Jobs.EnqueueJob(r.Context(), job)
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.requestLogger(r).Info("starting tenant export", zap.Int64("export_id", export.ID), zap.Int64("tenant_id", export.TenantID))
+	app.jobs.Notify()
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"export": export}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getTenantExportHandler() returns the status of an export job. Jobs belonging to another
+// tenant are reported as not found.
+func (app *application) getTenantExportHandler(w http.ResponseWriter, r *http.Request) {
+	exportID, err := app.readIDParam(r, "exportID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	export, err := app.models.Exports.GetTenantExportByID(r.Context(), exportID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if export.TenantID != app.contextGetUser(r).TenantID {
+		app.notFoundResponse(w, r)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"export": export}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// downloadTenantExportHandler() streams a finished export archive. The request does not need
+// an authentication token; instead it must carry a valid, unexpired signature as generated
+// by signExportDownload().
+func (app *application) downloadTenantExportHandler(w http.ResponseWriter, r *http.Request) {
+	exportID, err := app.readIDParam(r, "exportID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	qs := r.URL.Query()
+	expires, err := strconv.ParseInt(qs.Get("expires"), 10, 64)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusForbidden, ErrInvalidExportSignature.Error())
+		return
+	}
+	if err := app.verifyExportSignature(exportID, expires, qs.Get("signature")); err != nil {
+		app.errorResponse(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+	export, err := app.models.Exports.GetTenantExportByID(r.Context(), exportID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if export.Status != data.ExportStatusCompleted {
+		app.notFoundResponse(w, r)
+		return
+	}
+	archive, err := app.storage.Open(r.Context(), export.FileKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrObjectNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer archive.Close()
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="leadhub-export-%d.zip"`, export.ID))
+	w.Header().Set("Content-Length", strconv.FormatInt(export.FileSize, 10))
+	if _, err := io.Copy(w, archive); err != nil {
+		app.logError(r, err)
+	}
+}
+
+// buildTenantExportJob() runs an export.build job. A failed attempt puts the export back
+// to pending until the job gives up on it, then the export is marked as failed.
+func (app *application) buildTenantExportJob(ctx context.Context, job *data.Job) error {
+	var payload exportJob
+	if err := jobs.Decode(job, &payload); err != nil {
+		return err
+	}
+	export, err := app.models.Exports.GetTenantExportByID(ctx, payload.ExportID)
+	if err != nil {
+		if errors.Is(err, data.ErrGeneralRecordNotFound) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	// a job handed to another worker may find its export already finished
+	if export.Status == data.ExportStatusCompleted || export.Status == data.ExportStatusFailed {
+		return nil
+	}
+	export.Status = data.ExportStatusRunning
+	export.Error = ""
+	if err := app.models.Exports.UpdateTenantExport(ctx, export); err != nil {
+		return err
+	}
+	err = app.runTenantExport(ctx, export)
+	if err != nil {
+		app.recordTenantExportFailure(ctx, job, export, err)
+	}
+	return err
+}
+
+// runTenantExport() builds the export archive, stores it and emails the requesting user a
+// signed download link. The export is only marked as completed together with the email.
+func (app *application) runTenantExport(ctx context.Context, export *data.TenantExport) error {
+	tenant, size, err := app.buildTenantExport(ctx, export)
+	if err != nil {
+		return err
+	}
+	users, err := app.models.Users.GetAllUsersByTenantID(ctx, export.TenantID)
+	if err != nil {
+		return err
+	}
+	var user *data.User
+	for _, tenantUser := range users {
+		if tenantUser.ID == export.RequestedBy {
+			user = tenantUser
+		}
+	}
+	expires := time.Now().Add(app.config.export.linkTTL)
+	// the expiry is shown in the tenant's own time zone
+	_, settings := app.tenantBranding(ctx, tenant.ID)
+	// a failed transaction leaves the export as it was, so the failure can still be recorded
+	completed := *export
+	completedAt := time.Now()
+	completed.Status = data.ExportStatusCompleted
+	completed.FileKey = completed.StorageKey()
+	completed.FileSize = size
+	completed.CompletedAt = &completedAt
+	err = app.models.RunInTx(ctx, func(tx data.Models) error {
+		err := tx.Exports.UpdateTenantExport(ctx, &completed)
+		if err != nil || user == nil {
+			return err
+		}
+		emailData := map[string]any{
+			"userName":    user.Name,
+			"tenantName":  tenant.Name,
+			"downloadURL": app.signExportDownload(export.ID, expires),
+			"expiresAt":   expires.In(settings.Location()).Format(time.RFC1123),
+		}
+		return app.enqueueEmail(ctx, tx, user, "tenant_export_ready.tmpl", emailData)
+	})
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		app.logger.Warn("the requester of a tenant export no longer exists", zap.Int64("export_id", export.ID), zap.Int64("user_id", export.RequestedBy))
+		return nil
+	}
+	app.jobs.Notify()
+	return nil
+}
+
+// recordTenantExportFailure() stores the error of a failed export attempt. Failing to
+// record it is only logged, the job is retried or buried either way.
+func (app *application) recordTenantExportFailure(ctx context.Context, job *data.Job, export *data.TenantExport, err error) {
+	export.Status = data.ExportStatusPending
+	if jobs.IsPermanent(err) || jobs.LastAttempt(job) {
+		export.Status = data.ExportStatusFailed
+	}
+	export.Error = err.Error()
+	// the job's context may have timed out, which is likely why the export failed
+	recordErr := app.models.Exports.UpdateTenantExport(context.WithoutCancel(ctx), export)
+	if recordErr != nil {
+		app.logger.Error("failed to record the tenant export failure", zap.Int64("export_id", export.ID), zap.String("status", export.Status), zap.Error(recordErr))
+	}
+}
+
+// buildTenantExport() gathers the tenant's data and writes the archive to storage. It
+// returns the exported tenant and the size of the archive.
+func (app *application) buildTenantExport(ctx context.Context, export *data.TenantExport) (*data.Tenant, int64, error) {
+	archive := &tenantExportArchive{}
+	var err error
+	archive.Tenant, err = app.models.Tenants.GetTenantByID(ctx, export.TenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+	archive.Users, err = app.models.Users.GetAllUsersByTenantID(ctx, export.TenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+	archive.TradeLeads, err = app.models.TradeLeads.GetAllTradeLeadsForExport(ctx, export.TenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+	archive.History, err = app.models.TradeLeads.GetTradeLeadHistoryByTenantID(ctx, export.TenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if export.Currency != "" {
+		archive.Converter, err = newCurrencyConverter(ctx, app.models, export.Currency, export.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := writeTenantExportArchive(buf, archive); err != nil {
+		return nil, 0, err
+	}
+	size, err := app.storage.Put(ctx, export.StorageKey(), buf)
+	if err != nil {
+		return nil, 0, err
+	}
+	return archive.Tenant, size, nil
+}
+
+// writeTenantExportArchive() writes the archive as a ZIP file containing every dataset
+// both as JSON and as CSV. It fails when a trade lead value can't be converted to the
+// reporting currency.
+func writeTenantExportArchive(w io.Writer, archive *tenantExportArchive) error {
+	zw := zip.NewWriter(w)
+	leads := make([]exportTradeLead, 0, len(archive.TradeLeads))
+	for _, lead := range archive.TradeLeads {
+		exported := exportTradeLead{TradeLead: lead}
+		if archive.Converter != nil {
+			value, err := archive.Converter.Convert(lead.Value, lead.Currency)
+			if err != nil {
+				return err
+			}
+			value = value.Round(2)
+			exported.ReportingCurrency = archive.Converter.Currency
+			exported.ReportingValue = &value
+		}
+		leads = append(leads, exported)
+	}
+	users := make([]exportUser, 0, len(archive.Users))
+	for _, user := range archive.Users {
+		users = append(users, exportUser{
+			ID:        user.ID,
+			TenantID:  user.TenantID,
+			Name:      user.Name,
+			Email:     user.Email,
+			Activated: user.Activated,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+		})
+	}
+	jsonFiles := []struct {
+		name    string
+		content any
+	}{
+		{"tenant.json", archive.Tenant},
+		{"users.json", users},
+		{"trade_leads.json", leads},
+		{"trade_lead_history.json", archive.History},
+	}
+	for _, file := range jsonFiles {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(file.content); err != nil {
+			return err
+		}
+	}
+	// tenant.csv
+	tenant := archive.Tenant
+	err := writeExportCSV(zw, "tenant.csv",
+		[]string{"id", "name", "contact_email", "description", "created_at", "updated_at"},
+		[][]string{{
+			strconv.FormatInt(tenant.ID, 10), tenant.Name, tenant.ContactEmail, tenant.Description,
+			tenant.CreatedAt.Format(time.RFC3339), tenant.UpdatedAt.Format(time.RFC3339),
+		}})
+	if err != nil {
+		return err
+	}
+	// users.csv
+	rows := [][]string{}
+	for _, user := range users {
+		rows = append(rows, []string{
+			strconv.FormatInt(user.ID, 10), user.Name, user.Email, strconv.FormatBool(user.Activated),
+			user.CreatedAt.Format(time.RFC3339), user.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+	err = writeExportCSV(zw, "users.csv", []string{"id", "name", "email", "activated", "created_at", "updated_at"}, rows)
+	if err != nil {
+		return err
+	}
+	// trade_leads.csv
+	rows = [][]string{}
+	for _, lead := range leads {
+		customFields, err := json.Marshal(lead.CustomFields)
+		if err != nil {
+			return err
+		}
+		reportingValue := ""
+		if lead.ReportingValue != nil {
+			reportingValue = lead.ReportingValue.StringFixed(2)
+		}
+		rows = append(rows, []string{
+			strconv.FormatInt(lead.ID, 10), lead.Title, lead.Description, lead.Status, lead.Value.StringFixed(2), lead.Currency,
+			lead.ReportingCurrency, reportingValue, string(customFields), strconv.Itoa(int(lead.Version)),
+			lead.CreatedAt.Format(time.RFC3339), lead.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+	err = writeExportCSV(zw, "trade_leads.csv",
+		[]string{"id", "title", "description", "status", "value", "currency", "reporting_currency", "reporting_value", "custom_fields", "version", "created_at", "updated_at"}, rows)
+	if err != nil {
+		return err
+	}
+	// trade_lead_history.csv
+	rows = [][]string{}
+	for _, entry := range archive.History {
+		rows = append(rows, []string{
+			strconv.FormatInt(entry.ID, 10), strconv.FormatInt(entry.TradeLeadID, 10), entry.Status,
+			entry.Value.StringFixed(2), strconv.Itoa(int(entry.Version)), entry.ChangedAt.Format(time.RFC3339),
+		})
+	}
+	err = writeExportCSV(zw, "trade_lead_history.csv",
+		[]string{"id", "trade_lead_id", "status", "value", "version", "changed_at"}, rows)
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// writeExportCSV() adds a CSV file with the given header and rows to the archive.
+func writeExportCSV(zw *zip.Writer, name string, header []string, rows [][]string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// signExportDownload() returns a download URL for the export that stays valid until expires.
+func (app *application) signExportDownload(exportID int64, expires time.Time) string {
+	signature := app.exportSignature(exportID, expires.Unix())
+	return fmt.Sprintf("%s/%d/download?expires=%d&signature=%s",
+		app.config.url.exportDownloadURL, exportID, expires.Unix(), signature)
+}
+
+// verifyExportSignature() checks that a download link was signed by us and has not expired.
+func (app *application) verifyExportSignature(exportID, expires int64, signature string) error {
+	if time.Now().Unix() > expires {
+		return ErrInvalidExportSignature
+	}
+	expected := app.exportSignature(exportID, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidExportSignature
+	}
+	return nil
+}
+
+// exportSignature() computes the hex encoded HMAC-SHA256 of the export ID and expiry.
+func (app *application) exportSignature(exportID, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(app.config.export.signingSecret))
+	fmt.Fprintf(mac, "%d:%d", exportID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
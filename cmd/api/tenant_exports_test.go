@@ -0,0 +1,260 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/storage"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+func TestExportDownloadSignature(t *testing.T) {
+	app := &application{logger: zap.NewNop()}
+	app.config.export.signingSecret = "test-secret"
+	app.config.url.exportDownloadURL = "http://localhost:4000/v1/exports"
+
+	expires := time.Now().Add(time.Hour)
+	link, err := url.Parse(app.signExportDownload(42, expires))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Path != "/v1/exports/42/download" {
+		t.Errorf("unexpected download path: %s", link.Path)
+	}
+	signature := link.Query().Get("signature")
+
+	if err := app.verifyExportSignature(42, expires.Unix(), signature); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+	if err := app.verifyExportSignature(43, expires.Unix(), signature); err == nil {
+		t.Error("expected signature for a different export to be rejected")
+	}
+	if err := app.verifyExportSignature(42, expires.Unix()+1, signature); err == nil {
+		t.Error("expected tampered expiry to be rejected")
+	}
+	past := time.Now().Add(-time.Minute).Unix()
+	if err := app.verifyExportSignature(42, past, app.exportSignature(42, past)); err == nil {
+		t.Error("expected expired link to be rejected")
+	}
+}
+
+func TestWriteTenantExportArchive(t *testing.T) {
+	archive := &tenantExportArchive{
+		Tenant: &data.Tenant{ID: 1, Name: "TradeHub KE", ContactEmail: "admin@tradehub.co.ke"},
+		Users: []*data.User{
+			{ID: 7, TenantID: 1, Name: "Jane", Email: "jane@tradehub.co.ke", Activated: true},
+		},
+		TradeLeads: []*data.TradeLead{
+			{ID: 3, TenantID: 1, Title: "Coffee beans", Status: "new", Value: decimal.NewFromInt(1500), Currency: "KES"},
+		},
+		History: []*data.TradeLeadHistory{
+			{ID: 1, TradeLeadID: 3, TenantID: 1, Status: "new", Value: decimal.NewFromInt(1500), Version: 1},
+		},
+	}
+	buf := new(bytes.Buffer)
+	if err := writeTenantExportArchive(buf, archive); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+	for _, name := range []string{
+		"tenant.json", "users.json", "trade_leads.json", "trade_lead_history.json",
+		"tenant.csv", "users.csv", "trade_leads.csv", "trade_lead_history.csv",
+	} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected %s in archive", name)
+		}
+	}
+	if !strings.Contains(files["users.csv"], "jane@tradehub.co.ke") {
+		t.Errorf("users.csv missing user row: %s", files["users.csv"])
+	}
+	if strings.Contains(strings.ToLower(files["users.json"]), "password") {
+		t.Error("users.json must not contain password data")
+	}
+	if !strings.Contains(files["trade_leads.csv"], "1500.00,KES,,,") {
+		t.Errorf("trade_leads.csv missing lead value: %s", files["trade_leads.csv"])
+	}
+
+	// with a reporting currency, values are also exported converted
+	rates := []*data.ExchangeRate{{BaseCurrency: "USD", QuoteCurrency: "KES", Rate: decimal.NewFromInt(130)}}
+	archive.Converter = data.NewCurrencyConverter("USD", time.Now(), rates)
+	buf.Reset()
+	if err := writeTenantExportArchive(buf, archive); err != nil {
+		t.Fatal(err)
+	}
+	zr, err = zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+	if !strings.Contains(files["trade_leads.csv"], "1500.00,KES,USD,11.54,") {
+		t.Errorf("trade_leads.csv missing converted value: %s", files["trade_leads.csv"])
+	}
+	if !strings.Contains(files["trade_leads.json"], `"reporting_value": "11.54"`) {
+		t.Errorf("trade_leads.json missing converted value: %s", files["trade_leads.json"])
+	}
+	archive.Converter = data.NewCurrencyConverter("EUR", time.Now(), rates)
+	if err := writeTenantExportArchive(new(bytes.Buffer), archive); !errors.Is(err, data.ErrExchangeRateNotFound) {
+		t.Errorf("writeTenantExportArchive() without a rate error = %v, want ErrExchangeRateNotFound", err)
+	}
+}
+
+func TestBuildTenantExportJob(t *testing.T) {
+	ctx := context.Background()
+	exportStorage, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &application{
+		config:  config{env: "testing"},
+		logger:  zap.NewNop(),
+		models:  data.NewMemoryModels(),
+		storage: exportStorage,
+	}
+	app.config.export.signingSecret = "test-secret"
+	app.config.export.linkTTL = time.Hour
+	app.config.url.exportDownloadURL = "http://localhost:4000/v1/exports"
+	tenant := &data.Tenant{Name: "TradeHub KE", ContactEmail: "admin@tradehub.test"}
+	if err := app.models.Tenants.CreateTenant(ctx, tenant); err != nil {
+		t.Fatal(err)
+	}
+	user := &data.User{TenantID: tenant.ID, Name: "Ann", Email: "ann@tradehub.test", Activated: true}
+	if err := user.Password.Set("pa55word123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Users.Insert(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	lead := &data.TradeLead{Title: "Coffee", Value: decimal.NewFromInt(2500), Currency: "KES"}
+	if err := app.models.TradeLeads.CreateTradeLead(ctx, tenant.ID, lead); err != nil {
+		t.Fatal(err)
+	}
+
+	// requestExport() requests an export and returns it with the job building it
+	requestExport := func(t *testing.T, query string) (*data.TenantExport, *data.Job) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/v1/tenants/me/exports"+query, nil)
+		app.createTenantExportHandler(rr, app.contextSetUser(r, user))
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("status = %d: %s, want %d", rr.Code, rr.Body, http.StatusAccepted)
+		}
+		var response struct {
+			Export data.TenantExport `json:"export"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		claimed, err := app.models.Jobs.ClaimJobs(ctx, "test-worker", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(claimed) != 1 || claimed[0].Kind != jobKindBuildExport {
+			t.Fatalf("enqueued jobs = %+v, want one %s job", claimed, jobKindBuildExport)
+		}
+		return &response.Export, claimed[0]
+	}
+	getExport := func(t *testing.T, id int64) *data.TenantExport {
+		t.Helper()
+		export, err := app.models.Exports.GetTenantExportByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return export
+	}
+
+	t.Run("Completed", func(t *testing.T) {
+		export, job := requestExport(t, "")
+		if export.Status != data.ExportStatusPending {
+			t.Errorf("status of the new export = %q, want %q", export.Status, data.ExportStatusPending)
+		}
+		if err := app.buildTenantExportJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		export = getExport(t, export.ID)
+		if export.Status != data.ExportStatusCompleted || export.FileSize == 0 || export.CompletedAt == nil {
+			t.Errorf("export = %+v, want it completed", export)
+		}
+		claimed, err := app.models.Jobs.ClaimJobs(ctx, "test-worker", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(claimed) != 1 || claimed[0].Kind != jobKindSendEmail {
+			t.Fatalf("enqueued jobs = %+v, want one %s job", claimed, jobKindSendEmail)
+		}
+		var email emailJob
+		if err := json.Unmarshal(claimed[0].Payload, &email); err != nil {
+			t.Fatal(err)
+		}
+		if email.Recipient != user.Email || email.Template != "tenant_export_ready.tmpl" {
+			t.Errorf("email job = %+v", email)
+		}
+		// a job run again by another worker leaves the finished export alone
+		if err := app.buildTenantExportJob(ctx, job); err != nil {
+			t.Errorf("second run error = %v", err)
+		}
+		if again := getExport(t, export.ID); again.Version != export.Version {
+			t.Errorf("version after a second run = %d, want %d", again.Version, export.Version)
+		}
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		// there are no exchange rates to value the KES lead in USD
+		export, job := requestExport(t, "?currency=USD")
+		if err := app.buildTenantExportJob(ctx, job); err == nil {
+			t.Fatal("buildTenantExportJob() error = nil")
+		}
+		export = getExport(t, export.ID)
+		if export.Status != data.ExportStatusPending || export.Error == "" {
+			t.Errorf("export after a failed attempt = %+v, want it pending with the error", export)
+		}
+		// the last attempt buries the job
+		job.Attempts = job.MaxAttempts
+		if err := app.buildTenantExportJob(ctx, job); err == nil {
+			t.Fatal("buildTenantExportJob() error = nil")
+		}
+		export = getExport(t, export.ID)
+		if export.Status != data.ExportStatusFailed || export.Error == "" || export.CompletedAt != nil {
+			t.Errorf("export after the last attempt = %+v, want it failed", export)
+		}
+		claimed, err := app.models.Jobs.ClaimJobs(ctx, "test-worker", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(claimed) != 0 {
+			t.Errorf("enqueued jobs = %+v, want none", claimed)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/mailer"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+	"go.uber.org/zap"
+)
+
+// getTenantSettingsHandler() returns the settings and branding of the user's tenant.
+// Tenants that never saved any settings get the defaults with a version of 0.
+func (app *application) getTenantSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	settings, err := app.models.Settings.GetTenantSettings(r.Context(), app.contextGetUser(r).TenantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"settings": settings}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateTenantSettingsHandler() lets a tenant owner change their tenant's settings. Only
+// the provided fields are changed, and the version in the URL must match the current one.
+func (app *application) updateTenantSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	// settings that were never saved have a version of 0
+	version, err := app.readVersionParam(r, "versionID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	var input struct {
+		DisplayName     *string `json:"display_name"`
+		LogoURL         *string `json:"logo_url"`
+		PrimaryColor    *string `json:"primary_color"`
+		ReplyTo         *string `json:"reply_to"`
+		DefaultCurrency *string `json:"default_currency"`
+		Timezone        *string `json:"timezone"`
+		Locale          *string `json:"locale"`
+		AutoAssignLeads *bool   `json:"auto_assign_leads"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	settings, err := app.models.Settings.GetTenantSettings(r.Context(), app.contextGetUser(r).TenantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if input.DisplayName != nil {
+		settings.DisplayName = *input.DisplayName
+	}
+	if input.LogoURL != nil {
+		settings.LogoURL = *input.LogoURL
+	}
+	if input.PrimaryColor != nil {
+		settings.PrimaryColor = *input.PrimaryColor
+	}
+	if input.ReplyTo != nil {
+		settings.ReplyTo = *input.ReplyTo
+	}
+	if input.DefaultCurrency != nil {
+		settings.DefaultCurrency = *input.DefaultCurrency
+	}
+	if input.Timezone != nil {
+		settings.Timezone = *input.Timezone
+	}
+	if input.Locale != nil {
+		settings.Locale = *input.Locale
+	}
+	if input.AutoAssignLeads != nil {
+		settings.AutoAssignLeads = *input.AutoAssignLeads
+	}
+	v := validator.New()
+	if data.ValidateTenantSettings(v, settings); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.Settings.SaveTenantSettings(r.Context(), settings, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"settings": settings}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// tenantBranding() builds the email branding for a tenant from its settings, falling
+// back to the tenant name and the default LeadHub look. Failures are logged and never
+// stop an email from going out.
+func (app *application) tenantBranding(ctx context.Context, tenantID int64) (mailer.Branding, *data.TenantSettings) {
+	settings, err := app.models.Settings.GetTenantSettings(ctx, tenantID)
+	if err != nil {
+		app.contextLogger(ctx).Error("failed to load tenant settings", zap.Int64("tenant_id", tenantID), zap.Error(err))
+		return mailer.DefaultBranding(), data.DefaultTenantSettings(tenantID)
+	}
+	branding := mailer.Branding{
+		Name:         settings.DisplayName,
+		LogoURL:      settings.LogoURL,
+		PrimaryColor: settings.PrimaryColor,
+		ReplyTo:      settings.ReplyTo,
+	}
+	if branding.Name == "" {
+		tenant, err := app.models.Tenants.GetTenantByID(ctx, tenantID)
+		if err == nil {
+			branding.Name = tenant.Name
+		}
+	}
+	return branding, settings
+}
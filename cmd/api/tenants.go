@@ -17,7 +17,7 @@ func (app *application) getTenantByIDHandler(w http.ResponseWriter, r *http.Requ
 	user := app.contextGetUser(r)
 
 	// Fetch the tenant details from the database using the user ID and tenant ID.
-	tenant, err := app.models.Tenants.GetTenantByID(user.ID)
+	tenant, err := app.models.Tenants.GetTenantByID(r.Context(), user.ID)
 	if err != nil {
 		switch {
 		case err == data.ErrGeneralRecordNotFound:
@@ -60,7 +60,7 @@ func (app *application) adminGetAllTenantsHandler(w http.ResponseWriter, r *http
 		return
 	}
 	// Call the AdminGetAllTenants method to retrieve the tenants from the database.
-	tenants, metadata, err := app.models.Tenants.AdminGetAllTenants(input.Name, input.Filters)
+	tenants, metadata, err := app.models.Tenants.AdminGetAllTenants(r.Context(), input.Name, input.Filters)
 	if err != nil {
 		switch {
 		case err == data.ErrGeneralRecordNotFound:
@@ -82,6 +82,7 @@ func (app *application) adminGetAllTenantsHandler(w http.ResponseWriter, r *http
 // and then create the tenant in the database.
 func (app *application) createTenantHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
+		ParentID     *int64 `json:"parent_id"`
 		Name         string `json:"name"`
 		ContactEmail string `json:"contact_email"`
 		Description  string `json:"description"`
@@ -92,6 +93,7 @@ func (app *application) createTenantHandler(w http.ResponseWriter, r *http.Reque
 	}
 	// create a new tenant struct
 	tenant := &data.Tenant{
+		ParentID:     input.ParentID,
 		Name:         input.Name,
 		ContactEmail: input.ContactEmail,
 		Description:  input.Description,
@@ -104,11 +106,14 @@ func (app *application) createTenantHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	// Create the tenant in the database.
-	if err := app.models.Tenants.CreateTenant(tenant); err != nil {
+	if err := app.models.Tenants.CreateTenant(r.Context(), tenant); err != nil {
 		switch {
 		case err == data.ErrTenantAlreadyExists:
 			v.AddError("name", "tenant with this name already exists")
 			app.failedValidationResponse(w, r, v.Errors)
+		case err == data.ErrInvalidParentTenant:
+			v.AddError("parent_id", "the specified parent tenant does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
@@ -143,7 +148,9 @@ func (app *application) updateTenantHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	// make an input struct to hold the tenant details
+	// a parent_id of 0 detaches the tenant from its parent
 	var input struct {
+		ParentID     *int64  `json:"parent_id"`
 		Name         *string `json:"name"`
 		ContactEmail *string `json:"contact_email"`
 		Description  *string `json:"description"`
@@ -153,7 +160,7 @@ func (app *application) updateTenantHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	// get the tenant by ID
-	tenant, err := app.models.Tenants.GetTenantByID(tenantID)
+	tenant, err := app.models.Tenants.GetTenantByID(r.Context(), tenantID)
 	if err != nil {
 		switch {
 		case err == data.ErrGeneralRecordNotFound:
@@ -173,6 +180,12 @@ func (app *application) updateTenantHandler(w http.ResponseWriter, r *http.Reque
 	if input.Description != nil {
 		tenant.Description = *input.Description
 	}
+	if input.ParentID != nil {
+		tenant.ParentID = input.ParentID
+		if *input.ParentID == 0 {
+			tenant.ParentID = nil
+		}
+	}
 	// Validate the updated tenant details.
 	if data.ValidateTenant(v, tenant); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -184,13 +197,19 @@ func (app *application) updateTenantHandler(w http.ResponseWriter, r *http.Reque
 		app.badRequestResponse(w, r, errors.New("version ID out of range"))
 		return
 	}
-	if err := app.models.Tenants.UpdateTenant(tenant, int32(versionID)); err != nil {
+	if err := app.models.Tenants.UpdateTenant(r.Context(), tenant, int32(versionID)); err != nil {
 		switch {
 		case err == data.ErrGeneralRecordNotFound:
 			app.notFoundResponse(w, r)
 		case err == data.ErrTenantAlreadyExists:
 			v.AddError("name", "tenant with this name already exists")
 			app.failedValidationResponse(w, r, v.Errors)
+		case err == data.ErrInvalidParentTenant:
+			v.AddError("parent_id", "the specified parent tenant does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		case err == data.ErrTenantHierarchyCycle:
+			v.AddError("parent_id", "a tenant cannot be moved underneath itself or one of its sub-tenants")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
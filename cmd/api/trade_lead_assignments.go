@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+// assignTradeLeadHandler() assigns a trade lead of the user's tenant to one of the tenant's
+// activated users, replacing any previous assignee. The version in the URL must match the
+// lead's current version.
+func (app *application) assignTradeLeadHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		UserID *int64 `json:"user_id"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	if v.Check(input.UserID != nil, "user_id", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	app.updateTradeLeadAssignee(w, r, input.UserID)
+}
+
+// unassignTradeLeadHandler() removes the assignee of a trade lead of the user's tenant. The
+// version in the URL must match the lead's current version.
+func (app *application) unassignTradeLeadHandler(w http.ResponseWriter, r *http.Request) {
+	app.updateTradeLeadAssignee(w, r, nil)
+}
+
+// updateTradeLeadAssignee() sets the assignee of the trade lead in the URL and responds with
+// the updated lead. Leads of other tenants are reported as not found. A new assignee is
+// emailed about the lead, unless they assigned it to themselves.
+func (app *application) updateTradeLeadAssignee(w http.ResponseWriter, r *http.Request, assigneeID *int64) {
+	leadID, err := app.readIDParam(r, "leadID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	versionID, err := app.readIDParam(r, "versionID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate versionID can be safely converted to int32
+	if versionID > 2147483647 {
+		app.badRequestResponse(w, r, errors.New("version ID out of range"))
+		return
+	}
+	user := app.contextGetUser(r)
+	var lead *data.TradeLead
+	err = app.models.RunInTx(r.Context(), func(tx data.Models) error {
+		var err error
+		lead, err = tx.TradeLeads.GetTradeLeadByID(r.Context(), leadID)
+		if err != nil {
+			return err
+		}
+		if lead.TenantID != user.TenantID {
+			return data.ErrGeneralRecordNotFound
+		}
+		var assignee *data.User
+		if assigneeID != nil {
+			assignee, err = app.tradeLeadAssignee(r.Context(), tx, lead.TenantID, *assigneeID)
+			if err != nil {
+				return err
+			}
+		}
+		previousAssignee := lead.AssignedTo
+		err = tx.TradeLeads.AssignTradeLead(r.Context(), leadID, int32(versionID), assigneeID, lead)
+		if err != nil {
+			// the lead exists, so it was changed since the client read it
+			if errors.Is(err, data.ErrGeneralRecordNotFound) {
+				return data.ErrGeneralEditConflict
+			}
+			return err
+		}
+		err = app.publishTradeLeadEvent(r.Context(), tx, data.TradeLeadEventUpdated, lead)
+		if err != nil {
+			return err
+		}
+		eventData := envelope{"trade_lead": lead, "previous_assignee": previousAssignee}
+		err = app.publishWebhookEvent(r.Context(), tx, lead.TenantID, data.WebhookEventTradeLeadAssigned, eventData)
+		if err != nil {
+			return err
+		}
+		if assignee == nil || assignee.ID == user.ID || previousAssignee != nil && *previousAssignee == assignee.ID {
+			return nil
+		}
+		return app.enqueueTradeLeadAssignedEmail(r.Context(), tx, lead, assignee, user)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrGeneralEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrInvalidTradeLeadAssignee):
+			app.failedValidationResponse(w, r, map[string]string{"user_id": "must be an activated user of the lead's tenant"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.jobs.Notify()
+	err = app.writeJSON(w, http.StatusOK, envelope{"trade_lead": lead}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// tradeLeadAssignee() returns the user a lead of the tenant is assigned to. Only activated
+// users of the tenant can be assigned leads, other users return
+// data.ErrInvalidTradeLeadAssignee.
+func (app *application) tradeLeadAssignee(ctx context.Context, models data.Models, tenantID, userID int64) (*data.User, error) {
+	users, err := models.Users.GetAllUsersByTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if user.ID == userID && user.Activated {
+			return user, nil
+		}
+	}
+	return nil, data.ErrInvalidTradeLeadAssignee
+}
+
+// nextTradeLeadAssignee() picks the user a new lead of the tenant is assigned to when the
+// tenant assigns its leads automatically. It returns nil when the tenant has no activated
+// users, which leaves the lead unassigned.
+func (app *application) nextTradeLeadAssignee(ctx context.Context, models data.Models, tenantID int64) (*data.User, error) {
+	userID, err := models.TradeLeads.GetNextTradeLeadAssignee(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, data.ErrGeneralRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return app.tradeLeadAssignee(ctx, models, tenantID, userID)
+}
+
+// enqueueTradeLeadAssignedEmail() emails the assignee of a lead. assignedBy is the user who
+// assigned the lead, or nil when it was assigned automatically.
+func (app *application) enqueueTradeLeadAssignedEmail(ctx context.Context, models data.Models, lead *data.TradeLead, assignee, assignedBy *data.User) error {
+	emailData := map[string]any{
+		"userName":   assignee.Name,
+		"leadTitle":  lead.Title,
+		"leadValue":  lead.Value.StringFixed(2) + " " + lead.Currency,
+		"assignedBy": "",
+		"loginURL":   app.config.url.authenticationURL,
+	}
+	if assignedBy != nil {
+		emailData["assignedBy"] = assignedBy.Name
+	}
+	return app.enqueueEmail(ctx, models, assignee, "trade_lead_assigned.tmpl", emailData)
+}
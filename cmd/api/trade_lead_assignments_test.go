@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+func TestTradeLeadAssignment(t *testing.T) {
+	ctx := context.Background()
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.NewMemoryModels(),
+	}
+	tenant := &data.Tenant{Name: "TradeHub KE", ContactEmail: "admin@tradehub.test"}
+	otherTenant := &data.Tenant{Name: "Acme", ContactEmail: "admin@acme.test"}
+	for _, tenant := range []*data.Tenant{tenant, otherTenant} {
+		if err := app.models.Tenants.CreateTenant(ctx, tenant); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newUser := func(tenantID int64, name string, activated bool) *data.User {
+		user := &data.User{TenantID: tenantID, Name: name, Email: strings.ToLower(name) + "@tradehub.test", Activated: activated}
+		if err := user.Password.Set("pa55word123"); err != nil {
+			t.Fatal(err)
+		}
+		if err := app.models.Users.Insert(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	owner := newUser(tenant.ID, "Ann", true)
+	colleague := newUser(tenant.ID, "Baraka", true)
+	inactive := newUser(tenant.ID, "Chege", false)
+	outsider := newUser(otherTenant.ID, "Dan", true)
+	lead := &data.TradeLead{Title: "Coffee", Value: decimal.NewFromInt(2500), Currency: "KES"}
+	foreign := &data.TradeLead{Title: "Tea", Value: decimal.NewFromInt(100), Currency: "USD"}
+	if err := app.models.TradeLeads.CreateTradeLead(ctx, tenant.ID, lead); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.TradeLeads.CreateTradeLead(ctx, otherTenant.ID, foreign); err != nil {
+		t.Fatal(err)
+	}
+
+	router := chi.NewRouter()
+	router.Put("/trade_leads/{leadID}/assignee/{versionID}", app.assignTradeLeadHandler)
+	router.Delete("/trade_leads/{leadID}/assignee/{versionID}", app.unassignTradeLeadHandler)
+	tests := []struct {
+		name         string
+		method       string
+		leadID       int64
+		version      int32
+		body         string
+		wantStatus   int
+		wantAssignee *int64
+		wantEmail    bool
+	}{
+		{name: "Assign", method: http.MethodPut, leadID: lead.ID, version: 1, body: fmt.Sprintf(`{"user_id": %d}`, colleague.ID), wantStatus: http.StatusOK, wantAssignee: &colleague.ID, wantEmail: true},
+		{name: "Stale version", method: http.MethodPut, leadID: lead.ID, version: 1, body: fmt.Sprintf(`{"user_id": %d}`, owner.ID), wantStatus: http.StatusConflict},
+		{name: "Missing user", method: http.MethodPut, leadID: lead.ID, version: 2, body: `{}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "Inactive user", method: http.MethodPut, leadID: lead.ID, version: 2, body: fmt.Sprintf(`{"user_id": %d}`, inactive.ID), wantStatus: http.StatusUnprocessableEntity},
+		{name: "User of another tenant", method: http.MethodPut, leadID: lead.ID, version: 2, body: fmt.Sprintf(`{"user_id": %d}`, outsider.ID), wantStatus: http.StatusUnprocessableEntity},
+		{name: "Lead of another tenant", method: http.MethodPut, leadID: foreign.ID, version: 1, body: fmt.Sprintf(`{"user_id": %d}`, owner.ID), wantStatus: http.StatusNotFound},
+		{name: "Reassign to oneself", method: http.MethodPut, leadID: lead.ID, version: 2, body: fmt.Sprintf(`{"user_id": %d}`, owner.ID), wantStatus: http.StatusOK, wantAssignee: &owner.ID},
+		{name: "Unassign", method: http.MethodDelete, leadID: lead.ID, version: 3, wantStatus: http.StatusOK},
+		{name: "Unknown lead", method: http.MethodDelete, leadID: 999, version: 1, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := fmt.Sprintf("/trade_leads/%d/assignee/%d", tt.leadID, tt.version)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, app.contextSetUser(httptest.NewRequest(tt.method, target, strings.NewReader(tt.body)), owner))
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d: %s, want %d", rr.Code, rr.Body, tt.wantStatus)
+			}
+			if rr.Code == http.StatusOK {
+				var response struct {
+					Lead data.TradeLead `json:"trade_lead"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatal(err)
+				}
+				got := response.Lead.AssignedTo
+				if (got == nil) != (tt.wantAssignee == nil) || got != nil && *got != *tt.wantAssignee {
+					t.Errorf("assigned_to = %v, want %v", got, tt.wantAssignee)
+				}
+			}
+			// only a new assignee other than the user is emailed
+			claimed, err := app.models.Jobs.ClaimJobs(ctx, "test-worker", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.wantEmail {
+				if len(claimed) != 0 {
+					t.Errorf("enqueued jobs = %+v, want none", claimed)
+				}
+				return
+			}
+			if len(claimed) != 1 || claimed[0].Kind != jobKindSendEmail {
+				t.Fatalf("enqueued jobs = %+v, want one %s job", claimed, jobKindSendEmail)
+			}
+			var email emailJob
+			if err := json.Unmarshal(claimed[0].Payload, &email); err != nil {
+				t.Fatal(err)
+			}
+			if email.Recipient != colleague.Email || email.Template != "trade_lead_assigned.tmpl" ||
+				email.Data["assignedBy"] != owner.Name || email.Data["leadValue"] != "2500.00 KES" {
+				t.Errorf("email job = %+v", email)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+// errTradeLeadCommentNotPermitted is returned when a user changes a comment they may only
+// read, e.g. the comment of another user.
+var errTradeLeadCommentNotPermitted = errors.New("the comment can only be changed by its author")
+
+// createTradeLeadCommentHandler() adds a comment to a trade lead of the user's tenant. Admins
+// can comment on any lead, and only they can add internal notes. Users of the lead's tenant
+// who are @mentioned by their email address are emailed about the comment.
+func (app *application) createTradeLeadCommentHandler(w http.ResponseWriter, r *http.Request) {
+	leadID, err := app.readIDParam(r, "leadID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	var input struct {
+		Body     string `json:"body"`
+		Internal bool   `json:"internal"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	user := app.contextGetUser(r)
+	comment := &data.TradeLeadComment{
+		TradeLeadID: leadID,
+		AuthorID:    &user.ID,
+		AuthorName:  user.Name,
+		Body:        input.Body,
+		Internal:    input.Internal,
+	}
+	v := validator.New()
+	if data.ValidateTradeLeadComment(v, comment); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.models.RunInTx(r.Context(), func(tx data.Models) error {
+		lead, isAdmin, err := app.commentedTradeLead(r.Context(), tx, user, leadID)
+		if err != nil {
+			return err
+		}
+		if comment.Internal && !isAdmin {
+			return errTradeLeadCommentNotPermitted
+		}
+		comment.TenantID = lead.TenantID
+		err = tx.Comments.CreateTradeLeadComment(r.Context(), comment)
+		if err != nil {
+			return err
+		}
+		return app.notifyTradeLeadMentions(r.Context(), tx, lead, comment, user, "")
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, errTradeLeadCommentNotPermitted):
+			app.notPermittedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.jobs.Notify()
+	err = app.writeJSON(w, http.StatusCreated, envelope{"comment": comment}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getTradeLeadCommentsHandler() lists the comments of a trade lead of the user's tenant,
+// oldest first by default. Internal notes are only listed for admins.
+func (app *application) getTradeLeadCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	leadID, err := app.readIDParam(r, "leadID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	var input struct {
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "")
+	input.Filters.SortSafelist = []string{"", "created_at", "-created_at"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	_, isAdmin, err := app.commentedTradeLead(r.Context(), app.models, app.contextGetUser(r), leadID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	comments, metadata, err := app.models.Comments.GetTradeLeadComments(r.Context(), leadID, isAdmin, input.Filters)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"comments": comments, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateTradeLeadCommentHandler() edits the body of a comment. Only the author can edit a
+// comment, and the version in the URL must match the comment's current version. Users
+// mentioned for the first time by the edit are emailed about the comment.
+func (app *application) updateTradeLeadCommentHandler(w http.ResponseWriter, r *http.Request) {
+	leadID, err := app.readIDParam(r, "leadID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	commentID, err := app.readIDParam(r, "commentID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	versionID, err := app.readIDParam(r, "versionID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	// Validate versionID can be safely converted to int32
+	if versionID > 2147483647 {
+		app.badRequestResponse(w, r, errors.New("version ID out of range"))
+		return
+	}
+	var input struct {
+		Body string `json:"body"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	if data.ValidateTradeLeadComment(v, &data.TradeLeadComment{Body: input.Body}); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	user := app.contextGetUser(r)
+	var comment *data.TradeLeadComment
+	err = app.models.RunInTx(r.Context(), func(tx data.Models) error {
+		var (
+			lead    *data.TradeLead
+			isAdmin bool
+			err     error
+		)
+		lead, isAdmin, comment, err = app.commentOfTradeLead(r.Context(), tx, user, leadID, commentID)
+		if err != nil {
+			return err
+		}
+		if comment.AuthorID == nil || *comment.AuthorID != user.ID {
+			return errTradeLeadCommentNotPermitted
+		}
+		// an admin who lost their permissions can no longer edit their internal notes
+		if comment.Internal && !isAdmin {
+			return errTradeLeadCommentNotPermitted
+		}
+		previousBody := comment.Body
+		comment.Body = input.Body
+		err = tx.Comments.UpdateTradeLeadComment(r.Context(), comment, int32(versionID))
+		if err != nil {
+			return err
+		}
+		return app.notifyTradeLeadMentions(r.Context(), tx, lead, comment, user, previousBody)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrGeneralEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, errTradeLeadCommentNotPermitted):
+			app.notPermittedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.jobs.Notify()
+	err = app.writeJSON(w, http.StatusOK, envelope{"comment": comment}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteTradeLeadCommentHandler() removes a comment. Users can remove their own comments,
+// admins any comment they can see.
+func (app *application) deleteTradeLeadCommentHandler(w http.ResponseWriter, r *http.Request) {
+	leadID, err := app.readIDParam(r, "leadID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	commentID, err := app.readIDParam(r, "commentID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	user := app.contextGetUser(r)
+	err = app.models.RunInTx(r.Context(), func(tx data.Models) error {
+		_, isAdmin, comment, err := app.commentOfTradeLead(r.Context(), tx, user, leadID, commentID)
+		if err != nil {
+			return err
+		}
+		if !isAdmin && (comment.AuthorID == nil || *comment.AuthorID != user.ID) {
+			return errTradeLeadCommentNotPermitted
+		}
+		return tx.Comments.DeleteTradeLeadComment(r.Context(), leadID, commentID)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, errTradeLeadCommentNotPermitted):
+			app.notPermittedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "comment successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// commentedTradeLead() returns a lead whose comments the user can access, and whether the
+// user is an admin. Users access the leads of their own tenant, admins every lead. Other
+// leads are reported as not found.
+func (app *application) commentedTradeLead(ctx context.Context, models data.Models, user *data.User, leadID int64) (*data.TradeLead, bool, error) {
+	lead, err := models.TradeLeads.GetTradeLeadByID(ctx, leadID)
+	if err != nil {
+		return nil, false, err
+	}
+	permissions, err := models.Permissions.GetAllPermissionsForUser(ctx, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	isAdmin := permissions.IncludeAdmin()
+	if lead.TenantID != user.TenantID && !isAdmin {
+		return nil, false, data.ErrGeneralRecordNotFound
+	}
+	return lead, isAdmin, nil
+}
+
+// commentOfTradeLead() returns a comment the user can see along with its lead. Internal
+// notes are reported as not found to users who aren't admins.
+func (app *application) commentOfTradeLead(ctx context.Context, models data.Models, user *data.User, leadID, commentID int64) (*data.TradeLead, bool, *data.TradeLeadComment, error) {
+	lead, isAdmin, err := app.commentedTradeLead(ctx, models, user, leadID)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	comment, err := models.Comments.GetTradeLeadCommentByID(ctx, leadID, commentID)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	if comment.Internal && !isAdmin {
+		return nil, false, nil, data.ErrGeneralRecordNotFound
+	}
+	return lead, isAdmin, comment, nil
+}
+
+// notifyTradeLeadMentions() emails the users of the lead's tenant mentioned in a comment.
+// Only activated users are emailed, never the author, and internal notes only reach the
+// admins among them. Users already mentioned in the previous body of an edited comment are
+// not emailed again.
+func (app *application) notifyTradeLeadMentions(ctx context.Context, models data.Models, lead *data.TradeLead, comment *data.TradeLeadComment, author *data.User, previousBody string) error {
+	mentions := data.TradeLeadCommentMentions(comment.Body)
+	notified := data.TradeLeadCommentMentions(previousBody)
+	mentions = slices.DeleteFunc(mentions, func(email string) bool { return slices.Contains(notified, email) })
+	if len(mentions) == 0 {
+		return nil
+	}
+	users, err := models.Users.GetAllUsersByTenantID(ctx, lead.TenantID)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if !user.Activated || user.ID == author.ID || !slices.Contains(mentions, strings.ToLower(user.Email)) {
+			continue
+		}
+		if comment.Internal {
+			permissions, err := models.Permissions.GetAllPermissionsForUser(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+			if !permissions.IncludeAdmin() {
+				continue
+			}
+		}
+		err = app.enqueueEmail(ctx, models, user, "trade_lead_mention.tmpl", map[string]any{
+			"userName":    user.Name,
+			"authorName":  author.Name,
+			"leadTitle":   lead.Title,
+			"commentBody": comment.Body,
+			"loginURL":    app.config.url.authenticationURL,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+func TestTradeLeadComments(t *testing.T) {
+	ctx := context.Background()
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.NewMemoryModels(),
+	}
+	tenant := &data.Tenant{Name: "TradeHub KE", ContactEmail: "admin@tradehub.test"}
+	otherTenant := &data.Tenant{Name: "Acme", ContactEmail: "admin@acme.test"}
+	for _, tenant := range []*data.Tenant{tenant, otherTenant} {
+		if err := app.models.Tenants.CreateTenant(ctx, tenant); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newUser := func(tenantID int64, name string, activated bool, permissions ...string) *data.User {
+		user := &data.User{TenantID: tenantID, Name: name, Email: strings.ToLower(name) + "@tradehub.test", Activated: activated}
+		if err := user.Password.Set("pa55word123"); err != nil {
+			t.Fatal(err)
+		}
+		if err := app.models.Users.Insert(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		if len(permissions) > 0 {
+			if _, err := app.models.Permissions.AddPermissionsForUser(ctx, user.ID, permissions...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return user
+	}
+	owner := newUser(tenant.ID, "Ann", true)
+	colleague := newUser(tenant.ID, "Baraka", true)
+	newUser(tenant.ID, "Chege", false)
+	verifier := newUser(tenant.ID, "Wanjiku", true, data.PermissionAdminRead)
+	outsider := newUser(otherTenant.ID, "Dan", true)
+	lead := &data.TradeLead{Title: "Coffee", Value: decimal.NewFromInt(2500), Currency: "KES"}
+	if err := app.models.TradeLeads.CreateTradeLead(ctx, tenant.ID, lead); err != nil {
+		t.Fatal(err)
+	}
+
+	router := chi.NewRouter()
+	router.Post("/trade_leads/{leadID}/comments", app.createTradeLeadCommentHandler)
+	router.Get("/trade_leads/{leadID}/comments", app.getTradeLeadCommentsHandler)
+	router.Patch("/trade_leads/{leadID}/comments/{commentID}/{versionID}", app.updateTradeLeadCommentHandler)
+	router.Delete("/trade_leads/{leadID}/comments/{commentID}", app.deleteTradeLeadCommentHandler)
+	serve := func(t *testing.T, user *data.User, method, target, body string, wantStatus int) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, app.contextSetUser(httptest.NewRequest(method, target, strings.NewReader(body)), user))
+		if rr.Code != wantStatus {
+			t.Fatalf("%s %s: status = %d: %s, want %d", method, target, rr.Code, rr.Body, wantStatus)
+		}
+		return rr
+	}
+	// mentionedEmails() returns the recipients of the mention emails enqueued since the last call
+	mentionedEmails := func(t *testing.T) []string {
+		t.Helper()
+		claimed, err := app.models.Jobs.ClaimJobs(ctx, "test-worker", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recipients := []string{}
+		for _, job := range claimed {
+			var email emailJob
+			if err := json.Unmarshal(job.Payload, &email); err != nil {
+				t.Fatal(err)
+			}
+			if email.Template != "trade_lead_mention.tmpl" || email.Data["authorName"] == "" || email.Data["leadTitle"] != lead.Title {
+				t.Errorf("email job = %+v", email)
+			}
+			recipients = append(recipients, email.Recipient)
+		}
+		slices.Sort(recipients)
+		return recipients
+	}
+	create := func(t *testing.T, user *data.User, body string, internal bool, wantStatus int) *data.TradeLeadComment {
+		t.Helper()
+		payload, err := json.Marshal(map[string]any{"body": body, "internal": internal})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := serve(t, user, http.MethodPost, fmt.Sprintf("/trade_leads/%d/comments", lead.ID), string(payload), wantStatus)
+		var response struct {
+			Comment data.TradeLeadComment `json:"comment"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return &response.Comment
+	}
+	list := func(t *testing.T, user *data.User) []int64 {
+		t.Helper()
+		rr := serve(t, user, http.MethodGet, fmt.Sprintf("/trade_leads/%d/comments?page_size=10", lead.ID), "", http.StatusOK)
+		var response struct {
+			Comments []data.TradeLeadComment `json:"comments"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		ids := []int64{}
+		for _, comment := range response.Comments {
+			ids = append(ids, comment.ID)
+		}
+		return ids
+	}
+
+	// only activated users of the lead's tenant other than the author are emailed
+	body := "@baraka@tradehub.test @chege@tradehub.test @dan@tradehub.test @ann@tradehub.test please call the buyer"
+	comment := create(t, owner, body, false, http.StatusCreated)
+	if comment.AuthorID == nil || *comment.AuthorID != owner.ID || comment.AuthorName != owner.Name || comment.Version != 1 || comment.TenantID != tenant.ID {
+		t.Errorf("created comment = %+v", comment)
+	}
+	if got := mentionedEmails(t); !slices.Equal(got, []string{colleague.Email}) {
+		t.Errorf("mentioned = %v, want %v", got, []string{colleague.Email})
+	}
+	create(t, owner, "  ", false, http.StatusUnprocessableEntity)
+	create(t, owner, "Verified the buyer", true, http.StatusForbidden)
+	// internal notes only notify admins
+	note := create(t, verifier, "Documents verified, cc @ann@tradehub.test", true, http.StatusCreated)
+	if got := mentionedEmails(t); len(got) != 0 {
+		t.Errorf("mentioned in an internal note = %v, want none", got)
+	}
+
+	// internal notes are hidden from users of the tenant
+	if got, want := list(t, owner), []int64{comment.ID}; !slices.Equal(got, want) {
+		t.Errorf("comments listed to the owner = %v, want %v", got, want)
+	}
+	if got, want := list(t, verifier), []int64{comment.ID, note.ID}; !slices.Equal(got, want) {
+		t.Errorf("comments listed to the admin = %v, want %v", got, want)
+	}
+	serve(t, outsider, http.MethodGet, fmt.Sprintf("/trade_leads/%d/comments", lead.ID), "", http.StatusNotFound)
+	serve(t, owner, http.MethodGet, fmt.Sprintf("/trade_leads/%d/comments?sort=title", lead.ID), "", http.StatusUnprocessableEntity)
+
+	// only the author edits a comment, emailing the users it newly mentions
+	target := fmt.Sprintf("/trade_leads/%d/comments/%d/1", lead.ID, comment.ID)
+	serve(t, colleague, http.MethodPatch, target, `{"body": "hijacked"}`, http.StatusForbidden)
+	serve(t, owner, http.MethodPatch, fmt.Sprintf("/trade_leads/%d/comments/%d/1", lead.ID, note.ID), `{"body": "edited"}`, http.StatusNotFound)
+	serve(t, owner, http.MethodPatch, target, `{"body": "`+body+` @wanjiku@tradehub.test"}`, http.StatusOK)
+	if got := mentionedEmails(t); !slices.Equal(got, []string{verifier.Email}) {
+		t.Errorf("mentioned by the edit = %v, want %v", got, []string{verifier.Email})
+	}
+	serve(t, owner, http.MethodPatch, target, `{"body": "stale"}`, http.StatusConflict)
+
+	// users delete their own comments, admins any comment
+	serve(t, colleague, http.MethodDelete, fmt.Sprintf("/trade_leads/%d/comments/%d", lead.ID, comment.ID), "", http.StatusForbidden)
+	serve(t, owner, http.MethodDelete, fmt.Sprintf("/trade_leads/%d/comments/%d", lead.ID, note.ID), "", http.StatusNotFound)
+	serve(t, verifier, http.MethodDelete, fmt.Sprintf("/trade_leads/%d/comments/%d", lead.ID, comment.ID), "", http.StatusOK)
+	serve(t, owner, http.MethodGet, fmt.Sprintf("/trade_leads/%d/comments", lead.ID), "", http.StatusNotFound)
+}
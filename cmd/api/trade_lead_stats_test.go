@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+func TestTradeLeadStats(t *testing.T) {
+	ctx := context.Background()
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.NewMemoryModels(),
+	}
+	parent := &data.Tenant{Name: "TradeHub KE", ContactEmail: "admin@tradehub.test"}
+	if err := app.models.Tenants.CreateTenant(ctx, parent); err != nil {
+		t.Fatal(err)
+	}
+	child := &data.Tenant{Name: "TradeHub Mombasa", ContactEmail: "admin@mombasa.test", ParentID: &parent.ID}
+	if err := app.models.Tenants.CreateTenant(ctx, child); err != nil {
+		t.Fatal(err)
+	}
+	for i, tenantID := range []int64{parent.ID, parent.ID, child.ID} {
+		lead := &data.TradeLead{Title: fmt.Sprintf("Lead %d", i), Value: decimal.NewFromInt(100), Currency: "USD"}
+		if err := app.models.TradeLeads.CreateTradeLead(ctx, tenantID, lead); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if err := app.models.TradeLeads.AdminUpdateTradeLeadStatus(ctx, lead.ID, lead.Version, lead); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	user := &data.User{ID: 1, TenantID: parent.ID, Activated: true}
+	router := chi.NewRouter()
+	router.Get("/trade_leads/stats", app.getTradeLeadStatsHandler)
+	router.Get("/trade_leads/admin/stats", app.adminGetTradeLeadStatsHandler)
+	request := func(target string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, app.contextSetUser(httptest.NewRequest(http.MethodGet, target, nil), user))
+		return rr
+	}
+	today := time.Now().UTC().Format(time.DateOnly)
+
+	var tenantStats struct {
+		Stats data.TradeLeadAnalytics `json:"trade_lead_stats"`
+	}
+	rr := request("/trade_leads/stats?interval=week&currency=USD&to=" + today)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rr.Code, rr.Body)
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &tenantStats); err != nil {
+		t.Fatal(err)
+	}
+	stats := tenantStats.Stats
+	if stats.TotalLeads != 2 || stats.VerifiedLeads != 1 || stats.ConversionRate != 0.5 || stats.ByTenant != nil {
+		t.Errorf("tenant stats = %+v, want the 2 leads of the tenant alone", stats)
+	}
+	if last := stats.TimeSeries[len(stats.TimeSeries)-1]; last.Leads != 2 {
+		t.Errorf("the bucket of today holds %d leads, want 2", last.Leads)
+	}
+
+	// values are converted at the rates in effect on rate_date
+	rate := &data.ExchangeRate{BaseCurrency: "USD", QuoteCurrency: "KES", Rate: decimal.NewFromInt(130), RateDate: time.Now().UTC().AddDate(0, 0, -1)}
+	if err := app.models.ExchangeRates.SaveExchangeRate(ctx, rate); err != nil {
+		t.Fatal(err)
+	}
+	rr = request("/trade_leads/stats?currency=KES")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rr.Code, rr.Body)
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &tenantStats); err != nil {
+		t.Fatal(err)
+	}
+	if stats := tenantStats.Stats; stats.Currency != "KES" || !stats.TotalValue.Equal(decimal.NewFromInt(26000)) {
+		t.Errorf("stats in KES = %s %s, want 26000 KES", stats.TotalValue, stats.Currency)
+	}
+	// there is no rate before the one saved yesterday, nor any rate for EUR
+	yesterday := time.Now().UTC().AddDate(0, 0, -2).Format(time.DateOnly)
+	for _, query := range []string{"currency=KES&rate_date=" + yesterday, "currency=EUR"} {
+		rr := request("/trade_leads/stats?" + query)
+		if rr.Code != http.StatusUnprocessableEntity || !strings.Contains(rr.Body.String(), "no exchange rate") {
+			t.Errorf("%s: status = %d: %s, want no exchange rate", query, rr.Code, rr.Body)
+		}
+	}
+
+	var adminStats struct {
+		Currency  string                  `json:"currency"`
+		Analytics data.TradeLeadAnalytics `json:"trade_lead_analytics"`
+	}
+	for _, target := range []string{"/trade_leads/admin/stats", fmt.Sprintf("/trade_leads/admin/stats?tenant_id=%d", parent.ID)} {
+		rr = request(target)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", target, rr.Code, rr.Body)
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &adminStats); err != nil {
+			t.Fatal(err)
+		}
+		if analytics := adminStats.Analytics; analytics.TotalLeads != 3 || len(analytics.ByTenant) != 2 || analytics.ByTenant[1].TotalLeads != 1 {
+			t.Errorf("%s: analytics = %+v, want 3 leads across both tenants", target, analytics)
+		}
+		if adminStats.Currency != data.DefaultTenantCurrency || adminStats.Analytics.Currency != data.DefaultTenantCurrency {
+			t.Errorf("%s: currency = %q, want the default %q", target, adminStats.Currency, data.DefaultTenantCurrency)
+		}
+	}
+
+	for _, query := range []string{"interval=hour", "from=yesterday", "from=2026-02-01&to=2026-01-01", "from=2020-01-01&to=2026-01-01", "rate_date=today", "currency=usd"} {
+		if !strings.Contains(query, "currency=") {
+			query += "&currency=USD"
+		}
+		if rr := request("/trade_leads/stats?" + query); rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status = %d, want %d", query, rr.Code, http.StatusUnprocessableEntity)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/stream"
+)
+
+const (
+	// streamRetry is the reconnection delay suggested to clients, in milliseconds.
+	streamRetry = 3000
+	// defaultStreamHeartbeat keeps idle streams from being closed by proxies.
+	defaultStreamHeartbeat = 15 * time.Second
+)
+
+// streamTradeLeadsHandler() streams the created and updated trade leads of the user's tenant
+// as Server-Sent Events. A client reconnecting with a Last-Event-ID header, or a
+// last_event_id query parameter, first receives the events it missed. When they are no
+// longer buffered it receives a "reset" event and should reload its leads.
+func (app *application) streamTradeLeadsHandler(w http.ResponseWriter, r *http.Request) {
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var after int64
+	if lastEventID != "" {
+		var err error
+		after, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || after < 0 {
+			app.badRequestResponse(w, r, errors.New("invalid last event ID"))
+			return
+		}
+	}
+	sub, err := app.leadStream.Subscribe(app.contextGetUser(r).TenantID, after)
+	if err != nil {
+		switch {
+		case errors.Is(err, stream.ErrTooManyStreams):
+			app.errorResponse(w, r, http.StatusTooManyRequests, err.Error())
+		case errors.Is(err, stream.ErrBrokerClosed):
+			app.errorResponse(w, r, http.StatusServiceUnavailable, err.Error())
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer sub.Close()
+	// the stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// keep NGINX from buffering the events
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	if !sub.Resumed {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range sub.Replay {
+		writeTradeLeadEvent(w, event)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	heartbeat := time.NewTicker(cmp.Or(app.config.stream.heartbeat, defaultStreamHeartbeat))
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			// the stream fell behind or the server is shutting down, the client reconnects
+			if !ok {
+				return
+			}
+			writeTradeLeadEvent(w, event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeTradeLeadEvent() writes an event in the text/event-stream format. Its data is
+// compact JSON, which never spans more than one line.
+func writeTradeLeadEvent(w http.ResponseWriter, event *data.TradeLeadEvent) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
+
+// publishTradeLeadEvent() publishes a change to the lead to the live streams of its tenant.
+// Called inside a transaction, the event is only streamed once it commits.
+func (app *application) publishTradeLeadEvent(ctx context.Context, models data.Models, eventType string, lead *data.TradeLead) error {
+	event, err := data.NewTradeLeadEvent(eventType, lead)
+	if err != nil {
+		return err
+	}
+	return models.LeadEvents.PublishTradeLeadEvent(ctx, event)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/stream"
+	"go.uber.org/zap"
+)
+
+func TestStreamTradeLeads(t *testing.T) {
+	app := &application{
+		config:     config{env: "testing"},
+		logger:     zap.NewNop(),
+		models:     data.NewMemoryModels(),
+		leadStream: stream.NewBroker(stream.Config{MaxStreamsPerTenant: 1}),
+	}
+	app.config.stream.heartbeat = 50 * time.Millisecond
+	user := &data.User{ID: 1, TenantID: 1, Activated: true}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.streamTradeLeadsHandler(w, app.contextSetUser(r, user))
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	open := func(t *testing.T, lastEventID string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	// next() reads the stream up to the next blank line, skipping heartbeats unless asked for
+	next := func(t *testing.T, reader *bufio.Reader, heartbeats bool) string {
+		t.Helper()
+		var block strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading the stream: %v after %q", err, block.String())
+			}
+			if line != "\n" {
+				block.WriteString(line)
+				continue
+			}
+			if block.String() == ": heartbeat\n" && !heartbeats {
+				block.Reset()
+				continue
+			}
+			return block.String()
+		}
+	}
+	publish := func(id, tenantID int64) {
+		app.leadStream.Publish(&data.TradeLeadEvent{ID: id, TenantID: tenantID, Type: data.TradeLeadEventUpdated, Data: []byte(`{"trade_lead":{"id":7}}`)})
+	}
+
+	res := open(t, "")
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d, content type %q", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(res.Body)
+	if got := next(t, reader, false); got != "retry: 3000\n" {
+		t.Errorf("first block = %q, want the retry hint", got)
+	}
+	// a second stream of the tenant is over the cap
+	if second := open(t, ""); second.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("second stream status = %d, want %d", second.StatusCode, http.StatusTooManyRequests)
+	}
+	publish(41, 2)
+	publish(42, 1)
+	want := "id: 42\nevent: trade_lead.updated\ndata: {\"trade_lead\":{\"id\":7}}\n"
+	if got := next(t, reader, false); got != want {
+		t.Errorf("event = %q, want %q", got, want)
+	}
+	if got := next(t, reader, true); got != ": heartbeat\n" {
+		t.Errorf("idle stream sent %q, want a heartbeat", got)
+	}
+	publish(43, 1)
+	res.Body.Close()
+
+	// a reconnecting client gets what it missed
+	waitForStreams := func() {
+		for app.leadStream.Streams(1) != 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitForStreams()
+	res = open(t, "42")
+	reader = bufio.NewReader(res.Body)
+	next(t, reader, false)
+	if got := next(t, reader, false); !strings.HasPrefix(got, "id: 43\n") {
+		t.Errorf("resumed stream sent %q, want event 43", got)
+	}
+	res.Body.Close()
+	waitForStreams()
+	res = open(t, "7")
+	reader = bufio.NewReader(res.Body)
+	next(t, reader, false)
+	if got := next(t, reader, false); got != "event: reset\ndata: {}\n" {
+		t.Errorf("stream resumed from an evicted event sent %q, want a reset", got)
+	}
+
+	// streams end when the server shuts down
+	app.leadStream.Close()
+	if rest, err := io.ReadAll(res.Body); err != nil || strings.Contains(string(rest), "id:") {
+		t.Errorf("stream after Close() = %q, %v", rest, err)
+	}
+	res.Body.Close()
+
+	if res := open(t, "abc"); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid Last-Event-ID: status = %d, want %d", res.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -3,6 +3,9 @@ package main
 import (
 	"errors"
 	"net/http"
+	"net/url"
+	"slices"
+	"time"
 
 	"github.com/Blue-Davinci/leadhub-service/internal/data"
 	"github.com/Blue-Davinci/leadhub-service/internal/validator"
@@ -10,12 +13,17 @@ import (
 	"go.uber.org/zap"
 )
 
+// createTradeLeadHandler() creates a trade lead for the user's tenant. When the tenant
+// assigns its leads automatically, the lead goes to the next of its users in turn, who is
+// emailed about it.
 func (app *application) createTradeLeadHandler(w http.ResponseWriter, r *http.Request) {
 	// input struct
 	var input struct {
-		Title       string          `json:"title"`
-		Description string          `json:"description"`
-		Value       decimal.Decimal `json:"value"`
+		Title        string          `json:"title"`
+		Description  string          `json:"description"`
+		Value        decimal.Decimal `json:"value"`
+		Currency     string          `json:"currency"`
+		CustomFields map[string]any  `json:"custom_fields"`
 	}
 	// read the input from the request body
 	if err := app.readJSON(w, r, &input); err != nil {
@@ -24,19 +32,62 @@ func (app *application) createTradeLeadHandler(w http.ResponseWriter, r *http.Re
 	}
 	// make a new TradeLead struct
 	lead := &data.TradeLead{
-		Title:       input.Title,
-		Description: input.Description,
-		Value:       input.Value,
+		Title:        input.Title,
+		Description:  input.Description,
+		Value:        input.Value,
+		Currency:     input.Currency,
+		CustomFields: input.CustomFields,
+	}
+	// leads without a currency are valued in the tenant's default currency
+	settings, err := app.models.Settings.GetTenantSettings(r.Context(), app.contextGetUser(r).TenantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if lead.Currency == "" {
+		lead.Currency = settings.DefaultCurrency
+	}
+	// get the tenant's custom field schema to validate the custom fields against
+	definitions, err := app.models.CustomFields.GetCustomFieldsByTenantID(r.Context(), app.contextGetUser(r).TenantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 	// validate the input
 	v := validator.New()
-	if data.ValidateTradeLead(v, lead); !v.Valid() {
+	if data.ValidateTradeLead(v, lead, definitions); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	// create the trade lead in the database
+	// create the trade lead in the database and publish it to the tenant's streams and webhooks
 	// we use the user's tenant ID from the context to only create leads for the tenant they belong to
-	if err := app.models.TradeLeads.CreateTradeLead(app.contextGetUser(r).TenantID, lead); err != nil {
+	err = app.models.RunInTx(r.Context(), func(tx data.Models) error {
+		var assignee *data.User
+		if settings.AutoAssignLeads {
+			var err error
+			assignee, err = app.nextTradeLeadAssignee(r.Context(), tx, app.contextGetUser(r).TenantID)
+			if err != nil {
+				return err
+			}
+			if assignee != nil {
+				lead.AssignedTo = &assignee.ID
+			}
+		}
+		err := tx.TradeLeads.CreateTradeLead(r.Context(), app.contextGetUser(r).TenantID, lead)
+		if err != nil {
+			return err
+		}
+		err = app.publishTradeLeadEvent(r.Context(), tx, data.TradeLeadEventCreated, lead)
+		if err != nil {
+			return err
+		}
+		err = app.publishWebhookEvent(r.Context(), tx, lead.TenantID, data.WebhookEventTradeLeadCreated, envelope{"trade_lead": lead})
+		if err != nil || assignee == nil || assignee.ID == app.contextGetUser(r).ID {
+			return err
+		}
+		return app.enqueueTradeLeadAssignedEmail(r.Context(), tx, lead, assignee, nil)
+	})
+	if err != nil {
 		switch {
 		case err == data.ErrInvalidTenantReference:
 			app.notFoundResponse(w, r)
@@ -47,38 +98,65 @@ func (app *application) createTradeLeadHandler(w http.ResponseWriter, r *http.Re
 		}
 		return
 	}
-	err := app.writeJSON(w, http.StatusCreated, envelope{"trade_lead": lead}, nil)
+	app.jobs.Notify()
+	err = app.writeJSON(w, http.StatusCreated, envelope{"trade_lead": lead}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// tradeLeadSortSafelist() returns the standard sort values supported by the trade lead
+// listings. The empty value keeps the default newest-first order.
+func tradeLeadSortSafelist() []string {
+	return []string{"", "created_at", "-created_at", "title", "-title", "value", "-value"}
+}
+
 // getAllLeadsByTenantIDHandler() is a method that will handle requests to get all trade leads for a specific tenant.
+// mine=true narrows the listing down to the leads assigned to the user, and assignee to the
+// leads assigned to another user of the tenant.
 func (app *application) getAllLeadsByTenantIDHandler(w http.ResponseWriter, r *http.Request) {
 	// make a struct to hold what we would want from the queries
 	var input struct {
-		Name string
+		Name       string
+		AssigneeID int64
 		data.Filters
 	}
+	tenantID := app.contextGetUser(r).TenantID
+	// the tenant's custom fields can be used for filtering and sorting
+	definitions, err := app.models.CustomFields.GetCustomFieldsByTenantID(r.Context(), tenantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 	v := validator.New()
 	// Call r.URL.Query() to get the url.Values map containing the query string data.
 	qs := r.URL.Query()
 	// get our parameters
 	input.Name = app.readString(qs, "name", "")
+	mine := app.readBool(qs, "mine", false, v)
+	input.AssigneeID = int64(app.readInt(qs, "assignee", 0, v))
+	v.Check(input.AssigneeID >= 0, "assignee", "must be a user ID")
+	v.Check(!mine || input.AssigneeID == 0, "assignee", "must not be combined with mine")
+	if mine {
+		input.AssigneeID = app.contextGetUser(r).ID
+	}
+	customFilters := data.CustomFieldFilters{
+		Values:      app.readCustomFieldFilters(qs, definitions, v),
+		Definitions: definitions,
+	}
 	//get the page & pagesizes as ints and set to the embedded struct
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	// We don't use any sort for this endpoint
-	input.Filters.Sort = app.readString(qs, "", "")
-	// None of the sort values are supported for this endpoint
-	input.Filters.SortSafelist = []string{"", ""}
+	// Sort by a standard column or a custom field (cf.<key>), newest first by default
+	input.Filters.Sort = app.readString(qs, "sort", "")
+	input.Filters.SortSafelist = append(tradeLeadSortSafelist(), data.CustomFieldSortSafelist(definitions)...)
 	// Perform validation
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 	// Call the GetAllLeadsByTenantID method to retrieve the trade leads from the database.
-	leads, metadata, err := app.models.TradeLeads.GetAllLeadsByTenantID(app.contextGetUser(r).TenantID, input.Name, input.Filters)
+	leads, metadata, err := app.models.TradeLeads.GetAllLeadsByTenantID(r.Context(), tenantID, input.AssigneeID, input.Name, customFilters, input.Filters)
 	if err != nil {
 		switch {
 		case err == data.ErrGeneralRecordNotFound:
@@ -107,20 +185,34 @@ func (app *application) adminGetAllTradeLeadsHandler(w http.ResponseWriter, r *h
 	qs := r.URL.Query()
 	// get our parameters
 	input.Name = app.readString(qs, "name", "")
+	// custom fields are defined per tenant, so they can only be used once a tenant is picked
+	tenantID := int64(app.readInt(qs, "tenant_id", 0, v))
+	definitions := []*data.CustomField{}
+	if tenantID > 0 {
+		var err error
+		definitions, err = app.models.CustomFields.GetCustomFieldsByTenantID(r.Context(), tenantID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	customFilters := data.CustomFieldFilters{
+		Values:      app.readCustomFieldFilters(qs, definitions, v),
+		Definitions: definitions,
+	}
 	//get the page & pagesizes as ints and set to the embedded struct
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	// We don't use any sort for this endpoint
-	input.Filters.Sort = app.readString(qs, "", "")
-	// None of the sort values are supported for this endpoint
-	input.Filters.SortSafelist = []string{"", ""}
+	// Sort by a standard column or, with a tenant_id, a custom field (cf.<key>)
+	input.Filters.Sort = app.readString(qs, "sort", "")
+	input.Filters.SortSafelist = append(tradeLeadSortSafelist(), data.CustomFieldSortSafelist(definitions)...)
 	// Perform validation
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 	// Call the AdminGetAllTradeLeads method to retrieve the trade leads from the database.
-	leads, metadata, err := app.models.TradeLeads.AdminGetAllTradeLeads(input.Name, input.Filters)
+	leads, metadata, err := app.models.TradeLeads.AdminGetAllTradeLeads(r.Context(), tenantID, input.Name, customFilters, input.Filters)
 	if err != nil {
 		switch {
 		case err == data.ErrGeneralRecordNotFound:
@@ -138,25 +230,101 @@ func (app *application) adminGetAllTradeLeadsHandler(w http.ResponseWriter, r *h
 }
 
 // adminGetTradeLeadStatsHandler() is a method that will handle requests to retrieve trade lead statistics.
+// With a tenant_id query parameter the stats of that tenant are rolled up through its sub-tenants.
+// The response also carries the analytics of the leads created between from and to, across
+// every tenant or across the tenant's tree, broken down by tenant. Values are converted to the
+// currency query parameter, USD by default, at the exchange rates in effect on rate_date.
 func (app *application) adminGetTradeLeadStatsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	tenantID := int64(app.readInt(r.URL.Query(), "tenant_id", 0, v))
+	filter := app.readTradeLeadStatsFilter(r.URL.Query(), v)
+	converter, err := app.readCurrencyConverter(r, 0, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	filter.Converter = converter
+	if tenantID > 0 {
+		app.writeTenantTradeStats(w, r, tenantID, converter, &filter)
+		return
+	}
 	// Call the AdminGetTradeLeadStats method to retrieve the trade lead statistics from the database.
-	stats, err := app.models.TradeLeads.AdminGetTradeLeadStats()
+	stats, err := app.models.TradeLeads.AdminGetTradeLeadStats(r.Context(), converter)
 	if err != nil {
 		switch {
 		case err == data.ErrGeneralRecordNotFound:
 			app.notFoundResponse(w, r)
 		default:
-			app.serverErrorResponse(w, r, err)
+			app.currencyConversionErrorResponse(w, r, err)
 		}
 		return
 	}
+	analytics, err := app.models.TradeLeads.GetTradeLeadAnalytics(r.Context(), filter)
+	if err != nil {
+		app.currencyConversionErrorResponse(w, r, err)
+		return
+	}
 	// Write the trade lead statistics as a JSON response.
-	err = app.writeJSON(w, http.StatusOK, envelope{"trade_lead_stats": stats}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"trade_lead_stats": stats, "currency": converter.Currency, "trade_lead_analytics": analytics}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// getTradeLeadStatsHandler() returns the analytics of the trade leads created by the user's
+// tenant between from and to: counts per status, the conversion from new to verified, the
+// average time to verify and a time series bucketed by interval. Values are converted to the
+// currency query parameter, the tenant's default currency unless given.
+func (app *application) getTradeLeadStatsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	tenantID := app.contextGetUser(r).TenantID
+	filter := app.readTradeLeadStatsFilter(r.URL.Query(), v)
+	converter, err := app.readCurrencyConverter(r, tenantID, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	filter.TenantIDs = []int64{tenantID}
+	filter.Converter = converter
+	analytics, err := app.models.TradeLeads.GetTradeLeadAnalytics(r.Context(), filter)
+	if err != nil {
+		app.currencyConversionErrorResponse(w, r, err)
+		return
+	}
+	// a single tenant needs no breakdown by tenant
+	analytics.ByTenant = nil
+	err = app.writeJSON(w, http.StatusOK, envelope{"trade_lead_stats": analytics}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readTradeLeadStatsFilter() reads the from, to and interval query parameters of the stats
+// endpoints. A plain date for "to" includes that whole day. The range defaults to the last
+// 30 days, bucketed by day.
+func (app *application) readTradeLeadStatsFilter(qs url.Values, v *validator.Validator) data.TradeLeadStatsFilter {
+	var filter data.TradeLeadStatsFilter
+	to, dateOnly := app.readTime(qs, "to", time.Now().UTC(), v)
+	if dateOnly {
+		to = to.AddDate(0, 0, 1)
+	}
+	filter.To = to
+	filter.From, _ = app.readTime(qs, "from", to.Add(-data.DefaultTradeLeadStatsRange), v)
+	filter.Interval = app.readString(qs, "interval", data.TradeLeadStatsIntervalDay)
+	if v.Valid() {
+		data.ValidateTradeLeadStatsFilter(v, filter)
+	}
+	return filter
+}
+
 // adminUpdateTradeLeadStatusHandler() is a method that will handle requests to update the status of a trade lead.
 func (app *application) adminUpdateTradeLeadStatusHandler(w http.ResponseWriter, r *http.Request) {
 	// get trade ID from the URL parameters
@@ -171,11 +339,37 @@ func (app *application) adminUpdateTradeLeadStatusHandler(w http.ResponseWriter,
 		app.badRequestResponse(w, r, err)
 		return
 	}
-	app.logger.Info("Version and Lead ID", zap.Int64("leadID", leadID), zap.Int64("versionID", versionID))
-	// check if the lead exists
-	lead, err := app.models.TradeLeads.GetTradeLeadByID(leadID)
+	app.requestLogger(r).Info("Version and Lead ID", zap.Int64("leadID", leadID), zap.Int64("versionID", versionID))
+	// Validate versionID can be safely converted to int32 (range: -2,147,483,648 to 2,147,483,647)
+	if versionID > 2147483647 || versionID < -2147483648 {
+		app.badRequestResponse(w, r, errors.New("version ID out of range"))
+		return
+	}
+	// check if the lead exists and update its status in the same transaction, so the
+	// lead we respond with is the one we updated
+	var lead *data.TradeLead
+	err = app.models.RunInTx(r.Context(), func(tx data.Models) error {
+		var err error
+		lead, err = tx.TradeLeads.GetTradeLeadByID(r.Context(), leadID)
+		if err != nil {
+			return err
+		}
+		previousStatus := lead.Status
+		err = tx.TradeLeads.AdminUpdateTradeLeadStatus(r.Context(), leadID, int32(versionID), lead)
+		if err != nil {
+			return err
+		}
+		err = app.publishTradeLeadEvent(r.Context(), tx, data.TradeLeadEventUpdated, lead)
+		if err != nil || lead.Status == previousStatus {
+			return err
+		}
+		eventData := envelope{"trade_lead": lead, "previous_status": previousStatus}
+		return app.publishWebhookEvent(r.Context(), tx, lead.TenantID, data.WebhookEventTradeLeadStatusChanged, eventData)
+	})
 	if err != nil {
 		switch {
+		case err == data.ErrInvalidTradeLeadStatus:
+			app.badRequestResponse(w, r, err)
 		case err == data.ErrGeneralRecordNotFound:
 			app.notFoundResponse(w, r)
 		default:
@@ -183,28 +377,115 @@ func (app *application) adminUpdateTradeLeadStatusHandler(w http.ResponseWriter,
 		}
 		return
 	}
-	// let us update the lead status
-	// Validate versionID can be safely converted to int32 (range: -2,147,483,648 to 2,147,483,647)
-	if versionID > 2147483647 || versionID < -2147483648 {
-		app.badRequestResponse(w, r, errors.New("version ID out of range"))
+	app.jobs.Notify()
+	// Write a JSON response with the updated trade lead details.
+	err = app.writeJSON(w, http.StatusOK, envelope{"trade_lead": lead}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+
+}
+
+// getGroupTradeLeadsHandler() lists the trade leads of the user's tenant and all of its
+// sub-tenants for users holding the group role. An optional tenant_id narrows the listing
+// down to one tenant of the group.
+func (app *application) getGroupTradeLeadsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name string
+		data.Filters
+	}
+	tenantIDs, err := app.models.Tenants.GetTenantDescendantIDs(r.Context(), app.contextGetUser(r).TenantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Name = app.readString(qs, "name", "")
+	if tenantID := int64(app.readInt(qs, "tenant_id", 0, v)); tenantID > 0 {
+		// only tenants within the group can be picked
+		if !slices.Contains(tenantIDs, tenantID) {
+			app.notFoundResponse(w, r)
+			return
+		}
+		tenantIDs = []int64{tenantID}
+	}
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	// custom fields differ between tenants, so only the standard sorts are supported
+	input.Filters.Sort = app.readString(qs, "sort", "")
+	input.Filters.SortSafelist = tradeLeadSortSafelist()
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	err = app.models.TradeLeads.AdminUpdateTradeLeadStatus(leadID, int32(versionID), lead)
+	leads, metadata, err := app.models.TradeLeads.GetAllLeadsByTenantIDs(r.Context(), tenantIDs, 0, input.Name, data.CustomFieldFilters{}, input.Filters)
 	if err != nil {
 		switch {
-		case err == data.ErrInvalidTradeLeadStatus:
-			app.badRequestResponse(w, r, err)
-		case err == data.ErrGeneralRecordNotFound:
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
 			app.notFoundResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
-	// Write a JSON response with the updated trade lead details.
-	err = app.writeJSON(w, http.StatusOK, envelope{"trade_lead": lead}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"trade_leads": leads, "metadata": metadata}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
+}
+
+// getGroupTradeLeadStatsHandler() returns the trade lead stats of the user's tenant rolled
+// up through all of its sub-tenants, with values converted to the currency query parameter.
+func (app *application) getGroupTradeLeadStatsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	tenantID := app.contextGetUser(r).TenantID
+	converter, err := app.readCurrencyConverter(r, tenantID, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	app.writeTenantTradeStats(w, r, tenantID, converter, nil)
+}
 
+// writeTenantTradeStats() responds with the stats tree of a tenant and its sub-tenants, along
+// with the analytics of the whole tree when a filter is given.
+func (app *application) writeTenantTradeStats(w http.ResponseWriter, r *http.Request, tenantID int64, converter *data.CurrencyConverter, filter *data.TradeLeadStatsFilter) {
+	tree, err := app.models.Tenants.GetTenantTree(r.Context(), tenantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	tenantIDs := []int64{}
+	tree.Walk(func(t *data.Tenant) {
+		tenantIDs = append(tenantIDs, t.ID)
+	})
+	stats, err := app.models.TradeLeads.GetTradeLeadStatsByTenant(r.Context(), tenantIDs, converter)
+	if err != nil {
+		app.currencyConversionErrorResponse(w, r, err)
+		return
+	}
+	response := envelope{"trade_lead_stats": data.RollUpTradeStats(tree, stats), "currency": converter.Currency}
+	if filter != nil {
+		filter.TenantIDs = tenantIDs
+		analytics, err := app.models.TradeLeads.GetTradeLeadAnalytics(r.Context(), *filter)
+		if err != nil {
+			app.currencyConversionErrorResponse(w, r, err)
+			return
+		}
+		response["trade_lead_analytics"] = analytics
+	}
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
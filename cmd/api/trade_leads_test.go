@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"go.uber.org/zap"
+)
+
+func TestCreateTradeLead(t *testing.T) {
+	ctx := context.Background()
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.NewMemoryModels(),
+	}
+	tenant := &data.Tenant{Name: "TradeHub KE", ContactEmail: "admin@tradehub.test"}
+	if err := app.models.Tenants.CreateTenant(ctx, tenant); err != nil {
+		t.Fatal(err)
+	}
+	settings := data.DefaultTenantSettings(tenant.ID)
+	settings.DefaultCurrency = "KES"
+	settings.AutoAssignLeads = true
+	if err := app.models.Settings.SaveTenantSettings(ctx, settings, 0); err != nil {
+		t.Fatal(err)
+	}
+	field := &data.CustomField{TenantID: tenant.ID, Key: "grade", Label: "Grade", Type: data.CustomFieldTypeEnum, Required: true, Options: []string{"AA", "AB"}}
+	if err := app.models.CustomFields.CreateCustomField(ctx, field); err != nil {
+		t.Fatal(err)
+	}
+	newUser := func(name string) *data.User {
+		user := &data.User{TenantID: tenant.ID, Name: name, Email: strings.ToLower(name) + "@tradehub.test", Activated: true}
+		if err := user.Password.Set("pa55word123"); err != nil {
+			t.Fatal(err)
+		}
+		if err := app.models.Users.Insert(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+	owner := newUser("Ann")
+	colleague := newUser("Baraka")
+
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   int
+		wantCurrency string
+		wantAssignee int64
+		wantEmail    bool
+	}{
+		{name: "Missing custom field", body: `{"title": "Coffee", "value": "2500"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "Unknown custom field option", body: `{"title": "Coffee", "value": "2500", "custom_fields": {"grade": "PB"}}`, wantStatus: http.StatusUnprocessableEntity},
+		// leads are assigned round robin, the creator isn't emailed about their own lead
+		{name: "Default currency", body: `{"title": "Coffee", "value": "2500", "custom_fields": {"grade": "AA"}}`, wantStatus: http.StatusCreated, wantCurrency: "KES", wantAssignee: owner.ID},
+		{name: "Own currency", body: `{"title": "Tea", "value": "100", "currency": "USD", "custom_fields": {"grade": "AB"}}`, wantStatus: http.StatusCreated, wantCurrency: "USD", wantAssignee: colleague.ID, wantEmail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/v1/trade_leads", strings.NewReader(tt.body))
+			app.createTradeLeadHandler(rr, app.contextSetUser(r, owner))
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d: %s, want %d", rr.Code, rr.Body, tt.wantStatus)
+			}
+			claimed, err := app.models.Jobs.ClaimJobs(ctx, "test-worker", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rr.Code != http.StatusCreated {
+				if len(claimed) != 0 {
+					t.Errorf("enqueued jobs = %+v, want none", claimed)
+				}
+				return
+			}
+			var response struct {
+				Lead data.TradeLead `json:"trade_lead"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			lead := response.Lead
+			if lead.TenantID != tenant.ID || lead.Currency != tt.wantCurrency || lead.AssignedTo == nil || *lead.AssignedTo != tt.wantAssignee {
+				t.Errorf("trade lead = %+v, want currency %s assigned to %d", lead, tt.wantCurrency, tt.wantAssignee)
+			}
+			stored, err := app.models.TradeLeads.GetTradeLeadByID(ctx, lead.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.CustomFields["grade"] != lead.CustomFields["grade"] {
+				t.Errorf("stored custom fields = %v, want %v", stored.CustomFields, lead.CustomFields)
+			}
+			if !tt.wantEmail {
+				if len(claimed) != 0 {
+					t.Errorf("enqueued jobs = %+v, want none", claimed)
+				}
+				return
+			}
+			if len(claimed) != 1 || claimed[0].Kind != jobKindSendEmail {
+				t.Fatalf("enqueued jobs = %+v, want one %s job", claimed, jobKindSendEmail)
+			}
+			var email emailJob
+			if err := json.Unmarshal(claimed[0].Payload, &email); err != nil {
+				t.Fatal(err)
+			}
+			if email.Recipient != colleague.Email || email.Template != "trade_lead_assigned.tmpl" || email.Data["leadValue"] != "100.00 USD" {
+				t.Errorf("email job = %+v", email)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Name     string `json:"name"`
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Locale   string `json:"locale"`
 	}
 	err := app.readJSON(w, r, &input)
 	if err != nil {
@@ -28,6 +29,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		TenantID:  input.TenantID,
 		Name:      input.Name,
 		Email:     input.Email,
+		Locale:    input.Locale,
 		Activated: false,
 	}
 	// lets set the password for the user by using the Set method from the password struct
@@ -42,9 +44,35 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
+	// The tenant is decided by the email domain where possible, the client supplied
+	// tenant ID is only used for tenants without verified domains.
+	err = app.assignRegistrationTenant(r.Context(), user, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 
-	// insert our user to the DB
-	err = app.models.Users.Insert(user)
+	// Insert the user and enqueue the welcome email in one transaction, so a failure never
+	// leaves behind a user that can't be activated. The activation token is only created
+	// when the email is sent, see activationEmailData().
+	err = app.models.RunInTx(r.Context(), func(tx data.Models) error {
+		err := tx.Users.Insert(r.Context(), user)
+		if err != nil {
+			return err
+		}
+		emailData := map[string]any{
+			"userID": user.ID,
+		}
+		err = app.enqueueEmail(r.Context(), tx, user, "user_welcome.tmpl", emailData)
+		if err != nil {
+			return err
+		}
+		return app.publishWebhookEvent(r.Context(), tx, user.TenantID, data.WebhookEventUserCreated, envelope{"user": user})
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
@@ -59,27 +87,9 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 
 		return
 	}
-	app.logger.Info("registering a new user", zap.String("email", user.Email), zap.Int64("tenant_id", user.TenantID))
-	// After the user record has been created in the database, generate a new activation
-	// token for the user.
-	token, err := app.models.Tokens.New(user.ID, 3*24*time.Hour, data.ScopeActivation)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
-	}
-
-	app.background(func() {
-		data := map[string]any{
-			"activationURL":   app.config.url.activationURL + token.Plaintext,
-			"activationToken": token.Plaintext,
-			"userID":          user.ID,
-		}
-		// Send the welcome email, passing in the map above as dynamic data.
-		err = app.mailer.Send(user.Email, "user_welcome.tmpl", data)
-		if err != nil {
-			app.logger.Error("failed to send welcome email", zap.String("email", user.Email), zap.Error(err))
-		}
-	})
+	app.requestLogger(r).Info("registering a new user", zap.String("email", user.Email), zap.Int64("tenant_id", user.TenantID))
+	// send the welcome email right away instead of on the next poll
+	app.jobs.Notify()
 
 	//write our 202 response back to the user and check for any errors
 	err = app.writeJSON(w, http.StatusAccepted, envelope{"user": user}, nil)
@@ -110,7 +120,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 	// Retrieve the details of the user associated with the token using the
 	// GetForToken() method. If no matching record is found, then we let the
 	// client know that the token they provided is not valid.
-	user, err := app.models.Users.GetForToken(data.ScopeActivation, input.TokenPlaintext)
+	user, err := app.models.Users.GetForToken(r.Context(), data.ScopeActivation, input.TokenPlaintext)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrGeneralRecordNotFound):
@@ -121,12 +131,31 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return
 	}
-	app.logger.Info("User Version: ", zap.Int("Version", int(user.Version)))
+	app.requestLogger(r).Info("User Version: ", zap.Int("Version", int(user.Version)))
 	// Update the user's activation status.
 	user.Activated = true
-	// Save the updated user record in our database, checking for any edit conflicts in
-	// the same way that we did for our movie records.
-	err = app.models.Users.UpdateUser(user)
+	// Save the updated user record, checking for any edit conflicts, delete all of the
+	// user's activation tokens and enqueue the activation email in one transaction so a
+	// used token never outlives the activation.
+	err = app.models.RunInTx(r.Context(), func(tx data.Models) error {
+		err := tx.Users.UpdateUser(r.Context(), user)
+		if err != nil {
+			return err
+		}
+		err = tx.Tokens.DeleteAllForUser(r.Context(), data.ScopeActivation, user.ID)
+		if err != nil {
+			return err
+		}
+		emailData := map[string]any{
+			"loginURL": app.config.url.authenticationURL,
+			"userName": user.Name,
+		}
+		err = app.enqueueEmail(r.Context(), tx, user, "user_succesful_activation.tmpl", emailData)
+		if err != nil {
+			return err
+		}
+		return app.publishWebhookEvent(r.Context(), tx, user.TenantID, data.WebhookEventUserActivated, envelope{"user": user})
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrGeneralEditConflict):
@@ -136,29 +165,8 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return
 	}
-	// If everything went successfully, then we delete all activation tokens for the
-	// user.
-	err = app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-		return
-	}
 	// Succesful, so we send an email for a succesful activation
-	app.background(func() {
-		// As there are now multiple pieces of data that we want to pass to our email
-		// templates, we create a map to act as a 'holding structure' for the data. This
-		// contains the plaintext version of the activation token for the user, along
-		// with their ID.
-		data := map[string]any{
-			"loginURL": app.config.url.authenticationURL,
-			"userName": user.Name,
-		}
-		// Send the welcome email, passing in the map above as dynamic data.
-		err = app.mailer.Send(user.Email, "user_succesful_activation.tmpl", data)
-		if err != nil {
-			app.logger.Error("Error sending welcome email", zap.String("email", user.Email), zap.Error(err))
-		}
-	})
+	app.jobs.Notify()
 	// minimize data we send back to the client
 	newUser := data.UserSubInfo{
 		Name:      user.Name,
@@ -173,6 +181,41 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 
 }
 
+// updateUserLocaleHandler() changes the locale the authenticated user's emails are written
+// in. An empty locale makes the user's emails follow the tenant's locale again.
+func (app *application) updateUserLocaleHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Locale string `json:"locale"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	if data.ValidateLocale(v, input.Locale); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// update a copy, the user in the request context stays as it was authenticated
+	user := *app.contextGetUser(r)
+	user.Locale = input.Locale
+	err = app.models.Users.UpdateUser(r.Context(), &user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
 func (app *application) createAuthenticationApiKeyHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Email    string `json:"email"`
@@ -193,7 +236,7 @@ func (app *application) createAuthenticationApiKeyHandler(w http.ResponseWriter,
 		return
 	}
 	// get the user from the database
-	user, err := app.models.Users.GetByEmail(input.Email)
+	user, err := app.models.Users.GetByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		// if the user is not found, we return an invalid credentials response
@@ -218,7 +261,7 @@ func (app *application) createAuthenticationApiKeyHandler(w http.ResponseWriter,
 	}
 	// Otherwise, if the password is correct, we generate a new api_key with a 72-hour
 	// expiry time and the scope 'authentication', saving it to the DB
-	bearer_token, err := app.models.Tokens.New(user.ID, 72*time.Hour, data.ScopeAuthentication)
+	bearer_token, err := app.models.Tokens.New(r.Context(), user.ID, 72*time.Hour, data.ScopeAuthentication)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
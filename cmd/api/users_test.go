@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"go.uber.org/zap"
+)
+
+func TestRegisterUser(t *testing.T) {
+	ctx := context.Background()
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.NewMemoryModels(),
+	}
+	tenant := &data.Tenant{Name: "TradeHub KE", ContactEmail: "admin@tradehub.test"}
+	restricted := &data.Tenant{Name: "Acme", ContactEmail: "admin@acme.test"}
+	for _, tenant := range []*data.Tenant{tenant, restricted} {
+		if err := app.models.Tenants.CreateTenant(ctx, tenant); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Acme verified acme.test and has yet to verify its second domain
+	for _, name := range []string{"acme.test", "acme-unverified.test"} {
+		domain := &data.TenantDomain{TenantID: restricted.ID, Domain: name}
+		if err := app.models.Domains.CreateTenantDomain(ctx, domain); err != nil {
+			t.Fatal(err)
+		}
+		if name != "acme.test" {
+			continue
+		}
+		if err := app.models.Domains.MarkTenantDomainVerified(ctx, domain); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		tenantID   int64
+		email      string
+		locale     string
+		wantStatus int
+		wantTenant int64
+		wantError  string
+	}{
+		{name: "Register", tenantID: tenant.ID, email: "ann@tradehub.test", locale: "sw", wantStatus: http.StatusAccepted, wantTenant: tenant.ID},
+		// a verified domain decides the tenant, whatever the client sent
+		{name: "Verified domain", tenantID: tenant.ID, email: "eve@Acme.test", wantStatus: http.StatusAccepted, wantTenant: restricted.ID},
+		{name: "Verified domain without tenant", email: "fay@acme.test", wantStatus: http.StatusAccepted, wantTenant: restricted.ID},
+		{name: "Outside the verified domains", tenantID: restricted.ID, email: "gus@tradehub.test", wantStatus: http.StatusUnprocessableEntity, wantError: "email"},
+		{name: "Unverified domain", tenantID: restricted.ID, email: "hal@acme-unverified.test", wantStatus: http.StatusUnprocessableEntity, wantError: "email"},
+		{name: "Duplicate email", tenantID: tenant.ID, email: "ANN@tradehub.test", wantStatus: http.StatusUnprocessableEntity, wantError: "email"},
+		{name: "Unknown tenant", tenantID: 999, email: "baraka@tradehub.test", wantStatus: http.StatusUnprocessableEntity, wantError: "tenant_id"},
+		{name: "Missing tenant", email: "chege@tradehub.test", wantStatus: http.StatusUnprocessableEntity, wantError: "tenant_id"},
+		{name: "Malformed locale", tenantID: tenant.ID, email: "dan@tradehub.test", locale: "not a locale", wantStatus: http.StatusUnprocessableEntity, wantError: "locale"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"tenant_id": %d, "name": "Ann", "email": %q, "password": "pa55word123", "locale": %q}`, tt.tenantID, tt.email, tt.locale)
+			rr := httptest.NewRecorder()
+			app.registerUserHandler(rr, httptest.NewRequest(http.MethodPost, "/v1/api/users", strings.NewReader(body)))
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d: %s, want %d", rr.Code, rr.Body, tt.wantStatus)
+			}
+			claimed, err := app.models.Jobs.ClaimJobs(ctx, "test-worker", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rr.Code != http.StatusAccepted {
+				var response struct {
+					Error map[string]string `json:"error"`
+				}
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := response.Error[tt.wantError]; !ok {
+					t.Errorf("errors = %v, want one for %q", response.Error, tt.wantError)
+				}
+				if len(claimed) != 0 {
+					t.Errorf("enqueued jobs = %+v, want none", claimed)
+				}
+				return
+			}
+
+			var response struct {
+				User data.User `json:"user"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if response.User.TenantID != tt.wantTenant {
+				t.Errorf("tenant_id = %d, want %d", response.User.TenantID, tt.wantTenant)
+			}
+			user, err := app.models.Users.GetByEmail(ctx, tt.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.ID != response.User.ID || user.TenantID != tt.wantTenant || user.Activated || user.Locale != tt.locale {
+				t.Errorf("registered user = %+v", user)
+			}
+			// the user is welcomed in their own locale
+			if len(claimed) != 1 || claimed[0].Kind != jobKindSendEmail {
+				t.Fatalf("enqueued jobs = %+v, want one %s job", claimed, jobKindSendEmail)
+			}
+			var email emailJob
+			if err := json.Unmarshal(claimed[0].Payload, &email); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.EqualFold(email.Recipient, tt.email) || email.TenantID != tt.wantTenant || email.Template != "user_welcome.tmpl" || email.Locale != tt.locale {
+				t.Errorf("email job = %+v", email)
+			}
+		})
+	}
+}
+
+func TestUpdateUserLocale(t *testing.T) {
+	ctx := context.Background()
+	app := &application{
+		config: config{env: "testing"},
+		logger: zap.NewNop(),
+		models: data.NewMemoryModels(),
+	}
+	tenant := &data.Tenant{Name: "TradeHub KE", ContactEmail: "admin@tradehub.test"}
+	if err := app.models.Tenants.CreateTenant(ctx, tenant); err != nil {
+		t.Fatal(err)
+	}
+	user := &data.User{TenantID: tenant.ID, Name: "Ann", Email: "ann@tradehub.test", Activated: true}
+	if err := user.Password.Set("pa55word123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Users.Insert(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	// a user authenticated before another change was saved
+	stale := *user
+
+	tests := []struct {
+		name       string
+		user       *data.User
+		body       string
+		wantStatus int
+		wantLocale string
+	}{
+		{name: "Set locale", user: user, body: `{"locale": "sw"}`, wantStatus: http.StatusOK, wantLocale: "sw"},
+		{name: "Malformed locale", user: user, body: `{"locale": "Swahili"}`, wantStatus: http.StatusUnprocessableEntity, wantLocale: "sw"},
+		{name: "Stale user", user: &stale, body: `{"locale": "fr"}`, wantStatus: http.StatusConflict, wantLocale: "sw"},
+		{name: "Follow the tenant", body: `{"locale": ""}`, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticated := tt.user
+			if authenticated == nil {
+				// the user as authenticated for this request
+				var err error
+				authenticated, err = app.models.Users.GetByEmail(ctx, user.Email)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPut, "/v1/api/me/locale", strings.NewReader(tt.body))
+			app.updateUserLocaleHandler(rr, app.contextSetUser(r, authenticated))
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d: %s, want %d", rr.Code, rr.Body, tt.wantStatus)
+			}
+			stored, err := app.models.Users.GetByEmail(ctx, user.Email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Locale != tt.wantLocale || !stored.Activated {
+				t.Errorf("stored user = %+v, want locale %q", stored, tt.wantLocale)
+			}
+			if ok, _ := stored.Password.Matches("pa55word123"); !ok {
+				t.Error("updating the locale lost the password")
+			}
+		})
+	}
+}
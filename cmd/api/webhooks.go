@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/jobs"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+	"github.com/Blue-Davinci/leadhub-service/internal/webhooks"
+	"go.uber.org/zap"
+)
+
+// errWebhookEndpointDisabled fails the deliveries that were pending when their endpoint
+// got disabled.
+var errWebhookEndpointDisabled = errors.New("the webhook endpoint is disabled")
+
+// webhookJob is the payload of a webhook.deliver job.
+type webhookJob struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+// getWebhookEndpointsHandler() lists the webhook endpoints of the user's tenant.
+func (app *application) getWebhookEndpointsHandler(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := app.models.Webhooks.GetWebhookEndpointsByTenantID(r.Context(), app.contextGetUser(r).TenantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"webhooks": endpoints, "event_types": data.WebhookEventTypes}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createWebhookEndpointHandler() registers a webhook endpoint for the tenant. The response
+// holds the secret signing its payloads, which is not shown again.
+func (app *application) createWebhookEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		URL         string   `json:"url"`
+		Description string   `json:"description"`
+		EventTypes  []string `json:"event_types"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	endpoint := &data.WebhookEndpoint{
+		TenantID:    app.contextGetUser(r).TenantID,
+		URL:         input.URL,
+		Description: input.Description,
+		EventTypes:  input.EventTypes,
+	}
+	v := validator.New()
+	if data.ValidateWebhookEndpoint(v, endpoint); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	existing, err := app.models.Webhooks.GetWebhookEndpointsByTenantID(r.Context(), endpoint.TenantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if len(existing) >= data.MaxWebhookEndpointsPerTenant {
+		v.AddError("url", fmt.Sprintf("a tenant can't have more than %d webhook endpoints", data.MaxWebhookEndpointsPerTenant))
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if err := app.models.Webhooks.CreateWebhookEndpoint(r.Context(), endpoint); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusCreated, envelope{"webhook": endpoint, "secret": endpoint.Secret}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getWebhookEndpointHandler() returns a webhook endpoint of the user's tenant.
+func (app *application) getWebhookEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := app.readWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+	err := app.writeJSON(w, http.StatusOK, envelope{"webhook": endpoint}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateWebhookEndpointHandler() updates the URL, description and event types of a webhook
+// endpoint, and enables or disables it. Enabling an endpoint that was disabled after its
+// failures clears them.
+func (app *application) updateWebhookEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	versionID, err := app.readIDParam(r, "versionID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	var input struct {
+		URL         *string  `json:"url"`
+		Description *string  `json:"description"`
+		EventTypes  []string `json:"event_types"`
+		Enabled     *bool    `json:"enabled"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	endpoint, ok := app.readWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+	if input.URL != nil {
+		endpoint.URL = *input.URL
+	}
+	if input.Description != nil {
+		endpoint.Description = *input.Description
+	}
+	if input.EventTypes != nil {
+		endpoint.EventTypes = input.EventTypes
+	}
+	if input.Enabled != nil {
+		endpoint.Enabled = *input.Enabled
+	}
+	v := validator.New()
+	if data.ValidateWebhookEndpoint(v, endpoint); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// Validate versionID can be safely converted to int32
+	if versionID > 2147483647 {
+		app.badRequestResponse(w, r, errors.New("version ID out of range"))
+		return
+	}
+	if err := app.models.Webhooks.UpdateWebhookEndpoint(r.Context(), endpoint, int32(versionID)); err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"webhook": endpoint}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteWebhookEndpointHandler() removes a webhook endpoint and its delivery log. Pending
+// deliveries to it are dropped.
+func (app *application) deleteWebhookEndpointHandler(w http.ResponseWriter, r *http.Request) {
+	endpointID, err := app.readIDParam(r, "webhookID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	err = app.models.Webhooks.DeleteWebhookEndpoint(r.Context(), app.contextGetUser(r).TenantID, endpointID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "webhook endpoint successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getWebhookDeliveriesHandler() lists the delivery log of a webhook endpoint, newest first.
+// It can be filtered by status.
+func (app *application) getWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Status string
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Status = app.readString(qs, "status", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	// deliveries are always listed newest first
+	input.Filters.Sort = ""
+	input.Filters.SortSafelist = []string{""}
+	data.ValidateWebhookDeliveryStatus(v, input.Status)
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	endpoint, ok := app.readWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+	deliveries, metadata, err := app.models.Webhooks.GetWebhookDeliveriesByEndpointID(r.Context(), endpoint.ID, input.Status, input.Filters)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"deliveries": deliveries, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// redeliverWebhookHandler() sends the event of a delivery to its endpoint again, as a new
+// delivery with the same payload. Events can't be redelivered to a disabled endpoint.
+func (app *application) redeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	deliveryID, err := app.readIDParam(r, "deliveryID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	endpoint, ok := app.readWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+	original, err := app.models.Webhooks.GetWebhookDeliveryByID(r.Context(), deliveryID)
+	if err != nil || original.EndpointID != endpoint.ID {
+		switch {
+		case err == nil || errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !endpoint.Enabled {
+		app.errorResponse(w, r, http.StatusConflict, "the webhook endpoint is disabled, enable it before redelivering events")
+		return
+	}
+	delivery := &data.WebhookDelivery{
+		EndpointID: endpoint.ID,
+		TenantID:   endpoint.TenantID,
+		EventID:    original.EventID,
+		EventType:  original.EventType,
+		Payload:    original.Payload,
+	}
+	err = app.models.RunInTx(r.Context(), func(tx data.Models) error {
+		err := tx.Webhooks.CreateWebhookDelivery(r.Context(), delivery)
+		if err != nil {
+			return err
+		}
+		return app.enqueueWebhookDelivery(r.Context(), tx, delivery)
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.jobs.Notify()
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"delivery": delivery}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readWebhookEndpoint() returns the webhook endpoint of the user's tenant named by the
+// webhookID URL parameter. It writes the error response and returns false when there is none.
+func (app *application) readWebhookEndpoint(w http.ResponseWriter, r *http.Request) (*data.WebhookEndpoint, bool) {
+	endpointID, err := app.readIDParam(r, "webhookID")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return nil, false
+	}
+	endpoint, err := app.models.Webhooks.GetWebhookEndpointByID(r.Context(), app.contextGetUser(r).TenantID, endpointID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrGeneralRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+	return endpoint, true
+}
+
+// publishWebhookEvent() records a delivery of the event to every enabled endpoint of the
+// tenant subscribed to its type and enqueues the jobs sending them. Passing the models of
+// a transaction publishes the event only if the transaction commits; call
+// app.jobs.Notify() afterwards to send it without waiting for the next poll.
+func (app *application) publishWebhookEvent(ctx context.Context, models data.Models, tenantID int64, eventType string, eventData any) error {
+	endpoints, err := models.Webhooks.GetSubscribedWebhookEndpoints(ctx, tenantID, eventType)
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+	event, err := webhooks.NewEvent(eventType, tenantID, eventData)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return models.RunInTx(ctx, func(tx data.Models) error {
+		for _, endpoint := range endpoints {
+			delivery := &data.WebhookDelivery{
+				EndpointID: endpoint.ID,
+				TenantID:   tenantID,
+				EventID:    event.ID,
+				EventType:  eventType,
+				Payload:    payload,
+			}
+			if err := tx.Webhooks.CreateWebhookDelivery(ctx, delivery); err != nil {
+				return err
+			}
+			if err := app.enqueueWebhookDelivery(ctx, tx, delivery); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// enqueueWebhookDelivery() enqueues the job sending a recorded delivery. It is retried with
+// the queue's backoff up to the configured number of attempts.
+func (app *application) enqueueWebhookDelivery(ctx context.Context, models data.Models, delivery *data.WebhookDelivery) error {
+	job, err := data.NewJob(jobKindDeliverWebhook, webhookJob{DeliveryID: delivery.ID})
+	if err != nil {
+		return err
+	}
+	if app.config.webhooks.maxAttempts > 0 {
+		job.MaxAttempts = int32(app.config.webhooks.maxAttempts)
+	}
+	return models.Jobs.EnqueueJob(ctx, job)
+}
+
+// deliverWebhookJob() sends the event of a webhook.deliver job to its endpoint and records
+// the attempt. Deliveries of deleted endpoints are dropped, those of disabled endpoints
+// fail without being sent.
+func (app *application) deliverWebhookJob(ctx context.Context, job *data.Job) error {
+	var payload webhookJob
+	if err := jobs.Decode(job, &payload); err != nil {
+		return err
+	}
+	delivery, err := app.models.Webhooks.GetWebhookDeliveryByID(ctx, payload.DeliveryID)
+	if err != nil {
+		if errors.Is(err, data.ErrGeneralRecordNotFound) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	endpoint, err := app.models.Webhooks.GetWebhookEndpointByID(ctx, delivery.TenantID, delivery.EndpointID)
+	if err != nil {
+		if errors.Is(err, data.ErrGeneralRecordNotFound) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	if !endpoint.Enabled {
+		err := jobs.Permanent(errWebhookEndpointDisabled)
+		app.recordWebhookAttempt(ctx, job, delivery, nil, err)
+		return err
+	}
+	response, err := app.webhooks.Send(ctx, webhooks.Request{
+		URL:        endpoint.URL,
+		Secret:     endpoint.Secret,
+		DeliveryID: delivery.ID,
+		EventType:  delivery.EventType,
+		Body:       delivery.Payload,
+	})
+	if errors.Is(err, webhooks.ErrForbiddenAddress) {
+		err = jobs.Permanent(err)
+	}
+	app.recordWebhookAttempt(ctx, job, delivery, response, err)
+	return err
+}
+
+// recordWebhookAttempt() stores the outcome of a delivery attempt in the delivery log. A
+// failed attempt leaves the delivery pending until the job gives up on it. Once the
+// delivery succeeded or failed for good it counts towards the endpoint's failures in a
+// row, which disable the endpoint when there are too many of them. Failing to record the
+// outcome is only logged, retrying the job would send a delivered event twice.
+func (app *application) recordWebhookAttempt(ctx context.Context, job *data.Job, delivery *data.WebhookDelivery, response *webhooks.Response, err error) {
+	// the job's context may have timed out, which is likely why the delivery failed
+	ctx = context.WithoutCancel(ctx)
+	attempt := data.WebhookAttempt{Status: data.WebhookDeliverySucceeded}
+	if response != nil {
+		attempt.ResponseStatus = int32(response.StatusCode)
+		attempt.ResponseBody = response.Body
+		attempt.Duration = response.Duration
+	}
+	switch {
+	case err == nil:
+	case jobs.IsPermanent(err) || jobs.LastAttempt(job):
+		attempt.Status = data.WebhookDeliveryFailed
+		attempt.LastError = err.Error()
+	default:
+		attempt.Status = data.WebhookDeliveryPending
+		attempt.LastError = err.Error()
+	}
+	logger := app.logger.With(zap.Int64("delivery_id", delivery.ID), zap.Int64("endpoint_id", delivery.EndpointID))
+	recordErr := app.models.Webhooks.RecordWebhookDeliveryAttempt(ctx, delivery.ID, attempt)
+	if recordErr != nil {
+		logger.Error("failed to record the webhook delivery attempt", zap.String("status", attempt.Status), zap.Error(recordErr))
+	}
+	// deliveries skipped because the endpoint is disabled don't count against it
+	if attempt.Status == data.WebhookDeliveryPending || errors.Is(err, errWebhookEndpointDisabled) {
+		return
+	}
+	succeeded := attempt.Status == data.WebhookDeliverySucceeded
+	enabled, recordErr := app.models.Webhooks.RecordWebhookEndpointResult(ctx, delivery.EndpointID, succeeded, int32(app.config.webhooks.disableAfter))
+	switch {
+	case errors.Is(recordErr, data.ErrGeneralRecordNotFound):
+		// the endpoint was deleted in the meantime
+	case recordErr != nil:
+		logger.Error("failed to record the webhook endpoint result", zap.Error(recordErr))
+	case !enabled && attempt.Status == data.WebhookDeliveryFailed:
+		logger.Warn("webhook endpoint is disabled after its failed deliveries", zap.Int64("tenant_id", delivery.TenantID))
+	}
+}
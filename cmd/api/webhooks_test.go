@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/webhooks"
+	"github.com/go-chi/chi"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// webhookReceiver is a local webhook endpoint recording the requests it is sent.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.requests = append(rcv.requests, r)
+	rcv.bodies = append(rcv.bodies, body)
+	w.WriteHeader(rcv.status)
+	fmt.Fprintf(w, "status %d", rcv.status)
+}
+
+func (rcv *webhookReceiver) respondWith(status int) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.status = status
+}
+
+func (rcv *webhookReceiver) last() (*http.Request, []byte, int) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return rcv.requests[len(rcv.requests)-1], rcv.bodies[len(rcv.bodies)-1], len(rcv.requests)
+}
+
+func TestWebhooks(t *testing.T) {
+	ctx := context.Background()
+	app := &application{
+		config:   config{env: "testing"},
+		logger:   zap.NewNop(),
+		models:   data.NewMemoryModels(),
+		webhooks: webhooks.NewClient(webhooks.Config{AllowPrivateNetworks: true}),
+	}
+	app.config.webhooks.maxAttempts = 2
+	app.config.webhooks.disableAfter = 2
+	receiver := &webhookReceiver{status: http.StatusOK}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	var owners []*data.User
+	for _, name := range []string{"TradeHub KE", "Acme Imports"} {
+		tenant := &data.Tenant{Name: name, ContactEmail: "admin@" + strings.ReplaceAll(strings.ToLower(name), " ", "") + ".test"}
+		if err := app.models.Tenants.CreateTenant(ctx, tenant); err != nil {
+			t.Fatal(err)
+		}
+		owner := &data.User{TenantID: tenant.ID, Name: "Owner", Email: tenant.ContactEmail, Activated: true}
+		if err := app.models.Users.Insert(ctx, owner); err != nil {
+			t.Fatal(err)
+		}
+		owners = append(owners, owner)
+	}
+	owner, outsider := owners[0], owners[1]
+	router := chi.NewRouter()
+	router.Get("/webhooks", app.getWebhookEndpointsHandler)
+	router.Post("/webhooks", app.createWebhookEndpointHandler)
+	router.Get("/webhooks/{webhookID:[0-9]+}", app.getWebhookEndpointHandler)
+	router.Patch("/webhooks/{webhookID:[0-9]+}/{versionID:[0-9]+}", app.updateWebhookEndpointHandler)
+	router.Get("/webhooks/{webhookID:[0-9]+}/deliveries", app.getWebhookDeliveriesHandler)
+	router.Post("/webhooks/{webhookID:[0-9]+}/deliveries/{deliveryID:[0-9]+}/redeliver", app.redeliverWebhookHandler)
+	router.Patch("/trade_leads/admin/{leadID:[0-9]+}/{versionID:[0-9]+}", app.adminUpdateTradeLeadStatusHandler)
+	request := func(user *data.User, method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, app.contextSetUser(httptest.NewRequest(method, target, strings.NewReader(body)), user))
+		return rr
+	}
+	decode := func(t *testing.T, rr *httptest.ResponseRecorder, v any) {
+		t.Helper()
+		if err := json.Unmarshal(rr.Body.Bytes(), v); err != nil {
+			t.Fatalf("%v: %s", err, rr.Body)
+		}
+	}
+	deliver := func(t *testing.T, lastAttempt bool) (*data.Job, error) {
+		t.Helper()
+		claimed, err := app.models.Jobs.ClaimJobs(ctx, "test-worker", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(claimed) != 1 || claimed[0].Kind != jobKindDeliverWebhook || claimed[0].MaxAttempts != 2 {
+			t.Fatalf("claimed jobs = %+v, want one %s job", claimed, jobKindDeliverWebhook)
+		}
+		if lastAttempt {
+			claimed[0].Attempts = claimed[0].MaxAttempts
+		}
+		return claimed[0], app.deliverWebhookJob(ctx, claimed[0])
+	}
+	delivery := func(t *testing.T, endpointID int64, status string) *data.WebhookDelivery {
+		t.Helper()
+		deliveries, _, err := app.models.Webhooks.GetWebhookDeliveriesByEndpointID(ctx, endpointID, status, data.Filters{Page: 1, PageSize: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return deliveries[0]
+	}
+
+	if rr := request(owner, http.MethodPost, "/webhooks", `{"url": "https://crm.test/hooks", "event_types": ["trade_lead.deleted"]}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("unknown event type: status = %d, want %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+	rr := request(owner, http.MethodPost, "/webhooks", `{"url": "`+server.URL+`/leadhub", "description": "CRM", "event_types": ["trade_lead.status_changed"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", rr.Code, rr.Body)
+	}
+	var created struct {
+		Webhook data.WebhookEndpoint `json:"webhook"`
+		Secret  string               `json:"secret"`
+	}
+	decode(t, rr, &created)
+	endpoint, secret := created.Webhook, created.Secret
+	if endpoint.ID == 0 || !endpoint.Enabled || !strings.HasPrefix(secret, "whsec_") {
+		t.Fatalf("created webhook = %+v, secret %q", endpoint, secret)
+	}
+	endpointURL := fmt.Sprintf("/webhooks/%d", endpoint.ID)
+	if rr := request(owner, http.MethodGet, endpointURL, ""); rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), secret) {
+		t.Errorf("get webhook = %d %s, want it without the secret", rr.Code, rr.Body)
+	}
+	if rr := request(outsider, http.MethodGet, endpointURL, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("another tenant's webhook: status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	// verifying a lead publishes a signed trade_lead.status_changed event
+	newLead := func(t *testing.T) *data.TradeLead {
+		t.Helper()
+		lead := &data.TradeLead{Title: "Coffee beans", Value: decimal.NewFromInt(1200)}
+		if err := app.models.TradeLeads.CreateTradeLead(ctx, owner.TenantID, lead); err != nil {
+			t.Fatal(err)
+		}
+		rr := request(owner, http.MethodPatch, fmt.Sprintf("/trade_leads/admin/%d/%d", lead.ID, lead.Version), "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("verify lead status = %d: %s", rr.Code, rr.Body)
+		}
+		return lead
+	}
+	lead := newLead(t)
+	if _, err := deliver(t, false); err != nil {
+		t.Fatalf("deliverWebhookJob() error = %v", err)
+	}
+	received, body, _ := receiver.last()
+	if err := webhooks.Verify(secret, received.Header.Get(webhooks.SignatureHeader), body, webhooks.DefaultTolerance); err != nil {
+		t.Errorf("the signature does not verify: %v", err)
+	}
+	var event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			TradeLead      data.TradeLead `json:"trade_lead"`
+			PreviousStatus string         `json:"previous_status"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Type != data.WebhookEventTradeLeadStatusChanged || event.Data.TradeLead.ID != lead.ID ||
+		event.Data.TradeLead.Status != "verified" || event.Data.PreviousStatus != "new" {
+		t.Errorf("event = %+v", event)
+	}
+	if got := delivery(t, endpoint.ID, ""); got.Status != data.WebhookDeliverySucceeded || got.ResponseStatus != http.StatusOK || got.EventID != event.ID {
+		t.Errorf("delivery = %+v", got)
+	}
+
+	// two deliveries failing for good in a row disable the endpoint
+	receiver.respondWith(http.StatusServiceUnavailable)
+	for i := range 2 {
+		newLead(t)
+		job, err := deliver(t, i == 1)
+		if err == nil {
+			t.Fatal("deliverWebhookJob() succeeded against a failing endpoint")
+		}
+		if i == 0 {
+			// retried, then fails for good
+			if got := delivery(t, endpoint.ID, ""); got.Status != data.WebhookDeliveryPending || got.ResponseBody != "status 503" {
+				t.Errorf("delivery after a failed attempt = %+v", got)
+			}
+			job.Attempts++
+			if err := app.deliverWebhookJob(ctx, job); err == nil {
+				t.Fatal("deliverWebhookJob() succeeded against a failing endpoint")
+			}
+		}
+	}
+	rr = request(owner, http.MethodGet, endpointURL, "")
+	var shown struct {
+		Webhook data.WebhookEndpoint `json:"webhook"`
+	}
+	decode(t, rr, &shown)
+	if shown.Webhook.Enabled || shown.Webhook.DisabledReason == "" || shown.Webhook.ConsecutiveFailures != 2 {
+		t.Fatalf("webhook after its failures = %+v", shown.Webhook)
+	}
+	newLead(t)
+	if claimed, _ := app.models.Jobs.ClaimJobs(ctx, "test-worker", 10); len(claimed) != 0 {
+		t.Errorf("events are still delivered to the disabled endpoint: %+v", claimed)
+	}
+
+	rr = request(owner, http.MethodGet, endpointURL+"/deliveries?status=failed", "")
+	var listed struct {
+		Deliveries []data.WebhookDelivery `json:"deliveries"`
+		Metadata   data.Metadata          `json:"metadata"`
+	}
+	decode(t, rr, &listed)
+	if len(listed.Deliveries) != 2 || listed.Deliveries[0].Attempts != 1 || listed.Deliveries[1].Attempts != 2 {
+		t.Fatalf("failed deliveries = %+v", listed.Deliveries)
+	}
+	failed := listed.Deliveries[0]
+	redeliverURL := fmt.Sprintf("%s/deliveries/%d/redeliver", endpointURL, failed.ID)
+	if rr := request(owner, http.MethodPost, redeliverURL, ""); rr.Code != http.StatusConflict {
+		t.Errorf("redelivering to a disabled endpoint: status = %d, want %d", rr.Code, http.StatusConflict)
+	}
+
+	// enabling the endpoint clears its failures, and the failed event can be redelivered
+	rr = request(owner, http.MethodPatch, fmt.Sprintf("%s/%d", endpointURL, shown.Webhook.Version), `{"enabled": true}`)
+	shown.Webhook = data.WebhookEndpoint{}
+	decode(t, rr, &shown)
+	if rr.Code != http.StatusOK || !shown.Webhook.Enabled || shown.Webhook.ConsecutiveFailures != 0 || shown.Webhook.DisabledReason != "" {
+		t.Fatalf("enabled webhook = %d %+v", rr.Code, shown.Webhook)
+	}
+	receiver.respondWith(http.StatusNoContent)
+	if rr := request(outsider, http.MethodPost, redeliverURL, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("another tenant redelivering: status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := request(owner, http.MethodPost, redeliverURL, ""); rr.Code != http.StatusAccepted {
+		t.Fatalf("redeliver status = %d: %s", rr.Code, rr.Body)
+	}
+	if _, err := deliver(t, false); err != nil {
+		t.Fatalf("deliverWebhookJob() error = %v", err)
+	}
+	_, body, requests := receiver.last()
+	if !strings.Contains(string(body), failed.EventID) || requests != 5 {
+		t.Errorf("redelivered body = %s after %d requests", body, requests)
+	}
+	if got := delivery(t, endpoint.ID, ""); got.Status != data.WebhookDeliverySucceeded || got.EventID != failed.EventID || got.ID == failed.ID {
+		t.Errorf("redelivery = %+v", got)
+	}
+}
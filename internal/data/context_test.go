@@ -0,0 +1,102 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingDriver is a database/sql driver whose queries never finish on their own, they
+// only return once their context is done. It lets the tests observe that the models pass
+// the caller's context and their timeout down to the database.
+type blockingDriver struct{}
+
+func (blockingDriver) Open(string) (driver.Conn, error) { return blockingConn{}, nil }
+
+type blockingConn struct{}
+
+func (blockingConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (blockingConn) Close() error                        { return nil }
+func (blockingConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (blockingConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingConn) ExecContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Result, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func init() {
+	sql.Register("leadhub-blocking", blockingDriver{})
+}
+
+func newBlockingModels(t *testing.T, timeouts Timeouts) Models {
+	t.Helper()
+	db, err := sql.Open("leadhub-blocking", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewModels(db, timeouts)
+}
+
+func TestModelsHonourContext(t *testing.T) {
+	models := newBlockingModels(t, Timeouts{TradeLeads: time.Hour, Users: time.Hour})
+
+	t.Run("Cancelled request aborts the query", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel()
+		}()
+		done := make(chan error, 1)
+		go func() {
+			_, err := models.TradeLeads.GetTradeLeadByID(ctx, 1)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("GetTradeLeadByID() error = %v, want %v", err, context.Canceled)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("query kept running after the request was cancelled")
+		}
+	})
+
+	t.Run("Caller deadline applies", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := models.Users.GetByEmail(ctx, "alice@example.com")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("GetByEmail() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestModelTimeouts(t *testing.T) {
+	models := newBlockingModels(t, Timeouts{Tenants: 10 * time.Millisecond})
+
+	start := time.Now()
+	_, err := models.Tenants.GetTenantByID(context.Background(), 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetTenantByID() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("query took %v, want it bounded by the configured 10ms timeout", elapsed)
+	}
+
+	// models without a configured timeout keep their default
+	if models.TradeLeads.(TradeLeadModel).Timeout != DefaultLeadManagerDBContextTimeout {
+		t.Errorf("TradeLeads.Timeout = %v, want %v", models.TradeLeads.(TradeLeadModel).Timeout, DefaultLeadManagerDBContextTimeout)
+	}
+	if models.Permissions.(PermissionModel).Timeout != DefaultPermissionDBContextTimeout {
+		t.Errorf("Permissions.Timeout = %v, want %v", models.Permissions.(PermissionModel).Timeout, DefaultPermissionDBContextTimeout)
+	}
+}
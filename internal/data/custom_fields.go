@@ -0,0 +1,320 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/database"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+type CustomFieldModel struct {
+	DB      *database.Queries
+	Timeout time.Duration
+}
+
+const (
+	DefaultCustomFieldDBContextTimeout = 5 * time.Second
+	// CustomFieldDateLayout is the only accepted format for date custom fields.
+	CustomFieldDateLayout = "2006-01-02"
+	// CustomFieldQueryPrefix prefixes custom field keys in query strings, e.g. ?cf.hs_code=0901
+	CustomFieldQueryPrefix = "cf."
+)
+
+// Define constants for the supported custom field types.
+const (
+	CustomFieldTypeText    = "text"
+	CustomFieldTypeNumber  = "number"
+	CustomFieldTypeDate    = "date"
+	CustomFieldTypeEnum    = "enum"
+	CustomFieldTypeBoolean = "boolean"
+)
+
+var (
+	ErrDuplicateCustomField = errors.New("duplicate custom field key")
+	ErrInvalidCustomField   = errors.New("invalid custom field value")
+)
+
+var customFieldKeyRX = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// CustomField is a tenant defined attribute that can be set on the tenant's trade leads.
+type CustomField struct {
+	ID        int64     `json:"id"`
+	TenantID  int64     `json:"tenant_id"`
+	Key       string    `json:"key"`
+	Label     string    `json:"label"`
+	Type      string    `json:"type"`
+	Required  bool      `json:"required"`
+	Options   []string  `json:"options"`
+	Version   int32     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CustomFieldFilters holds the custom field equality filters for a trade lead listing,
+// together with the tenant's definitions which decide how custom field sorts behave.
+type CustomFieldFilters struct {
+	Values      map[string]any
+	Definitions []*CustomField
+}
+
+// ValidateCustomField validates a custom field definition.
+func ValidateCustomField(v *validator.Validator, field *CustomField) {
+	v.Check(field.Key != "", "key", "must be provided")
+	v.Check(len(field.Key) <= 50, "key", "must not be more than 50 characters long")
+	v.Check(customFieldKeyRX.MatchString(field.Key), "key", "must start with a letter and contain only lowercase letters, digits and underscores")
+	v.Check(field.Label != "", "label", "must be provided")
+	v.Check(len(field.Label) <= 100, "label", "must not be more than 100 characters long")
+	v.Check(validator.PermittedValue(field.Type,
+		CustomFieldTypeText, CustomFieldTypeNumber, CustomFieldTypeDate, CustomFieldTypeEnum, CustomFieldTypeBoolean),
+		"type", "must be one of text, number, date, enum or boolean")
+	if field.Type == CustomFieldTypeEnum {
+		v.Check(len(field.Options) > 0, "options", "must be provided for enum fields")
+		v.Check(validator.Unique(field.Options), "options", "must not contain duplicate values")
+	} else {
+		v.Check(len(field.Options) == 0, "options", "are only supported for enum fields")
+	}
+}
+
+// validateCustomFieldValues checks the custom field values of a lead against the tenant's
+// definitions. Errors are keyed as "custom_fields.<key>".
+func validateCustomFieldValues(v *validator.Validator, values map[string]any, definitions []*CustomField) {
+	known := make(map[string]*CustomField, len(definitions))
+	for _, def := range definitions {
+		known[def.Key] = def
+		value, ok := values[def.Key]
+		if !ok || value == nil || value == "" {
+			v.Check(!def.Required, "custom_fields."+def.Key, "must be provided")
+			continue
+		}
+		if err := checkCustomFieldValue(def, value); err != nil {
+			v.AddError("custom_fields."+def.Key, err.Error())
+		}
+	}
+	for key := range values {
+		if _, ok := known[key]; !ok {
+			v.AddError("custom_fields."+key, "is not a defined custom field")
+		}
+	}
+}
+
+// checkCustomFieldValue verifies that a decoded JSON value matches the field's type.
+func checkCustomFieldValue(def *CustomField, value any) error {
+	switch def.Type {
+	case CustomFieldTypeText:
+		s, ok := value.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		if len(s) > 1000 {
+			return errors.New("must not be more than 1000 characters long")
+		}
+	case CustomFieldTypeNumber:
+		if _, ok := value.(float64); !ok {
+			return errors.New("must be a number")
+		}
+	case CustomFieldTypeDate:
+		s, ok := value.(string)
+		if !ok {
+			return errors.New("must be a date in the format YYYY-MM-DD")
+		}
+		if _, err := time.Parse(CustomFieldDateLayout, s); err != nil {
+			return errors.New("must be a date in the format YYYY-MM-DD")
+		}
+	case CustomFieldTypeEnum:
+		s, ok := value.(string)
+		if !ok || !validator.PermittedValue(s, def.Options...) {
+			return fmt.Errorf("must be one of %s", strings.Join(def.Options, ", "))
+		}
+	case CustomFieldTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return errors.New("must be a boolean")
+		}
+	}
+	return nil
+}
+
+// ParseCustomFieldValue converts a raw query string value into the JSON type of the
+// field so it can be used in a containment filter.
+func ParseCustomFieldValue(def *CustomField, raw string) (any, error) {
+	var value any = raw
+	switch def.Type {
+	case CustomFieldTypeNumber:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, ErrInvalidCustomField
+		}
+		value = f
+	case CustomFieldTypeBoolean:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, ErrInvalidCustomField
+		}
+		value = b
+	}
+	if err := checkCustomFieldValue(def, value); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCustomField, err.Error())
+	}
+	return value, nil
+}
+
+// CustomFieldSortSafelist returns the sort values ("cf.key" and "-cf.key") allowed for
+// the given definitions.
+func CustomFieldSortSafelist(definitions []*CustomField) []string {
+	safelist := []string{}
+	for _, def := range definitions {
+		safelist = append(safelist, CustomFieldQueryPrefix+def.Key, "-"+CustomFieldQueryPrefix+def.Key)
+	}
+	return safelist
+}
+
+// FindCustomField returns the definition with the given key, or nil.
+func FindCustomField(definitions []*CustomField, key string) *CustomField {
+	for _, def := range definitions {
+		if def.Key == key {
+			return def
+		}
+	}
+	return nil
+}
+
+// CreateCustomField() creates a new custom field definition for a tenant.
+func (m CustomFieldModel) CreateCustomField(ctx context.Context, field *CustomField) error {
+	ctx, span := startSpan(ctx, "CustomFieldModel.CreateCustomField")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	if field.Options == nil {
+		field.Options = []string{}
+	}
+	newField, err := m.DB.CreateCustomField(ctx, database.CreateCustomFieldParams{
+		TenantID:  field.TenantID,
+		FieldKey:  field.Key,
+		Label:     field.Label,
+		FieldType: field.Type,
+		Required:  field.Required,
+		Options:   field.Options,
+	})
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "tenant_custom_fields_tenant_key"):
+			return ErrDuplicateCustomField
+		default:
+			return err
+		}
+	}
+	field.ID = newField.ID
+	field.Version = newField.Version
+	field.CreatedAt = newField.CreatedAt
+	field.UpdatedAt = newField.UpdatedAt
+	return nil
+}
+
+// GetCustomFieldsByTenantID() retrieves all custom field definitions of a tenant.
+func (m CustomFieldModel) GetCustomFieldsByTenantID(ctx context.Context, tenantID int64) ([]*CustomField, error) {
+	ctx, span := startSpan(ctx, "CustomFieldModel.GetCustomFieldsByTenantID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	fields, err := m.DB.GetCustomFieldsByTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	customFields := []*CustomField{}
+	for _, field := range fields {
+		customFields = append(customFields, populateCustomField(field))
+	}
+	return customFields, nil
+}
+
+// GetCustomFieldByID() retrieves a single custom field definition belonging to the tenant.
+func (m CustomFieldModel) GetCustomFieldByID(ctx context.Context, tenantID, fieldID int64) (*CustomField, error) {
+	ctx, span := startSpan(ctx, "CustomFieldModel.GetCustomFieldByID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	field, err := m.DB.GetCustomFieldByID(ctx, database.GetCustomFieldByIDParams{
+		ID:       fieldID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrGeneralRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return populateCustomField(field), nil
+}
+
+// UpdateCustomField() updates the label, required flag and options of a custom field.
+// The key and type cannot be changed once leads may hold values for them.
+func (m CustomFieldModel) UpdateCustomField(ctx context.Context, field *CustomField, version int32) error {
+	ctx, span := startSpan(ctx, "CustomFieldModel.UpdateCustomField")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	if field.Options == nil {
+		field.Options = []string{}
+	}
+	updatedField, err := m.DB.UpdateCustomField(ctx, database.UpdateCustomFieldParams{
+		ID:       field.ID,
+		Version:  version,
+		Label:    field.Label,
+		Required: field.Required,
+		Options:  field.Options,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrGeneralEditConflict
+		default:
+			return err
+		}
+	}
+	field.Version = updatedField.Version
+	field.UpdatedAt = updatedField.UpdatedAt
+	return nil
+}
+
+// DeleteCustomField() removes a custom field definition from the tenant. Values already
+// stored on leads are left untouched.
+func (m CustomFieldModel) DeleteCustomField(ctx context.Context, tenantID, fieldID int64) error {
+	ctx, span := startSpan(ctx, "CustomFieldModel.DeleteCustomField")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.DeleteCustomField(ctx, database.DeleteCustomFieldParams{
+		ID:       fieldID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrGeneralRecordNotFound
+	}
+	return nil
+}
+
+func populateCustomField(field database.TenantCustomField) *CustomField {
+	return &CustomField{
+		ID:        field.ID,
+		TenantID:  field.TenantID,
+		Key:       field.FieldKey,
+		Label:     field.Label,
+		Type:      field.FieldType,
+		Required:  field.Required,
+		Options:   field.Options,
+		Version:   field.Version,
+		CreatedAt: field.CreatedAt,
+		UpdatedAt: field.UpdatedAt,
+	}
+}
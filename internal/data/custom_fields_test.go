@@ -0,0 +1,178 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+	"github.com/shopspring/decimal"
+)
+
+func testCustomFieldDefinitions() []*CustomField {
+	return []*CustomField{
+		{Key: "incoterm", Type: CustomFieldTypeEnum, Required: true, Options: []string{"FOB", "CIF"}},
+		{Key: "quantity", Type: CustomFieldTypeNumber},
+		{Key: "ship_by", Type: CustomFieldTypeDate},
+		{Key: "insured", Type: CustomFieldTypeBoolean},
+		{Key: "notes", Type: CustomFieldTypeText},
+	}
+}
+
+func TestValidateTradeLeadCustomFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		customFields map[string]any
+		wantValid    bool
+		wantError    string
+	}{
+		{
+			name: "Valid custom fields",
+			customFields: map[string]any{
+				"incoterm": "FOB",
+				"quantity": float64(20),
+				"ship_by":  "2025-03-01",
+				"insured":  true,
+				"notes":    "fragile",
+			},
+			wantValid: true,
+		},
+		{
+			name:         "Missing required field should fail",
+			customFields: map[string]any{"quantity": float64(20)},
+			wantValid:    false,
+			wantError:    "custom_fields.incoterm",
+		},
+		{
+			name:         "Enum value outside options should fail",
+			customFields: map[string]any{"incoterm": "DDP"},
+			wantValid:    false,
+			wantError:    "custom_fields.incoterm",
+		},
+		{
+			name:         "Wrong number type should fail",
+			customFields: map[string]any{"incoterm": "CIF", "quantity": "twenty"},
+			wantValid:    false,
+			wantError:    "custom_fields.quantity",
+		},
+		{
+			name:         "Invalid date should fail",
+			customFields: map[string]any{"incoterm": "CIF", "ship_by": "01/03/2025"},
+			wantValid:    false,
+			wantError:    "custom_fields.ship_by",
+		},
+		{
+			name:         "Undefined field should fail",
+			customFields: map[string]any{"incoterm": "CIF", "color": "red"},
+			wantValid:    false,
+			wantError:    "custom_fields.color",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lead := &TradeLead{
+				TenantID:     1,
+				Title:        "Coffee beans",
+				Description:  "Green arabica",
+				Value:        decimal.NewFromInt(1000),
+				Currency:     "KES",
+				Status:       "new",
+				CustomFields: tt.customFields,
+			}
+			v := validator.New()
+			ValidateTradeLead(v, lead, testCustomFieldDefinitions())
+
+			if v.Valid() != tt.wantValid {
+				t.Errorf("ValidateTradeLead() valid = %v, want %v, errors: %v", v.Valid(), tt.wantValid, v.Errors)
+			}
+			if !tt.wantValid && tt.wantError != "" {
+				if _, exists := v.Errors[tt.wantError]; !exists {
+					t.Errorf("Expected error for field %s, but got errors: %v", tt.wantError, v.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCustomField(t *testing.T) {
+	tests := []struct {
+		name      string
+		field     *CustomField
+		wantValid bool
+		wantError string
+	}{
+		{
+			name:      "Valid enum field",
+			field:     &CustomField{Key: "incoterm", Label: "Incoterm", Type: CustomFieldTypeEnum, Options: []string{"FOB", "CIF"}},
+			wantValid: true,
+		},
+		{
+			name:      "Invalid key should fail",
+			field:     &CustomField{Key: "Ship By", Label: "Ship by", Type: CustomFieldTypeDate},
+			wantValid: false,
+			wantError: "key",
+		},
+		{
+			name:      "Unknown type should fail",
+			field:     &CustomField{Key: "size", Label: "Size", Type: "json"},
+			wantValid: false,
+			wantError: "type",
+		},
+		{
+			name:      "Enum without options should fail",
+			field:     &CustomField{Key: "incoterm", Label: "Incoterm", Type: CustomFieldTypeEnum},
+			wantValid: false,
+			wantError: "options",
+		},
+		{
+			name:      "Options on a non enum field should fail",
+			field:     &CustomField{Key: "quantity", Label: "Quantity", Type: CustomFieldTypeNumber, Options: []string{"1"}},
+			wantValid: false,
+			wantError: "options",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateCustomField(v, tt.field)
+
+			if v.Valid() != tt.wantValid {
+				t.Errorf("ValidateCustomField() valid = %v, want %v, errors: %v", v.Valid(), tt.wantValid, v.Errors)
+			}
+			if !tt.wantValid && tt.wantError != "" {
+				if _, exists := v.Errors[tt.wantError]; !exists {
+					t.Errorf("Expected error for field %s, but got errors: %v", tt.wantError, v.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCustomFieldValue(t *testing.T) {
+	defs := testCustomFieldDefinitions()
+	tests := []struct {
+		key     string
+		raw     string
+		want    any
+		wantErr bool
+	}{
+		{key: "quantity", raw: "12.5", want: 12.5},
+		{key: "quantity", raw: "lots", wantErr: true},
+		{key: "insured", raw: "true", want: true},
+		{key: "incoterm", raw: "CIF", want: "CIF"},
+		{key: "incoterm", raw: "DDP", wantErr: true},
+		{key: "ship_by", raw: "2025-13-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.raw, func(t *testing.T) {
+			got, err := ParseCustomFieldValue(FindCustomField(defs, tt.key), tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCustomFieldValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseCustomFieldValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/database"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+type EmailOutboxModel struct {
+	DB      *database.Queries
+	Timeout time.Duration
+}
+
+const (
+	DefaultEmailOutboxDBContextTimeout = 5 * time.Second
+)
+
+// Define constants for the delivery status of an outbox email. A queued email is waiting
+// for its first or next attempt, a failed one has run out of attempts.
+const (
+	EmailStatusQueued = "queued"
+	EmailStatusSent   = "sent"
+	EmailStatusFailed = "failed"
+)
+
+var (
+	ErrOutboxEmailNotFailed = errors.New("only failed emails can be re-sent")
+)
+
+// OutboxEmail is the record of an email sent by the API, from the moment it is queued.
+// The template data is kept to re-send the email, but never returned by the API as it
+// may hold tokens.
+type OutboxEmail struct {
+	ID                int64           `json:"id"`
+	TenantID          int64           `json:"tenant_id"`
+	Recipient         string          `json:"recipient"`
+	Template          string          `json:"template"`
+	Locale            string          `json:"locale,omitempty"`
+	Data              json.RawMessage `json:"-"`
+	Subject           string          `json:"subject,omitempty"`
+	Status            string          `json:"status"`
+	Attempts          int32           `json:"attempts"`
+	LastError         string          `json:"last_error,omitempty"`
+	ProviderMessageID string          `json:"provider_message_id,omitempty"`
+	SentAt            *time.Time      `json:"sent_at,omitempty"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+}
+
+// EmailAttempt is the outcome of one delivery attempt of an outbox email.
+type EmailAttempt struct {
+	Status            string
+	Subject           string
+	LastError         string
+	ProviderMessageID string
+}
+
+// NewOutboxEmail() returns a queued email with the template data encoded as JSON.
+func NewOutboxEmail(tenantID int64, recipient, locale, templateFile string, templateData any) (*OutboxEmail, error) {
+	encoded, err := json.Marshal(templateData)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxEmail{
+		TenantID:  tenantID,
+		Recipient: recipient,
+		Template:  templateFile,
+		Locale:    locale,
+		Data:      encoded,
+		Status:    EmailStatusQueued,
+	}, nil
+}
+
+// ValidateEmailStatus() checks an optional status filter of the outbox listing.
+func ValidateEmailStatus(v *validator.Validator, status string) {
+	v.Check(status == "" || validator.PermittedValue(status, EmailStatusQueued, EmailStatusSent, EmailStatusFailed), "status", "must be one of queued, sent or failed")
+}
+
+// CreateOutboxEmail() stores a new queued email.
+func (m EmailOutboxModel) CreateOutboxEmail(ctx context.Context, email *OutboxEmail) error {
+	ctx, span := startSpan(ctx, "EmailOutboxModel.CreateOutboxEmail")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	if len(email.Data) == 0 {
+		email.Data = json.RawMessage(`{}`)
+	}
+	newEmail, err := m.DB.CreateOutboxEmail(ctx, database.CreateOutboxEmailParams{
+		TenantID:  email.TenantID,
+		Recipient: email.Recipient,
+		Template:  email.Template,
+		Locale:    email.Locale,
+		Data:      email.Data,
+	})
+	if err != nil {
+		return err
+	}
+	email.ID = newEmail.ID
+	email.Status = newEmail.Status
+	email.Attempts = newEmail.Attempts
+	email.CreatedAt = newEmail.CreatedAt
+	email.UpdatedAt = newEmail.UpdatedAt
+	return nil
+}
+
+// GetOutboxEmailByID() retrieves an outbox email by its ID.
+func (m EmailOutboxModel) GetOutboxEmailByID(ctx context.Context, id int64) (*OutboxEmail, error) {
+	ctx, span := startSpan(ctx, "EmailOutboxModel.GetOutboxEmailByID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	email, err := m.DB.GetOutboxEmailByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrGeneralRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return populateOutboxEmail(email), nil
+}
+
+// AdminGetAllOutboxEmails() lists the outbox emails, newest first. A zero tenantID and
+// empty status and recipient don't filter.
+func (m EmailOutboxModel) AdminGetAllOutboxEmails(ctx context.Context, tenantID int64, status, recipient string, filters Filters) ([]*OutboxEmail, Metadata, error) {
+	ctx, span := startSpan(ctx, "EmailOutboxModel.AdminGetAllOutboxEmails")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.AdminGetAllOutboxEmails(ctx, database.AdminGetAllOutboxEmailsParams{
+		TenantID:   tenantID,
+		Status:     status,
+		Recipient:  recipient,
+		PageLimit:  filters.limitInt32(),
+		PageOffset: filters.offsetInt32(),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	if len(rows) == 0 {
+		return nil, Metadata{}, ErrGeneralRecordNotFound
+	}
+	emails := []*OutboxEmail{}
+	totalRows := 0
+	for _, row := range rows {
+		totalRows = int(row.TotalCount)
+		emails = append(emails, populateOutboxEmail(database.EmailOutbox{
+			ID:                row.ID,
+			TenantID:          row.TenantID,
+			Recipient:         row.Recipient,
+			Template:          row.Template,
+			Locale:            row.Locale,
+			Data:              row.Data,
+			Subject:           row.Subject,
+			Status:            row.Status,
+			Attempts:          row.Attempts,
+			LastError:         row.LastError,
+			ProviderMessageID: row.ProviderMessageID,
+			SentAt:            row.SentAt,
+			CreatedAt:         row.CreatedAt,
+			UpdatedAt:         row.UpdatedAt,
+		}))
+	}
+	return emails, calculateMetadata(totalRows, filters.Page, filters.PageSize), nil
+}
+
+// RecordOutboxEmailAttempt() counts a delivery attempt of the email and stores its outcome.
+func (m EmailOutboxModel) RecordOutboxEmailAttempt(ctx context.Context, id int64, attempt EmailAttempt) error {
+	ctx, span := startSpan(ctx, "EmailOutboxModel.RecordOutboxEmailAttempt")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	var sentAt sql.NullTime
+	if attempt.Status == EmailStatusSent {
+		sentAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	rows, err := m.DB.RecordOutboxEmailAttempt(ctx, database.RecordOutboxEmailAttemptParams{
+		ID:                id,
+		Status:            attempt.Status,
+		Subject:           attempt.Subject,
+		LastError:         attempt.LastError,
+		ProviderMessageID: attempt.ProviderMessageID,
+		SentAt:            sentAt,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrGeneralRecordNotFound
+	}
+	return nil
+}
+
+// RequeueOutboxEmail() moves a failed email back to the queued state, to be sent again.
+func (m EmailOutboxModel) RequeueOutboxEmail(ctx context.Context, id int64) error {
+	ctx, span := startSpan(ctx, "EmailOutboxModel.RequeueOutboxEmail")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.RequeueOutboxEmail(ctx, id)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrOutboxEmailNotFailed
+	}
+	return nil
+}
+
+func populateOutboxEmail(emailRow database.EmailOutbox) *OutboxEmail {
+	email := &OutboxEmail{
+		ID:                emailRow.ID,
+		TenantID:          emailRow.TenantID,
+		Recipient:         emailRow.Recipient,
+		Template:          emailRow.Template,
+		Locale:            emailRow.Locale,
+		Data:              emailRow.Data,
+		Subject:           emailRow.Subject,
+		Status:            emailRow.Status,
+		Attempts:          emailRow.Attempts,
+		LastError:         emailRow.LastError,
+		ProviderMessageID: emailRow.ProviderMessageID,
+		CreatedAt:         emailRow.CreatedAt,
+		UpdatedAt:         emailRow.UpdatedAt,
+	}
+	if emailRow.SentAt.Valid {
+		email.SentAt = &emailRow.SentAt.Time
+	}
+	return email
+}
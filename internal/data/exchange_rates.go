@@ -0,0 +1,298 @@
+package data
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/database"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+	"github.com/shopspring/decimal"
+)
+
+type ExchangeRateModel struct {
+	DB      *database.Queries
+	Timeout time.Duration
+}
+
+const (
+	DefaultExchangeRateDBContextTimeout = 5 * time.Second
+	// MaxExchangeRatesPerImport caps the rates loaded from a single file.
+	MaxExchangeRatesPerImport = 10000
+)
+
+// Define constants for the file formats exchange rates are loaded from.
+const (
+	ExchangeRateFormatCSV  = "csv"
+	ExchangeRateFormatJSON = "json"
+)
+
+var (
+	ErrExchangeRateNotFound = errors.New("no exchange rate")
+)
+
+// exchangeRateColumns lists the columns of an exchange rates CSV file, which may come in
+// any order.
+var exchangeRateColumns = []string{"rate_date", "base_currency", "quote_currency", "rate"}
+
+// ExchangeRate states that on RateDate, 1 unit of BaseCurrency was worth Rate units of
+// QuoteCurrency.
+type ExchangeRate struct {
+	ID            int64           `json:"id"`
+	BaseCurrency  string          `json:"base_currency"`
+	QuoteCurrency string          `json:"quote_currency"`
+	Rate          decimal.Decimal `json:"rate"`
+	RateDate      time.Time       `json:"rate_date"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// exchangeRateRecord is an exchange rate as written in an import file.
+type exchangeRateRecord struct {
+	RateDate      string          `json:"rate_date"`
+	BaseCurrency  string          `json:"base_currency"`
+	QuoteCurrency string          `json:"quote_currency"`
+	Rate          decimal.Decimal `json:"rate"`
+}
+
+// ValidateExchangeRates validates rates loaded from a file. Errors are keyed by the
+// position of the rate, e.g. "rates[2].rate".
+func ValidateExchangeRates(v *validator.Validator, rates []*ExchangeRate) {
+	v.Check(len(rates) > 0, "rates", "must contain at least one exchange rate")
+	v.Check(len(rates) <= MaxExchangeRatesPerImport, "rates", fmt.Sprintf("must not contain more than %d exchange rates", MaxExchangeRatesPerImport))
+	for i, rate := range rates {
+		key := fmt.Sprintf("rates[%d]", i)
+		v.Check(IsCurrency(rate.BaseCurrency), key+".base_currency", "must be an ISO 4217 currency code such as KES")
+		v.Check(IsCurrency(rate.QuoteCurrency), key+".quote_currency", "must be an ISO 4217 currency code such as KES")
+		v.Check(rate.BaseCurrency != rate.QuoteCurrency, key+".quote_currency", "must differ from the base currency")
+		v.Check(rate.Rate.GreaterThan(decimal.Zero), key+".rate", "must be greater than zero")
+	}
+}
+
+// ReadExchangeRates() reads exchange rates from a file in the given format.
+func ReadExchangeRates(r io.Reader, format string) ([]*ExchangeRate, error) {
+	switch format {
+	case ExchangeRateFormatCSV:
+		return ReadExchangeRatesCSV(r)
+	case ExchangeRateFormatJSON:
+		return ReadExchangeRatesJSON(r)
+	default:
+		return nil, fmt.Errorf("unsupported exchange rates format %q, want csv or json", format)
+	}
+}
+
+// ReadExchangeRatesCSV() reads exchange rates from a CSV file whose header names the
+// rate_date (YYYY-MM-DD), base_currency, quote_currency and rate columns.
+func ReadExchangeRatesCSV(r io.Reader) ([]*ExchangeRate, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("the file is empty")
+		}
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range exchangeRateColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("the header is missing the %q column", name)
+		}
+	}
+	var rates []*ExchangeRate
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rates) == MaxExchangeRatesPerImport {
+			return nil, fmt.Errorf("the file must not contain more than %d exchange rates", MaxExchangeRatesPerImport)
+		}
+		record := exchangeRateRecord{
+			RateDate:      row[columns["rate_date"]],
+			BaseCurrency:  row[columns["base_currency"]],
+			QuoteCurrency: row[columns["quote_currency"]],
+		}
+		line, _ := cr.FieldPos(columns["rate"])
+		record.Rate, err = decimal.NewFromString(strings.TrimSpace(row[columns["rate"]]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, row[columns["rate"]])
+		}
+		rate, err := record.exchangeRate()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+}
+
+// ReadExchangeRatesJSON() reads exchange rates from a JSON array of objects holding the
+// same fields as the CSV format, e.g. {"rate_date": "2026-01-31", "base_currency": "USD",
+// "quote_currency": "KES", "rate": "129.25"}.
+func ReadExchangeRatesJSON(r io.Reader) ([]*ExchangeRate, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var records []exchangeRateRecord
+	if err := dec.Decode(&records); err != nil {
+		return nil, fmt.Errorf("invalid exchange rates JSON: %w", err)
+	}
+	if len(records) > MaxExchangeRatesPerImport {
+		return nil, fmt.Errorf("the file must not contain more than %d exchange rates", MaxExchangeRatesPerImport)
+	}
+	rates := make([]*ExchangeRate, 0, len(records))
+	for i, record := range records {
+		rate, err := record.exchangeRate()
+		if err != nil {
+			return nil, fmt.Errorf("exchange rate %d: %w", i, err)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+func (record exchangeRateRecord) exchangeRate() (*ExchangeRate, error) {
+	rateDate, err := time.Parse(time.DateOnly, strings.TrimSpace(record.RateDate))
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate_date %q, want YYYY-MM-DD", record.RateDate)
+	}
+	return &ExchangeRate{
+		BaseCurrency:  strings.TrimSpace(record.BaseCurrency),
+		QuoteCurrency: strings.TrimSpace(record.QuoteCurrency),
+		Rate:          record.Rate,
+		RateDate:      rateDate,
+	}, nil
+}
+
+// SaveExchangeRate() stores a rate, replacing the rate of the same currency pair and date.
+func (m ExchangeRateModel) SaveExchangeRate(ctx context.Context, rate *ExchangeRate) error {
+	ctx, span := startSpan(ctx, "ExchangeRateModel.SaveExchangeRate")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	saved, err := m.DB.UpsertExchangeRate(ctx, database.UpsertExchangeRateParams{
+		BaseCurrency:  rate.BaseCurrency,
+		QuoteCurrency: rate.QuoteCurrency,
+		Rate:          rate.Rate.String(),
+		RateDate:      rate.RateDate,
+	})
+	if err != nil {
+		return err
+	}
+	rate.ID = saved.ID
+	rate.CreatedAt = saved.CreatedAt
+	rate.UpdatedAt = saved.UpdatedAt
+	return nil
+}
+
+// GetExchangeRatesOnDate() returns the rate of every currency pair in effect on the date,
+// which is the latest rate dated on or before it.
+func (m ExchangeRateModel) GetExchangeRatesOnDate(ctx context.Context, date time.Time) ([]*ExchangeRate, error) {
+	ctx, span := startSpan(ctx, "ExchangeRateModel.GetExchangeRatesOnDate")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.GetExchangeRatesOnDate(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	rates := []*ExchangeRate{}
+	for _, row := range rows {
+		rates = append(rates, &ExchangeRate{
+			ID:            row.ID,
+			BaseCurrency:  row.BaseCurrency,
+			QuoteCurrency: row.QuoteCurrency,
+			Rate:          decimal.RequireFromString(row.Rate),
+			RateDate:      row.RateDate,
+			CreatedAt:     row.CreatedAt,
+			UpdatedAt:     row.UpdatedAt,
+		})
+	}
+	return rates, nil
+}
+
+// currencyPair identifies the rate quoting one currency in another.
+type currencyPair struct {
+	base  string
+	quote string
+}
+
+// CurrencyConverter converts amounts to a reporting currency at the exchange rates in
+// effect on a date.
+type CurrencyConverter struct {
+	Currency string
+	Date     time.Time
+
+	rates      map[currencyPair]decimal.Decimal
+	currencies []string
+}
+
+// NewCurrencyConverter() returns a converter to currency using the given rates, as
+// returned by GetExchangeRatesOnDate() for date.
+func NewCurrencyConverter(currency string, date time.Time, rates []*ExchangeRate) *CurrencyConverter {
+	c := &CurrencyConverter{
+		Currency: currency,
+		Date:     date,
+		rates:    make(map[currencyPair]decimal.Decimal, len(rates)),
+	}
+	for _, rate := range rates {
+		c.rates[currencyPair{rate.BaseCurrency, rate.QuoteCurrency}] = rate.Rate
+		c.currencies = append(c.currencies, rate.BaseCurrency, rate.QuoteCurrency)
+	}
+	slices.Sort(c.currencies)
+	c.currencies = slices.Compact(c.currencies)
+	return c
+}
+
+// Rate() returns the units of the reporting currency one unit of currency is worth. A
+// rate quoted the other way round is inverted, and currencies without a rate against the
+// reporting currency are crossed through a currency both have a rate against.
+func (c *CurrencyConverter) Rate(currency string) (decimal.Decimal, error) {
+	if currency == c.Currency {
+		return decimal.NewFromInt(1), nil
+	}
+	if rate, ok := c.pairRate(currency, c.Currency); ok {
+		return rate, nil
+	}
+	for _, via := range c.currencies {
+		toVia, ok := c.pairRate(currency, via)
+		if !ok {
+			continue
+		}
+		if fromVia, ok := c.pairRate(via, c.Currency); ok {
+			return toVia.Mul(fromVia), nil
+		}
+	}
+	return decimal.Zero, fmt.Errorf("%w from %s to %s on %s", ErrExchangeRateNotFound, currency, c.Currency, c.Date.Format(time.DateOnly))
+}
+
+// Convert() converts an amount in currency to the reporting currency. The result is not
+// rounded, so sums of converted amounts stay exact.
+func (c *CurrencyConverter) Convert(amount decimal.Decimal, currency string) (decimal.Decimal, error) {
+	rate, err := c.Rate(currency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return amount.Mul(rate), nil
+}
+
+func (c *CurrencyConverter) pairRate(base, quote string) (decimal.Decimal, bool) {
+	if rate, ok := c.rates[currencyPair{base, quote}]; ok {
+		return rate, true
+	}
+	if rate, ok := c.rates[currencyPair{quote, base}]; ok {
+		return decimal.NewFromInt(1).Div(rate), true
+	}
+	return decimal.Zero, false
+}
@@ -0,0 +1,128 @@
+package data
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+	"github.com/shopspring/decimal"
+)
+
+func TestCurrencyConverter(t *testing.T) {
+	date := time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)
+	rates := []*ExchangeRate{
+		{BaseCurrency: "USD", QuoteCurrency: "KES", Rate: decimal.NewFromInt(125)},
+		{BaseCurrency: "EUR", QuoteCurrency: "USD", Rate: decimal.RequireFromString("1.2")},
+		{BaseCurrency: "USD", QuoteCurrency: "UGX", Rate: decimal.NewFromInt(3700)},
+	}
+	converter := NewCurrencyConverter("KES", date, rates)
+	tests := []struct {
+		name     string
+		amount   string
+		currency string
+		want     string
+	}{
+		{name: "Same currency", amount: "100", currency: "KES", want: "100"},
+		{name: "Direct rate", amount: "2", currency: "USD", want: "250"},
+		{name: "Cross rate", amount: "10", currency: "EUR", want: "1500"},
+		{name: "Cross rate of an inverted rate", amount: "7400", currency: "UGX", want: "250"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := converter.Convert(decimal.RequireFromString(tt.amount), tt.currency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Round(8).Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("Convert(%s %s) = %s, want %s", tt.amount, tt.currency, got, tt.want)
+			}
+		})
+	}
+	t.Run("Inverted rate", func(t *testing.T) {
+		got, err := NewCurrencyConverter("USD", date, rates).Convert(decimal.NewFromInt(500), "KES")
+		if err != nil || !got.Equal(decimal.NewFromInt(4)) {
+			t.Errorf("Convert(500 KES) = %s, %v, want 4 USD", got, err)
+		}
+	})
+	t.Run("Missing rate", func(t *testing.T) {
+		_, err := converter.Convert(decimal.NewFromInt(1), "TZS")
+		if !errors.Is(err, ErrExchangeRateNotFound) || !strings.Contains(err.Error(), "TZS to KES on 2026-01-31") {
+			t.Errorf("Convert(1 TZS) error = %v, want ErrExchangeRateNotFound", err)
+		}
+	})
+}
+
+func TestReadExchangeRates(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		input     string
+		wantRates int
+		wantError string
+	}{
+		{name: "CSV", format: ExchangeRateFormatCSV, input: "rate_date,base_currency,quote_currency,rate\n2026-01-31,USD,KES,129.25\n2026-01-31, EUR, USD, 1.08\n", wantRates: 2},
+		{name: "CSV columns in any order", format: ExchangeRateFormatCSV, input: "rate,quote_currency,base_currency,rate_date\n129.25,KES,USD,2026-01-31\n", wantRates: 1},
+		{name: "CSV without rows", format: ExchangeRateFormatCSV, input: "rate_date,base_currency,quote_currency,rate\n"},
+		{name: "Empty CSV", format: ExchangeRateFormatCSV, input: "", wantError: "the file is empty"},
+		{name: "CSV missing a column", format: ExchangeRateFormatCSV, input: "rate_date,base_currency,rate\n", wantError: `missing the "quote_currency" column`},
+		{name: "CSV with a bad rate", format: ExchangeRateFormatCSV, input: "rate_date,base_currency,quote_currency,rate\n2026-01-31,USD,KES,129.25\n2026-01-31,USD,UGX,lots\n", wantError: "line 3: invalid rate"},
+		{name: "CSV with a bad date", format: ExchangeRateFormatCSV, input: "rate_date,base_currency,quote_currency,rate\n31/01/2026,USD,KES,129.25\n", wantError: "line 2: invalid rate_date"},
+		{name: "JSON", format: ExchangeRateFormatJSON, input: `[{"rate_date": "2026-01-31", "base_currency": "USD", "quote_currency": "KES", "rate": "129.25"}]`, wantRates: 1},
+		{name: "JSON with an unknown field", format: ExchangeRateFormatJSON, input: `[{"date": "2026-01-31"}]`, wantError: "invalid exchange rates JSON"},
+		{name: "JSON with a bad date", format: ExchangeRateFormatJSON, input: `[{"rate_date": "today", "base_currency": "USD", "quote_currency": "KES", "rate": 129}]`, wantError: "exchange rate 0: invalid rate_date"},
+		{name: "Unknown format", format: "xml", input: "<rates/>", wantError: "unsupported exchange rates format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rates, err := ReadExchangeRates(strings.NewReader(tt.input), tt.format)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Errorf("ReadExchangeRates() error = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rates) != tt.wantRates {
+				t.Fatalf("ReadExchangeRates() = %d rates, want %d", len(rates), tt.wantRates)
+			}
+			if tt.wantRates > 0 && (rates[0].BaseCurrency != "USD" || rates[0].QuoteCurrency != "KES" ||
+				!rates[0].Rate.Equal(decimal.RequireFromString("129.25")) || !rates[0].RateDate.Equal(time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC))) {
+				t.Errorf("first rate = %+v", rates[0])
+			}
+		})
+	}
+}
+
+func TestValidateExchangeRates(t *testing.T) {
+	rate := func(base, quote, value string) *ExchangeRate {
+		return &ExchangeRate{BaseCurrency: base, QuoteCurrency: quote, Rate: decimal.RequireFromString(value)}
+	}
+	tests := []struct {
+		name      string
+		rates     []*ExchangeRate
+		wantField string
+	}{
+		{name: "Valid", rates: []*ExchangeRate{rate("USD", "KES", "129.25"), rate("EUR", "USD", "1.08")}},
+		{name: "No rates", rates: nil, wantField: "rates"},
+		{name: "Unknown currency", rates: []*ExchangeRate{rate("USD", "KES", "129.25"), rate("USD", "XYZ", "1")}, wantField: "rates[1].quote_currency"},
+		{name: "Lowercase currency", rates: []*ExchangeRate{rate("usd", "KES", "129.25")}, wantField: "rates[0].base_currency"},
+		{name: "Same currencies", rates: []*ExchangeRate{rate("KES", "KES", "1")}, wantField: "rates[0].quote_currency"},
+		{name: "Zero rate", rates: []*ExchangeRate{rate("USD", "KES", "0")}, wantField: "rates[0].rate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateExchangeRates(v, tt.rates)
+			if tt.wantField == "" && !v.Valid() {
+				t.Errorf("unexpected errors: %v", v.Errors)
+			}
+			if _, ok := v.Errors[tt.wantField]; tt.wantField != "" && !ok {
+				t.Errorf("errors = %v, want one for %q", v.Errors, tt.wantField)
+			}
+		})
+	}
+}
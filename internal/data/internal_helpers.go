@@ -4,9 +4,24 @@ import (
 	"context"
 	"time"
 
+	"github.com/Blue-Davinci/leadhub-service/internal/tracing"
 	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer creates the spans of the model methods.
+var tracer = tracing.Tracer("github.com/Blue-Davinci/leadhub-service/internal/data")
+
+// startSpan() starts a child span of the span carried by ctx for a model method, such as
+// "TradeLeadModel.GetTradeLeadByID". The caller must end the span.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system.name", "postgresql")),
+	)
+}
+
 // contextGenerator() is a helper function that generates a new context.Context from a
 // context.Context and a timeout duration.
 func contextGenerator(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
@@ -0,0 +1,232 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/database"
+)
+
+type JobModel struct {
+	DB      *database.Queries
+	Timeout time.Duration
+}
+
+const (
+	DefaultJobDBContextTimeout = 5 * time.Second
+	// DefaultJobMaxAttempts is how often a job runs before it is moved to the dead state.
+	DefaultJobMaxAttempts = 5
+)
+
+// Define constants for the background job statuses. Dead jobs have used up all of their
+// attempts and are kept for inspection.
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusDead      = "dead"
+)
+
+var (
+	// ErrJobLockLost is returned when a worker finishes a job it no longer holds, because its
+	// lock expired and the job was handed to another worker.
+	ErrJobLockLost = errors.New("job is no longer locked by this worker")
+)
+
+// Job is a unit of background work stored in the database, so it survives restarts.
+type Job struct {
+	ID          int64           `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	MaxAttempts int32           `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LastError   string          `json:"last_error,omitempty"`
+	LockedBy    string          `json:"-"`
+	LockedAt    *time.Time      `json:"-"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// NewJob() returns a pending job of the given kind, with the payload encoded as JSON. The
+// job runs as soon as a worker is free, set RunAt to delay it.
+func NewJob(kind string, payload any) (*Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Job{
+		Kind:        kind,
+		Payload:     encoded,
+		MaxAttempts: DefaultJobMaxAttempts,
+	}, nil
+}
+
+// EnqueueJob() stores a new pending job. A zero RunAt runs it right away and a zero
+// MaxAttempts uses DefaultJobMaxAttempts.
+func (m JobModel) EnqueueJob(ctx context.Context, job *Job) error {
+	ctx, span := startSpan(ctx, "JobModel.EnqueueJob")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	prepareJob(job)
+	newJob, err := m.DB.EnqueueJob(ctx, database.EnqueueJobParams{
+		Kind:        job.Kind,
+		Payload:     job.Payload,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+	})
+	if err != nil {
+		return err
+	}
+	job.ID = newJob.ID
+	job.Status = newJob.Status
+	job.Attempts = newJob.Attempts
+	job.CreatedAt = newJob.CreatedAt
+	job.UpdatedAt = newJob.UpdatedAt
+	return nil
+}
+
+// ClaimJobs() locks up to limit due pending jobs for the worker and marks them running.
+// Jobs locked by other workers are skipped, so any number of workers can claim at once.
+func (m JobModel) ClaimJobs(ctx context.Context, workerID string, limit int32) ([]*Job, error) {
+	ctx, span := startSpan(ctx, "JobModel.ClaimJobs")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.ClaimJobs(ctx, database.ClaimJobsParams{
+		LockedBy: workerID,
+		Limit:    limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*Job, 0, len(rows))
+	for _, row := range rows {
+		jobs = append(jobs, populateJob(row))
+	}
+	return jobs, nil
+}
+
+// CompleteJob() marks a job held by the worker as completed.
+func (m JobModel) CompleteJob(ctx context.Context, id int64, workerID string) error {
+	ctx, span := startSpan(ctx, "JobModel.CompleteJob")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.CompleteJob(ctx, database.CompleteJobParams{
+		ID:       id,
+		LockedBy: workerID,
+	})
+	return jobUpdateResult(rows, err)
+}
+
+// RetryJob() releases a failed job held by the worker, to run again at runAt.
+func (m JobModel) RetryJob(ctx context.Context, id int64, workerID string, runAt time.Time, lastError string) error {
+	ctx, span := startSpan(ctx, "JobModel.RetryJob")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.RetryJob(ctx, database.RetryJobParams{
+		ID:        id,
+		LockedBy:  workerID,
+		RunAt:     runAt,
+		LastError: lastError,
+	})
+	return jobUpdateResult(rows, err)
+}
+
+// BuryJob() moves a job held by the worker to the dead state, it is not run again.
+func (m JobModel) BuryJob(ctx context.Context, id int64, workerID string, lastError string) error {
+	ctx, span := startSpan(ctx, "JobModel.BuryJob")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.BuryJob(ctx, database.BuryJobParams{
+		ID:        id,
+		LockedBy:  workerID,
+		LastError: lastError,
+	})
+	return jobUpdateResult(rows, err)
+}
+
+// RequeueStaleJobs() returns running jobs locked before lockedBefore to the pending state.
+// Their worker is assumed to have crashed. It returns the number of requeued jobs.
+func (m JobModel) RequeueStaleJobs(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "JobModel.RequeueStaleJobs")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	return m.DB.RequeueStaleJobs(ctx, sql.NullTime{Time: lockedBefore, Valid: true})
+}
+
+// GetJobByID() retrieves a job by its ID.
+func (m JobModel) GetJobByID(ctx context.Context, id int64) (*Job, error) {
+	ctx, span := startSpan(ctx, "JobModel.GetJobByID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	job, err := m.DB.GetJobByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrGeneralRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return populateJob(job), nil
+}
+
+// prepareJob() fills in the defaults of a job about to be enqueued.
+func prepareJob(job *Job) {
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultJobMaxAttempts
+	}
+	if len(job.Payload) == 0 {
+		job.Payload = json.RawMessage(`{}`)
+	}
+}
+
+// jobUpdateResult() maps the result of an update of a locked job, no affected rows means
+// the worker lost its lock.
+func jobUpdateResult(rows int64, err error) error {
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrJobLockLost
+	}
+	return nil
+}
+
+func populateJob(jobRow database.Job) *Job {
+	job := &Job{
+		ID:          jobRow.ID,
+		Kind:        jobRow.Kind,
+		Payload:     jobRow.Payload,
+		Status:      jobRow.Status,
+		Attempts:    jobRow.Attempts,
+		MaxAttempts: jobRow.MaxAttempts,
+		RunAt:       jobRow.RunAt,
+		LastError:   jobRow.LastError,
+		LockedBy:    jobRow.LockedBy,
+		CreatedAt:   jobRow.CreatedAt,
+		UpdatedAt:   jobRow.UpdatedAt,
+	}
+	if jobRow.LockedAt.Valid {
+		job.LockedAt = &jobRow.LockedAt.Time
+	}
+	if jobRow.CompletedAt.Valid {
+		job.CompletedAt = &jobRow.CompletedAt.Time
+	}
+	return job
+}
@@ -0,0 +1,2240 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// NewMemoryModels() returns models whose stores keep their data in memory. They follow the
+// semantics of the Postgres models, including optimistic locking, uniqueness and reference
+// errors and tenant scoping, which makes them a drop-in for handler tests. RunInTx() rolls
+// back on failure but doesn't isolate a transaction from writes made outside of it.
+func NewMemoryModels() Models {
+	db := newMemoryDB()
+	return Models{
+		Tenants:       memoryTenantStore{db: db},
+		Users:         memoryUserStore{db: db},
+		Tokens:        memoryTokenStore{db: db},
+		Permissions:   memoryPermissionStore{db: db},
+		TradeLeads:    memoryTradeLeadStore{db: db},
+		Jobs:          memoryJobStore{db: db},
+		Emails:        memoryEmailOutboxStore{db: db},
+		Webhooks:      memoryWebhookStore{db: db},
+		LeadEvents:    memoryTradeLeadEventStore{db: db},
+		ExchangeRates: memoryExchangeRateStore{db: db},
+		Comments:      memoryTradeLeadCommentStore{db: db},
+		Exports:       memoryTenantExportStore{db: db},
+		CustomFields:  memoryCustomFieldStore{db: db},
+		Settings:      memoryTenantSettingsStore{db: db},
+		Domains:       memoryTenantDomainStore{db: db},
+		memory:        db,
+	}
+}
+
+var (
+	_ TenantStore           = memoryTenantStore{}
+	_ UserStore             = memoryUserStore{}
+	_ TokenStore            = memoryTokenStore{}
+	_ PermissionStore       = memoryPermissionStore{}
+	_ TradeLeadStore        = memoryTradeLeadStore{}
+	_ JobStore              = memoryJobStore{}
+	_ EmailOutboxStore      = memoryEmailOutboxStore{}
+	_ WebhookStore          = memoryWebhookStore{}
+	_ TradeLeadEventStore   = memoryTradeLeadEventStore{}
+	_ ExchangeRateStore     = memoryExchangeRateStore{}
+	_ TradeLeadCommentStore = memoryTradeLeadCommentStore{}
+	_ TenantExportStore     = memoryTenantExportStore{}
+	_ CustomFieldStore      = memoryCustomFieldStore{}
+	_ TenantSettingsStore   = memoryTenantSettingsStore{}
+	_ TenantDomainStore     = memoryTenantDomainStore{}
+)
+
+// memoryDB holds the tables shared by the in-memory stores.
+type memoryDB struct {
+	mu    sync.RWMutex
+	txMu  sync.Mutex
+	state memoryState
+}
+
+type memoryState struct {
+	sequences       map[string]int64
+	tenants         map[int64]Tenant
+	users           map[int64]User
+	tokens          map[string]Token
+	permissions     map[int64]string
+	userPermissions map[int64]map[int64]bool
+	leads           map[int64]memoryLead
+	leadHistory     []TradeLeadHistory
+	jobs            map[int64]Job
+	emails          map[int64]OutboxEmail
+	webhooks        map[int64]WebhookEndpoint
+	deliveries      map[int64]WebhookDelivery
+	exchangeRates   map[int64]ExchangeRate
+	comments        map[int64]TradeLeadComment
+	exports         map[int64]TenantExport
+	customFields    map[int64]CustomField
+	settings        map[int64]TenantSettings
+	domains         map[int64]TenantDomain
+}
+
+// memoryLead is a stored trade lead. Custom fields are kept encoded, as in the JSONB column,
+// so every read hands out its own copy.
+type memoryLead struct {
+	lead         TradeLead
+	customFields json.RawMessage
+}
+
+func newMemoryDB() *memoryDB {
+	db := &memoryDB{state: memoryState{
+		sequences:       map[string]int64{},
+		tenants:         map[int64]Tenant{},
+		users:           map[int64]User{},
+		tokens:          map[string]Token{},
+		permissions:     map[int64]string{},
+		userPermissions: map[int64]map[int64]bool{},
+		leads:           map[int64]memoryLead{},
+		jobs:            map[int64]Job{},
+		emails:          map[int64]OutboxEmail{},
+		webhooks:        map[int64]WebhookEndpoint{},
+		deliveries:      map[int64]WebhookDelivery{},
+		exchangeRates:   map[int64]ExchangeRate{},
+		comments:        map[int64]TradeLeadComment{},
+		exports:         map[int64]TenantExport{},
+		customFields:    map[int64]CustomField{},
+		settings:        map[int64]TenantSettings{},
+		domains:         map[int64]TenantDomain{},
+	}}
+	// the permissions seeded by the migrations
+	for _, code := range []string{PermissionAdminRead, PermissionAdminWrite, PermissionTenantAdmin, PermissionTenantGroup} {
+		db.state.permissions[db.state.nextID("permissions")] = code
+	}
+	return db
+}
+
+// nextID() returns the next value of a table's ID sequence.
+func (s *memoryState) nextID(table string) int64 {
+	s.sequences[table]++
+	return s.sequences[table]
+}
+
+// clone() copies the tables. Stored values are never modified in place, so copying the
+// maps is enough.
+func (s *memoryState) clone() memoryState {
+	userPermissions := make(map[int64]map[int64]bool, len(s.userPermissions))
+	for userID, granted := range s.userPermissions {
+		userPermissions[userID] = maps.Clone(granted)
+	}
+	return memoryState{
+		sequences:       maps.Clone(s.sequences),
+		tenants:         maps.Clone(s.tenants),
+		users:           maps.Clone(s.users),
+		tokens:          maps.Clone(s.tokens),
+		permissions:     maps.Clone(s.permissions),
+		userPermissions: userPermissions,
+		leads:           maps.Clone(s.leads),
+		leadHistory:     slices.Clone(s.leadHistory),
+		jobs:            maps.Clone(s.jobs),
+		emails:          maps.Clone(s.emails),
+		webhooks:        maps.Clone(s.webhooks),
+		deliveries:      maps.Clone(s.deliveries),
+		exchangeRates:   maps.Clone(s.exchangeRates),
+		comments:        maps.Clone(s.comments),
+		exports:         maps.Clone(s.exports),
+		customFields:    maps.Clone(s.customFields),
+		settings:        maps.Clone(s.settings),
+		domains:         maps.Clone(s.domains),
+	}
+}
+
+// read() runs fn with the tables locked for reading, unless ctx is already done.
+func (db *memoryDB) read(ctx context.Context, fn func(s *memoryState) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return fn(&db.state)
+}
+
+// write() runs fn with the tables locked for writing, unless ctx is already done.
+func (db *memoryDB) write(ctx context.Context, fn func(s *memoryState) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return fn(&db.state)
+}
+
+// runInTx() runs fn and restores the tables when it fails or panics. Transactions run one
+// at a time.
+func (db *memoryDB) runInTx(fn func() error) error {
+	db.txMu.Lock()
+	defer db.txMu.Unlock()
+	db.mu.RLock()
+	saved := db.state.clone()
+	db.mu.RUnlock()
+	rollback := func() {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		db.state = saved
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+// memoryTenantStore is the in-memory TenantStore.
+type memoryTenantStore struct {
+	db *memoryDB
+}
+
+func (m memoryTenantStore) GetTenantByID(ctx context.Context, id int64) (*Tenant, error) {
+	var tenant *Tenant
+	err := m.db.read(ctx, func(s *memoryState) error {
+		stored, ok := s.tenants[id]
+		if !ok {
+			return ErrGeneralRecordNotFound
+		}
+		tenant = &stored
+		return nil
+	})
+	return tenant, err
+}
+
+func (m memoryTenantStore) AdminGetAllTenants(ctx context.Context, tenantName string, filters Filters) ([]*Tenant, Metadata, error) {
+	var (
+		tenants     []*Tenant
+		descendants []*Tenant
+		totalRows   int
+	)
+	err := m.db.read(ctx, func(s *memoryState) error {
+		matched := []*Tenant{}
+		for _, stored := range s.tenants {
+			if tenantName == "" && stored.ParentID == nil || tenantName != "" && matchesTextSearch(stored.Name, tenantName) {
+				matched = append(matched, &stored)
+			}
+		}
+		sortTenantsNewestFirst(matched)
+		totalRows = len(matched)
+		tenants = paginate(matched, filters)
+		rootIDs := []int64{}
+		for _, tenant := range tenants {
+			rootIDs = append(rootIDs, tenant.ID)
+		}
+		descendants = s.tenantSubtrees(rootIDs)
+		return nil
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	if len(tenants) == 0 {
+		return nil, Metadata{}, ErrGeneralRecordNotFound
+	}
+	buildTenantTree(tenants, descendants)
+	return tenants, calculateMetadata(totalRows, filters.Page, filters.PageSize), nil
+}
+
+func (m memoryTenantStore) CreateTenant(ctx context.Context, tenant *Tenant) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		if s.tenantNameTaken(tenant.Name, 0) {
+			return ErrTenantAlreadyExists
+		}
+		if tenant.ParentID != nil {
+			if _, ok := s.tenants[*tenant.ParentID]; !ok {
+				return ErrInvalidParentTenant
+			}
+		}
+		now := time.Now()
+		stored := Tenant{
+			ID:           s.nextID("tenants"),
+			ParentID:     copyID(tenant.ParentID),
+			Name:         tenant.Name,
+			ContactEmail: tenant.ContactEmail,
+			Description:  tenant.Description,
+			Version:      1,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		s.tenants[stored.ID] = stored
+		tenant.ID = stored.ID
+		tenant.Version = stored.Version
+		tenant.CreatedAt = stored.CreatedAt
+		tenant.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryTenantStore) UpdateTenant(ctx context.Context, tenant *Tenant, versionID int32) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		if tenant.ParentID != nil {
+			if slices.Contains(s.tenantDescendantIDs(tenant.ID), *tenant.ParentID) {
+				return ErrTenantHierarchyCycle
+			}
+		}
+		stored, ok := s.tenants[tenant.ID]
+		if !ok || stored.Version != versionID {
+			return ErrGeneralEditConflict
+		}
+		if s.tenantNameTaken(tenant.Name, tenant.ID) {
+			return ErrTenantAlreadyExists
+		}
+		if tenant.ParentID != nil {
+			if _, ok := s.tenants[*tenant.ParentID]; !ok {
+				return ErrInvalidParentTenant
+			}
+		}
+		stored.Name = tenant.Name
+		stored.ContactEmail = tenant.ContactEmail
+		stored.Description = tenant.Description
+		stored.ParentID = copyID(tenant.ParentID)
+		stored.Version++
+		stored.UpdatedAt = time.Now()
+		s.tenants[stored.ID] = stored
+		tenant.UpdatedAt = stored.UpdatedAt
+		tenant.Version = stored.Version
+		return nil
+	})
+}
+
+func (m memoryTenantStore) GetTenantDescendantIDs(ctx context.Context, tenantID int64) ([]int64, error) {
+	var ids []int64
+	err := m.db.read(ctx, func(s *memoryState) error {
+		ids = s.tenantDescendantIDs(tenantID)
+		return nil
+	})
+	return ids, err
+}
+
+func (m memoryTenantStore) GetTenantTree(ctx context.Context, tenantID int64) (*Tenant, error) {
+	root, err := m.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	var descendants []*Tenant
+	err = m.db.read(ctx, func(s *memoryState) error {
+		descendants = s.tenantSubtrees([]int64{tenantID})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	buildTenantTree([]*Tenant{root}, descendants)
+	return root, nil
+}
+
+// tenantNameTaken() reports whether a tenant other than exceptID has the name.
+func (s *memoryState) tenantNameTaken(name string, exceptID int64) bool {
+	for _, stored := range s.tenants {
+		if stored.Name == name && stored.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// tenantDescendantIDs() returns the tenant and all of its sub-tenants, ordered by ID. An
+// unknown tenant has no descendants.
+func (s *memoryState) tenantDescendantIDs(tenantID int64) []int64 {
+	if _, ok := s.tenants[tenantID]; !ok {
+		return []int64{}
+	}
+	ids := []int64{tenantID}
+	for _, tenant := range s.tenantSubtrees([]int64{tenantID}) {
+		ids = append(ids, tenant.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// tenantSubtrees() returns copies of every sub-tenant of the roots, at any depth, newest
+// first. The roots themselves are only included when they sit beneath another root.
+func (s *memoryState) tenantSubtrees(rootIDs []int64) []*Tenant {
+	seen := map[int64]bool{}
+	queue := slices.Clone(rootIDs)
+	subtree := []*Tenant{}
+	for len(queue) > 0 {
+		parentID := queue[0]
+		queue = queue[1:]
+		for _, stored := range s.tenants {
+			if stored.ParentID == nil || *stored.ParentID != parentID || seen[stored.ID] {
+				continue
+			}
+			seen[stored.ID] = true
+			subtree = append(subtree, &stored)
+			queue = append(queue, stored.ID)
+		}
+	}
+	sortTenantsNewestFirst(subtree)
+	return subtree
+}
+
+func sortTenantsNewestFirst(tenants []*Tenant) {
+	slices.SortFunc(tenants, func(a, b *Tenant) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+	})
+}
+
+// memoryUserStore is the in-memory UserStore. Emails are unique regardless of case, like
+// the citext column.
+type memoryUserStore struct {
+	db *memoryDB
+}
+
+func (m memoryUserStore) Insert(ctx context.Context, user *User) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		if s.emailTaken(user.Email, 0) {
+			return ErrDuplicateEmail
+		}
+		if _, ok := s.tenants[user.TenantID]; !ok {
+			return ErrInvalidTenantID
+		}
+		now := time.Now()
+		stored := User{
+			ID:        s.nextID("users"),
+			TenantID:  user.TenantID,
+			Name:      user.Name,
+			Email:     user.Email,
+			Password:  password{hash: user.Password.hash},
+			Activated: user.Activated,
+			Locale:    user.Locale,
+			Version:   1,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		s.users[stored.ID] = stored
+		user.ID = stored.ID
+		user.CreatedAt = stored.CreatedAt
+		user.Version = stored.Version
+		return nil
+	})
+}
+
+func (m memoryUserStore) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
+	var user *User
+	err := m.db.read(ctx, func(s *memoryState) error {
+		token, ok := s.tokens[string(tokenHash[:])]
+		if !ok || token.Scope != tokenScope || !token.Expiry.After(time.Now()) {
+			return ErrGeneralRecordNotFound
+		}
+		stored, ok := s.users[token.UserID]
+		if !ok {
+			return ErrGeneralRecordNotFound
+		}
+		user = &stored
+		return nil
+	})
+	return user, err
+}
+
+func (m memoryUserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	var user *User
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, stored := range s.users {
+			if strings.EqualFold(stored.Email, email) {
+				user = &stored
+				return nil
+			}
+		}
+		return ErrGeneralRecordNotFound
+	})
+	return user, err
+}
+
+func (m memoryUserStore) UpdateUser(ctx context.Context, user *User) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.users[user.ID]
+		if !ok || stored.Version != user.Version {
+			return ErrGeneralEditConflict
+		}
+		if s.emailTaken(user.Email, user.ID) {
+			return ErrDuplicateEmail
+		}
+		stored.Name = user.Name
+		stored.Email = user.Email
+		stored.Password = password{hash: user.Password.hash}
+		stored.Activated = user.Activated
+		stored.Locale = user.Locale
+		stored.Version++
+		stored.UpdatedAt = time.Now()
+		s.users[stored.ID] = stored
+		user.Version = stored.Version
+		user.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryUserStore) GetAllUsersByTenantID(ctx context.Context, tenantID int64) ([]*User, error) {
+	users := []*User{}
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, stored := range s.users {
+			if stored.TenantID != tenantID {
+				continue
+			}
+			// password hashes are never handed out in listings
+			stored.Password = password{}
+			users = append(users, &stored)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(users, func(a, b *User) int { return cmp.Compare(a.ID, b.ID) })
+	return users, nil
+}
+
+// emailTaken() reports whether a user other than exceptID has the email.
+func (s *memoryState) emailTaken(email string, exceptID int64) bool {
+	for _, stored := range s.users {
+		if strings.EqualFold(stored.Email, email) && stored.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// memoryTokenStore is the in-memory TokenStore, tokens are keyed by their hash.
+type memoryTokenStore struct {
+	db *memoryDB
+}
+
+func (m memoryTokenStore) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
+	api_key, err := generateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+	err = m.Insert(ctx, api_key)
+	return api_key, err
+}
+
+func (m memoryTokenStore) Insert(ctx context.Context, api_key *Token) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		if _, ok := s.users[api_key.UserID]; !ok {
+			return fmt.Errorf("api key references unknown user %d", api_key.UserID)
+		}
+		stored := *api_key
+		stored.Plaintext = ""
+		// the expiry column has a precision of seconds
+		stored.Expiry = api_key.Expiry.Round(time.Second)
+		s.tokens[string(api_key.Hash)] = stored
+		return nil
+	})
+}
+
+func (m memoryTokenStore) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		maps.DeleteFunc(s.tokens, func(_ string, token Token) bool {
+			return token.Scope == scope && token.UserID == userID
+		})
+		return nil
+	})
+}
+
+// memoryPermissionStore is the in-memory PermissionStore, seeded with the permissions of
+// the migrations.
+type memoryPermissionStore struct {
+	db *memoryDB
+}
+
+func (m memoryPermissionStore) GetAllPermissions(ctx context.Context) ([]*UserPermission, error) {
+	var allPermissions []*UserPermission
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, id := range slices.Sorted(maps.Keys(s.permissions)) {
+			allPermissions = append(allPermissions, &UserPermission{
+				PermissionID: id,
+				Permissions:  []string{s.permissions[id]},
+			})
+		}
+		return nil
+	})
+	return allPermissions, err
+}
+
+func (m memoryPermissionStore) GetAllPermissionsForUser(ctx context.Context, userID int64) (Permissions, error) {
+	var permissions Permissions
+	err := m.db.read(ctx, func(s *memoryState) error {
+		if _, ok := s.users[userID]; !ok {
+			return nil
+		}
+		for _, id := range slices.Sorted(maps.Keys(s.userPermissions[userID])) {
+			permissions = append(permissions, s.permissions[id])
+		}
+		return nil
+	})
+	return permissions, err
+}
+
+func (m memoryPermissionStore) AddPermissionsForUser(ctx context.Context, userID int64, codes ...string) (*UserPermission, error) {
+	var userPermission *UserPermission
+	err := m.db.write(ctx, func(s *memoryState) error {
+		ids := []int64{}
+		for _, id := range slices.Sorted(maps.Keys(s.permissions)) {
+			if slices.Contains(codes, s.permissions[id]) {
+				ids = append(ids, id)
+			}
+		}
+		// like the INSERT ... RETURNING of a single row when no permission matched
+		if len(ids) == 0 {
+			return sql.ErrNoRows
+		}
+		if _, ok := s.users[userID]; !ok {
+			return fmt.Errorf("permissions reference unknown user %d", userID)
+		}
+		granted := s.userPermissions[userID]
+		for _, id := range ids {
+			if granted[id] {
+				return ErrDuplicatePermission
+			}
+		}
+		if granted == nil {
+			granted = map[int64]bool{}
+			s.userPermissions[userID] = granted
+		}
+		for _, id := range ids {
+			granted[id] = true
+		}
+		userPermission = &UserPermission{
+			PermissionID: ids[0],
+			UserID:       userID,
+			Permissions:  codes,
+		}
+		return nil
+	})
+	return userPermission, err
+}
+
+func (m memoryPermissionStore) DeletePermissionsForUser(ctx context.Context, userID int64, permissionCode string) (int64, error) {
+	var permissionID int64
+	err := m.db.write(ctx, func(s *memoryState) error {
+		for id := range s.userPermissions[userID] {
+			if s.permissions[id] == permissionCode {
+				delete(s.userPermissions[userID], id)
+				permissionID = id
+				return nil
+			}
+		}
+		return ErrPermissionNotFound
+	})
+	return permissionID, err
+}
+
+// memoryTradeLeadStore is the in-memory TradeLeadStore. It records the lead history the
+// way the database trigger does.
+type memoryTradeLeadStore struct {
+	db *memoryDB
+}
+
+func (m memoryTradeLeadStore) CreateTradeLead(ctx context.Context, tenantID int64, tenantLead *TradeLead) error {
+	customFields, err := encodeCustomFields(tenantLead.CustomFields)
+	if err != nil {
+		return err
+	}
+	return m.db.write(ctx, func(s *memoryState) error {
+		if _, ok := s.tenants[tenantID]; !ok {
+			return ErrInvalidTenantReference
+		}
+		if !s.isTenantUser(tenantLead.AssignedTo, tenantID) {
+			return ErrInvalidTradeLeadAssignee
+		}
+		now := time.Now()
+		stored := memoryLead{
+			lead: TradeLead{
+				ID:          s.nextID("trade_leads"),
+				TenantID:    tenantID,
+				Title:       tenantLead.Title,
+				Description: tenantLead.Description,
+				Status:      "new",
+				// the value column is NUMERIC(18, 2)
+				Value:      tenantLead.Value.Round(2),
+				Currency:   tenantLead.Currency,
+				AssignedTo: tenantLead.AssignedTo,
+				Version:    1,
+				CreatedAt:  now,
+				UpdatedAt:  now,
+			},
+			customFields: customFields,
+		}
+		s.leads[stored.lead.ID] = stored
+		s.recordLeadHistory(stored.lead)
+		tenantLead.ID = stored.lead.ID
+		tenantLead.TenantID = stored.lead.TenantID
+		tenantLead.Version = stored.lead.Version
+		tenantLead.Status = stored.lead.Status
+		tenantLead.CreatedAt = stored.lead.CreatedAt
+		tenantLead.UpdatedAt = stored.lead.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryTradeLeadStore) GetTradeLeadByID(ctx context.Context, id int64) (*TradeLead, error) {
+	var lead *TradeLead
+	err := m.db.read(ctx, func(s *memoryState) error {
+		stored, ok := s.leads[id]
+		if !ok {
+			return ErrGeneralRecordNotFound
+		}
+		lead = stored.copy()
+		return nil
+	})
+	return lead, err
+}
+
+func (m memoryTradeLeadStore) GetAllLeadsByTenantID(ctx context.Context, tenantID, assigneeID int64, name string, customFilters CustomFieldFilters, filters Filters) ([]*TradeLead, Metadata, error) {
+	return m.GetAllLeadsByTenantIDs(ctx, []int64{tenantID}, assigneeID, name, customFilters, filters)
+}
+
+func (m memoryTradeLeadStore) GetAllLeadsByTenantIDs(ctx context.Context, tenantIDs []int64, assigneeID int64, name string, customFilters CustomFieldFilters, filters Filters) ([]*TradeLead, Metadata, error) {
+	return m.listLeads(ctx, func(lead TradeLead) bool {
+		if assigneeID != 0 && (lead.AssignedTo == nil || *lead.AssignedTo != assigneeID) {
+			return false
+		}
+		return slices.Contains(tenantIDs, lead.TenantID)
+	}, name, customFilters, filters)
+}
+
+func (m memoryTradeLeadStore) AdminGetAllTradeLeads(ctx context.Context, tenantID int64, name string, customFilters CustomFieldFilters, filters Filters) ([]*TradeLead, Metadata, error) {
+	return m.listLeads(ctx, func(lead TradeLead) bool {
+		return tenantID == 0 || lead.TenantID == tenantID
+	}, name, customFilters, filters)
+}
+
+func (m memoryTradeLeadStore) AdminUpdateTradeLeadStatus(ctx context.Context, leadID int64, version int32, lead *TradeLead) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.leads[leadID]
+		if !ok || stored.lead.Version != version {
+			return ErrGeneralRecordNotFound
+		}
+		statusChanged := stored.lead.Status != "verified"
+		stored.lead.Status = "verified"
+		stored.lead.Version++
+		stored.lead.UpdatedAt = time.Now()
+		s.leads[leadID] = stored
+		if statusChanged {
+			s.recordLeadHistory(stored.lead)
+		}
+		lead.Version = stored.lead.Version
+		lead.Status = stored.lead.Status
+		lead.UpdatedAt = stored.lead.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryTradeLeadStore) AssignTradeLead(ctx context.Context, leadID int64, version int32, assigneeID *int64, lead *TradeLead) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.leads[leadID]
+		if !ok || stored.lead.Version != version {
+			return ErrGeneralRecordNotFound
+		}
+		if !s.isTenantUser(assigneeID, stored.lead.TenantID) {
+			return ErrInvalidTradeLeadAssignee
+		}
+		stored.lead.AssignedTo = assigneeID
+		stored.lead.Version++
+		stored.lead.UpdatedAt = time.Now()
+		s.leads[leadID] = stored
+		lead.Version = stored.lead.Version
+		lead.AssignedTo = assigneeID
+		lead.UpdatedAt = stored.lead.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryTradeLeadStore) GetNextTradeLeadAssignee(ctx context.Context, tenantID int64) (int64, error) {
+	var userID int64
+	err := m.db.read(ctx, func(s *memoryState) error {
+		// the assignee of the tenant's newest assigned lead
+		var newest *TradeLead
+		for _, stored := range s.leads {
+			lead := stored.lead
+			if lead.TenantID != tenantID || lead.AssignedTo == nil {
+				continue
+			}
+			if newest == nil || lead.CreatedAt.After(newest.CreatedAt) ||
+				lead.CreatedAt.Equal(newest.CreatedAt) && lead.ID > newest.ID {
+				newest = &lead
+			}
+		}
+		userIDs := []int64{}
+		for _, user := range s.users {
+			if user.TenantID == tenantID && user.Activated {
+				userIDs = append(userIDs, user.ID)
+			}
+		}
+		if len(userIDs) == 0 {
+			return ErrGeneralRecordNotFound
+		}
+		slices.Sort(userIDs)
+		userID = userIDs[0]
+		if newest != nil {
+			for _, id := range userIDs {
+				if id > *newest.AssignedTo {
+					userID = id
+					break
+				}
+			}
+		}
+		return nil
+	})
+	return userID, err
+}
+
+func (m memoryTradeLeadStore) AdminGetTradeLeadStats(ctx context.Context, converter *CurrencyConverter) (*TradeStats, error) {
+	leads := []TradeLead{}
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, stored := range s.leads {
+			leads = append(leads, stored.lead)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newTradeStats(tradeStatsTotals(leads), converter)
+}
+
+func (m memoryTradeLeadStore) GetTradeLeadStatsByTenant(ctx context.Context, tenantIDs []int64, converter *CurrencyConverter) (map[int64]*TradeStats, error) {
+	leads := map[int64][]TradeLead{}
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, stored := range s.leads {
+			if slices.Contains(tenantIDs, stored.lead.TenantID) {
+				leads[stored.lead.TenantID] = append(leads[stored.lead.TenantID], stored.lead)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[int64]*TradeStats, len(leads))
+	for tenantID, tenantLeads := range leads {
+		stats[tenantID], err = newTradeStats(tradeStatsTotals(tenantLeads), converter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+func (m memoryTradeLeadStore) GetTradeLeadAnalytics(ctx context.Context, filter TradeLeadStatsFilter) (*TradeLeadAnalytics, error) {
+	type statusKey struct {
+		tenantID int64
+		status   string
+		currency string
+	}
+	type bucketKey struct {
+		start    time.Time
+		currency string
+	}
+	counts := map[statusKey]*tradeLeadStatusCount{}
+	verifications := map[int64]*tradeLeadVerificationTime{}
+	buckets := map[bucketKey]*tradeLeadBucketTotal{}
+	err := m.db.read(ctx, func(s *memoryState) error {
+		// the first time each lead was verified
+		verifiedAt := map[int64]time.Time{}
+		for _, entry := range s.leadHistory {
+			if first, ok := verifiedAt[entry.TradeLeadID]; entry.Status == "verified" && (!ok || entry.ChangedAt.Before(first)) {
+				verifiedAt[entry.TradeLeadID] = entry.ChangedAt
+			}
+		}
+		for _, stored := range s.leads {
+			lead := stored.lead
+			if len(filter.TenantIDs) > 0 && !slices.Contains(filter.TenantIDs, lead.TenantID) {
+				continue
+			}
+			if lead.CreatedAt.Before(filter.From) || !lead.CreatedAt.Before(filter.To) {
+				continue
+			}
+			key := statusKey{tenantID: lead.TenantID, status: lead.Status, currency: lead.Currency}
+			if counts[key] == nil {
+				counts[key] = &tradeLeadStatusCount{TenantID: lead.TenantID, Status: lead.Status, Currency: lead.Currency}
+			}
+			counts[key].Leads++
+			counts[key].TotalValue = counts[key].TotalValue.Add(lead.Value)
+			if at, ok := verifiedAt[lead.ID]; ok {
+				if verifications[lead.TenantID] == nil {
+					verifications[lead.TenantID] = &tradeLeadVerificationTime{TenantID: lead.TenantID}
+				}
+				verifications[lead.TenantID].VerifiedLeads++
+				verifications[lead.TenantID].SecondsToVerify += at.Sub(lead.CreatedAt).Seconds()
+			}
+			bucket := bucketKey{start: truncateToInterval(lead.CreatedAt, filter.Interval), currency: lead.Currency}
+			if buckets[bucket] == nil {
+				buckets[bucket] = &tradeLeadBucketTotal{Start: bucket.start, Currency: lead.Currency}
+			}
+			buckets[bucket].Leads++
+			buckets[bucket].TotalValue = buckets[bucket].TotalValue.Add(lead.Value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	statusCounts := []tradeLeadStatusCount{}
+	for _, count := range counts {
+		statusCounts = append(statusCounts, *count)
+	}
+	verificationTimes := []tradeLeadVerificationTime{}
+	for _, verification := range verifications {
+		verificationTimes = append(verificationTimes, *verification)
+	}
+	bucketTotals := []tradeLeadBucketTotal{}
+	for _, bucket := range buckets {
+		bucketTotals = append(bucketTotals, *bucket)
+	}
+	return newTradeLeadAnalytics(filter, statusCounts, verificationTimes, bucketTotals)
+}
+
+func (m memoryTradeLeadStore) GetAllTradeLeadsForExport(ctx context.Context, tenantID int64) ([]*TradeLead, error) {
+	leads := []*TradeLead{}
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, stored := range s.leads {
+			if stored.lead.TenantID == tenantID {
+				leads = append(leads, stored.copy())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(leads, func(a, b *TradeLead) int { return cmp.Compare(a.ID, b.ID) })
+	return leads, nil
+}
+
+func (m memoryTradeLeadStore) GetTradeLeadHistoryByTenantID(ctx context.Context, tenantID int64) ([]*TradeLeadHistory, error) {
+	history := []*TradeLeadHistory{}
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, entry := range s.leadHistory {
+			if entry.TenantID == tenantID {
+				history = append(history, &entry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(history, func(a, b *TradeLeadHistory) int {
+		return cmp.Or(cmp.Compare(a.TradeLeadID, b.TradeLeadID), cmp.Compare(a.ID, b.ID))
+	})
+	return history, nil
+}
+
+// listLeads() filters, sorts and paginates the leads the way the listing queries do.
+func (m memoryTradeLeadStore) listLeads(ctx context.Context, include func(TradeLead) bool, name string, customFilters CustomFieldFilters, filters Filters) ([]*TradeLead, Metadata, error) {
+	customFields, err := encodeCustomFields(customFilters.Values)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	var wanted any
+	if err := json.Unmarshal(customFields, &wanted); err != nil {
+		return nil, Metadata{}, err
+	}
+	matched := []*TradeLead{}
+	err = m.db.read(ctx, func(s *memoryState) error {
+		for _, stored := range s.leads {
+			if !include(stored.lead) || name != "" && !matchesTextSearch(stored.lead.Title, name) {
+				continue
+			}
+			var values any
+			if err := json.Unmarshal(stored.customFields, &values); err != nil {
+				return err
+			}
+			if jsonContains(values, wanted) {
+				matched = append(matched, stored.copy())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	sortLeads(matched, leadSortFor(filters, customFilters.Definitions))
+	leads := paginate(matched, filters)
+	if len(leads) == 0 {
+		return nil, Metadata{}, ErrGeneralRecordNotFound
+	}
+	return leads, calculateMetadata(len(matched), filters.Page, filters.PageSize), nil
+}
+
+// memoryJobStore is the in-memory JobStore. Jobs are claimed in run_at order, like the
+// FOR UPDATE SKIP LOCKED query, the write lock keeps claims from overlapping.
+type memoryJobStore struct {
+	db *memoryDB
+}
+
+func (m memoryJobStore) EnqueueJob(ctx context.Context, job *Job) error {
+	prepareJob(job)
+	return m.db.write(ctx, func(s *memoryState) error {
+		now := time.Now()
+		stored := Job{
+			ID:          s.nextID("jobs"),
+			Kind:        job.Kind,
+			Payload:     slices.Clone(job.Payload),
+			Status:      JobStatusPending,
+			MaxAttempts: job.MaxAttempts,
+			RunAt:       job.RunAt,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		s.jobs[stored.ID] = stored
+		job.ID = stored.ID
+		job.Status = stored.Status
+		job.Attempts = stored.Attempts
+		job.CreatedAt = stored.CreatedAt
+		job.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryJobStore) ClaimJobs(ctx context.Context, workerID string, limit int32) ([]*Job, error) {
+	var jobs []*Job
+	err := m.db.write(ctx, func(s *memoryState) error {
+		now := time.Now()
+		due := []Job{}
+		for _, stored := range s.jobs {
+			if stored.Status == JobStatusPending && !stored.RunAt.After(now) {
+				due = append(due, stored)
+			}
+		}
+		slices.SortFunc(due, func(a, b Job) int {
+			if c := a.RunAt.Compare(b.RunAt); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.ID, b.ID)
+		})
+		for _, stored := range due[:min(int(limit), len(due))] {
+			stored.Status = JobStatusRunning
+			stored.Attempts++
+			stored.LockedBy = workerID
+			stored.LockedAt = &now
+			stored.UpdatedAt = now
+			s.jobs[stored.ID] = stored
+			jobs = append(jobs, stored.copy())
+		}
+		return nil
+	})
+	return jobs, err
+}
+
+func (m memoryJobStore) CompleteJob(ctx context.Context, id int64, workerID string) error {
+	return m.finishJob(ctx, id, workerID, func(job *Job, now time.Time) {
+		job.Status = JobStatusCompleted
+		job.LastError = ""
+		job.CompletedAt = &now
+	})
+}
+
+func (m memoryJobStore) RetryJob(ctx context.Context, id int64, workerID string, runAt time.Time, lastError string) error {
+	return m.finishJob(ctx, id, workerID, func(job *Job, now time.Time) {
+		job.Status = JobStatusPending
+		job.RunAt = runAt
+		job.LastError = lastError
+	})
+}
+
+func (m memoryJobStore) BuryJob(ctx context.Context, id int64, workerID string, lastError string) error {
+	return m.finishJob(ctx, id, workerID, func(job *Job, now time.Time) {
+		job.Status = JobStatusDead
+		job.LastError = lastError
+		job.CompletedAt = &now
+	})
+}
+
+func (m memoryJobStore) RequeueStaleJobs(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	var requeued int64
+	err := m.db.write(ctx, func(s *memoryState) error {
+		for id, stored := range s.jobs {
+			if stored.Status != JobStatusRunning || stored.LockedAt == nil || !stored.LockedAt.Before(lockedBefore) {
+				continue
+			}
+			stored.Status = JobStatusPending
+			stored.LastError = "worker lock expired"
+			stored.LockedBy = ""
+			stored.LockedAt = nil
+			stored.UpdatedAt = time.Now()
+			s.jobs[id] = stored
+			requeued++
+		}
+		return nil
+	})
+	return requeued, err
+}
+
+func (m memoryJobStore) GetJobByID(ctx context.Context, id int64) (*Job, error) {
+	var job *Job
+	err := m.db.read(ctx, func(s *memoryState) error {
+		stored, ok := s.jobs[id]
+		if !ok {
+			return ErrGeneralRecordNotFound
+		}
+		job = stored.copy()
+		return nil
+	})
+	return job, err
+}
+
+// finishJob() applies update to a running job held by the worker and releases its lock.
+func (m memoryJobStore) finishJob(ctx context.Context, id int64, workerID string, update func(job *Job, now time.Time)) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.jobs[id]
+		if !ok || stored.Status != JobStatusRunning || stored.LockedBy != workerID {
+			return ErrJobLockLost
+		}
+		now := time.Now()
+		update(&stored, now)
+		stored.LockedBy = ""
+		stored.LockedAt = nil
+		stored.UpdatedAt = now
+		s.jobs[id] = stored
+		return nil
+	})
+}
+
+// memoryEmailOutboxStore is the in-memory EmailOutboxStore.
+type memoryEmailOutboxStore struct {
+	db *memoryDB
+}
+
+func (m memoryEmailOutboxStore) CreateOutboxEmail(ctx context.Context, email *OutboxEmail) error {
+	if len(email.Data) == 0 {
+		email.Data = json.RawMessage(`{}`)
+	}
+	return m.db.write(ctx, func(s *memoryState) error {
+		now := time.Now()
+		stored := OutboxEmail{
+			ID:        s.nextID("email_outbox"),
+			TenantID:  email.TenantID,
+			Recipient: email.Recipient,
+			Template:  email.Template,
+			Locale:    email.Locale,
+			Data:      slices.Clone(email.Data),
+			Status:    EmailStatusQueued,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		s.emails[stored.ID] = stored
+		email.ID = stored.ID
+		email.Status = stored.Status
+		email.Attempts = stored.Attempts
+		email.CreatedAt = stored.CreatedAt
+		email.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryEmailOutboxStore) GetOutboxEmailByID(ctx context.Context, id int64) (*OutboxEmail, error) {
+	var email *OutboxEmail
+	err := m.db.read(ctx, func(s *memoryState) error {
+		stored, ok := s.emails[id]
+		if !ok {
+			return ErrGeneralRecordNotFound
+		}
+		email = stored.copy()
+		return nil
+	})
+	return email, err
+}
+
+func (m memoryEmailOutboxStore) AdminGetAllOutboxEmails(ctx context.Context, tenantID int64, status, recipient string, filters Filters) ([]*OutboxEmail, Metadata, error) {
+	var (
+		emails    []*OutboxEmail
+		totalRows int
+	)
+	err := m.db.read(ctx, func(s *memoryState) error {
+		matched := []*OutboxEmail{}
+		for _, stored := range s.emails {
+			if (tenantID == 0 || stored.TenantID == tenantID) &&
+				(status == "" || stored.Status == status) &&
+				(recipient == "" || strings.EqualFold(stored.Recipient, recipient)) {
+				matched = append(matched, stored.copy())
+			}
+		}
+		slices.SortFunc(matched, func(a, b *OutboxEmail) int {
+			if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+				return c
+			}
+			return cmp.Compare(b.ID, a.ID)
+		})
+		totalRows = len(matched)
+		emails = paginate(matched, filters)
+		return nil
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	if len(emails) == 0 {
+		return nil, Metadata{}, ErrGeneralRecordNotFound
+	}
+	return emails, calculateMetadata(totalRows, filters.Page, filters.PageSize), nil
+}
+
+func (m memoryEmailOutboxStore) RecordOutboxEmailAttempt(ctx context.Context, id int64, attempt EmailAttempt) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.emails[id]
+		if !ok {
+			return ErrGeneralRecordNotFound
+		}
+		now := time.Now()
+		stored.Status = attempt.Status
+		stored.Attempts++
+		stored.Subject = attempt.Subject
+		stored.LastError = attempt.LastError
+		stored.ProviderMessageID = attempt.ProviderMessageID
+		stored.SentAt = nil
+		if attempt.Status == EmailStatusSent {
+			stored.SentAt = &now
+		}
+		stored.UpdatedAt = now
+		s.emails[id] = stored
+		return nil
+	})
+}
+
+func (m memoryEmailOutboxStore) RequeueOutboxEmail(ctx context.Context, id int64) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.emails[id]
+		if !ok || stored.Status != EmailStatusFailed {
+			return ErrOutboxEmailNotFailed
+		}
+		stored.Status = EmailStatusQueued
+		stored.LastError = ""
+		stored.UpdatedAt = time.Now()
+		s.emails[id] = stored
+		return nil
+	})
+}
+
+// memoryWebhookStore is the in-memory WebhookStore.
+type memoryWebhookStore struct {
+	db *memoryDB
+}
+
+func (m memoryWebhookStore) CreateWebhookEndpoint(ctx context.Context, endpoint *WebhookEndpoint) error {
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return err
+	}
+	return m.db.write(ctx, func(s *memoryState) error {
+		now := time.Now()
+		stored := WebhookEndpoint{
+			ID:          s.nextID("webhook_endpoints"),
+			TenantID:    endpoint.TenantID,
+			URL:         endpoint.URL,
+			Description: endpoint.Description,
+			Secret:      secret,
+			EventTypes:  slices.Clone(endpoint.EventTypes),
+			Enabled:     true,
+			Version:     1,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		s.webhooks[stored.ID] = stored
+		endpoint.ID = stored.ID
+		endpoint.Secret = stored.Secret
+		endpoint.Enabled = stored.Enabled
+		endpoint.ConsecutiveFailures = stored.ConsecutiveFailures
+		endpoint.Version = stored.Version
+		endpoint.CreatedAt = stored.CreatedAt
+		endpoint.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryWebhookStore) GetWebhookEndpointByID(ctx context.Context, tenantID, endpointID int64) (*WebhookEndpoint, error) {
+	var endpoint *WebhookEndpoint
+	err := m.db.read(ctx, func(s *memoryState) error {
+		stored, ok := s.webhooks[endpointID]
+		if !ok || stored.TenantID != tenantID {
+			return ErrGeneralRecordNotFound
+		}
+		endpoint = stored.copy()
+		return nil
+	})
+	return endpoint, err
+}
+
+func (m memoryWebhookStore) GetWebhookEndpointsByTenantID(ctx context.Context, tenantID int64) ([]*WebhookEndpoint, error) {
+	return m.findEndpoints(ctx, func(endpoint WebhookEndpoint) bool {
+		return endpoint.TenantID == tenantID
+	})
+}
+
+func (m memoryWebhookStore) GetSubscribedWebhookEndpoints(ctx context.Context, tenantID int64, eventType string) ([]*WebhookEndpoint, error) {
+	return m.findEndpoints(ctx, func(endpoint WebhookEndpoint) bool {
+		return endpoint.TenantID == tenantID && endpoint.Enabled && slices.Contains(endpoint.EventTypes, eventType)
+	})
+}
+
+// findEndpoints() returns the endpoints matching the predicate, ordered by ID.
+func (m memoryWebhookStore) findEndpoints(ctx context.Context, match func(endpoint WebhookEndpoint) bool) ([]*WebhookEndpoint, error) {
+	endpoints := []*WebhookEndpoint{}
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, stored := range s.webhooks {
+			if match(stored) {
+				endpoints = append(endpoints, stored.copy())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(endpoints, func(a, b *WebhookEndpoint) int { return cmp.Compare(a.ID, b.ID) })
+	return endpoints, nil
+}
+
+func (m memoryWebhookStore) UpdateWebhookEndpoint(ctx context.Context, endpoint *WebhookEndpoint, version int32) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.webhooks[endpoint.ID]
+		if !ok || stored.Version != version {
+			return ErrGeneralEditConflict
+		}
+		if endpoint.Enabled {
+			if !stored.Enabled {
+				stored.ConsecutiveFailures = 0
+			}
+			stored.DisabledReason = ""
+		}
+		stored.URL = endpoint.URL
+		stored.Description = endpoint.Description
+		stored.EventTypes = slices.Clone(endpoint.EventTypes)
+		stored.Enabled = endpoint.Enabled
+		stored.Version++
+		stored.UpdatedAt = time.Now()
+		s.webhooks[stored.ID] = stored
+		endpoint.DisabledReason = stored.DisabledReason
+		endpoint.ConsecutiveFailures = stored.ConsecutiveFailures
+		endpoint.Version = stored.Version
+		endpoint.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryWebhookStore) DeleteWebhookEndpoint(ctx context.Context, tenantID, endpointID int64) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.webhooks[endpointID]
+		if !ok || stored.TenantID != tenantID {
+			return ErrGeneralRecordNotFound
+		}
+		delete(s.webhooks, endpointID)
+		// the deliveries go with the endpoint, like ON DELETE CASCADE
+		maps.DeleteFunc(s.deliveries, func(_ int64, delivery WebhookDelivery) bool {
+			return delivery.EndpointID == endpointID
+		})
+		return nil
+	})
+}
+
+func (m memoryWebhookStore) RecordWebhookEndpointResult(ctx context.Context, endpointID int64, succeeded bool, disableAfter int32) (bool, error) {
+	var enabled bool
+	err := m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.webhooks[endpointID]
+		if !ok {
+			return ErrGeneralRecordNotFound
+		}
+		if succeeded {
+			stored.ConsecutiveFailures = 0
+		} else {
+			stored.ConsecutiveFailures++
+			if stored.Enabled && disableAfter > 0 && stored.ConsecutiveFailures >= disableAfter {
+				stored.Enabled = false
+				stored.DisabledReason = webhookDisabledReason(disableAfter)
+				stored.Version++
+			}
+		}
+		stored.UpdatedAt = time.Now()
+		s.webhooks[endpointID] = stored
+		enabled = stored.Enabled
+		return nil
+	})
+	return enabled, err
+}
+
+func (m memoryWebhookStore) CreateWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		if _, ok := s.webhooks[delivery.EndpointID]; !ok {
+			return fmt.Errorf("webhook endpoint %d does not exist", delivery.EndpointID)
+		}
+		now := time.Now()
+		stored := WebhookDelivery{
+			ID:         s.nextID("webhook_deliveries"),
+			EndpointID: delivery.EndpointID,
+			TenantID:   delivery.TenantID,
+			EventID:    delivery.EventID,
+			EventType:  delivery.EventType,
+			Payload:    slices.Clone(delivery.Payload),
+			Status:     WebhookDeliveryPending,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		}
+		s.deliveries[stored.ID] = stored
+		delivery.ID = stored.ID
+		delivery.Status = stored.Status
+		delivery.Attempts = stored.Attempts
+		delivery.CreatedAt = stored.CreatedAt
+		delivery.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryWebhookStore) GetWebhookDeliveryByID(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	var delivery *WebhookDelivery
+	err := m.db.read(ctx, func(s *memoryState) error {
+		stored, ok := s.deliveries[id]
+		if !ok {
+			return ErrGeneralRecordNotFound
+		}
+		delivery = stored.copy()
+		return nil
+	})
+	return delivery, err
+}
+
+func (m memoryWebhookStore) GetWebhookDeliveriesByEndpointID(ctx context.Context, endpointID int64, status string, filters Filters) ([]*WebhookDelivery, Metadata, error) {
+	var (
+		deliveries []*WebhookDelivery
+		totalRows  int
+	)
+	err := m.db.read(ctx, func(s *memoryState) error {
+		matched := []*WebhookDelivery{}
+		for _, stored := range s.deliveries {
+			if stored.EndpointID == endpointID && (status == "" || stored.Status == status) {
+				matched = append(matched, stored.copy())
+			}
+		}
+		slices.SortFunc(matched, func(a, b *WebhookDelivery) int {
+			if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+				return c
+			}
+			return cmp.Compare(b.ID, a.ID)
+		})
+		totalRows = len(matched)
+		deliveries = paginate(matched, filters)
+		return nil
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	if len(deliveries) == 0 {
+		return nil, Metadata{}, ErrGeneralRecordNotFound
+	}
+	return deliveries, calculateMetadata(totalRows, filters.Page, filters.PageSize), nil
+}
+
+func (m memoryWebhookStore) RecordWebhookDeliveryAttempt(ctx context.Context, id int64, attempt WebhookAttempt) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.deliveries[id]
+		if !ok {
+			return ErrGeneralRecordNotFound
+		}
+		now := time.Now()
+		stored.Status = attempt.Status
+		stored.Attempts++
+		stored.ResponseStatus = attempt.ResponseStatus
+		stored.ResponseBody = attempt.ResponseBody
+		stored.LastError = attempt.LastError
+		stored.DurationMS = attempt.durationMS()
+		stored.DeliveredAt = nil
+		if attempt.Status == WebhookDeliverySucceeded {
+			stored.DeliveredAt = &now
+		}
+		stored.UpdatedAt = now
+		s.deliveries[id] = stored
+		return nil
+	})
+}
+
+// copy() returns the email with its own template data.
+func (e OutboxEmail) copy() *OutboxEmail {
+	e.Data = slices.Clone(e.Data)
+	return &e
+}
+
+// copy() returns the endpoint with its own event types.
+func (e WebhookEndpoint) copy() *WebhookEndpoint {
+	e.EventTypes = slices.Clone(e.EventTypes)
+	return &e
+}
+
+// copy() returns the delivery with its own payload.
+func (d WebhookDelivery) copy() *WebhookDelivery {
+	d.Payload = slices.Clone(d.Payload)
+	return &d
+}
+
+// copy() returns the job with its own payload.
+func (j Job) copy() *Job {
+	j.Payload = slices.Clone(j.Payload)
+	return &j
+}
+
+// copy() returns the lead with its own custom fields map.
+func (l memoryLead) copy() *TradeLead {
+	lead := l.lead
+	lead.CustomFields = decodeCustomFields(l.customFields)
+	lead.AssignedTo = copyID(lead.AssignedTo)
+	return &lead
+}
+
+// isTenantUser() reports whether an optional user ID is either nil or one of the tenant's
+// users, as the trade lead assignee foreign key requires.
+func (s *memoryState) isTenantUser(userID *int64, tenantID int64) bool {
+	if userID == nil {
+		return true
+	}
+	user, ok := s.users[*userID]
+	return ok && user.TenantID == tenantID
+}
+
+// recordLeadHistory() appends the current state of a lead to its history.
+func (s *memoryState) recordLeadHistory(lead TradeLead) {
+	s.leadHistory = append(s.leadHistory, TradeLeadHistory{
+		ID:          s.nextID("trade_lead_history"),
+		TradeLeadID: lead.ID,
+		TenantID:    lead.TenantID,
+		Status:      lead.Status,
+		Value:       lead.Value,
+		Version:     lead.Version,
+		ChangedAt:   lead.UpdatedAt,
+	})
+}
+
+// addLead() counts a lead into the stats, only verified leads add to the value.
+func (t *tradeStatsTotal) addLead(lead TradeLead) {
+	t.TotalLeads = t.TotalLeads.Add(decimal.NewFromInt(1))
+	if lead.Status == "verified" {
+		t.VerifiedLeads = t.VerifiedLeads.Add(decimal.NewFromInt(1))
+		t.TotalVerifiedValue = t.TotalVerifiedValue.Add(lead.Value)
+	}
+}
+
+// tradeStatsTotals() sums up the leads per currency, like the stats queries.
+func tradeStatsTotals(leads []TradeLead) []tradeStatsTotal {
+	totals := map[string]*tradeStatsTotal{}
+	for _, lead := range leads {
+		if totals[lead.Currency] == nil {
+			totals[lead.Currency] = &tradeStatsTotal{Currency: lead.Currency}
+		}
+		totals[lead.Currency].addLead(lead)
+	}
+	result := []tradeStatsTotal{}
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	return result
+}
+
+// sortLeads() orders leads like the ORDER BY of the listing queries: by the requested
+// column, then newest first. Leads missing a sorted custom field come last in ascending
+// and first in descending order, as NULLs do in Postgres. Text is compared byte-wise
+// rather than by the database collation.
+func sortLeads(leads []*TradeLead, sort leadSort) {
+	compareColumn := func(a, b *TradeLead) int {
+		switch sort.column {
+		case "title":
+			return strings.Compare(a.Title, b.Title)
+		case "value":
+			return a.Value.Cmp(b.Value)
+		case "created_at":
+			return a.CreatedAt.Compare(b.CreatedAt)
+		case "custom_field":
+			return compareNullsLast(customFieldSortValue(a, sort), customFieldSortValue(b, sort))
+		default:
+			return 0
+		}
+	}
+	slices.SortStableFunc(leads, func(a, b *TradeLead) int {
+		c := compareColumn(a, b)
+		if sort.direction == "DESC" {
+			c = -c
+		}
+		return cmp.Or(c, b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+	})
+}
+
+// sortValue is a custom field value as sorted by the listing queries, nil for NULL.
+type sortValue interface {
+	compare(other sortValue) int
+}
+
+type numericSortValue decimal.Decimal
+
+func (v numericSortValue) compare(other sortValue) int {
+	return decimal.Decimal(v).Cmp(decimal.Decimal(other.(numericSortValue)))
+}
+
+type textSortValue string
+
+func (v textSortValue) compare(other sortValue) int {
+	return strings.Compare(string(v), string(other.(textSortValue)))
+}
+
+// customFieldSortValue() returns the sorted custom field of a lead, read as text like the
+// ->> operator, or as a number for number fields.
+func customFieldSortValue(lead *TradeLead, sort leadSort) sortValue {
+	value, ok := lead.CustomFields[sort.key]
+	if !ok || value == nil {
+		return nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil
+		}
+		text = string(encoded)
+	}
+	if !sort.numeric {
+		return textSortValue(text)
+	}
+	// like the jsonb_typeof() guard of the queries, only JSON numbers sort numerically
+	if _, isString := value.(string); isString {
+		return nil
+	}
+	number, err := decimal.NewFromString(text)
+	if err != nil {
+		return nil
+	}
+	return numericSortValue(number)
+}
+
+// compareNullsLast() compares two sort values, ordering NULLs after every other value.
+func compareNullsLast(a, b sortValue) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	default:
+		return a.compare(b)
+	}
+}
+
+// matchesTextSearch() mirrors to_tsvector('simple', text) @@ plainto_tsquery('simple',
+// query): every word of the query has to appear in the text, ignoring case.
+func matchesTextSearch(text, query string) bool {
+	queryWords := textSearchWords(query)
+	if len(queryWords) == 0 {
+		return false
+	}
+	textWords := textSearchWords(text)
+	for _, word := range queryWords {
+		if !slices.Contains(textWords, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func textSearchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// jsonContains() mirrors the jsonb @> operator for decoded JSON values.
+func jsonContains(container, contained any) bool {
+	switch contained := contained.(type) {
+	case map[string]any:
+		object, ok := container.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, value := range contained {
+			if have, ok := object[key]; !ok || !jsonContains(have, value) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		array, ok := container.([]any)
+		if !ok {
+			return false
+		}
+		for _, value := range contained {
+			if !slices.ContainsFunc(array, func(have any) bool { return jsonContains(have, value) }) {
+				return false
+			}
+		}
+		return true
+	default:
+		return container == contained
+	}
+}
+
+// paginate() returns the page of items selected by the filters.
+func paginate[T any](items []T, filters Filters) []T {
+	offset, limit := filters.offset(), filters.limit()
+	if offset >= len(items) || limit <= 0 {
+		return []T{}
+	}
+	return items[offset:min(offset+limit, len(items))]
+}
+
+func copyID(id *int64) *int64 {
+	if id == nil {
+		return nil
+	}
+	value := *id
+	return &value
+}
+
+// memoryTradeLeadEventStore is the in-memory TradeLeadEventStore. Nothing listens to the
+// events, it only hands out their IDs.
+type memoryTradeLeadEventStore struct {
+	db *memoryDB
+}
+
+func (m memoryTradeLeadEventStore) PublishTradeLeadEvent(ctx context.Context, event *TradeLeadEvent) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		event.ID = s.nextID("trade_lead_events")
+		return nil
+	})
+}
+
+// memoryExchangeRateStore is the in-memory ExchangeRateStore.
+type memoryExchangeRateStore struct {
+	db *memoryDB
+}
+
+func (m memoryExchangeRateStore) SaveExchangeRate(ctx context.Context, rate *ExchangeRate) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		now := time.Now()
+		stored := ExchangeRate{
+			BaseCurrency:  rate.BaseCurrency,
+			QuoteCurrency: rate.QuoteCurrency,
+			Rate:          rate.Rate,
+			RateDate:      rate.RateDate.UTC().Truncate(24 * time.Hour),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		// the rate of the same pair and date is replaced
+		for id, existing := range s.exchangeRates {
+			if existing.BaseCurrency == stored.BaseCurrency && existing.QuoteCurrency == stored.QuoteCurrency && existing.RateDate.Equal(stored.RateDate) {
+				stored.ID = id
+				stored.CreatedAt = existing.CreatedAt
+			}
+		}
+		if stored.ID == 0 {
+			stored.ID = s.nextID("exchange_rates")
+		}
+		s.exchangeRates[stored.ID] = stored
+		rate.ID = stored.ID
+		rate.CreatedAt = stored.CreatedAt
+		rate.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryExchangeRateStore) GetExchangeRatesOnDate(ctx context.Context, date time.Time) ([]*ExchangeRate, error) {
+	latest := map[currencyPair]ExchangeRate{}
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, rate := range s.exchangeRates {
+			if rate.RateDate.After(date) {
+				continue
+			}
+			pair := currencyPair{rate.BaseCurrency, rate.QuoteCurrency}
+			if current, ok := latest[pair]; !ok || rate.RateDate.After(current.RateDate) {
+				latest[pair] = rate
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	rates := []*ExchangeRate{}
+	for _, rate := range latest {
+		rates = append(rates, &rate)
+	}
+	slices.SortFunc(rates, func(a, b *ExchangeRate) int {
+		return cmp.Or(cmp.Compare(a.BaseCurrency, b.BaseCurrency), cmp.Compare(a.QuoteCurrency, b.QuoteCurrency))
+	})
+	return rates, nil
+}
+
+// memoryTradeLeadCommentStore is the in-memory TradeLeadCommentStore.
+type memoryTradeLeadCommentStore struct {
+	db *memoryDB
+}
+
+func (m memoryTradeLeadCommentStore) CreateTradeLeadComment(ctx context.Context, comment *TradeLeadComment) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		if _, ok := s.leads[comment.TradeLeadID]; !ok {
+			return ErrGeneralRecordNotFound
+		}
+		now := time.Now()
+		stored := *comment
+		stored.ID = s.nextID("trade_lead_comments")
+		stored.AuthorID = copyID(comment.AuthorID)
+		stored.AuthorName = ""
+		stored.Version = 1
+		stored.CreatedAt = now
+		stored.UpdatedAt = now
+		s.comments[stored.ID] = stored
+		comment.ID = stored.ID
+		comment.Version = stored.Version
+		comment.CreatedAt = stored.CreatedAt
+		comment.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryTradeLeadCommentStore) GetTradeLeadCommentByID(ctx context.Context, tradeLeadID, id int64) (*TradeLeadComment, error) {
+	var comment *TradeLeadComment
+	err := m.db.read(ctx, func(s *memoryState) error {
+		stored, ok := s.comments[id]
+		if !ok || stored.TradeLeadID != tradeLeadID {
+			return ErrGeneralRecordNotFound
+		}
+		comment = s.tradeLeadComment(stored)
+		return nil
+	})
+	return comment, err
+}
+
+func (m memoryTradeLeadCommentStore) GetTradeLeadComments(ctx context.Context, tradeLeadID int64, includeInternal bool, filters Filters) ([]*TradeLeadComment, Metadata, error) {
+	comments := []*TradeLeadComment{}
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, stored := range s.comments {
+			if stored.TradeLeadID == tradeLeadID && (includeInternal || !stored.Internal) {
+				comments = append(comments, s.tradeLeadComment(stored))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	slices.SortFunc(comments, func(a, b *TradeLeadComment) int {
+		order := cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+		if filters.sortDirection() == "DESC" {
+			return -order
+		}
+		return order
+	})
+	page := paginate(comments, filters)
+	if len(page) == 0 {
+		return nil, Metadata{}, ErrGeneralRecordNotFound
+	}
+	return page, calculateMetadata(len(comments), filters.Page, filters.PageSize), nil
+}
+
+func (m memoryTradeLeadCommentStore) UpdateTradeLeadComment(ctx context.Context, comment *TradeLeadComment, version int32) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.comments[comment.ID]
+		if !ok || stored.Version != version {
+			return ErrGeneralEditConflict
+		}
+		stored.Body = comment.Body
+		stored.Version++
+		stored.UpdatedAt = time.Now()
+		s.comments[stored.ID] = stored
+		comment.Version = stored.Version
+		comment.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryTradeLeadCommentStore) DeleteTradeLeadComment(ctx context.Context, tradeLeadID, id int64) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.comments[id]
+		if !ok || stored.TradeLeadID != tradeLeadID {
+			return ErrGeneralRecordNotFound
+		}
+		delete(s.comments, id)
+		return nil
+	})
+}
+
+// tradeLeadComment() returns a copy of a stored comment with the name of its author, as
+// joined by the Postgres queries.
+func (s *memoryState) tradeLeadComment(stored TradeLeadComment) *TradeLeadComment {
+	comment := stored
+	comment.AuthorID = copyID(stored.AuthorID)
+	if comment.AuthorID != nil {
+		comment.AuthorName = s.users[*comment.AuthorID].Name
+	}
+	return &comment
+}
+
+// memoryTenantExportStore is the in-memory TenantExportStore.
+type memoryTenantExportStore struct {
+	db *memoryDB
+}
+
+func (m memoryTenantExportStore) CreateTenantExport(ctx context.Context, export *TenantExport) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		now := time.Now()
+		stored := TenantExport{
+			ID:          s.nextID("tenant_exports"),
+			TenantID:    export.TenantID,
+			RequestedBy: export.RequestedBy,
+			Currency:    export.Currency,
+			Status:      ExportStatusPending,
+			Version:     1,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		s.exports[stored.ID] = stored
+		export.ID = stored.ID
+		export.Status = stored.Status
+		export.Version = stored.Version
+		export.CreatedAt = stored.CreatedAt
+		export.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryTenantExportStore) GetTenantExportByID(ctx context.Context, id int64) (*TenantExport, error) {
+	var export *TenantExport
+	err := m.db.read(ctx, func(s *memoryState) error {
+		stored, ok := s.exports[id]
+		if !ok {
+			return ErrGeneralRecordNotFound
+		}
+		export = stored.copy()
+		return nil
+	})
+	return export, err
+}
+
+func (m memoryTenantExportStore) UpdateTenantExport(ctx context.Context, export *TenantExport) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.exports[export.ID]
+		if !ok || stored.Version != export.Version {
+			return ErrGeneralEditConflict
+		}
+		stored.Status = export.Status
+		stored.FileKey = export.FileKey
+		stored.FileSize = export.FileSize
+		stored.Error = export.Error
+		stored.CompletedAt = nil
+		if export.CompletedAt != nil {
+			completedAt := *export.CompletedAt
+			stored.CompletedAt = &completedAt
+		}
+		stored.Version++
+		stored.UpdatedAt = time.Now()
+		s.exports[stored.ID] = stored
+		export.Version = stored.Version
+		export.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+// memoryCustomFieldStore is the in-memory CustomFieldStore. Keys are unique per tenant.
+type memoryCustomFieldStore struct {
+	db *memoryDB
+}
+
+func (m memoryCustomFieldStore) CreateCustomField(ctx context.Context, field *CustomField) error {
+	if field.Options == nil {
+		field.Options = []string{}
+	}
+	return m.db.write(ctx, func(s *memoryState) error {
+		for _, existing := range s.customFields {
+			if existing.TenantID == field.TenantID && existing.Key == field.Key {
+				return ErrDuplicateCustomField
+			}
+		}
+		now := time.Now()
+		stored := *field
+		stored.ID = s.nextID("tenant_custom_fields")
+		stored.Options = slices.Clone(field.Options)
+		stored.Version = 1
+		stored.CreatedAt = now
+		stored.UpdatedAt = now
+		s.customFields[stored.ID] = stored
+		field.ID = stored.ID
+		field.Version = stored.Version
+		field.CreatedAt = stored.CreatedAt
+		field.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryCustomFieldStore) GetCustomFieldsByTenantID(ctx context.Context, tenantID int64) ([]*CustomField, error) {
+	fields := []*CustomField{}
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, stored := range s.customFields {
+			if stored.TenantID == tenantID {
+				fields = append(fields, stored.copy())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(fields, func(a, b *CustomField) int { return cmp.Compare(a.ID, b.ID) })
+	return fields, nil
+}
+
+func (m memoryCustomFieldStore) GetCustomFieldByID(ctx context.Context, tenantID, fieldID int64) (*CustomField, error) {
+	var field *CustomField
+	err := m.db.read(ctx, func(s *memoryState) error {
+		stored, ok := s.customFields[fieldID]
+		if !ok || stored.TenantID != tenantID {
+			return ErrGeneralRecordNotFound
+		}
+		field = stored.copy()
+		return nil
+	})
+	return field, err
+}
+
+func (m memoryCustomFieldStore) UpdateCustomField(ctx context.Context, field *CustomField, version int32) error {
+	if field.Options == nil {
+		field.Options = []string{}
+	}
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.customFields[field.ID]
+		if !ok || stored.Version != version {
+			return ErrGeneralEditConflict
+		}
+		stored.Label = field.Label
+		stored.Required = field.Required
+		stored.Options = slices.Clone(field.Options)
+		stored.Version++
+		stored.UpdatedAt = time.Now()
+		s.customFields[stored.ID] = stored
+		field.Version = stored.Version
+		field.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryCustomFieldStore) DeleteCustomField(ctx context.Context, tenantID, fieldID int64) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.customFields[fieldID]
+		if !ok || stored.TenantID != tenantID {
+			return ErrGeneralRecordNotFound
+		}
+		delete(s.customFields, fieldID)
+		return nil
+	})
+}
+
+// memoryTenantSettingsStore is the in-memory TenantSettingsStore, keyed by tenant ID.
+type memoryTenantSettingsStore struct {
+	db *memoryDB
+}
+
+func (m memoryTenantSettingsStore) GetTenantSettings(ctx context.Context, tenantID int64) (*TenantSettings, error) {
+	var settings *TenantSettings
+	err := m.db.read(ctx, func(s *memoryState) error {
+		stored, ok := s.settings[tenantID]
+		if !ok {
+			settings = DefaultTenantSettings(tenantID)
+			return nil
+		}
+		settings = &stored
+		return nil
+	})
+	return settings, err
+}
+
+func (m memoryTenantSettingsStore) SaveTenantSettings(ctx context.Context, settings *TenantSettings, version int32) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.settings[settings.TenantID]
+		// version 0 only creates settings, later saves must match the stored version
+		if ok != (version != 0) || stored.Version != version {
+			return ErrGeneralEditConflict
+		}
+		now := time.Now()
+		saved := *settings
+		saved.Version = version + 1
+		saved.CreatedAt = now
+		if ok {
+			saved.CreatedAt = stored.CreatedAt
+		}
+		saved.UpdatedAt = now
+		s.settings[saved.TenantID] = saved
+		settings.Version = saved.Version
+		settings.CreatedAt = saved.CreatedAt
+		settings.UpdatedAt = saved.UpdatedAt
+		return nil
+	})
+}
+
+// memoryTenantDomainStore is the in-memory TenantDomainStore. A domain is unique per tenant,
+// and only one tenant can verify it.
+type memoryTenantDomainStore struct {
+	db *memoryDB
+}
+
+func (m memoryTenantDomainStore) CreateTenantDomain(ctx context.Context, domain *TenantDomain) error {
+	token, err := generateVerificationToken()
+	if err != nil {
+		return err
+	}
+	return m.db.write(ctx, func(s *memoryState) error {
+		for _, existing := range s.domains {
+			if existing.TenantID == domain.TenantID && existing.Domain == domain.Domain {
+				return ErrDuplicateTenantDomain
+			}
+		}
+		now := time.Now()
+		stored := TenantDomain{
+			ID:                s.nextID("tenant_domains"),
+			TenantID:          domain.TenantID,
+			Domain:            domain.Domain,
+			VerificationToken: token,
+			Version:           1,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		}
+		s.domains[stored.ID] = stored
+		domain.ID = stored.ID
+		domain.VerificationToken = stored.VerificationToken
+		domain.Verified = stored.Verified
+		domain.Version = stored.Version
+		domain.CreatedAt = stored.CreatedAt
+		domain.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryTenantDomainStore) GetTenantDomainsByTenantID(ctx context.Context, tenantID int64) ([]*TenantDomain, error) {
+	domains := []*TenantDomain{}
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, stored := range s.domains {
+			if stored.TenantID == tenantID {
+				domains = append(domains, stored.copy())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(domains, func(a, b *TenantDomain) int { return cmp.Compare(a.ID, b.ID) })
+	return domains, nil
+}
+
+func (m memoryTenantDomainStore) GetTenantDomainByID(ctx context.Context, tenantID, domainID int64) (*TenantDomain, error) {
+	var domain *TenantDomain
+	err := m.db.read(ctx, func(s *memoryState) error {
+		stored, ok := s.domains[domainID]
+		if !ok || stored.TenantID != tenantID {
+			return ErrGeneralRecordNotFound
+		}
+		domain = stored.copy()
+		return nil
+	})
+	return domain, err
+}
+
+func (m memoryTenantDomainStore) GetVerifiedTenantDomain(ctx context.Context, domain string) (*TenantDomain, error) {
+	var verified *TenantDomain
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, stored := range s.domains {
+			if stored.Verified && stored.Domain == NormalizeDomain(domain) {
+				verified = stored.copy()
+				return nil
+			}
+		}
+		return ErrGeneralRecordNotFound
+	})
+	return verified, err
+}
+
+func (m memoryTenantDomainStore) TenantHasVerifiedDomains(ctx context.Context, tenantID int64) (bool, error) {
+	var hasVerified bool
+	err := m.db.read(ctx, func(s *memoryState) error {
+		for _, stored := range s.domains {
+			if stored.TenantID == tenantID && stored.Verified {
+				hasVerified = true
+			}
+		}
+		return nil
+	})
+	return hasVerified, err
+}
+
+func (m memoryTenantDomainStore) MarkTenantDomainVerified(ctx context.Context, domain *TenantDomain) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.domains[domain.ID]
+		if !ok || stored.Version != domain.Version {
+			return ErrGeneralEditConflict
+		}
+		for _, existing := range s.domains {
+			if existing.ID != stored.ID && existing.Verified && existing.Domain == stored.Domain {
+				return ErrDomainAlreadyVerified
+			}
+		}
+		now := time.Now()
+		stored.Verified = true
+		stored.VerifiedAt = &now
+		stored.Version++
+		stored.UpdatedAt = now
+		s.domains[stored.ID] = stored
+		domain.Verified = stored.Verified
+		verifiedAt := now
+		domain.VerifiedAt = &verifiedAt
+		domain.Version = stored.Version
+		domain.UpdatedAt = stored.UpdatedAt
+		return nil
+	})
+}
+
+func (m memoryTenantDomainStore) DeleteTenantDomain(ctx context.Context, tenantID, domainID int64) error {
+	return m.db.write(ctx, func(s *memoryState) error {
+		stored, ok := s.domains[domainID]
+		if !ok || stored.TenantID != tenantID {
+			return ErrGeneralRecordNotFound
+		}
+		delete(s.domains, domainID)
+		return nil
+	})
+}
+
+// copy() returns the export with its own completion time.
+func (e TenantExport) copy() *TenantExport {
+	if e.CompletedAt != nil {
+		completedAt := *e.CompletedAt
+		e.CompletedAt = &completedAt
+	}
+	return &e
+}
+
+// copy() returns the custom field with its own options.
+func (f CustomField) copy() *CustomField {
+	f.Options = slices.Clone(f.Options)
+	return &f
+}
+
+// copy() returns the domain with its own verification time.
+func (d TenantDomain) copy() *TenantDomain {
+	if d.VerifiedAt != nil {
+		verifiedAt := *d.VerifiedAt
+		d.VerifiedAt = &verifiedAt
+	}
+	return &d
+}
@@ -1,7 +1,10 @@
 package data
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/Blue-Davinci/leadhub-service/internal/database"
 )
@@ -9,22 +12,132 @@ import (
 var (
 	ErrGeneralRecordNotFound = errors.New("finance record not found")
 	ErrGeneralEditConflict   = errors.New("edit conflict")
+	ErrTransactionsDisabled  = errors.New("models are not backed by a database")
 )
 
+// Timeouts holds the per-query timeout of every model. A model bounds each of its queries
+// by its timeout, on top of any deadline of the caller's context. A zero value keeps the
+// model's default, e.g. DefaultLeadManagerDBContextTimeout for the trade leads.
+type Timeouts struct {
+	Tenants       time.Duration
+	Users         time.Duration
+	Tokens        time.Duration
+	Permissions   time.Duration
+	TradeLeads    time.Duration
+	Exports       time.Duration
+	CustomFields  time.Duration
+	Settings      time.Duration
+	Domains       time.Duration
+	Jobs          time.Duration
+	Emails        time.Duration
+	Webhooks      time.Duration
+	LeadEvents    time.Duration
+	ExchangeRates time.Duration
+	Comments      time.Duration
+}
+
 type Models struct {
-	Tenants     TenantsModel
-	Users       UserModel
-	Tokens      TokenModel
-	Permissions PermissionModel
-	TradeLeads  TradeLeadModel
+	Tenants       TenantStore
+	Users         UserStore
+	Tokens        TokenStore
+	Permissions   PermissionStore
+	TradeLeads    TradeLeadStore
+	Jobs          JobStore
+	Emails        EmailOutboxStore
+	Webhooks      WebhookStore
+	LeadEvents    TradeLeadEventStore
+	ExchangeRates ExchangeRateStore
+	Comments      TradeLeadCommentStore
+	Exports       TenantExportStore
+	CustomFields  CustomFieldStore
+	Settings      TenantSettingsStore
+	Domains       TenantDomainStore
+
+	db       *sql.DB
+	tx       *sql.Tx
+	queries  *database.Queries
+	timeouts Timeouts
+	memory   *memoryDB
+	memoryTx bool
+}
+
+func NewModels(db *sql.DB, timeouts Timeouts) Models {
+	models := newModels(database.New(db), timeouts)
+	models.db = db
+	return models
 }
 
-func NewModels(db *database.Queries) Models {
+func newModels(queries *database.Queries, timeouts Timeouts) Models {
 	return Models{
-		Tenants:     TenantsModel{DB: db},
-		Users:       UserModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		Permissions: PermissionModel{DB: db},
-		TradeLeads:  TradeLeadModel{DB: db},
+		Tenants:       TenantsModel{DB: queries, Timeout: timeoutOrDefault(timeouts.Tenants, DefaultTenantManagerDBContextTimeout)},
+		Users:         UserModel{DB: queries, Timeout: timeoutOrDefault(timeouts.Users, DefaultUserManagerDBContextTimeout)},
+		Tokens:        TokenModel{DB: queries, Timeout: timeoutOrDefault(timeouts.Tokens, DefaultTokenDBContextTimeout)},
+		Permissions:   PermissionModel{DB: queries, Timeout: timeoutOrDefault(timeouts.Permissions, DefaultPermissionDBContextTimeout)},
+		TradeLeads:    TradeLeadModel{DB: queries, Timeout: timeoutOrDefault(timeouts.TradeLeads, DefaultLeadManagerDBContextTimeout)},
+		Jobs:          JobModel{DB: queries, Timeout: timeoutOrDefault(timeouts.Jobs, DefaultJobDBContextTimeout)},
+		Emails:        EmailOutboxModel{DB: queries, Timeout: timeoutOrDefault(timeouts.Emails, DefaultEmailOutboxDBContextTimeout)},
+		Webhooks:      WebhookModel{DB: queries, Timeout: timeoutOrDefault(timeouts.Webhooks, DefaultWebhookDBContextTimeout)},
+		LeadEvents:    TradeLeadEventModel{DB: queries, Timeout: timeoutOrDefault(timeouts.LeadEvents, DefaultTradeLeadEventDBContextTimeout)},
+		ExchangeRates: ExchangeRateModel{DB: queries, Timeout: timeoutOrDefault(timeouts.ExchangeRates, DefaultExchangeRateDBContextTimeout)},
+		Comments:      TradeLeadCommentModel{DB: queries, Timeout: timeoutOrDefault(timeouts.Comments, DefaultTradeLeadCommentDBContextTimeout)},
+		Exports:       TenantExportModel{DB: queries, Timeout: timeoutOrDefault(timeouts.Exports, DefaultTenantExportDBContextTimeout)},
+		CustomFields:  CustomFieldModel{DB: queries, Timeout: timeoutOrDefault(timeouts.CustomFields, DefaultCustomFieldDBContextTimeout)},
+		Settings:      TenantSettingsModel{DB: queries, Timeout: timeoutOrDefault(timeouts.Settings, DefaultTenantSettingsDBContextTimeout)},
+		Domains:       TenantDomainModel{DB: queries, Timeout: timeoutOrDefault(timeouts.Domains, DefaultTenantDomainDBContextTimeout)},
+		queries:       queries,
+		timeouts:      timeouts,
+	}
+}
+
+// WithTx() returns a copy of the models whose queries all run within the given transaction.
+func (m Models) WithTx(tx *sql.Tx) Models {
+	models := newModels(m.queries.WithTx(tx), m.timeouts)
+	models.db = m.db
+	models.tx = tx
+	return models
+}
+
+// RunInTx() runs fn as a single unit of work. The models handed to fn share one
+// transaction, which is committed when fn returns nil and rolled back when it returns an
+// error or panics. Calling RunInTx() on models that are already in a transaction runs
+// fn in that same transaction, leaving the commit to the outermost call.
+func (m Models) RunInTx(ctx context.Context, fn func(tx Models) error) (err error) {
+	if m.tx != nil || m.memoryTx {
+		return fn(m)
+	}
+	if m.memory != nil {
+		tx := m
+		tx.memoryTx = true
+		return m.memory.runInTx(func() error { return fn(tx) })
+	}
+	if m.db == nil {
+		return ErrTransactionsDisabled
+	}
+	ctx, span := startSpan(ctx, "Models.RunInTx")
+	defer span.End()
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(m.WithTx(tx)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// timeoutOrDefault() returns the configured timeout, or the fallback when none is set.
+func timeoutOrDefault(timeout, fallback time.Duration) time.Duration {
+	if timeout <= 0 {
+		return fallback
 	}
+	return timeout
 }
@@ -0,0 +1,161 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// txDriver records the transaction lifecycle and the statements run through it.
+type txDriver struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (d *txDriver) record(event string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, event)
+}
+
+func (d *txDriver) Events() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.events...)
+}
+
+func (d *txDriver) Open(string) (driver.Conn, error) { return &txConn{driver: d}, nil }
+
+type txConn struct {
+	driver *txDriver
+	inTx   bool
+}
+
+func (c *txConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *txConn) Close() error                        { return nil }
+func (c *txConn) Begin() (driver.Tx, error) {
+	c.driver.record("begin")
+	c.inTx = true
+	return c, nil
+}
+
+func (c *txConn) Commit() error {
+	c.driver.record("commit")
+	c.inTx = false
+	return nil
+}
+
+func (c *txConn) Rollback() error {
+	c.driver.record("rollback")
+	c.inTx = false
+	return nil
+}
+
+func (c *txConn) ExecContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Result, error) {
+	if c.inTx {
+		c.driver.record("exec in tx")
+	} else {
+		c.driver.record("exec")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func newTxModels(t *testing.T) (Models, *txDriver) {
+	t.Helper()
+	d := &txDriver{}
+	name := "leadhub-tx-" + t.Name()
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return NewModels(db, Timeouts{}), d
+}
+
+func TestRunInTx(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name    string
+		fn      func(ctx context.Context, tx Models) error
+		wantErr error
+		want    []string
+	}{
+		{
+			name: "Commit",
+			fn: func(ctx context.Context, tx Models) error {
+				return tx.Tokens.DeleteAllForUser(ctx, ScopeActivation, 1)
+			},
+			want: []string{"begin", "exec in tx", "commit"},
+		},
+		{
+			name: "Rollback on error",
+			fn: func(ctx context.Context, tx Models) error {
+				if err := tx.Tokens.DeleteAllForUser(ctx, ScopeActivation, 1); err != nil {
+					return err
+				}
+				return errFailed
+			},
+			wantErr: errFailed,
+			want:    []string{"begin", "exec in tx", "rollback"},
+		},
+		{
+			name: "Nested calls share the transaction",
+			fn: func(ctx context.Context, tx Models) error {
+				return tx.RunInTx(ctx, func(nested Models) error {
+					return nested.Tokens.DeleteAllForUser(ctx, ScopeActivation, 1)
+				})
+			},
+			want: []string{"begin", "exec in tx", "commit"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models, d := newTxModels(t)
+			ctx := context.Background()
+			err := models.RunInTx(ctx, func(tx Models) error { return tt.fn(ctx, tx) })
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RunInTx() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := d.Events(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Rollback on panic", func(t *testing.T) {
+		models, d := newTxModels(t)
+		defer func() {
+			if recover() == nil {
+				t.Fatal("RunInTx() swallowed the panic")
+			}
+			if got, want := d.Events(), []string{"begin", "rollback"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("events = %v, want %v", got, want)
+			}
+		}()
+		_ = models.RunInTx(context.Background(), func(tx Models) error { panic("boom") })
+	})
+
+	t.Run("No database", func(t *testing.T) {
+		var models Models
+		err := models.RunInTx(context.Background(), func(tx Models) error { return nil })
+		if !errors.Is(err, ErrTransactionsDisabled) {
+			t.Errorf("RunInTx() error = %v, want %v", err, ErrTransactionsDisabled)
+		}
+	})
+
+	t.Run("Queries outside the transaction", func(t *testing.T) {
+		models, d := newTxModels(t)
+		if err := models.Tokens.DeleteAllForUser(context.Background(), ScopeActivation, 1); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := d.Events(), []string{"exec"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("events = %v, want %v", got, want)
+		}
+	})
+}
@@ -5,24 +5,32 @@ import (
 	"database/sql"
 	"errors"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/Blue-Davinci/leadhub-service/internal/database"
 	"github.com/Blue-Davinci/leadhub-service/internal/validator"
 )
 
+const (
+	DefaultPermissionDBContextTimeout = 3 * time.Second
+)
+
 var (
 	ErrDuplicatePermission = errors.New("duplicate permission")
 	ErrPermissionNotFound  = errors.New("permission not found")
 )
 var (
-	PermissionAdminWrite = "admin:write"
-	PermissionAdminRead  = "admin:read"
+	PermissionAdminWrite  = "admin:write"
+	PermissionAdminRead   = "admin:read"
+	PermissionTenantAdmin = "tenant:admin"
+	PermissionTenantGroup = "tenant:group"
 )
 
 // Define the PermissionModel type.
 type PermissionModel struct {
-	DB *database.Queries
+	DB      *database.Queries
+	Timeout time.Duration
 }
 
 type UserPermission struct {
@@ -71,9 +79,22 @@ func (p Permissions) Include(code string) bool {
 	return false
 }
 
+// IncludeAdmin() reports whether the permissions hold any admin permission, e.g.
+// "admin:read".
+func (p Permissions) IncludeAdmin() bool {
+	for i := range p {
+		if strings.HasPrefix(p[i], "admin:") {
+			return true
+		}
+	}
+	return false
+}
+
 // GetAllPermissions() just returns all available permissions currently in the system.
-func (m PermissionModel) GetAllPermissions() ([]*UserPermission, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m PermissionModel) GetAllPermissions(ctx context.Context) ([]*UserPermission, error) {
+	ctx, span := startSpan(ctx, "PermissionModel.GetAllPermissions")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 
 	permissions, err := m.DB.GetAllPermissions(ctx)
@@ -93,9 +114,10 @@ func (m PermissionModel) GetAllPermissions() ([]*UserPermission, error) {
 
 // GetAllPermissionsForUser() is a method that retrieves all permissions for a specific user
 // from the database. It expects the user's ID as input and returns a slice of permission codes.
-func (m PermissionModel) GetAllPermissionsForUser(userID int64) (Permissions, error) {
-	// set up context
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m PermissionModel) GetAllPermissionsForUser(ctx context.Context, userID int64) (Permissions, error) {
+	ctx, span := startSpan(ctx, "PermissionModel.GetAllPermissionsForUser")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 	// create our permissions
 	var permissions Permissions
@@ -113,9 +135,10 @@ func (m PermissionModel) GetAllPermissionsForUser(userID int64) (Permissions, er
 
 // AddPermissionsForUser() is an admin method that adds permissions for a specific user
 // in the database. It expects the user's ID and a slice of permission codes as input.
-func (m PermissionModel) AddPermissionsForUser(userID int64, codes ...string) (*UserPermission, error) {
-	// setup our context timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m PermissionModel) AddPermissionsForUser(ctx context.Context, userID int64, codes ...string) (*UserPermission, error) {
+	ctx, span := startSpan(ctx, "PermissionModel.AddPermissionsForUser")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 	// insert our permissions
 	queryResult, err := m.DB.AddPermissionsForUser(ctx, database.AddPermissionsForUserParams{
@@ -142,9 +165,10 @@ func (m PermissionModel) AddPermissionsForUser(userID int64, codes ...string) (*
 
 // DeletePermissionsForUser() is an admin method that deletes permissions for a specific user
 // in the database. It expects the user's ID and a permission code as input.
-func (m PermissionModel) DeletePermissionsForUser(userID int64, permissionCode string) (int64, error) {
-	// Setup our context timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+func (m PermissionModel) DeletePermissionsForUser(ctx context.Context, userID int64, permissionCode string) (int64, error) {
+	ctx, span := startSpan(ctx, "PermissionModel.DeletePermissionsForUser")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 
 	// Execute the deletion query
@@ -0,0 +1,167 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// The store interfaces describe the data access the handlers rely on. The Postgres backed
+// models implement them, as do the in-memory stores returned by NewMemoryModels(), and
+// both are checked against the same contract tests.
+
+// TenantStore manages tenants and their hierarchy.
+type TenantStore interface {
+	GetTenantByID(ctx context.Context, id int64) (*Tenant, error)
+	AdminGetAllTenants(ctx context.Context, tenantName string, filters Filters) ([]*Tenant, Metadata, error)
+	CreateTenant(ctx context.Context, tenant *Tenant) error
+	UpdateTenant(ctx context.Context, tenant *Tenant, versionID int32) error
+	GetTenantDescendantIDs(ctx context.Context, tenantID int64) ([]int64, error)
+	GetTenantTree(ctx context.Context, tenantID int64) (*Tenant, error)
+}
+
+// UserStore manages user accounts.
+type UserStore interface {
+	Insert(ctx context.Context, user *User) error
+	GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	UpdateUser(ctx context.Context, user *User) error
+	GetAllUsersByTenantID(ctx context.Context, tenantID int64) ([]*User, error)
+}
+
+// TokenStore manages the activation and authentication tokens of users.
+type TokenStore interface {
+	New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error)
+	Insert(ctx context.Context, api_key *Token) error
+	DeleteAllForUser(ctx context.Context, scope string, userID int64) error
+}
+
+// PermissionStore manages the permissions granted to users.
+type PermissionStore interface {
+	GetAllPermissions(ctx context.Context) ([]*UserPermission, error)
+	GetAllPermissionsForUser(ctx context.Context, userID int64) (Permissions, error)
+	AddPermissionsForUser(ctx context.Context, userID int64, codes ...string) (*UserPermission, error)
+	DeletePermissionsForUser(ctx context.Context, userID int64, permissionCode string) (int64, error)
+}
+
+// TradeLeadStore manages trade leads, their assignment, history and stats.
+type TradeLeadStore interface {
+	CreateTradeLead(ctx context.Context, tenantID int64, tenantLead *TradeLead) error
+	GetTradeLeadByID(ctx context.Context, id int64) (*TradeLead, error)
+	GetAllLeadsByTenantID(ctx context.Context, tenantID, assigneeID int64, name string, customFilters CustomFieldFilters, filters Filters) ([]*TradeLead, Metadata, error)
+	GetAllLeadsByTenantIDs(ctx context.Context, tenantIDs []int64, assigneeID int64, name string, customFilters CustomFieldFilters, filters Filters) ([]*TradeLead, Metadata, error)
+	AdminGetAllTradeLeads(ctx context.Context, tenantID int64, name string, customFilters CustomFieldFilters, filters Filters) ([]*TradeLead, Metadata, error)
+	AdminUpdateTradeLeadStatus(ctx context.Context, leadID int64, version int32, lead *TradeLead) error
+	AssignTradeLead(ctx context.Context, leadID int64, version int32, assigneeID *int64, lead *TradeLead) error
+	GetNextTradeLeadAssignee(ctx context.Context, tenantID int64) (int64, error)
+	AdminGetTradeLeadStats(ctx context.Context, converter *CurrencyConverter) (*TradeStats, error)
+	GetTradeLeadStatsByTenant(ctx context.Context, tenantIDs []int64, converter *CurrencyConverter) (map[int64]*TradeStats, error)
+	GetTradeLeadAnalytics(ctx context.Context, filter TradeLeadStatsFilter) (*TradeLeadAnalytics, error)
+	GetAllTradeLeadsForExport(ctx context.Context, tenantID int64) ([]*TradeLead, error)
+	GetTradeLeadHistoryByTenantID(ctx context.Context, tenantID int64) ([]*TradeLeadHistory, error)
+}
+
+// JobStore persists background jobs and hands them out to workers.
+type JobStore interface {
+	EnqueueJob(ctx context.Context, job *Job) error
+	ClaimJobs(ctx context.Context, workerID string, limit int32) ([]*Job, error)
+	CompleteJob(ctx context.Context, id int64, workerID string) error
+	RetryJob(ctx context.Context, id int64, workerID string, runAt time.Time, lastError string) error
+	BuryJob(ctx context.Context, id int64, workerID string, lastError string) error
+	RequeueStaleJobs(ctx context.Context, lockedBefore time.Time) (int64, error)
+	GetJobByID(ctx context.Context, id int64) (*Job, error)
+}
+
+// EmailOutboxStore records the emails sent by the API and the outcome of their delivery.
+type EmailOutboxStore interface {
+	CreateOutboxEmail(ctx context.Context, email *OutboxEmail) error
+	GetOutboxEmailByID(ctx context.Context, id int64) (*OutboxEmail, error)
+	AdminGetAllOutboxEmails(ctx context.Context, tenantID int64, status, recipient string, filters Filters) ([]*OutboxEmail, Metadata, error)
+	RecordOutboxEmailAttempt(ctx context.Context, id int64, attempt EmailAttempt) error
+	RequeueOutboxEmail(ctx context.Context, id int64) error
+}
+
+// WebhookStore manages the webhook endpoints of tenants and the log of their deliveries.
+type WebhookStore interface {
+	CreateWebhookEndpoint(ctx context.Context, endpoint *WebhookEndpoint) error
+	GetWebhookEndpointByID(ctx context.Context, tenantID, endpointID int64) (*WebhookEndpoint, error)
+	GetWebhookEndpointsByTenantID(ctx context.Context, tenantID int64) ([]*WebhookEndpoint, error)
+	GetSubscribedWebhookEndpoints(ctx context.Context, tenantID int64, eventType string) ([]*WebhookEndpoint, error)
+	UpdateWebhookEndpoint(ctx context.Context, endpoint *WebhookEndpoint, version int32) error
+	DeleteWebhookEndpoint(ctx context.Context, tenantID, endpointID int64) error
+	RecordWebhookEndpointResult(ctx context.Context, endpointID int64, succeeded bool, disableAfter int32) (bool, error)
+	CreateWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	GetWebhookDeliveryByID(ctx context.Context, id int64) (*WebhookDelivery, error)
+	GetWebhookDeliveriesByEndpointID(ctx context.Context, endpointID int64, status string, filters Filters) ([]*WebhookDelivery, Metadata, error)
+	RecordWebhookDeliveryAttempt(ctx context.Context, id int64, attempt WebhookAttempt) error
+}
+
+// TradeLeadEventStore publishes the live trade lead events streamed to tenants.
+type TradeLeadEventStore interface {
+	PublishTradeLeadEvent(ctx context.Context, event *TradeLeadEvent) error
+}
+
+// ExchangeRateStore keeps the dated exchange rates lead values are converted with.
+type ExchangeRateStore interface {
+	SaveExchangeRate(ctx context.Context, rate *ExchangeRate) error
+	GetExchangeRatesOnDate(ctx context.Context, date time.Time) ([]*ExchangeRate, error)
+}
+
+// TradeLeadCommentStore manages the comments and internal notes on trade leads.
+type TradeLeadCommentStore interface {
+	CreateTradeLeadComment(ctx context.Context, comment *TradeLeadComment) error
+	GetTradeLeadCommentByID(ctx context.Context, tradeLeadID, id int64) (*TradeLeadComment, error)
+	GetTradeLeadComments(ctx context.Context, tradeLeadID int64, includeInternal bool, filters Filters) ([]*TradeLeadComment, Metadata, error)
+	UpdateTradeLeadComment(ctx context.Context, comment *TradeLeadComment, version int32) error
+	DeleteTradeLeadComment(ctx context.Context, tradeLeadID, id int64) error
+}
+
+// TenantExportStore records the data exports requested by tenants.
+type TenantExportStore interface {
+	CreateTenantExport(ctx context.Context, export *TenantExport) error
+	GetTenantExportByID(ctx context.Context, id int64) (*TenantExport, error)
+	UpdateTenantExport(ctx context.Context, export *TenantExport) error
+}
+
+// CustomFieldStore manages the custom field definitions of tenants.
+type CustomFieldStore interface {
+	CreateCustomField(ctx context.Context, field *CustomField) error
+	GetCustomFieldsByTenantID(ctx context.Context, tenantID int64) ([]*CustomField, error)
+	GetCustomFieldByID(ctx context.Context, tenantID, fieldID int64) (*CustomField, error)
+	UpdateCustomField(ctx context.Context, field *CustomField, version int32) error
+	DeleteCustomField(ctx context.Context, tenantID, fieldID int64) error
+}
+
+// TenantSettingsStore keeps the preferences and email branding of tenants.
+type TenantSettingsStore interface {
+	GetTenantSettings(ctx context.Context, tenantID int64) (*TenantSettings, error)
+	SaveTenantSettings(ctx context.Context, settings *TenantSettings, version int32) error
+}
+
+// TenantDomainStore manages the email domains claimed and verified by tenants.
+type TenantDomainStore interface {
+	CreateTenantDomain(ctx context.Context, domain *TenantDomain) error
+	GetTenantDomainsByTenantID(ctx context.Context, tenantID int64) ([]*TenantDomain, error)
+	GetTenantDomainByID(ctx context.Context, tenantID, domainID int64) (*TenantDomain, error)
+	GetVerifiedTenantDomain(ctx context.Context, domain string) (*TenantDomain, error)
+	TenantHasVerifiedDomains(ctx context.Context, tenantID int64) (bool, error)
+	MarkTenantDomainVerified(ctx context.Context, domain *TenantDomain) error
+	DeleteTenantDomain(ctx context.Context, tenantID, domainID int64) error
+}
+
+var (
+	_ TenantStore           = TenantsModel{}
+	_ UserStore             = UserModel{}
+	_ TokenStore            = TokenModel{}
+	_ PermissionStore       = PermissionModel{}
+	_ TradeLeadStore        = TradeLeadModel{}
+	_ JobStore              = JobModel{}
+	_ EmailOutboxStore      = EmailOutboxModel{}
+	_ WebhookStore          = WebhookModel{}
+	_ TradeLeadEventStore   = TradeLeadEventModel{}
+	_ ExchangeRateStore     = ExchangeRateModel{}
+	_ TradeLeadCommentStore = TradeLeadCommentModel{}
+	_ TenantExportStore     = TenantExportModel{}
+	_ CustomFieldStore      = CustomFieldModel{}
+	_ TenantSettingsStore   = TenantSettingsModel{}
+	_ TenantDomainStore     = TenantDomainModel{}
+)
@@ -0,0 +1,1107 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// unknownID is an ID no record has in the contract tests.
+const unknownID = int64(1) << 40
+
+func TestMemoryStores(t *testing.T) {
+	testStoreContract(t, func(t *testing.T) Models { return NewMemoryModels() })
+}
+
+// TestPostgresStores runs the contract against a migrated database, set through
+// LEADHUB_TEST_DB_DSN. Every test creates its own uniquely named records, so the database
+// doesn't have to be empty.
+func TestPostgresStores(t *testing.T) {
+	dsn := os.Getenv("LEADHUB_TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("LEADHUB_TEST_DB_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	testStoreContract(t, func(t *testing.T) Models { return NewModels(db, Timeouts{}) })
+}
+
+// testStoreContract() checks that a set of stores follows the semantics the handlers rely on.
+func testStoreContract(t *testing.T, newModels func(t *testing.T) Models) {
+	t.Run("Tenants", func(t *testing.T) { testTenantStore(t, newModels(t)) })
+	t.Run("Users", func(t *testing.T) { testUserStore(t, newModels(t)) })
+	t.Run("Tokens", func(t *testing.T) { testTokenStore(t, newModels(t)) })
+	t.Run("Permissions", func(t *testing.T) { testPermissionStore(t, newModels(t)) })
+	t.Run("TradeLeads", func(t *testing.T) { testTradeLeadStore(t, newModels(t)) })
+	t.Run("TradeLeadAssignment", func(t *testing.T) { testTradeLeadAssignment(t, newModels(t)) })
+	t.Run("TradeLeadComments", func(t *testing.T) { testTradeLeadCommentStore(t, newModels(t)) })
+	t.Run("TenantSettings", func(t *testing.T) { testTenantSettings(t, newModels(t)) })
+	t.Run("TenantDomains", func(t *testing.T) { testTenantDomainStore(t, newModels(t)) })
+	t.Run("CustomFields", func(t *testing.T) { testCustomFieldStore(t, newModels(t)) })
+	t.Run("TenantExports", func(t *testing.T) { testTenantExportStore(t, newModels(t)) })
+	t.Run("TradeLeadAnalytics", func(t *testing.T) { testTradeLeadAnalytics(t, newModels(t)) })
+	t.Run("Jobs", func(t *testing.T) { testJobStore(t, newModels(t)) })
+	t.Run("EmailOutbox", func(t *testing.T) { testEmailOutboxStore(t, newModels(t)) })
+	t.Run("Webhooks", func(t *testing.T) { testWebhookStore(t, newModels(t)) })
+	t.Run("LeadEvents", func(t *testing.T) { testTradeLeadEventStore(t, newModels(t)) })
+	t.Run("ExchangeRates", func(t *testing.T) { testExchangeRateStore(t, newModels(t)) })
+	t.Run("Transactions", func(t *testing.T) { testStoreTransactions(t, newModels(t)) })
+}
+
+func testTenantStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	word := uniqueWord(t)
+	parent := &Tenant{Name: "Acme " + word, ContactEmail: "ops@acme.test"}
+	mustNot(t, models.Tenants.CreateTenant(ctx, parent))
+	if parent.ID == 0 || parent.Version != 1 {
+		t.Fatalf("CreateTenant() set ID %d, version %d", parent.ID, parent.Version)
+	}
+	child := &Tenant{Name: "Branch " + word, ContactEmail: "ops@branch.test", ParentID: &parent.ID}
+	mustNot(t, models.Tenants.CreateTenant(ctx, child))
+
+	wantErr(t, models.Tenants.CreateTenant(ctx, &Tenant{Name: parent.Name, ContactEmail: "x@acme.test"}), ErrTenantAlreadyExists)
+	badParent := unknownID
+	wantErr(t, models.Tenants.CreateTenant(ctx, &Tenant{Name: "Orphan " + word, ContactEmail: "x@acme.test", ParentID: &badParent}), ErrInvalidParentTenant)
+	_, err := models.Tenants.GetTenantByID(ctx, unknownID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	got, err := models.Tenants.GetTenantByID(ctx, child.ID)
+	mustNot(t, err)
+	if got.Name != child.Name || got.ParentID == nil || *got.ParentID != parent.ID {
+		t.Errorf("GetTenantByID() = %+v, want %+v", got, child)
+	}
+
+	// optimistic locking
+	parent.Description = "updated"
+	mustNot(t, models.Tenants.UpdateTenant(ctx, parent, 1))
+	if parent.Version != 2 {
+		t.Errorf("version after update = %d, want 2", parent.Version)
+	}
+	wantErr(t, models.Tenants.UpdateTenant(ctx, parent, 1), ErrGeneralEditConflict)
+	wantErr(t, models.Tenants.UpdateTenant(ctx, &Tenant{ID: unknownID, Name: "Ghost " + word, ContactEmail: "x@acme.test"}, 1), ErrGeneralEditConflict)
+	parent.Name = child.Name
+	wantErr(t, models.Tenants.UpdateTenant(ctx, parent, parent.Version), ErrTenantAlreadyExists)
+	parent.Name = "Acme " + word
+	parent.ParentID = &child.ID
+	wantErr(t, models.Tenants.UpdateTenant(ctx, parent, parent.Version), ErrTenantHierarchyCycle)
+	parent.ParentID = nil
+
+	ids, err := models.Tenants.GetTenantDescendantIDs(ctx, parent.ID)
+	mustNot(t, err)
+	if !slices.Equal(ids, []int64{parent.ID, child.ID}) {
+		t.Errorf("GetTenantDescendantIDs() = %v, want %v", ids, []int64{parent.ID, child.ID})
+	}
+	tree, err := models.Tenants.GetTenantTree(ctx, parent.ID)
+	mustNot(t, err)
+	if len(tree.Children) != 1 || tree.Children[0].ID != child.ID {
+		t.Errorf("GetTenantTree() children = %v, want the child tenant", tree.Children)
+	}
+
+	filters := Filters{Page: 1, PageSize: 10}
+	tenants, metadata, err := models.Tenants.AdminGetAllTenants(ctx, word, filters)
+	mustNot(t, err)
+	if len(tenants) != 2 || metadata.TotalRecords != 2 || tenants[0].ID != child.ID {
+		t.Errorf("AdminGetAllTenants() = %d tenants, %+v, want both tenants newest first", len(tenants), metadata)
+	}
+	_, _, err = models.Tenants.AdminGetAllTenants(ctx, uniqueWord(t), filters)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+}
+
+func testUserStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	tenant := createTestTenant(t, models)
+	user := newTestUser(t, tenant.ID)
+	user.Locale = "fr"
+	mustNot(t, models.Users.Insert(ctx, user))
+	if user.ID == 0 || user.Version != 1 {
+		t.Fatalf("Insert() set ID %d, version %d", user.ID, user.Version)
+	}
+
+	// emails are unique regardless of case
+	duplicate := newTestUser(t, tenant.ID)
+	duplicate.Email = strings.ToUpper(user.Email)
+	wantErr(t, models.Users.Insert(ctx, duplicate), ErrDuplicateEmail)
+	wantErr(t, models.Users.Insert(ctx, newTestUser(t, unknownID)), ErrInvalidTenantID)
+
+	got, err := models.Users.GetByEmail(ctx, strings.ToUpper(user.Email))
+	mustNot(t, err)
+	if match, _ := got.Password.Matches("pa55word123"); got.ID != user.ID || !match || got.Locale != "fr" {
+		t.Errorf("GetByEmail() = %+v, want the inserted user with its password and locale", got)
+	}
+	_, err = models.Users.GetByEmail(ctx, "nobody-"+uniqueWord(t)+"@example.test")
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	// optimistic locking
+	got.Activated = true
+	got.Locale = "sw"
+	mustNot(t, models.Users.UpdateUser(ctx, got))
+	if got.Version != 2 {
+		t.Errorf("version after update = %d, want 2", got.Version)
+	}
+	stale := *got
+	stale.Version = 1
+	wantErr(t, models.Users.UpdateUser(ctx, &stale), ErrGeneralEditConflict)
+	other := newTestUser(t, tenant.ID)
+	mustNot(t, models.Users.Insert(ctx, other))
+	got.Email = other.Email
+	wantErr(t, models.Users.UpdateUser(ctx, got), ErrDuplicateEmail)
+
+	users, err := models.Users.GetAllUsersByTenantID(ctx, tenant.ID)
+	mustNot(t, err)
+	if len(users) != 2 || users[0].ID != user.ID || !users[0].Activated || users[0].Locale != "sw" {
+		t.Fatalf("GetAllUsersByTenantID() = %+v, want both users ordered by ID", users)
+	}
+	if users[0].Password.hash != nil {
+		t.Error("GetAllUsersByTenantID() handed out a password hash")
+	}
+}
+
+func testTokenStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	tenant := createTestTenant(t, models)
+	user := newTestUser(t, tenant.ID)
+	mustNot(t, models.Users.Insert(ctx, user))
+
+	token, err := models.Tokens.New(ctx, user.ID, time.Hour, ScopeActivation)
+	mustNot(t, err)
+	got, err := models.Users.GetForToken(ctx, ScopeActivation, token.Plaintext)
+	mustNot(t, err)
+	if got.ID != user.ID {
+		t.Errorf("GetForToken() = user %d, want %d", got.ID, user.ID)
+	}
+	_, err = models.Users.GetForToken(ctx, ScopeAuthentication, token.Plaintext)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	expired, err := models.Tokens.New(ctx, user.ID, -time.Hour, ScopeAuthentication)
+	mustNot(t, err)
+	_, err = models.Users.GetForToken(ctx, ScopeAuthentication, expired.Plaintext)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	mustNot(t, models.Tokens.DeleteAllForUser(ctx, ScopeActivation, user.ID))
+	_, err = models.Users.GetForToken(ctx, ScopeActivation, token.Plaintext)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+}
+
+func testPermissionStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	tenant := createTestTenant(t, models)
+	user := newTestUser(t, tenant.ID)
+	mustNot(t, models.Users.Insert(ctx, user))
+
+	all, err := models.Permissions.GetAllPermissions(ctx)
+	mustNot(t, err)
+	codes := []string{}
+	for _, permission := range all {
+		codes = append(codes, permission.Permissions...)
+	}
+	for _, code := range []string{PermissionAdminRead, PermissionAdminWrite, PermissionTenantAdmin, PermissionTenantGroup} {
+		if !slices.Contains(codes, code) {
+			t.Errorf("GetAllPermissions() is missing %q", code)
+		}
+	}
+
+	permissions, err := models.Permissions.GetAllPermissionsForUser(ctx, user.ID)
+	mustNot(t, err)
+	if len(permissions) != 0 {
+		t.Errorf("new user has permissions %v", permissions)
+	}
+	_, err = models.Permissions.AddPermissionsForUser(ctx, user.ID, PermissionAdminWrite, PermissionTenantAdmin)
+	mustNot(t, err)
+	permissions, err = models.Permissions.GetAllPermissionsForUser(ctx, user.ID)
+	mustNot(t, err)
+	if !permissions.Include(PermissionAdminWrite) || !permissions.Include(PermissionTenantAdmin) || permissions.Include(PermissionAdminRead) {
+		t.Errorf("GetAllPermissionsForUser() = %v", permissions)
+	}
+	_, err = models.Permissions.AddPermissionsForUser(ctx, user.ID, PermissionAdminWrite)
+	wantErr(t, err, ErrDuplicatePermission)
+
+	_, err = models.Permissions.DeletePermissionsForUser(ctx, user.ID, PermissionTenantAdmin)
+	mustNot(t, err)
+	_, err = models.Permissions.DeletePermissionsForUser(ctx, user.ID, PermissionTenantAdmin)
+	wantErr(t, err, ErrPermissionNotFound)
+	permissions, err = models.Permissions.GetAllPermissionsForUser(ctx, user.ID)
+	mustNot(t, err)
+	if !slices.Equal(permissions, Permissions{PermissionAdminWrite}) {
+		t.Errorf("GetAllPermissionsForUser() = %v, want only %q", permissions, PermissionAdminWrite)
+	}
+}
+
+func testTradeLeadStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	word := uniqueWord(t)
+	tenant := createTestTenant(t, models)
+	otherTenant := createTestTenant(t, models)
+
+	coffee := &TradeLead{Title: "Coffee " + word, Value: decimal.NewFromInt(100), Currency: "USD", CustomFields: map[string]any{"grade": "AA"}}
+	tea := &TradeLead{Title: "Tea " + word, Value: decimal.NewFromInt(250), Currency: "USD", CustomFields: map[string]any{"grade": "B"}}
+	foreign := &TradeLead{Title: "Coffee " + word, Value: decimal.NewFromInt(75), Currency: "USD"}
+	mustNot(t, models.TradeLeads.CreateTradeLead(ctx, tenant.ID, coffee))
+	mustNot(t, models.TradeLeads.CreateTradeLead(ctx, tenant.ID, tea))
+	mustNot(t, models.TradeLeads.CreateTradeLead(ctx, otherTenant.ID, foreign))
+	if coffee.Status != "new" || coffee.Version != 1 || coffee.TenantID != tenant.ID {
+		t.Errorf("CreateTradeLead() set %+v", coffee)
+	}
+	wantErr(t, models.TradeLeads.CreateTradeLead(ctx, unknownID, &TradeLead{Title: "Lost", Value: decimal.NewFromInt(1), Currency: "USD"}), ErrInvalidTenantReference)
+
+	got, err := models.TradeLeads.GetTradeLeadByID(ctx, coffee.ID)
+	mustNot(t, err)
+	if got.Title != coffee.Title || !got.Value.Equal(coffee.Value) || got.Currency != "USD" || got.CustomFields["grade"] != "AA" {
+		t.Errorf("GetTradeLeadByID() = %+v", got)
+	}
+	_, err = models.TradeLeads.GetTradeLeadByID(ctx, unknownID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	// listings are scoped to the tenants asked for
+	filters := Filters{Page: 1, PageSize: 10, SortSafelist: []string{"value", "-value"}}
+	leads, metadata, err := models.TradeLeads.GetAllLeadsByTenantID(ctx, tenant.ID, 0, "", CustomFieldFilters{}, filters)
+	mustNot(t, err)
+	if len(leads) != 2 || metadata.TotalRecords != 2 || leads[0].ID != tea.ID {
+		t.Errorf("GetAllLeadsByTenantID() = %v, want the tenant's two leads newest first", leadIDs(leads))
+	}
+	leads, _, err = models.TradeLeads.GetAllLeadsByTenantID(ctx, tenant.ID, 0, "coffee", CustomFieldFilters{}, filters)
+	mustNot(t, err)
+	if !slices.Equal(leadIDs(leads), []int64{coffee.ID}) {
+		t.Errorf("title search = %v, want %v", leadIDs(leads), []int64{coffee.ID})
+	}
+	leads, _, err = models.TradeLeads.GetAllLeadsByTenantID(ctx, tenant.ID, 0, "", CustomFieldFilters{Values: map[string]any{"grade": "B"}}, filters)
+	mustNot(t, err)
+	if !slices.Equal(leadIDs(leads), []int64{tea.ID}) {
+		t.Errorf("custom field filter = %v, want %v", leadIDs(leads), []int64{tea.ID})
+	}
+	_, _, err = models.TradeLeads.GetAllLeadsByTenantID(ctx, tenant.ID, 0, uniqueWord(t), CustomFieldFilters{}, filters)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	sorted := filters
+	sorted.Sort = "value"
+	sorted.PageSize = 1
+	leads, metadata, err = models.TradeLeads.GetAllLeadsByTenantIDs(ctx, []int64{tenant.ID, otherTenant.ID}, 0, "", CustomFieldFilters{}, sorted)
+	mustNot(t, err)
+	if !slices.Equal(leadIDs(leads), []int64{foreign.ID}) || metadata.LastPage != 3 {
+		t.Errorf("sorted page = %v, %+v, want the cheapest lead of 3", leadIDs(leads), metadata)
+	}
+	// values stored before a field became a number field sort after the numbers
+	gradedTenant := createTestTenant(t, models)
+	graded := &TradeLead{Title: "Cocoa " + word, Value: decimal.NewFromInt(50), Currency: "USD", CustomFields: map[string]any{"grade": 2}}
+	mustNot(t, models.TradeLeads.CreateTradeLead(ctx, gradedTenant.ID, graded))
+	mustNot(t, models.TradeLeads.CreateTradeLead(ctx, gradedTenant.ID, &TradeLead{Title: "Cocoa " + word, Value: decimal.NewFromInt(50), Currency: "USD", CustomFields: map[string]any{"grade": "AA"}}))
+	byGrade := Filters{Page: 1, PageSize: 10, Sort: "cf.grade", SortSafelist: []string{"cf.grade"}}
+	definitions := []*CustomField{{Key: "grade", Type: CustomFieldTypeNumber}}
+	leads, _, err = models.TradeLeads.GetAllLeadsByTenantID(ctx, gradedTenant.ID, 0, "", CustomFieldFilters{Definitions: definitions}, byGrade)
+	mustNot(t, err)
+	if len(leads) != 2 || leads[0].ID != graded.ID {
+		t.Errorf("numeric custom field sort = %v, want %d first", leadIDs(leads), graded.ID)
+	}
+	leads, _, err = models.TradeLeads.AdminGetAllTradeLeads(ctx, otherTenant.ID, "", CustomFieldFilters{}, filters)
+	mustNot(t, err)
+	if !slices.Equal(leadIDs(leads), []int64{foreign.ID}) {
+		t.Errorf("AdminGetAllTradeLeads() = %v, want %v", leadIDs(leads), []int64{foreign.ID})
+	}
+
+	// optimistic locking of the status update
+	mustNot(t, models.TradeLeads.AdminUpdateTradeLeadStatus(ctx, coffee.ID, 1, coffee))
+	if coffee.Status != "verified" || coffee.Version != 2 {
+		t.Errorf("status update set status %q, version %d", coffee.Status, coffee.Version)
+	}
+	wantErr(t, models.TradeLeads.AdminUpdateTradeLeadStatus(ctx, coffee.ID, 1, coffee), ErrGeneralRecordNotFound)
+
+	usd := NewCurrencyConverter("USD", time.Now(), nil)
+	stats, err := models.TradeLeads.GetTradeLeadStatsByTenant(ctx, []int64{tenant.ID, otherTenant.ID}, usd)
+	mustNot(t, err)
+	own := stats[tenant.ID]
+	if own == nil || !own.TotalLeads.Equal(decimal.NewFromInt(2)) || !own.VerifiedLeads.Equal(decimal.NewFromInt(1)) || !own.TotalVerifiedValue.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("stats of the tenant = %+v", own)
+	}
+	if other := stats[otherTenant.ID]; other == nil || !other.TotalLeads.Equal(decimal.NewFromInt(1)) || !other.TotalVerifiedValue.IsZero() {
+		t.Errorf("stats of the other tenant = %+v", other)
+	}
+	if _, err := models.TradeLeads.AdminGetTradeLeadStats(ctx, usd); err != nil {
+		t.Errorf("AdminGetTradeLeadStats() error = %v", err)
+	}
+	// values in other currencies are converted, or fail the stats without a rate
+	shilling := &TradeLead{Title: "Maize " + word, Value: decimal.NewFromInt(12900), Currency: "KES"}
+	mustNot(t, models.TradeLeads.CreateTradeLead(ctx, otherTenant.ID, shilling))
+	mustNot(t, models.TradeLeads.AdminUpdateTradeLeadStatus(ctx, shilling.ID, shilling.Version, shilling))
+	_, err = models.TradeLeads.GetTradeLeadStatsByTenant(ctx, []int64{otherTenant.ID}, usd)
+	wantErr(t, err, ErrExchangeRateNotFound)
+	rates := []*ExchangeRate{{BaseCurrency: "USD", QuoteCurrency: "KES", Rate: decimal.NewFromInt(129), RateDate: time.Now()}}
+	stats, err = models.TradeLeads.GetTradeLeadStatsByTenant(ctx, []int64{otherTenant.ID}, NewCurrencyConverter("USD", time.Now(), rates))
+	mustNot(t, err)
+	if other := stats[otherTenant.ID]; other == nil || !other.TotalLeads.Equal(decimal.NewFromInt(2)) || !other.TotalVerifiedValue.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("converted stats of the other tenant = %+v", other)
+	}
+
+	exported, err := models.TradeLeads.GetAllTradeLeadsForExport(ctx, tenant.ID)
+	mustNot(t, err)
+	if !slices.Equal(leadIDs(exported), []int64{coffee.ID, tea.ID}) {
+		t.Errorf("GetAllTradeLeadsForExport() = %v", leadIDs(exported))
+	}
+	history, err := models.TradeLeads.GetTradeLeadHistoryByTenantID(ctx, tenant.ID)
+	mustNot(t, err)
+	statuses := []string{}
+	for _, entry := range history {
+		statuses = append(statuses, entry.Status)
+	}
+	if !slices.Equal(statuses, []string{"new", "verified", "new"}) {
+		t.Errorf("history statuses = %v, want the coffee lead's two states then the tea lead's", statuses)
+	}
+}
+
+func testTradeLeadAssignment(t *testing.T, models Models) {
+	ctx := context.Background()
+	tenant := createTestTenant(t, models)
+	otherTenant := createTestTenant(t, models)
+	users := []*User{}
+	for range 3 {
+		user := newTestUser(t, tenant.ID)
+		user.Activated = true
+		mustNot(t, models.Users.Insert(ctx, user))
+		users = append(users, user)
+	}
+	// inactive users are never picked for new leads
+	inactive := newTestUser(t, tenant.ID)
+	mustNot(t, models.Users.Insert(ctx, inactive))
+	outsider := newTestUser(t, otherTenant.ID)
+	outsider.Activated = true
+	mustNot(t, models.Users.Insert(ctx, outsider))
+
+	// users take turns, wrapping around after the last one
+	want := []int64{users[0].ID, users[1].ID, users[2].ID, users[0].ID}
+	leads := []*TradeLead{}
+	for i, wantID := range want {
+		next, err := models.TradeLeads.GetNextTradeLeadAssignee(ctx, tenant.ID)
+		mustNot(t, err)
+		if next != wantID {
+			t.Errorf("assignee of lead %d = %d, want %d", i, next, wantID)
+		}
+		lead := &TradeLead{Title: "Lead " + uniqueWord(t), Value: decimal.NewFromInt(10), Currency: "USD", AssignedTo: &next}
+		mustNot(t, models.TradeLeads.CreateTradeLead(ctx, tenant.ID, lead))
+		leads = append(leads, lead)
+	}
+	_, err := models.TradeLeads.GetNextTradeLeadAssignee(ctx, createTestTenant(t, models).ID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	// leads can only be assigned to users of their own tenant
+	wantErr(t, models.TradeLeads.CreateTradeLead(ctx, tenant.ID, &TradeLead{Title: "Lost", Value: decimal.NewFromInt(1), Currency: "USD", AssignedTo: &outsider.ID}), ErrInvalidTradeLeadAssignee)
+	lead := leads[0]
+	wantErr(t, models.TradeLeads.AssignTradeLead(ctx, lead.ID, lead.Version, &outsider.ID, lead), ErrInvalidTradeLeadAssignee)
+	mustNot(t, models.TradeLeads.AssignTradeLead(ctx, lead.ID, lead.Version, &users[2].ID, lead))
+	if lead.AssignedTo == nil || *lead.AssignedTo != users[2].ID || lead.Version != 2 {
+		t.Errorf("reassigned lead = %+v", lead)
+	}
+	wantErr(t, models.TradeLeads.AssignTradeLead(ctx, lead.ID, 1, nil, lead), ErrGeneralRecordNotFound)
+
+	filters := Filters{Page: 1, PageSize: 10}
+	assigned, _, err := models.TradeLeads.GetAllLeadsByTenantID(ctx, tenant.ID, users[2].ID, "", CustomFieldFilters{}, filters)
+	mustNot(t, err)
+	if !slices.Equal(leadIDs(assigned), []int64{leads[2].ID, lead.ID}) {
+		t.Errorf("leads of the third user = %v, want %v", leadIDs(assigned), []int64{leads[2].ID, lead.ID})
+	}
+	mustNot(t, models.TradeLeads.AssignTradeLead(ctx, lead.ID, lead.Version, nil, lead))
+	got, err := models.TradeLeads.GetTradeLeadByID(ctx, lead.ID)
+	mustNot(t, err)
+	if got.AssignedTo != nil || lead.AssignedTo != nil {
+		t.Errorf("unassigned lead = %+v", got)
+	}
+	_, _, err = models.TradeLeads.GetAllLeadsByTenantID(ctx, tenant.ID, inactive.ID, "", CustomFieldFilters{}, filters)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+}
+
+func testTradeLeadCommentStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	tenant := createTestTenant(t, models)
+	author := newTestUser(t, tenant.ID)
+	mustNot(t, models.Users.Insert(ctx, author))
+	lead := &TradeLead{Title: "Lead " + uniqueWord(t), Value: decimal.NewFromInt(10), Currency: "USD"}
+	mustNot(t, models.TradeLeads.CreateTradeLead(ctx, tenant.ID, lead))
+
+	comments := []*TradeLeadComment{}
+	for _, internal := range []bool{false, true, false} {
+		comment := &TradeLeadComment{TradeLeadID: lead.ID, TenantID: tenant.ID, AuthorID: &author.ID, Body: "Note " + uniqueWord(t), Internal: internal}
+		mustNot(t, models.Comments.CreateTradeLeadComment(ctx, comment))
+		if comment.ID == 0 || comment.Version != 1 || comment.CreatedAt.IsZero() {
+			t.Fatalf("CreateTradeLeadComment() set ID %d, version %d, created at %v", comment.ID, comment.Version, comment.CreatedAt)
+		}
+		comments = append(comments, comment)
+	}
+	wantErr(t, models.Comments.CreateTradeLeadComment(ctx, &TradeLeadComment{TradeLeadID: unknownID, TenantID: tenant.ID, Body: "Lost"}), ErrGeneralRecordNotFound)
+
+	got, err := models.Comments.GetTradeLeadCommentByID(ctx, lead.ID, comments[1].ID)
+	mustNot(t, err)
+	if got.Body != comments[1].Body || !got.Internal || got.AuthorID == nil || *got.AuthorID != author.ID || got.AuthorName != author.Name {
+		t.Errorf("GetTradeLeadCommentByID() = %+v", got)
+	}
+	_, err = models.Comments.GetTradeLeadCommentByID(ctx, unknownID, comments[1].ID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	// internal notes are only listed on request, oldest first unless sorted otherwise
+	commentIDs := func(comments []*TradeLeadComment) []int64 {
+		ids := []int64{}
+		for _, comment := range comments {
+			ids = append(ids, comment.ID)
+		}
+		return ids
+	}
+	listed, metadata, err := models.Comments.GetTradeLeadComments(ctx, lead.ID, false, Filters{Page: 1, PageSize: 10})
+	mustNot(t, err)
+	if want := []int64{comments[0].ID, comments[2].ID}; !slices.Equal(commentIDs(listed), want) || metadata.TotalRecords != 2 {
+		t.Errorf("public comments = %v (%d), want %v", commentIDs(listed), metadata.TotalRecords, want)
+	}
+	listed, metadata, err = models.Comments.GetTradeLeadComments(ctx, lead.ID, true, Filters{Page: 1, PageSize: 2, Sort: "-created_at"})
+	mustNot(t, err)
+	if want := []int64{comments[2].ID, comments[1].ID}; !slices.Equal(commentIDs(listed), want) || metadata.TotalRecords != 3 || metadata.LastPage != 2 {
+		t.Errorf("first page of all comments = %v (%+v), want %v", commentIDs(listed), metadata, want)
+	}
+	_, _, err = models.Comments.GetTradeLeadComments(ctx, lead.ID, true, Filters{Page: 3, PageSize: 2})
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	edited := comments[0]
+	edited.Body = "Edited " + uniqueWord(t)
+	mustNot(t, models.Comments.UpdateTradeLeadComment(ctx, edited, 1))
+	if edited.Version != 2 {
+		t.Errorf("version after UpdateTradeLeadComment() = %d, want 2", edited.Version)
+	}
+	wantErr(t, models.Comments.UpdateTradeLeadComment(ctx, edited, 1), ErrGeneralEditConflict)
+
+	wantErr(t, models.Comments.DeleteTradeLeadComment(ctx, unknownID, edited.ID), ErrGeneralRecordNotFound)
+	mustNot(t, models.Comments.DeleteTradeLeadComment(ctx, lead.ID, edited.ID))
+	_, err = models.Comments.GetTradeLeadCommentByID(ctx, lead.ID, edited.ID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+	wantErr(t, models.Comments.DeleteTradeLeadComment(ctx, lead.ID, edited.ID), ErrGeneralRecordNotFound)
+}
+
+func testTenantSettings(t *testing.T, models Models) {
+	ctx := context.Background()
+	tenant := createTestTenant(t, models)
+	settings, err := models.Settings.GetTenantSettings(ctx, tenant.ID)
+	mustNot(t, err)
+	if settings.Version != 0 {
+		t.Fatalf("version of unsaved settings = %d, want 0", settings.Version)
+	}
+	// unsaved settings are created with version 0 only, and only once
+	settings.DisplayName = "Acme " + uniqueWord(t)
+	wantErr(t, models.Settings.SaveTenantSettings(ctx, settings, 1), ErrGeneralEditConflict)
+	mustNot(t, models.Settings.SaveTenantSettings(ctx, settings, 0))
+	if settings.Version != 1 {
+		t.Errorf("version of created settings = %d, want 1", settings.Version)
+	}
+	wantErr(t, models.Settings.SaveTenantSettings(ctx, settings, 0), ErrGeneralEditConflict)
+
+	settings.AutoAssignLeads = true
+	mustNot(t, models.Settings.SaveTenantSettings(ctx, settings, 1))
+	wantErr(t, models.Settings.SaveTenantSettings(ctx, settings, 1), ErrGeneralEditConflict)
+	got, err := models.Settings.GetTenantSettings(ctx, tenant.ID)
+	mustNot(t, err)
+	if got.Version != 2 || got.DisplayName != settings.DisplayName || !got.AutoAssignLeads {
+		t.Errorf("saved settings = %+v", got)
+	}
+}
+
+func testTenantDomainStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	tenant := createTestTenant(t, models)
+	other := createTestTenant(t, models)
+	name := uniqueWord(t) + ".example.test"
+
+	domain := &TenantDomain{TenantID: tenant.ID, Domain: name}
+	mustNot(t, models.Domains.CreateTenantDomain(ctx, domain))
+	if domain.ID == 0 || domain.Version != 1 || domain.Verified || domain.VerificationToken == "" {
+		t.Fatalf("CreateTenantDomain() = %+v", domain)
+	}
+	wantErr(t, models.Domains.CreateTenantDomain(ctx, &TenantDomain{TenantID: tenant.ID, Domain: name}), ErrDuplicateTenantDomain)
+	// another tenant may claim the domain, only one of them can verify it
+	claim := &TenantDomain{TenantID: other.ID, Domain: name}
+	mustNot(t, models.Domains.CreateTenantDomain(ctx, claim))
+
+	_, err := models.Domains.GetTenantDomainByID(ctx, other.ID, domain.ID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+	_, err = models.Domains.GetVerifiedTenantDomain(ctx, name)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+	verified, err := models.Domains.TenantHasVerifiedDomains(ctx, tenant.ID)
+	mustNot(t, err)
+	if verified {
+		t.Error("TenantHasVerifiedDomains() = true before any verification")
+	}
+
+	stale := *domain
+	mustNot(t, models.Domains.MarkTenantDomainVerified(ctx, domain))
+	if !domain.Verified || domain.VerifiedAt == nil || domain.Version != 2 {
+		t.Errorf("MarkTenantDomainVerified() = %+v", domain)
+	}
+	wantErr(t, models.Domains.MarkTenantDomainVerified(ctx, &stale), ErrGeneralEditConflict)
+	wantErr(t, models.Domains.MarkTenantDomainVerified(ctx, claim), ErrDomainAlreadyVerified)
+
+	got, err := models.Domains.GetVerifiedTenantDomain(ctx, " "+strings.ToUpper(name)+". ")
+	mustNot(t, err)
+	if got.ID != domain.ID || got.TenantID != tenant.ID {
+		t.Errorf("GetVerifiedTenantDomain() = %+v, want domain %d", got, domain.ID)
+	}
+	verified, err = models.Domains.TenantHasVerifiedDomains(ctx, tenant.ID)
+	mustNot(t, err)
+	if !verified {
+		t.Error("TenantHasVerifiedDomains() = false after verification")
+	}
+
+	second := &TenantDomain{TenantID: tenant.ID, Domain: "mail." + name}
+	mustNot(t, models.Domains.CreateTenantDomain(ctx, second))
+	domains, err := models.Domains.GetTenantDomainsByTenantID(ctx, tenant.ID)
+	mustNot(t, err)
+	if len(domains) != 2 || domains[0].ID != domain.ID || domains[1].ID != second.ID {
+		t.Errorf("GetTenantDomainsByTenantID() = %+v, want both domains ordered by ID", domains)
+	}
+
+	wantErr(t, models.Domains.DeleteTenantDomain(ctx, other.ID, domain.ID), ErrGeneralRecordNotFound)
+	mustNot(t, models.Domains.DeleteTenantDomain(ctx, tenant.ID, domain.ID))
+	_, err = models.Domains.GetTenantDomainByID(ctx, tenant.ID, domain.ID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+	// the domain is free to be verified again
+	mustNot(t, models.Domains.MarkTenantDomainVerified(ctx, claim))
+}
+
+func testCustomFieldStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	tenant := createTestTenant(t, models)
+	other := createTestTenant(t, models)
+
+	field := &CustomField{TenantID: tenant.ID, Key: "grade", Label: "Grade", Type: CustomFieldTypeEnum, Options: []string{"A", "B"}}
+	mustNot(t, models.CustomFields.CreateCustomField(ctx, field))
+	if field.ID == 0 || field.Version != 1 {
+		t.Fatalf("CreateCustomField() set ID %d, version %d", field.ID, field.Version)
+	}
+	wantErr(t, models.CustomFields.CreateCustomField(ctx, &CustomField{TenantID: tenant.ID, Key: "grade", Label: "Again", Type: CustomFieldTypeText}), ErrDuplicateCustomField)
+	// keys are unique per tenant only
+	mustNot(t, models.CustomFields.CreateCustomField(ctx, &CustomField{TenantID: other.ID, Key: "grade", Label: "Grade", Type: CustomFieldTypeText}))
+	text := &CustomField{TenantID: tenant.ID, Key: "hs_code", Label: "HS code", Type: CustomFieldTypeText}
+	mustNot(t, models.CustomFields.CreateCustomField(ctx, text))
+
+	got, err := models.CustomFields.GetCustomFieldByID(ctx, tenant.ID, text.ID)
+	mustNot(t, err)
+	if got.Key != "hs_code" || got.Options == nil || len(got.Options) != 0 {
+		t.Errorf("GetCustomFieldByID() = %+v, want the text field with empty options", got)
+	}
+	_, err = models.CustomFields.GetCustomFieldByID(ctx, other.ID, text.ID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	fields, err := models.CustomFields.GetCustomFieldsByTenantID(ctx, tenant.ID)
+	mustNot(t, err)
+	if len(fields) != 2 || fields[0].ID != field.ID || fields[1].ID != text.ID || !slices.Equal(fields[0].Options, field.Options) {
+		t.Errorf("GetCustomFieldsByTenantID() = %+v, want both fields ordered by ID", fields)
+	}
+
+	// optimistic locking
+	field.Label = "Quality grade"
+	field.Required = true
+	field.Options = []string{"A", "B", "C"}
+	mustNot(t, models.CustomFields.UpdateCustomField(ctx, field, 1))
+	if field.Version != 2 {
+		t.Errorf("version after update = %d, want 2", field.Version)
+	}
+	wantErr(t, models.CustomFields.UpdateCustomField(ctx, field, 1), ErrGeneralEditConflict)
+	got, err = models.CustomFields.GetCustomFieldByID(ctx, tenant.ID, field.ID)
+	mustNot(t, err)
+	if got.Label != "Quality grade" || !got.Required || !slices.Equal(got.Options, field.Options) || got.Type != CustomFieldTypeEnum {
+		t.Errorf("updated field = %+v", got)
+	}
+
+	wantErr(t, models.CustomFields.DeleteCustomField(ctx, other.ID, field.ID), ErrGeneralRecordNotFound)
+	mustNot(t, models.CustomFields.DeleteCustomField(ctx, tenant.ID, field.ID))
+	wantErr(t, models.CustomFields.DeleteCustomField(ctx, tenant.ID, field.ID), ErrGeneralRecordNotFound)
+}
+
+func testTenantExportStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	tenant := createTestTenant(t, models)
+	user := newTestUser(t, tenant.ID)
+	mustNot(t, models.Users.Insert(ctx, user))
+
+	export := &TenantExport{TenantID: tenant.ID, RequestedBy: user.ID, Currency: "EUR"}
+	mustNot(t, models.Exports.CreateTenantExport(ctx, export))
+	if export.ID == 0 || export.Version != 1 || export.Status != ExportStatusPending {
+		t.Fatalf("CreateTenantExport() = %+v", export)
+	}
+	_, err := models.Exports.GetTenantExportByID(ctx, unknownID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	// optimistic locking
+	stale := *export
+	completedAt := time.Now()
+	export.Status = ExportStatusCompleted
+	export.FileKey = export.StorageKey()
+	export.FileSize = 1024
+	export.CompletedAt = &completedAt
+	mustNot(t, models.Exports.UpdateTenantExport(ctx, export))
+	if export.Version != 2 {
+		t.Errorf("version after update = %d, want 2", export.Version)
+	}
+	stale.Status = ExportStatusFailed
+	wantErr(t, models.Exports.UpdateTenantExport(ctx, &stale), ErrGeneralEditConflict)
+
+	got, err := models.Exports.GetTenantExportByID(ctx, export.ID)
+	mustNot(t, err)
+	if got.Status != ExportStatusCompleted || got.FileKey != export.FileKey || got.FileSize != 1024 || got.Currency != "EUR" || got.CompletedAt == nil {
+		t.Errorf("GetTenantExportByID() = %+v", got)
+	}
+}
+
+func testJobStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	word := uniqueWord(t)
+	worker := "worker-" + word
+	// jobs due long ago are claimed before anything else pending in a shared database
+	due := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	enqueueAndClaim := func(t *testing.T) *Job {
+		t.Helper()
+		due = due.Add(time.Second)
+		job, err := NewJob("test."+word, map[string]string{"word": word})
+		mustNot(t, err)
+		job.RunAt = due
+		mustNot(t, models.Jobs.EnqueueJob(ctx, job))
+		claimed, err := models.Jobs.ClaimJobs(ctx, worker, 1)
+		mustNot(t, err)
+		if len(claimed) != 1 || claimed[0].ID != job.ID {
+			t.Fatalf("ClaimJobs() = %v, want job %d", claimed, job.ID)
+		}
+		return claimed[0]
+	}
+
+	delayed, err := NewJob("test."+word, nil)
+	mustNot(t, err)
+	delayed.RunAt = time.Now().Add(time.Hour)
+	mustNot(t, models.Jobs.EnqueueJob(ctx, delayed))
+	if delayed.ID == 0 || delayed.Status != JobStatusPending || delayed.MaxAttempts != DefaultJobMaxAttempts {
+		t.Fatalf("EnqueueJob() = %+v", delayed)
+	}
+
+	// complete
+	job := enqueueAndClaim(t)
+	var payload map[string]string
+	mustNot(t, json.Unmarshal(job.Payload, &payload))
+	if job.Status != JobStatusRunning || job.Attempts != 1 || job.LockedBy != worker || payload["word"] != word {
+		t.Errorf("claimed job = %+v", job)
+	}
+	wantErr(t, models.Jobs.CompleteJob(ctx, job.ID, "another-worker"), ErrJobLockLost)
+	mustNot(t, models.Jobs.CompleteJob(ctx, job.ID, worker))
+	wantErr(t, models.Jobs.CompleteJob(ctx, job.ID, worker), ErrJobLockLost)
+	got, err := models.Jobs.GetJobByID(ctx, job.ID)
+	mustNot(t, err)
+	if got.Status != JobStatusCompleted || got.CompletedAt == nil || got.LockedBy != "" {
+		t.Errorf("completed job = %+v", got)
+	}
+
+	// retry later
+	job = enqueueAndClaim(t)
+	retryAt := time.Now().Add(time.Hour)
+	mustNot(t, models.Jobs.RetryJob(ctx, job.ID, worker, retryAt, "smtp unavailable"))
+	got, err = models.Jobs.GetJobByID(ctx, job.ID)
+	mustNot(t, err)
+	if got.Status != JobStatusPending || got.LastError != "smtp unavailable" || got.Attempts != 1 || got.RunAt.Sub(retryAt).Abs() > time.Second {
+		t.Errorf("retried job = %+v", got)
+	}
+
+	// dead letter
+	job = enqueueAndClaim(t)
+	mustNot(t, models.Jobs.BuryJob(ctx, job.ID, worker, "invalid payload"))
+	got, err = models.Jobs.GetJobByID(ctx, job.ID)
+	mustNot(t, err)
+	if got.Status != JobStatusDead || got.LastError != "invalid payload" {
+		t.Errorf("buried job = %+v", got)
+	}
+
+	// a crashed worker's job goes back to the queue and the worker loses it
+	job = enqueueAndClaim(t)
+	requeued, err := models.Jobs.RequeueStaleJobs(ctx, time.Now().Add(time.Minute))
+	mustNot(t, err)
+	if requeued < 1 {
+		t.Errorf("RequeueStaleJobs() = %d, want at least 1", requeued)
+	}
+	got, err = models.Jobs.GetJobByID(ctx, job.ID)
+	mustNot(t, err)
+	if got.Status != JobStatusPending || got.LockedBy != "" {
+		t.Errorf("requeued job = %+v", got)
+	}
+	wantErr(t, models.Jobs.CompleteJob(ctx, job.ID, worker), ErrJobLockLost)
+	claimed, err := models.Jobs.ClaimJobs(ctx, "replacement-"+word, 1)
+	mustNot(t, err)
+	if len(claimed) != 1 || claimed[0].ID != job.ID || claimed[0].Attempts != 2 {
+		t.Fatalf("ClaimJobs() after requeue = %v, want job %d on its second attempt", claimed, job.ID)
+	}
+	mustNot(t, models.Jobs.CompleteJob(ctx, job.ID, "replacement-"+word))
+
+	got, err = models.Jobs.GetJobByID(ctx, delayed.ID)
+	mustNot(t, err)
+	if got.Status != JobStatusPending || got.Attempts != 0 {
+		t.Errorf("delayed job = %+v, want it left pending", got)
+	}
+	_, err = models.Jobs.GetJobByID(ctx, unknownID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+}
+
+func testEmailOutboxStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	tenant := createTestTenant(t, models)
+	recipient := uniqueWord(t) + "@example.test"
+	newEmail := func(t *testing.T) *OutboxEmail {
+		t.Helper()
+		email, err := NewOutboxEmail(tenant.ID, recipient, "fr", "user_welcome.tmpl", map[string]any{"userID": 7})
+		mustNot(t, err)
+		mustNot(t, models.Emails.CreateOutboxEmail(ctx, email))
+		return email
+	}
+
+	sent := newEmail(t)
+	if sent.ID == 0 || sent.Status != EmailStatusQueued || sent.Attempts != 0 {
+		t.Fatalf("CreateOutboxEmail() = %+v", sent)
+	}
+	mustNot(t, models.Emails.RecordOutboxEmailAttempt(ctx, sent.ID, EmailAttempt{Status: EmailStatusQueued, Subject: "Bienvenue", LastError: "smtp unavailable"}))
+	mustNot(t, models.Emails.RecordOutboxEmailAttempt(ctx, sent.ID, EmailAttempt{Status: EmailStatusSent, Subject: "Bienvenue", ProviderMessageID: "<1@leadhub.test>"}))
+	got, err := models.Emails.GetOutboxEmailByID(ctx, sent.ID)
+	mustNot(t, err)
+	if got.Status != EmailStatusSent || got.Attempts != 2 || got.SentAt == nil || got.LastError != "" ||
+		got.Subject != "Bienvenue" || got.ProviderMessageID != "<1@leadhub.test>" || got.Locale != "fr" {
+		t.Errorf("sent email = %+v", got)
+	}
+	var templateData map[string]int
+	if err := json.Unmarshal(got.Data, &templateData); err != nil || templateData["userID"] != 7 {
+		t.Errorf("template data = %s", got.Data)
+	}
+	wantErr(t, models.Emails.RequeueOutboxEmail(ctx, sent.ID), ErrOutboxEmailNotFailed)
+
+	failed := newEmail(t)
+	mustNot(t, models.Emails.RecordOutboxEmailAttempt(ctx, failed.ID, EmailAttempt{Status: EmailStatusFailed, LastError: "mailbox unavailable"}))
+	emails, metadata, err := models.Emails.AdminGetAllOutboxEmails(ctx, tenant.ID, EmailStatusFailed, strings.ToUpper(recipient), Filters{Page: 1, PageSize: 10})
+	mustNot(t, err)
+	if len(emails) != 1 || emails[0].ID != failed.ID || metadata.TotalRecords != 1 {
+		t.Errorf("failed emails = %+v", emails)
+	}
+	mustNot(t, models.Emails.RequeueOutboxEmail(ctx, failed.ID))
+	got, err = models.Emails.GetOutboxEmailByID(ctx, failed.ID)
+	mustNot(t, err)
+	if got.Status != EmailStatusQueued || got.LastError != "" || got.Attempts != 1 {
+		t.Errorf("requeued email = %+v", got)
+	}
+
+	// newest first
+	emails, metadata, err = models.Emails.AdminGetAllOutboxEmails(ctx, tenant.ID, "", "", Filters{Page: 1, PageSize: 1})
+	mustNot(t, err)
+	if len(emails) != 1 || emails[0].ID != failed.ID || metadata.TotalRecords != 2 {
+		t.Errorf("AdminGetAllOutboxEmails() = %+v, %+v", emails, metadata)
+	}
+	_, _, err = models.Emails.AdminGetAllOutboxEmails(ctx, tenant.ID, EmailStatusFailed, "", Filters{Page: 1, PageSize: 10})
+	wantErr(t, err, ErrGeneralRecordNotFound)
+	_, err = models.Emails.GetOutboxEmailByID(ctx, unknownID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+	wantErr(t, models.Emails.RecordOutboxEmailAttempt(ctx, unknownID, EmailAttempt{Status: EmailStatusSent}), ErrGeneralRecordNotFound)
+}
+
+func testWebhookStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	tenant := createTestTenant(t, models)
+	other := createTestTenant(t, models)
+	endpoint := &WebhookEndpoint{
+		TenantID:    tenant.ID,
+		URL:         "https://hooks.acme.test/leadhub",
+		Description: "CRM sync",
+		EventTypes:  []string{WebhookEventTradeLeadCreated, WebhookEventUserActivated},
+	}
+	mustNot(t, models.Webhooks.CreateWebhookEndpoint(ctx, endpoint))
+	if endpoint.ID == 0 || !endpoint.Enabled || endpoint.Version != 1 || !strings.HasPrefix(endpoint.Secret, webhookSecretPrefix) {
+		t.Fatalf("CreateWebhookEndpoint() = %+v", endpoint)
+	}
+	got, err := models.Webhooks.GetWebhookEndpointByID(ctx, tenant.ID, endpoint.ID)
+	mustNot(t, err)
+	if got.Secret != endpoint.Secret || !slices.Equal(got.EventTypes, endpoint.EventTypes) {
+		t.Errorf("GetWebhookEndpointByID() = %+v", got)
+	}
+	_, err = models.Webhooks.GetWebhookEndpointByID(ctx, other.ID, endpoint.ID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	subscribed, err := models.Webhooks.GetSubscribedWebhookEndpoints(ctx, tenant.ID, WebhookEventUserActivated)
+	mustNot(t, err)
+	if len(subscribed) != 1 || subscribed[0].ID != endpoint.ID {
+		t.Errorf("subscribed endpoints = %+v", subscribed)
+	}
+	for _, eventType := range []string{WebhookEventUserCreated, WebhookEventTradeLeadCreated} {
+		tenantID := tenant.ID
+		if eventType == WebhookEventTradeLeadCreated {
+			tenantID = other.ID
+		}
+		subscribed, err := models.Webhooks.GetSubscribedWebhookEndpoints(ctx, tenantID, eventType)
+		mustNot(t, err)
+		if len(subscribed) != 0 {
+			t.Errorf("GetSubscribedWebhookEndpoints(%d, %s) = %+v", tenantID, eventType, subscribed)
+		}
+	}
+
+	// the second failure in a row disables the endpoint, a success resets the count
+	for i, succeeded := range []bool{false, true, false, false} {
+		enabled, err := models.Webhooks.RecordWebhookEndpointResult(ctx, endpoint.ID, succeeded, 2)
+		mustNot(t, err)
+		if want := i < 3; enabled != want {
+			t.Errorf("result %d: enabled = %v, want %v", i, enabled, want)
+		}
+	}
+	got, err = models.Webhooks.GetWebhookEndpointByID(ctx, tenant.ID, endpoint.ID)
+	mustNot(t, err)
+	if got.Enabled || got.ConsecutiveFailures != 2 || got.DisabledReason == "" || got.Version != 2 {
+		t.Errorf("disabled endpoint = %+v", got)
+	}
+	subscribed, err = models.Webhooks.GetSubscribedWebhookEndpoints(ctx, tenant.ID, WebhookEventUserActivated)
+	mustNot(t, err)
+	if len(subscribed) != 0 {
+		t.Errorf("a disabled endpoint is subscribed: %+v", subscribed)
+	}
+	wantErr(t, models.Webhooks.UpdateWebhookEndpoint(ctx, endpoint, endpoint.Version), ErrGeneralEditConflict)
+	got.Enabled = true
+	got.EventTypes = []string{WebhookEventUserCreated}
+	mustNot(t, models.Webhooks.UpdateWebhookEndpoint(ctx, got, got.Version))
+	if got.Version != 3 || got.ConsecutiveFailures != 0 || got.DisabledReason != "" {
+		t.Errorf("re-enabled endpoint = %+v", got)
+	}
+
+	payload := json.RawMessage(`{"id": "evt_1", "type": "user.created"}`)
+	newDelivery := func(t *testing.T) *WebhookDelivery {
+		t.Helper()
+		delivery := &WebhookDelivery{EndpointID: endpoint.ID, TenantID: tenant.ID, EventID: "evt_1", EventType: WebhookEventUserCreated, Payload: payload}
+		mustNot(t, models.Webhooks.CreateWebhookDelivery(ctx, delivery))
+		return delivery
+	}
+	failed := newDelivery(t)
+	if failed.ID == 0 || failed.Status != WebhookDeliveryPending || failed.Attempts != 0 {
+		t.Fatalf("CreateWebhookDelivery() = %+v", failed)
+	}
+	mustNot(t, models.Webhooks.RecordWebhookDeliveryAttempt(ctx, failed.ID, WebhookAttempt{Status: WebhookDeliveryFailed, ResponseStatus: 500, ResponseBody: "oops", LastError: "500 Internal Server Error", Duration: 42 * time.Millisecond}))
+	succeeded := newDelivery(t)
+	mustNot(t, models.Webhooks.RecordWebhookDeliveryAttempt(ctx, succeeded.ID, WebhookAttempt{Status: WebhookDeliverySucceeded, ResponseStatus: 204}))
+	gotDelivery, err := models.Webhooks.GetWebhookDeliveryByID(ctx, failed.ID)
+	mustNot(t, err)
+	var decoded map[string]string
+	if err := json.Unmarshal(gotDelivery.Payload, &decoded); err != nil || decoded["id"] != "evt_1" {
+		t.Errorf("payload = %s", gotDelivery.Payload)
+	}
+	if gotDelivery.Status != WebhookDeliveryFailed || gotDelivery.Attempts != 1 || gotDelivery.ResponseStatus != 500 ||
+		gotDelivery.ResponseBody != "oops" || gotDelivery.DurationMS != 42 || gotDelivery.DeliveredAt != nil {
+		t.Errorf("failed delivery = %+v", gotDelivery)
+	}
+
+	// newest first
+	deliveries, metadata, err := models.Webhooks.GetWebhookDeliveriesByEndpointID(ctx, endpoint.ID, "", Filters{Page: 1, PageSize: 1})
+	mustNot(t, err)
+	if len(deliveries) != 1 || deliveries[0].ID != succeeded.ID || deliveries[0].DeliveredAt == nil || metadata.TotalRecords != 2 {
+		t.Errorf("GetWebhookDeliveriesByEndpointID() = %+v, %+v", deliveries, metadata)
+	}
+	deliveries, _, err = models.Webhooks.GetWebhookDeliveriesByEndpointID(ctx, endpoint.ID, WebhookDeliveryFailed, Filters{Page: 1, PageSize: 10})
+	mustNot(t, err)
+	if len(deliveries) != 1 || deliveries[0].ID != failed.ID {
+		t.Errorf("failed deliveries = %+v", deliveries)
+	}
+	_, _, err = models.Webhooks.GetWebhookDeliveriesByEndpointID(ctx, endpoint.ID, WebhookDeliveryPending, Filters{Page: 1, PageSize: 10})
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	// deleting the endpoint removes its deliveries
+	wantErr(t, models.Webhooks.DeleteWebhookEndpoint(ctx, other.ID, endpoint.ID), ErrGeneralRecordNotFound)
+	mustNot(t, models.Webhooks.DeleteWebhookEndpoint(ctx, tenant.ID, endpoint.ID))
+	_, err = models.Webhooks.GetWebhookDeliveryByID(ctx, failed.ID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+	_, err = models.Webhooks.RecordWebhookEndpointResult(ctx, endpoint.ID, true, 2)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+	wantErr(t, models.Webhooks.RecordWebhookDeliveryAttempt(ctx, unknownID, WebhookAttempt{Status: WebhookDeliverySucceeded}), ErrGeneralRecordNotFound)
+}
+
+func testTradeLeadAnalytics(t *testing.T, models Models) {
+	ctx := context.Background()
+	tenant := createTestTenant(t, models)
+	otherTenant := createTestTenant(t, models)
+	for i, value := range []int64{100, 250, 400} {
+		lead := &TradeLead{Title: "Lead " + uniqueWord(t), Value: decimal.NewFromInt(value), Currency: "USD"}
+		mustNot(t, models.TradeLeads.CreateTradeLead(ctx, tenant.ID, lead))
+		if i > 0 {
+			mustNot(t, models.TradeLeads.AdminUpdateTradeLeadStatus(ctx, lead.ID, lead.Version, lead))
+		}
+	}
+	mustNot(t, models.TradeLeads.CreateTradeLead(ctx, otherTenant.ID, &TradeLead{Title: "Foreign", Value: decimal.NewFromInt(75), Currency: "USD"}))
+
+	now := time.Now()
+	filter := TradeLeadStatsFilter{TenantIDs: []int64{tenant.ID}, From: now.Add(-time.Hour), To: now.Add(time.Hour), Interval: TradeLeadStatsIntervalDay,
+		Converter: NewCurrencyConverter("USD", now, nil)}
+	analytics, err := models.TradeLeads.GetTradeLeadAnalytics(ctx, filter)
+	mustNot(t, err)
+	if analytics.TotalLeads != 3 || !analytics.TotalValue.Equal(decimal.NewFromInt(750)) || analytics.VerifiedLeads != 2 ||
+		analytics.ConversionRate != 0.6667 || analytics.AverageSecondsToVerify == nil {
+		t.Errorf("GetTradeLeadAnalytics() = %+v", analytics)
+	}
+	for _, status := range analytics.ByStatus {
+		if want := map[string]int64{"new": 1, "verified": 2}[status.Status]; status.Leads != want {
+			t.Errorf("%s leads = %d, want %d", status.Status, status.Leads, want)
+		}
+	}
+	if len(analytics.ByTenant) != 1 || analytics.ByTenant[0].TenantID != tenant.ID {
+		t.Errorf("by tenant = %+v, want only the filtered tenant", analytics.ByTenant)
+	}
+	var bucketed int64
+	for _, bucket := range analytics.TimeSeries {
+		bucketed += bucket.Leads
+	}
+	if len(analytics.TimeSeries) == 0 || bucketed != 3 {
+		t.Errorf("time series = %+v, want the 3 leads bucketed", analytics.TimeSeries)
+	}
+
+	// without tenants every tenant is covered, leads outside the range are not
+	filter.TenantIDs = nil
+	analytics, err = models.TradeLeads.GetTradeLeadAnalytics(ctx, filter)
+	mustNot(t, err)
+	if analytics.TotalLeads < 4 || len(analytics.ByTenant) < 2 {
+		t.Errorf("GetTradeLeadAnalytics() of every tenant = %d leads of %d tenants", analytics.TotalLeads, len(analytics.ByTenant))
+	}
+	filter.From, filter.To = now.Add(time.Hour), now.Add(2*time.Hour)
+	analytics, err = models.TradeLeads.GetTradeLeadAnalytics(ctx, filter)
+	mustNot(t, err)
+	if analytics.TotalLeads != 0 || analytics.AverageSecondsToVerify != nil || analytics.ConversionRate != 0 {
+		t.Errorf("GetTradeLeadAnalytics() of a range without leads = %+v", analytics)
+	}
+}
+
+func testTradeLeadEventStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	lead := &TradeLead{ID: 1, TenantID: createTestTenant(t, models).ID, Status: "new", Version: 1}
+	var previous int64
+	for range 3 {
+		event, err := NewTradeLeadEvent(TradeLeadEventCreated, lead)
+		mustNot(t, err)
+		mustNot(t, models.LeadEvents.PublishTradeLeadEvent(ctx, event))
+		if event.ID <= previous {
+			t.Fatalf("event ID = %d, want it above %d", event.ID, previous)
+		}
+		previous = event.ID
+	}
+}
+
+func testExchangeRateStore(t *testing.T, models Models) {
+	ctx := context.Background()
+	// far in the future, so rates saved by other runs against the same database don't interfere
+	day := time.Date(2999, time.January, 10, 0, 0, 0, 0, time.UTC)
+	older := &ExchangeRate{BaseCurrency: "USD", QuoteCurrency: "KES", Rate: decimal.NewFromInt(128), RateDate: day.AddDate(0, 0, -3)}
+	rate := &ExchangeRate{BaseCurrency: "USD", QuoteCurrency: "KES", Rate: decimal.NewFromInt(130), RateDate: day}
+	mustNot(t, models.ExchangeRates.SaveExchangeRate(ctx, older))
+	mustNot(t, models.ExchangeRates.SaveExchangeRate(ctx, rate))
+	// saving the same pair and date again replaces the rate
+	replaced := &ExchangeRate{BaseCurrency: "USD", QuoteCurrency: "KES", Rate: decimal.RequireFromString("129.5"), RateDate: day}
+	mustNot(t, models.ExchangeRates.SaveExchangeRate(ctx, replaced))
+	if replaced.ID != rate.ID {
+		t.Errorf("SaveExchangeRate() of the same pair and date = ID %d, want %d", replaced.ID, rate.ID)
+	}
+
+	findRate := func(date time.Time) *ExchangeRate {
+		rates, err := models.ExchangeRates.GetExchangeRatesOnDate(ctx, date)
+		mustNot(t, err)
+		for _, rate := range rates {
+			if rate.BaseCurrency == "USD" && rate.QuoteCurrency == "KES" {
+				return rate
+			}
+		}
+		return nil
+	}
+	if got := findRate(day.AddDate(0, 0, 1)); got == nil || !got.Rate.Equal(replaced.Rate) || !got.RateDate.Equal(day) {
+		t.Errorf("rate in effect the day after = %+v, want the replaced rate", got)
+	}
+	if got := findRate(day.AddDate(0, 0, -1)); got == nil || !got.Rate.Equal(older.Rate) {
+		t.Errorf("rate in effect the day before = %+v, want the older rate", got)
+	}
+}
+
+func testStoreTransactions(t *testing.T, models Models) {
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+	var tenant *Tenant
+	err := models.RunInTx(ctx, func(tx Models) error {
+		tenant = &Tenant{Name: "Rolled back " + uniqueWord(t), ContactEmail: "ops@acme.test"}
+		if err := tx.Tenants.CreateTenant(ctx, tenant); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	wantErr(t, err, errFailed)
+	_, err = models.Tenants.GetTenantByID(ctx, tenant.ID)
+	wantErr(t, err, ErrGeneralRecordNotFound)
+
+	err = models.RunInTx(ctx, func(tx Models) error {
+		tenant = &Tenant{Name: "Committed " + uniqueWord(t), ContactEmail: "ops@acme.test"}
+		return tx.Tenants.CreateTenant(ctx, tenant)
+	})
+	mustNot(t, err)
+	_, err = models.Tenants.GetTenantByID(ctx, tenant.ID)
+	mustNot(t, err)
+}
+
+// uniqueWord() returns a random word, keeping names unique and searches precise on a
+// shared database.
+func uniqueWord(t *testing.T) string {
+	t.Helper()
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return "w" + hex.EncodeToString(b)
+}
+
+func createTestTenant(t *testing.T, models Models) *Tenant {
+	t.Helper()
+	tenant := &Tenant{Name: "Tenant " + uniqueWord(t), ContactEmail: "ops@tenant.test"}
+	mustNot(t, models.Tenants.CreateTenant(context.Background(), tenant))
+	return tenant
+}
+
+func newTestUser(t *testing.T, tenantID int64) *User {
+	t.Helper()
+	user := &User{TenantID: tenantID, Name: "Alice", Email: uniqueWord(t) + "@example.test"}
+	// a cheap hash keeps the tests fast, Set() always uses cost 12
+	user.Password.hash = mustHash(t, "pa55word123")
+	return user
+}
+
+func mustHash(t *testing.T, plaintext string) []byte {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(plaintext), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func leadIDs(leads []*TradeLead) []int64 {
+	ids := []int64{}
+	for _, lead := range leads {
+		ids = append(ids, lead.ID)
+	}
+	return ids
+}
+
+func mustNot(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func wantErr(t *testing.T, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Errorf("error = %v, want %v", err, want)
+	}
+}
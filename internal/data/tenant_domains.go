@@ -0,0 +1,240 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/database"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+type TenantDomainModel struct {
+	DB      *database.Queries
+	Timeout time.Duration
+}
+
+const (
+	DefaultTenantDomainDBContextTimeout = 5 * time.Second
+)
+
+var (
+	ErrDuplicateTenantDomain = errors.New("domain already added to this tenant")
+	ErrDomainAlreadyVerified = errors.New("domain already verified by another tenant")
+)
+
+var domainRX = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// TenantDomain is an email domain claimed by a tenant. Once verified, users registering
+// with an address on the domain join the tenant automatically.
+type TenantDomain struct {
+	ID                int64      `json:"id"`
+	TenantID          int64      `json:"tenant_id"`
+	Domain            string     `json:"domain"`
+	VerificationToken string     `json:"verification_token"`
+	Verified          bool       `json:"verified"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty"`
+	Version           int32      `json:"version"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// NormalizeDomain() lowercases a domain and strips surrounding whitespace and dots.
+func NormalizeDomain(domain string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+// EmailDomain() returns the normalized domain part of an email address.
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return NormalizeDomain(email[at+1:])
+}
+
+// ValidateTenantDomain validates a domain name as entered by a tenant owner.
+func ValidateTenantDomain(v *validator.Validator, domain string) {
+	v.Check(domain != "", "domain", "must be provided")
+	v.Check(len(domain) <= 253, "domain", "must not be more than 253 characters long")
+	v.Check(validator.Matches(domain, domainRX), "domain", "must be a valid domain name such as example.com")
+}
+
+// CreateTenantDomain() adds an unverified domain to a tenant along with a freshly
+// generated verification token.
+func (m TenantDomainModel) CreateTenantDomain(ctx context.Context, domain *TenantDomain) error {
+	ctx, span := startSpan(ctx, "TenantDomainModel.CreateTenantDomain")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	token, err := generateVerificationToken()
+	if err != nil {
+		return err
+	}
+	domain.VerificationToken = token
+	newDomain, err := m.DB.CreateTenantDomain(ctx, database.CreateTenantDomainParams{
+		TenantID:          domain.TenantID,
+		Domain:            domain.Domain,
+		VerificationToken: domain.VerificationToken,
+	})
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "tenant_domains_tenant_domain_key"):
+			return ErrDuplicateTenantDomain
+		default:
+			return err
+		}
+	}
+	domain.ID = newDomain.ID
+	domain.Verified = newDomain.Verified
+	domain.Version = newDomain.Version
+	domain.CreatedAt = newDomain.CreatedAt
+	domain.UpdatedAt = newDomain.UpdatedAt
+	return nil
+}
+
+// GetTenantDomainsByTenantID() retrieves all domains claimed by a tenant.
+func (m TenantDomainModel) GetTenantDomainsByTenantID(ctx context.Context, tenantID int64) ([]*TenantDomain, error) {
+	ctx, span := startSpan(ctx, "TenantDomainModel.GetTenantDomainsByTenantID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	domains, err := m.DB.GetTenantDomainsByTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	tenantDomains := []*TenantDomain{}
+	for _, domain := range domains {
+		tenantDomains = append(tenantDomains, populateTenantDomain(domain))
+	}
+	return tenantDomains, nil
+}
+
+// GetTenantDomainByID() retrieves a single domain belonging to the tenant.
+func (m TenantDomainModel) GetTenantDomainByID(ctx context.Context, tenantID, domainID int64) (*TenantDomain, error) {
+	ctx, span := startSpan(ctx, "TenantDomainModel.GetTenantDomainByID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	domain, err := m.DB.GetTenantDomainByID(ctx, database.GetTenantDomainByIDParams{
+		ID:       domainID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrGeneralRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return populateTenantDomain(domain), nil
+}
+
+// GetVerifiedTenantDomain() returns the verified domain record for a domain name, or
+// ErrGeneralRecordNotFound if no tenant has verified it.
+func (m TenantDomainModel) GetVerifiedTenantDomain(ctx context.Context, domain string) (*TenantDomain, error) {
+	ctx, span := startSpan(ctx, "TenantDomainModel.GetVerifiedTenantDomain")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	tenantDomain, err := m.DB.GetVerifiedTenantDomainByDomain(ctx, NormalizeDomain(domain))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrGeneralRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return populateTenantDomain(tenantDomain), nil
+}
+
+// TenantHasVerifiedDomains() reports whether the tenant has verified at least one domain.
+// Such tenants only accept registrations from email addresses on their domains.
+func (m TenantDomainModel) TenantHasVerifiedDomains(ctx context.Context, tenantID int64) (bool, error) {
+	ctx, span := startSpan(ctx, "TenantDomainModel.TenantHasVerifiedDomains")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	return m.DB.TenantHasVerifiedDomains(ctx, tenantID)
+}
+
+// MarkTenantDomainVerified() records a successful DNS verification of the domain.
+func (m TenantDomainModel) MarkTenantDomainVerified(ctx context.Context, domain *TenantDomain) error {
+	ctx, span := startSpan(ctx, "TenantDomainModel.MarkTenantDomainVerified")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	verifiedDomain, err := m.DB.VerifyTenantDomain(ctx, database.VerifyTenantDomainParams{
+		ID:      domain.ID,
+		Version: domain.Version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrGeneralEditConflict
+		case strings.Contains(err.Error(), "tenant_domains_verified_domain_key"):
+			return ErrDomainAlreadyVerified
+		default:
+			return err
+		}
+	}
+	domain.Verified = verifiedDomain.Verified
+	if verifiedDomain.VerifiedAt.Valid {
+		domain.VerifiedAt = &verifiedDomain.VerifiedAt.Time
+	}
+	domain.Version = verifiedDomain.Version
+	domain.UpdatedAt = verifiedDomain.UpdatedAt
+	return nil
+}
+
+// DeleteTenantDomain() removes a domain from the tenant.
+func (m TenantDomainModel) DeleteTenantDomain(ctx context.Context, tenantID, domainID int64) error {
+	ctx, span := startSpan(ctx, "TenantDomainModel.DeleteTenantDomain")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.DeleteTenantDomain(ctx, database.DeleteTenantDomainParams{
+		ID:       domainID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrGeneralRecordNotFound
+	}
+	return nil
+}
+
+// generateVerificationToken() returns a random hex token for the DNS TXT record.
+func generateVerificationToken() (string, error) {
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(randomBytes), nil
+}
+
+func populateTenantDomain(domainRow database.TenantDomain) *TenantDomain {
+	domain := &TenantDomain{
+		ID:                domainRow.ID,
+		TenantID:          domainRow.TenantID,
+		Domain:            domainRow.Domain,
+		VerificationToken: domainRow.VerificationToken,
+		Verified:          domainRow.Verified,
+		Version:           domainRow.Version,
+		CreatedAt:         domainRow.CreatedAt,
+		UpdatedAt:         domainRow.UpdatedAt,
+	}
+	if domainRow.VerifiedAt.Valid {
+		domain.VerifiedAt = &domainRow.VerifiedAt.Time
+	}
+	return domain
+}
@@ -0,0 +1,44 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+func TestEmailDomain(t *testing.T) {
+	tests := map[string]string{
+		"jane@TradeHub.co.ke":  "tradehub.co.ke",
+		"john.doe@example.com": "example.com",
+		"odd@name@example.org": "example.org",
+		"no-at-sign":           "",
+	}
+	for email, want := range tests {
+		if got := EmailDomain(email); got != want {
+			t.Errorf("EmailDomain(%q) = %q, want %q", email, got, want)
+		}
+	}
+}
+
+func TestValidateTenantDomain(t *testing.T) {
+	tests := []struct {
+		domain    string
+		wantValid bool
+	}{
+		{domain: "tradehub.co.ke", wantValid: true},
+		{domain: "mail.example.com", wantValid: true},
+		{domain: "", wantValid: false},
+		{domain: "localhost", wantValid: false},
+		{domain: "bad_domain.com", wantValid: false},
+		{domain: "-example.com", wantValid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			v := validator.New()
+			ValidateTenantDomain(v, tt.domain)
+			if v.Valid() != tt.wantValid {
+				t.Errorf("ValidateTenantDomain(%q) valid = %v, want %v, errors: %v", tt.domain, v.Valid(), tt.wantValid, v.Errors)
+			}
+		})
+	}
+}
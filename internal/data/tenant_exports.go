@@ -0,0 +1,143 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/database"
+)
+
+type TenantExportModel struct {
+	DB      *database.Queries
+	Timeout time.Duration
+}
+
+const (
+	DefaultTenantExportDBContextTimeout = 5 * time.Second
+)
+
+// Define constants for the export job statuses.
+const (
+	ExportStatusPending   = "pending"
+	ExportStatusRunning   = "running"
+	ExportStatusCompleted = "completed"
+	ExportStatusFailed    = "failed"
+)
+
+// TenantExport represents a single data export (takeout) job for a tenant. Trade lead values
+// are also exported converted to Currency, when set.
+type TenantExport struct {
+	ID          int64      `json:"id"`
+	TenantID    int64      `json:"tenant_id"`
+	RequestedBy int64      `json:"requested_by"`
+	Currency    string     `json:"currency,omitempty"`
+	Status      string     `json:"status"`
+	FileKey     string     `json:"-"`
+	FileSize    int64      `json:"file_size"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Version     int32      `json:"version"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// StorageKey() returns the key under which the export archive is stored.
+func (e *TenantExport) StorageKey() string {
+	return fmt.Sprintf("exports/%d/%d.zip", e.TenantID, e.ID)
+}
+
+// CreateTenantExport() records a new pending export job for the tenant.
+func (m TenantExportModel) CreateTenantExport(ctx context.Context, export *TenantExport) error {
+	ctx, span := startSpan(ctx, "TenantExportModel.CreateTenantExport")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	newExport, err := m.DB.CreateTenantExport(ctx, database.CreateTenantExportParams{
+		TenantID:    export.TenantID,
+		RequestedBy: export.RequestedBy,
+		Currency:    export.Currency,
+	})
+	if err != nil {
+		return err
+	}
+	export.ID = newExport.ID
+	export.Status = newExport.Status
+	export.Version = newExport.Version
+	export.CreatedAt = newExport.CreatedAt
+	export.UpdatedAt = newExport.UpdatedAt
+	return nil
+}
+
+// GetTenantExportByID() retrieves an export job by its ID.
+func (m TenantExportModel) GetTenantExportByID(ctx context.Context, id int64) (*TenantExport, error) {
+	ctx, span := startSpan(ctx, "TenantExportModel.GetTenantExportByID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	export, err := m.DB.GetTenantExportByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrGeneralRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return populateTenantExport(export), nil
+}
+
+// UpdateTenantExport() saves the status, file details and error of an export job,
+// using the version for optimistic locking.
+func (m TenantExportModel) UpdateTenantExport(ctx context.Context, export *TenantExport) error {
+	ctx, span := startSpan(ctx, "TenantExportModel.UpdateTenantExport")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	completedAt := sql.NullTime{}
+	if export.CompletedAt != nil {
+		completedAt = sql.NullTime{Time: *export.CompletedAt, Valid: true}
+	}
+	updatedExport, err := m.DB.UpdateTenantExport(ctx, database.UpdateTenantExportParams{
+		ID:          export.ID,
+		Version:     export.Version,
+		Status:      export.Status,
+		FileKey:     export.FileKey,
+		FileSize:    export.FileSize,
+		Error:       export.Error,
+		CompletedAt: completedAt,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrGeneralEditConflict
+		default:
+			return err
+		}
+	}
+	export.Version = updatedExport.Version
+	export.UpdatedAt = updatedExport.UpdatedAt
+	return nil
+}
+
+func populateTenantExport(exportRow database.TenantExport) *TenantExport {
+	export := &TenantExport{
+		ID:          exportRow.ID,
+		TenantID:    exportRow.TenantID,
+		RequestedBy: exportRow.RequestedBy,
+		Currency:    exportRow.Currency,
+		Status:      exportRow.Status,
+		FileKey:     exportRow.FileKey,
+		FileSize:    exportRow.FileSize,
+		Error:       exportRow.Error,
+		Version:     exportRow.Version,
+		CreatedAt:   exportRow.CreatedAt,
+		UpdatedAt:   exportRow.UpdatedAt,
+	}
+	if exportRow.CompletedAt.Valid {
+		export.CompletedAt = &exportRow.CompletedAt.Time
+	}
+	return export
+}
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBuildTenantTreeAndRollUp(t *testing.T) {
+	groupID, kenyaID := int64(1), int64(2)
+	group := &Tenant{ID: groupID, Name: "Trading Group"}
+	descendants := []*Tenant{
+		{ID: 2, Name: "Group Kenya", ParentID: &groupID},
+		{ID: 3, Name: "Group Uganda", ParentID: &groupID},
+		{ID: 4, Name: "Group Mombasa", ParentID: &kenyaID},
+	}
+	buildTenantTree([]*Tenant{group}, descendants)
+
+	if len(group.Children) != 2 {
+		t.Fatalf("group has %d children, want 2", len(group.Children))
+	}
+	visited := []int64{}
+	group.Walk(func(tenant *Tenant) { visited = append(visited, tenant.ID) })
+	if len(visited) != 4 || visited[0] != groupID {
+		t.Fatalf("Walk() visited %v, want all 4 tenants starting with the group", visited)
+	}
+
+	stats := map[int64]*TradeStats{
+		1: {TotalLeads: decimal.NewFromInt(1), VerifiedLeads: decimal.NewFromInt(1), TotalVerifiedValue: decimal.NewFromInt(100)},
+		2: {TotalLeads: decimal.NewFromInt(2), VerifiedLeads: decimal.Zero, TotalVerifiedValue: decimal.Zero},
+		4: {TotalLeads: decimal.NewFromInt(5), VerifiedLeads: decimal.NewFromInt(3), TotalVerifiedValue: decimal.NewFromInt(250)},
+	}
+	rollUp := RollUpTradeStats(group, stats)
+
+	if !rollUp.Stats.TotalLeads.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("group own leads = %s, want 1", rollUp.Stats.TotalLeads)
+	}
+	if !rollUp.Total.TotalLeads.Equal(decimal.NewFromInt(8)) {
+		t.Errorf("group total leads = %s, want 8", rollUp.Total.TotalLeads)
+	}
+	if !rollUp.Total.TotalVerifiedValue.Equal(decimal.NewFromInt(350)) {
+		t.Errorf("group total verified value = %s, want 350", rollUp.Total.TotalVerifiedValue)
+	}
+	kenya := rollUp.Children[0]
+	if kenya.TenantID != kenyaID || !kenya.Total.TotalLeads.Equal(decimal.NewFromInt(7)) {
+		t.Errorf("kenya total leads = %s, want 7", kenya.Total.TotalLeads)
+	}
+	if !rollUp.Children[1].Total.TotalLeads.IsZero() {
+		t.Errorf("uganda total leads = %s, want 0", rollUp.Children[1].Total.TotalLeads)
+	}
+}
@@ -0,0 +1,183 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"regexp"
+	"time"
+
+	// embed the IANA time zone database so timezone settings validate on minimal images
+	_ "time/tzdata"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/database"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+type TenantSettingsModel struct {
+	DB      *database.Queries
+	Timeout time.Duration
+}
+
+const (
+	DefaultTenantSettingsDBContextTimeout = 5 * time.Second
+	// Defaults used for tenants that have not saved any settings yet.
+	DefaultTenantCurrency = "USD"
+	DefaultTenantTimezone = "UTC"
+	DefaultTenantLocale   = "en"
+)
+
+var (
+	hexColorRX = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	localeRX   = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+)
+
+// TenantSettings holds a tenant's preferences and the branding applied to its emails.
+// AutoAssignLeads makes the tenant's users take turns at being assigned its new leads.
+type TenantSettings struct {
+	TenantID        int64     `json:"tenant_id"`
+	DisplayName     string    `json:"display_name"`
+	LogoURL         string    `json:"logo_url"`
+	PrimaryColor    string    `json:"primary_color"`
+	ReplyTo         string    `json:"reply_to"`
+	DefaultCurrency string    `json:"default_currency"`
+	Timezone        string    `json:"timezone"`
+	Locale          string    `json:"locale"`
+	AutoAssignLeads bool      `json:"auto_assign_leads"`
+	Version         int32     `json:"version"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// DefaultTenantSettings() returns the settings used for a tenant that has not saved any.
+// A version of 0 means the settings have never been stored.
+func DefaultTenantSettings(tenantID int64) *TenantSettings {
+	return &TenantSettings{
+		TenantID:        tenantID,
+		DefaultCurrency: DefaultTenantCurrency,
+		Timezone:        DefaultTenantTimezone,
+		Locale:          DefaultTenantLocale,
+	}
+}
+
+// Location() returns the tenant's time zone, falling back to UTC for unknown zones.
+func (s *TenantSettings) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ValidateTenantSettings validates the tenant settings. Branding fields are optional.
+func ValidateTenantSettings(v *validator.Validator, settings *TenantSettings) {
+	v.Check(len(settings.DisplayName) <= 100, "display_name", "must not be more than 100 characters long")
+	if settings.LogoURL != "" {
+		logoURL, err := url.Parse(settings.LogoURL)
+		v.Check(err == nil && (logoURL.Scheme == "https" || logoURL.Scheme == "http") && logoURL.Host != "",
+			"logo_url", "must be a valid http or https URL")
+		v.Check(len(settings.LogoURL) <= 500, "logo_url", "must not be more than 500 characters long")
+	}
+	if settings.PrimaryColor != "" {
+		v.Check(validator.Matches(settings.PrimaryColor, hexColorRX), "primary_color", "must be a hex color such as #1a73e8")
+	}
+	if settings.ReplyTo != "" {
+		v.Check(validator.Matches(settings.ReplyTo, validator.EmailRX), "reply_to", "must be a valid email address")
+	}
+	v.Check(IsCurrency(settings.DefaultCurrency), "default_currency", "must be an ISO 4217 currency code such as KES")
+	_, err := time.LoadLocation(settings.Timezone)
+	v.Check(settings.Timezone != "" && err == nil, "timezone", "must be a valid IANA time zone such as Africa/Nairobi")
+	v.Check(validator.Matches(settings.Locale, localeRX), "locale", "must be a locale such as en or fr-FR")
+}
+
+// GetTenantSettings() retrieves a tenant's settings, returning the defaults if the tenant
+// has not saved any yet.
+func (m TenantSettingsModel) GetTenantSettings(ctx context.Context, tenantID int64) (*TenantSettings, error) {
+	ctx, span := startSpan(ctx, "TenantSettingsModel.GetTenantSettings")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	settings, err := m.DB.GetTenantSettingsByTenantID(ctx, tenantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return DefaultTenantSettings(tenantID), nil
+		default:
+			return nil, err
+		}
+	}
+	return populateTenantSettings(settings), nil
+}
+
+// SaveTenantSettings() creates or updates a tenant's settings. Settings that were never
+// saved have a version of 0 and are created, later saves must match the stored version.
+// Concurrent edits fail with ErrGeneralEditConflict.
+func (m TenantSettingsModel) SaveTenantSettings(ctx context.Context, settings *TenantSettings, version int32) error {
+	ctx, span := startSpan(ctx, "TenantSettingsModel.SaveTenantSettings")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	var (
+		saved database.InsertTenantSettingsRow
+		err   error
+	)
+	if version == 0 {
+		saved, err = m.DB.InsertTenantSettings(ctx, database.InsertTenantSettingsParams{
+			TenantID:        settings.TenantID,
+			DisplayName:     settings.DisplayName,
+			LogoUrl:         settings.LogoURL,
+			PrimaryColor:    settings.PrimaryColor,
+			ReplyTo:         settings.ReplyTo,
+			DefaultCurrency: settings.DefaultCurrency,
+			Timezone:        settings.Timezone,
+			Locale:          settings.Locale,
+			AutoAssignLeads: settings.AutoAssignLeads,
+		})
+	} else {
+		var updated database.UpdateTenantSettingsRow
+		updated, err = m.DB.UpdateTenantSettings(ctx, database.UpdateTenantSettingsParams{
+			TenantID:        settings.TenantID,
+			DisplayName:     settings.DisplayName,
+			LogoUrl:         settings.LogoURL,
+			PrimaryColor:    settings.PrimaryColor,
+			ReplyTo:         settings.ReplyTo,
+			DefaultCurrency: settings.DefaultCurrency,
+			Timezone:        settings.Timezone,
+			Locale:          settings.Locale,
+			AutoAssignLeads: settings.AutoAssignLeads,
+			Version:         version,
+		})
+		saved = database.InsertTenantSettingsRow(updated)
+	}
+	if err != nil {
+		switch {
+		// the settings were saved before, or changed since the client read them
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrGeneralEditConflict
+		default:
+			return err
+		}
+	}
+	settings.Version = saved.Version
+	settings.CreatedAt = saved.CreatedAt
+	settings.UpdatedAt = saved.UpdatedAt
+	return nil
+}
+
+func populateTenantSettings(settings database.TenantSetting) *TenantSettings {
+	return &TenantSettings{
+		TenantID:        settings.TenantID,
+		DisplayName:     settings.DisplayName,
+		LogoURL:         settings.LogoUrl,
+		PrimaryColor:    settings.PrimaryColor,
+		ReplyTo:         settings.ReplyTo,
+		DefaultCurrency: settings.DefaultCurrency,
+		Timezone:        settings.Timezone,
+		Locale:          settings.Locale,
+		AutoAssignLeads: settings.AutoAssignLeads,
+		Version:         settings.Version,
+		CreatedAt:       settings.CreatedAt,
+		UpdatedAt:       settings.UpdatedAt,
+	}
+}
@@ -0,0 +1,53 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+func TestValidateTenantSettings(t *testing.T) {
+	valid := func() *TenantSettings {
+		return &TenantSettings{
+			DisplayName:     "TradeHub KE",
+			LogoURL:         "https://tradehub.co.ke/logo.png",
+			PrimaryColor:    "#1a73e8",
+			ReplyTo:         "support@tradehub.co.ke",
+			DefaultCurrency: "KES",
+			Timezone:        "Africa/Nairobi",
+			Locale:          "en",
+		}
+	}
+	tests := []struct {
+		name      string
+		modify    func(s *TenantSettings)
+		wantError string
+	}{
+		{name: "Valid settings", modify: func(s *TenantSettings) {}},
+		{name: "Defaults are valid", modify: func(s *TenantSettings) { *s = *DefaultTenantSettings(1) }},
+		{name: "Invalid logo URL should fail", modify: func(s *TenantSettings) { s.LogoURL = "javascript:alert(1)" }, wantError: "logo_url"},
+		{name: "Invalid color should fail", modify: func(s *TenantSettings) { s.PrimaryColor = "blue" }, wantError: "primary_color"},
+		{name: "Invalid reply-to should fail", modify: func(s *TenantSettings) { s.ReplyTo = "not-an-email" }, wantError: "reply_to"},
+		{name: "Invalid currency should fail", modify: func(s *TenantSettings) { s.DefaultCurrency = "kes" }, wantError: "default_currency"},
+		{name: "Unknown timezone should fail", modify: func(s *TenantSettings) { s.Timezone = "Mars/Olympus" }, wantError: "timezone"},
+		{name: "Invalid locale should fail", modify: func(s *TenantSettings) { s.Locale = "english" }, wantError: "locale"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := valid()
+			tt.modify(settings)
+			v := validator.New()
+			ValidateTenantSettings(v, settings)
+
+			if tt.wantError == "" && !v.Valid() {
+				t.Errorf("ValidateTenantSettings() unexpected errors: %v", v.Errors)
+			}
+			if tt.wantError != "" {
+				if _, exists := v.Errors[tt.wantError]; !exists {
+					t.Errorf("Expected error for field %s, but got errors: %v", tt.wantError, v.Errors)
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,11 +13,14 @@ import (
 )
 
 type TenantsModel struct {
-	DB *database.Queries
+	DB      *database.Queries
+	Timeout time.Duration
 }
 
 var (
-	ErrTenantAlreadyExists = errors.New("tenant already exists")
+	ErrTenantAlreadyExists  = errors.New("tenant already exists")
+	ErrInvalidParentTenant  = errors.New("invalid parent tenant")
+	ErrTenantHierarchyCycle = errors.New("tenant cannot be its own ancestor")
 )
 
 const (
@@ -25,12 +29,14 @@ const (
 
 type Tenant struct {
 	ID           int64     `json:"id"`
+	ParentID     *int64    `json:"parent_id"`
 	Name         string    `json:"name"`
 	ContactEmail string    `json:"contact_email"`
 	Description  string    `json:"description"`
 	Version      int32     `json:"version"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	Children     []*Tenant `json:"children,omitempty"`
 }
 
 func ValidateTenant(v *validator.Validator, tenant *Tenant) {
@@ -42,11 +48,19 @@ func ValidateTenant(v *validator.Validator, tenant *Tenant) {
 
 	// Check if the description is not too long
 	v.Check(len(tenant.Description) <= 500, "description", "must not be more than 500 characters long")
+
+	// A tenant can't be its own parent
+	if tenant.ParentID != nil {
+		v.Check(*tenant.ParentID > 0, "parent_id", "must be a positive integer")
+		v.Check(*tenant.ParentID != tenant.ID, "parent_id", "must not be the tenant itself")
+	}
 }
 
 // GetTenantByID() retrieves a tenant by its ID from the database.
-func (m TenantsModel) GetTenantByID(id int64) (*Tenant, error) {
-	ctx, cancel := contextGenerator(context.Background(), DefaultTenantManagerDBContextTimeout)
+func (m TenantsModel) GetTenantByID(ctx context.Context, id int64) (*Tenant, error) {
+	ctx, span := startSpan(ctx, "TenantsModel.GetTenantByID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 	// get tenant by ID
 	tenant, err := m.DB.GetTenantByID(ctx, id)
@@ -62,9 +76,13 @@ func (m TenantsModel) GetTenantByID(id int64) (*Tenant, error) {
 	return populateTenants(tenant), nil
 }
 
-// AdminGetAllTenants() retrieves all tenants from the database.
-func (m TenantsModel) AdminGetAllTenants(tenantName string, filters Filters) ([]*Tenant, Metadata, error) {
-	ctx, cancel := contextGenerator(context.Background(), DefaultTenantManagerDBContextTimeout)
+// AdminGetAllTenants() retrieves all tenants from the database as a tree. Without a name
+// search only top level tenants are paginated, each carrying its sub-tenants as children.
+// A name search matches tenants at any level.
+func (m TenantsModel) AdminGetAllTenants(ctx context.Context, tenantName string, filters Filters) ([]*Tenant, Metadata, error) {
+	ctx, span := startSpan(ctx, "TenantsModel.AdminGetAllTenants")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 	// get all tenants
 	tenants, err := m.DB.AdminGetAllTenants(ctx, database.AdminGetAllTenantsParams{
@@ -87,10 +105,22 @@ func (m TenantsModel) AdminGetAllTenants(tenantName string, filters Filters) ([]
 	// populate the tenants slice
 	tenantRows := []*Tenant{}
 	totalRows := 0
+	rootIDs := []int64{}
 	for _, tenantRow := range tenants {
 		totalRows = int(tenantRow.TotalCount)
 		tenantRows = append(tenantRows, populateTenants(tenantRow))
+		rootIDs = append(rootIDs, tenantRow.ID)
+	}
+	// attach every tenant's sub-tenants
+	subtrees, err := m.DB.GetTenantSubtrees(ctx, rootIDs)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	descendants := []*Tenant{}
+	for _, tenantRow := range subtrees {
+		descendants = append(descendants, populateTenants(tenantRow))
 	}
+	buildTenantTree(tenantRows, descendants)
 	// metadata
 	metadata := calculateMetadata(totalRows, filters.Page, filters.PageSize)
 	// return
@@ -98,8 +128,10 @@ func (m TenantsModel) AdminGetAllTenants(tenantName string, filters Filters) ([]
 }
 
 // CreateTenant() creates a new tenant in the database.
-func (m TenantsModel) CreateTenant(tenant *Tenant) error {
-	ctx, cancel := contextGenerator(context.Background(), DefaultTenantManagerDBContextTimeout)
+func (m TenantsModel) CreateTenant(ctx context.Context, tenant *Tenant) error {
+	ctx, span := startSpan(ctx, "TenantsModel.CreateTenant")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 
 	// Create the tenant in the database
@@ -107,11 +139,14 @@ func (m TenantsModel) CreateTenant(tenant *Tenant) error {
 		Name:         tenant.Name,
 		ContactEmail: tenant.ContactEmail,
 		Description:  sql.NullString{String: tenant.Description, Valid: true},
+		ParentID:     nullTenantID(tenant.ParentID),
 	})
 	if err != nil {
 		switch {
 		case strings.Contains(err.Error(), "tenants_name_key"):
 			return ErrTenantAlreadyExists
+		case strings.Contains(err.Error(), "tenants_parent_id_fkey"):
+			return ErrInvalidParentTenant
 		default:
 			return err
 		}
@@ -125,10 +160,22 @@ func (m TenantsModel) CreateTenant(tenant *Tenant) error {
 	return nil
 }
 
-// UpdateTenant() updates an existing tenant in the database.
-func (m TenantsModel) UpdateTenant(tenant *Tenant, versionID int32) error {
-	ctx, cancel := contextGenerator(context.Background(), DefaultTenantManagerDBContextTimeout)
+// UpdateTenant() updates an existing tenant in the database. Moving a tenant underneath
+// one of its own descendants is rejected with ErrTenantHierarchyCycle.
+func (m TenantsModel) UpdateTenant(ctx context.Context, tenant *Tenant, versionID int32) error {
+	ctx, span := startSpan(ctx, "TenantsModel.UpdateTenant")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
+	if tenant.ParentID != nil {
+		descendantIDs, err := m.DB.GetTenantDescendantIDs(ctx, tenant.ID)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(descendantIDs, *tenant.ParentID) {
+			return ErrTenantHierarchyCycle
+		}
+	}
 
 	// Update the tenant in the database
 	updatedTenant, err := m.DB.UpdateTenant(ctx, database.UpdateTenantParams{
@@ -137,6 +184,7 @@ func (m TenantsModel) UpdateTenant(tenant *Tenant, versionID int32) error {
 		ContactEmail: tenant.ContactEmail,
 		Description:  sql.NullString{String: tenant.Description, Valid: true},
 		Version:      versionID,
+		ParentID:     nullTenantID(tenant.ParentID),
 	})
 	if err != nil {
 		switch {
@@ -144,6 +192,10 @@ func (m TenantsModel) UpdateTenant(tenant *Tenant, versionID int32) error {
 			return ErrGeneralEditConflict
 		case strings.Contains(err.Error(), "tenants_name_key"):
 			return ErrTenantAlreadyExists
+		case strings.Contains(err.Error(), "tenants_parent_id_fkey"):
+			return ErrInvalidParentTenant
+		case strings.Contains(err.Error(), "tenants_parent_not_self"):
+			return ErrTenantHierarchyCycle
 		default:
 			return err
 		}
@@ -155,6 +207,74 @@ func (m TenantsModel) UpdateTenant(tenant *Tenant, versionID int32) error {
 	return nil
 }
 
+// GetTenantDescendantIDs() returns the IDs of a tenant and all of its sub-tenants, at
+// any depth.
+func (m TenantsModel) GetTenantDescendantIDs(ctx context.Context, tenantID int64) ([]int64, error) {
+	ctx, span := startSpan(ctx, "TenantsModel.GetTenantDescendantIDs")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	return m.DB.GetTenantDescendantIDs(ctx, tenantID)
+}
+
+// GetTenantTree() retrieves a tenant with all of its sub-tenants attached as children.
+func (m TenantsModel) GetTenantTree(ctx context.Context, tenantID int64) (*Tenant, error) {
+	root, err := m.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := startSpan(ctx, "TenantsModel.GetTenantTree")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	subtree, err := m.DB.GetTenantSubtrees(ctx, []int64{tenantID})
+	if err != nil {
+		return nil, err
+	}
+	descendants := []*Tenant{}
+	for _, tenantRow := range subtree {
+		descendants = append(descendants, populateTenants(tenantRow))
+	}
+	buildTenantTree([]*Tenant{root}, descendants)
+	return root, nil
+}
+
+// Walk() calls fn for the tenant and every tenant beneath it, parents before children.
+func (t *Tenant) Walk(fn func(*Tenant)) {
+	fn(t)
+	for _, child := range t.Children {
+		child.Walk(fn)
+	}
+}
+
+// buildTenantTree() attaches the descendants to their parents, which are either one of
+// the roots or another descendant.
+func buildTenantTree(roots, descendants []*Tenant) {
+	byID := make(map[int64]*Tenant, len(roots)+len(descendants))
+	for _, tenant := range roots {
+		byID[tenant.ID] = tenant
+	}
+	for _, tenant := range descendants {
+		byID[tenant.ID] = tenant
+	}
+	for _, tenant := range descendants {
+		if tenant.ParentID == nil {
+			continue
+		}
+		if parent, ok := byID[*tenant.ParentID]; ok {
+			parent.Children = append(parent.Children, tenant)
+		}
+	}
+}
+
+// nullTenantID() converts an optional tenant ID to its database representation.
+func nullTenantID(id *int64) sql.NullInt64 {
+	if id == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *id, Valid: true}
+}
+
 func populateTenants(tenantRow any) *Tenant {
 	switch tenantRow := tenantRow.(type) {
 	case database.Tenant:
@@ -166,6 +286,7 @@ func populateTenants(tenantRow any) *Tenant {
 			Version:      tenantRow.Version,
 			CreatedAt:    tenantRow.CreatedAt,
 			UpdatedAt:    tenantRow.UpdatedAt,
+			ParentID:     tenantParentID(tenantRow.ParentID),
 		}
 	case database.AdminGetAllTenantsRow:
 		return &Tenant{
@@ -176,9 +297,17 @@ func populateTenants(tenantRow any) *Tenant {
 			Version:      tenantRow.Version,
 			CreatedAt:    tenantRow.CreatedAt,
 			UpdatedAt:    tenantRow.UpdatedAt,
+			ParentID:     tenantParentID(tenantRow.ParentID),
 		}
 	default:
 		// return nil
 		return nil
 	}
 }
+
+func tenantParentID(parentID sql.NullInt64) *int64 {
+	if !parentID.Valid {
+		return nil
+	}
+	return &parentID.Int64
+}
@@ -12,7 +12,8 @@ import (
 )
 
 type TokenModel struct {
-	DB *database.Queries
+	DB      *database.Queries
+	Timeout time.Duration
 }
 
 const (
@@ -72,20 +73,21 @@ func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error
 	return token, nil
 }
 
-func (m TokenModel) New(userID int64, ttl time.Duration, scope string) (*Token, error) {
+func (m TokenModel) New(ctx context.Context, userID int64, ttl time.Duration, scope string) (*Token, error) {
 	api_key, err := generateToken(userID, ttl, scope)
 	if err != nil {
 		return nil, err
 	}
 	//fmt.Printf("API Key: %v\n || User ID: %d", api_key, userID)
 	// insert the api key into the database
-	err = m.Insert(api_key)
+	err = m.Insert(ctx, api_key)
 	return api_key, err
 }
 
-func (m TokenModel) Insert(api_key *Token) error {
-	// create our timeout context. All of them will just be 5 seconds
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (m TokenModel) Insert(ctx context.Context, api_key *Token) error {
+	ctx, span := startSpan(ctx, "TokenModel.Insert")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 	_, err := m.DB.InsertApiKey(ctx, database.InsertApiKeyParams{
 		ApiKey: api_key.Hash,
@@ -97,9 +99,10 @@ func (m TokenModel) Insert(api_key *Token) error {
 }
 
 // DeleteAllForUser() deletes all tokens for a specific user and scope.
-func (m TokenModel) DeleteAllForUser(scope string, userID int64) error {
-	// create our timeout context. All of them will just be 5 seconds
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (m TokenModel) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	ctx, span := startSpan(ctx, "TokenModel.DeleteAllForUser")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 	err := m.DB.DeletAllAPIKeysForUser(ctx, database.DeletAllAPIKeysForUserParams{
 		UserID: userID,
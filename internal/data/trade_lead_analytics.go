@@ -0,0 +1,326 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/database"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+	"github.com/shopspring/decimal"
+)
+
+// Define constants for the bucket sizes of the trade lead time series.
+const (
+	TradeLeadStatsIntervalDay   = "day"
+	TradeLeadStatsIntervalWeek  = "week"
+	TradeLeadStatsIntervalMonth = "month"
+)
+
+const (
+	// MaxTradeLeadStatsBuckets caps the length of a time series, e.g. a year of days.
+	MaxTradeLeadStatsBuckets = 366
+	// DefaultTradeLeadStatsRange is the range covered when no start date is given.
+	DefaultTradeLeadStatsRange = 30 * 24 * time.Hour
+)
+
+var (
+	// TradeLeadStatuses lists the statuses of a trade lead in the order of its life cycle.
+	TradeLeadStatuses = []string{"new", "verified", "closed"}
+	// TradeLeadStatsIntervals lists the supported time series bucket sizes.
+	TradeLeadStatsIntervals = []string{TradeLeadStatsIntervalDay, TradeLeadStatsIntervalWeek, TradeLeadStatsIntervalMonth}
+)
+
+// TradeLeadStatsFilter selects the leads covered by the analytics: those created in
+// [From, To) by the given tenants, or by every tenant when TenantIDs is empty. Their
+// values are reported in the currency of the Converter.
+type TradeLeadStatsFilter struct {
+	TenantIDs []int64
+	From      time.Time
+	To        time.Time
+	Interval  string
+	Converter *CurrencyConverter
+}
+
+// TradeLeadAnalytics describes the leads created in a date range. A lead counts as verified
+// once it has ever been verified, even if it was closed since, and the time to verify is
+// measured from its creation to the first time it was verified.
+type TradeLeadAnalytics struct {
+	From                   time.Time               `json:"from"`
+	To                     time.Time               `json:"to"`
+	Interval               string                  `json:"interval"`
+	Currency               string                  `json:"currency"`
+	RateDate               time.Time               `json:"rate_date"`
+	TotalLeads             int64                   `json:"total_leads"`
+	TotalValue             decimal.Decimal         `json:"total_value"`
+	VerifiedLeads          int64                   `json:"verified_leads"`
+	ConversionRate         float64                 `json:"conversion_rate"`
+	AverageSecondsToVerify *float64                `json:"average_seconds_to_verify"`
+	ByStatus               []*TradeLeadStatusStats `json:"by_status"`
+	ByTenant               []*TradeLeadTenantStats `json:"by_tenant,omitempty"`
+	TimeSeries             []*TradeLeadStatsBucket `json:"time_series"`
+}
+
+// TradeLeadStatusStats counts the leads currently in a status.
+type TradeLeadStatusStats struct {
+	Status     string          `json:"status"`
+	Leads      int64           `json:"leads"`
+	TotalValue decimal.Decimal `json:"total_value"`
+}
+
+// TradeLeadTenantStats holds the figures of a single tenant.
+type TradeLeadTenantStats struct {
+	TenantID               int64           `json:"tenant_id"`
+	TotalLeads             int64           `json:"total_leads"`
+	TotalValue             decimal.Decimal `json:"total_value"`
+	VerifiedLeads          int64           `json:"verified_leads"`
+	ConversionRate         float64         `json:"conversion_rate"`
+	AverageSecondsToVerify *float64        `json:"average_seconds_to_verify"`
+
+	secondsToVerify float64
+}
+
+// TradeLeadStatsBucket counts the leads created in the interval starting at Start.
+type TradeLeadStatsBucket struct {
+	Start      time.Time       `json:"start"`
+	Leads      int64           `json:"leads"`
+	TotalValue decimal.Decimal `json:"total_value"`
+}
+
+// tradeLeadStatusCount, tradeLeadVerificationTime and the buckets are what the stores
+// aggregate, newTradeLeadAnalytics() puts them together.
+type tradeLeadStatusCount struct {
+	TenantID   int64
+	Status     string
+	Currency   string
+	Leads      int64
+	TotalValue decimal.Decimal
+}
+
+type tradeLeadVerificationTime struct {
+	TenantID        int64
+	VerifiedLeads   int64
+	SecondsToVerify float64
+}
+
+type tradeLeadBucketTotal struct {
+	Start      time.Time
+	Currency   string
+	Leads      int64
+	TotalValue decimal.Decimal
+}
+
+// ValidateTradeLeadStatsFilter validates the range and interval of a TradeLeadStatsFilter.
+func ValidateTradeLeadStatsFilter(v *validator.Validator, filter TradeLeadStatsFilter) {
+	v.Check(validator.PermittedValue(filter.Interval, TradeLeadStatsIntervals...), "interval", "must be one of day, week or month")
+	v.Check(filter.To.After(filter.From), "to", "must be after from")
+	if v.Valid() {
+		v.Check(len(tradeLeadStatsBuckets(filter)) <= MaxTradeLeadStatsBuckets, "interval", "must not split the range into more than 366 buckets")
+	}
+}
+
+// GetTradeLeadAnalytics() returns the analytics of the leads selected by the filter.
+func (m TradeLeadModel) GetTradeLeadAnalytics(ctx context.Context, filter TradeLeadStatsFilter) (*TradeLeadAnalytics, error) {
+	ctx, span := startSpan(ctx, "TradeLeadModel.GetTradeLeadAnalytics")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	statusRows, err := m.DB.GetTradeLeadStatusCounts(ctx, database.GetTradeLeadStatusCountsParams{
+		TenantIds:   filter.TenantIDs,
+		CreatedFrom: filter.From,
+		CreatedTo:   filter.To,
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]tradeLeadStatusCount, 0, len(statusRows))
+	for _, row := range statusRows {
+		counts = append(counts, tradeLeadStatusCount{
+			TenantID:   row.TenantID,
+			Status:     row.Status,
+			Currency:   row.Currency,
+			Leads:      row.Leads,
+			TotalValue: decimal.RequireFromString(row.TotalValue),
+		})
+	}
+	verificationRows, err := m.DB.GetTradeLeadVerificationTimes(ctx, database.GetTradeLeadVerificationTimesParams{
+		TenantIds:   filter.TenantIDs,
+		CreatedFrom: filter.From,
+		CreatedTo:   filter.To,
+	})
+	if err != nil {
+		return nil, err
+	}
+	verifications := make([]tradeLeadVerificationTime, 0, len(verificationRows))
+	for _, row := range verificationRows {
+		verifications = append(verifications, tradeLeadVerificationTime{
+			TenantID:        row.TenantID,
+			VerifiedLeads:   row.VerifiedLeads,
+			SecondsToVerify: row.SecondsToVerify,
+		})
+	}
+	bucketRows, err := m.DB.GetTradeLeadTimeSeries(ctx, database.GetTradeLeadTimeSeriesParams{
+		Bucket:      filter.Interval,
+		TenantIds:   filter.TenantIDs,
+		CreatedFrom: filter.From,
+		CreatedTo:   filter.To,
+	})
+	if err != nil {
+		return nil, err
+	}
+	buckets := make([]tradeLeadBucketTotal, 0, len(bucketRows))
+	for _, row := range bucketRows {
+		buckets = append(buckets, tradeLeadBucketTotal{
+			Start:      row.BucketStart,
+			Currency:   row.Currency,
+			Leads:      row.Leads,
+			TotalValue: decimal.RequireFromString(row.TotalValue),
+		})
+	}
+	return newTradeLeadAnalytics(filter, counts, verifications, buckets)
+}
+
+// newTradeLeadAnalytics() puts the aggregates together, converting their values to the
+// reporting currency. Every status and every bucket of the range is listed, those without
+// leads with zeros. It fails with ErrExchangeRateNotFound when a currency can't be converted.
+func newTradeLeadAnalytics(filter TradeLeadStatsFilter, counts []tradeLeadStatusCount, verifications []tradeLeadVerificationTime, buckets []tradeLeadBucketTotal) (*TradeLeadAnalytics, error) {
+	analytics := &TradeLeadAnalytics{
+		From:     filter.From,
+		To:       filter.To,
+		Interval: filter.Interval,
+		Currency: filter.Converter.Currency,
+		RateDate: filter.Converter.Date,
+	}
+	byStatus := map[string]*TradeLeadStatusStats{}
+	for _, status := range TradeLeadStatuses {
+		byStatus[status] = &TradeLeadStatusStats{Status: status}
+		analytics.ByStatus = append(analytics.ByStatus, byStatus[status])
+	}
+	byTenant := map[int64]*TradeLeadTenantStats{}
+	tenant := func(tenantID int64) *TradeLeadTenantStats {
+		if byTenant[tenantID] == nil {
+			byTenant[tenantID] = &TradeLeadTenantStats{TenantID: tenantID}
+			analytics.ByTenant = append(analytics.ByTenant, byTenant[tenantID])
+		}
+		return byTenant[tenantID]
+	}
+	for _, count := range counts {
+		value, err := filter.Converter.Convert(count.TotalValue, count.Currency)
+		if err != nil {
+			return nil, err
+		}
+		status, ok := byStatus[count.Status]
+		if !ok {
+			status = &TradeLeadStatusStats{Status: count.Status}
+			byStatus[count.Status] = status
+			analytics.ByStatus = append(analytics.ByStatus, status)
+		}
+		status.Leads += count.Leads
+		status.TotalValue = status.TotalValue.Add(value)
+		tenantStats := tenant(count.TenantID)
+		tenantStats.TotalLeads += count.Leads
+		tenantStats.TotalValue = tenantStats.TotalValue.Add(value)
+		analytics.TotalLeads += count.Leads
+		analytics.TotalValue = analytics.TotalValue.Add(value)
+	}
+	// converted sums are exact, round them to cents once they are complete
+	analytics.TotalValue = analytics.TotalValue.Round(2)
+	for _, status := range analytics.ByStatus {
+		status.TotalValue = status.TotalValue.Round(2)
+	}
+	var secondsToVerify float64
+	for _, verification := range verifications {
+		tenantStats := tenant(verification.TenantID)
+		tenantStats.VerifiedLeads += verification.VerifiedLeads
+		tenantStats.secondsToVerify += verification.SecondsToVerify
+		analytics.VerifiedLeads += verification.VerifiedLeads
+		secondsToVerify += verification.SecondsToVerify
+	}
+	analytics.ConversionRate = conversionRate(analytics.VerifiedLeads, analytics.TotalLeads)
+	analytics.AverageSecondsToVerify = averageSeconds(secondsToVerify, analytics.VerifiedLeads)
+	for _, tenantStats := range analytics.ByTenant {
+		tenantStats.ConversionRate = conversionRate(tenantStats.VerifiedLeads, tenantStats.TotalLeads)
+		tenantStats.AverageSecondsToVerify = averageSeconds(tenantStats.secondsToVerify, tenantStats.VerifiedLeads)
+		tenantStats.TotalValue = tenantStats.TotalValue.Round(2)
+	}
+	slices.SortFunc(analytics.ByTenant, func(a, b *TradeLeadTenantStats) int {
+		return cmp.Compare(a.TenantID, b.TenantID)
+	})
+	// fill in the buckets without leads
+	analytics.TimeSeries = tradeLeadStatsBuckets(filter)
+	for _, bucket := range analytics.TimeSeries {
+		for _, total := range buckets {
+			if !total.Start.Equal(bucket.Start) {
+				continue
+			}
+			value, err := filter.Converter.Convert(total.TotalValue, total.Currency)
+			if err != nil {
+				return nil, err
+			}
+			bucket.Leads += total.Leads
+			bucket.TotalValue = bucket.TotalValue.Add(value)
+		}
+		bucket.TotalValue = bucket.TotalValue.Round(2)
+	}
+	return analytics, nil
+}
+
+// tradeLeadStatsBuckets() returns an empty bucket for every interval overlapping the range.
+func tradeLeadStatsBuckets(filter TradeLeadStatsFilter) []*TradeLeadStatsBucket {
+	buckets := []*TradeLeadStatsBucket{}
+	for start := truncateToInterval(filter.From, filter.Interval); start.Before(filter.To); start = nextInterval(start, filter.Interval) {
+		buckets = append(buckets, &TradeLeadStatsBucket{Start: start})
+		// stop early on ranges far beyond the cap, they fail validation anyway
+		if len(buckets) > MaxTradeLeadStatsBuckets {
+			break
+		}
+	}
+	return buckets
+}
+
+// truncateToInterval() returns the start of the UTC day, ISO week or month of t, like
+// Postgres' date_trunc() in the UTC time zone.
+func truncateToInterval(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case TradeLeadStatsIntervalWeek:
+		// weeks start on Monday
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case TradeLeadStatsIntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// nextInterval() returns the start of the interval following the one starting at start.
+func nextInterval(start time.Time, interval string) time.Time {
+	switch interval {
+	case TradeLeadStatsIntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case TradeLeadStatsIntervalMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// conversionRate() returns the share of verified leads, rounded to four decimals.
+func conversionRate(verified, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(verified)/float64(total)*10000) / 10000
+}
+
+// averageSeconds() returns the average time to verify, or nil when no lead was verified.
+func averageSeconds(total float64, verified int64) *float64 {
+	if verified == 0 {
+		return nil
+	}
+	average := math.Round(total / float64(verified))
+	return &average
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+	"github.com/shopspring/decimal"
+)
+
+func TestTruncateToInterval(t *testing.T) {
+	// a Thursday evening in Nairobi, still Thursday in UTC
+	at := time.Date(2026, time.January, 15, 21, 30, 0, 0, time.FixedZone("EAT", 3*60*60))
+	tests := []struct {
+		interval string
+		want     time.Time
+		next     time.Time
+	}{
+		{interval: TradeLeadStatsIntervalDay, want: time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC), next: time.Date(2026, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		{interval: TradeLeadStatsIntervalWeek, want: time.Date(2026, time.January, 12, 0, 0, 0, 0, time.UTC), next: time.Date(2026, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{interval: TradeLeadStatsIntervalMonth, want: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), next: time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.interval, func(t *testing.T) {
+			got := truncateToInterval(at, tt.interval)
+			if !got.Equal(tt.want) {
+				t.Errorf("truncateToInterval() = %v, want %v", got, tt.want)
+			}
+			if next := nextInterval(got, tt.interval); !next.Equal(tt.next) {
+				t.Errorf("nextInterval() = %v, want %v", next, tt.next)
+			}
+		})
+	}
+}
+
+func TestValidateTradeLeadStatsFilter(t *testing.T) {
+	from := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		filter    TradeLeadStatsFilter
+		wantField string
+	}{
+		{name: "Valid", filter: TradeLeadStatsFilter{From: from, To: from.AddDate(0, 1, 0), Interval: TradeLeadStatsIntervalDay}},
+		{name: "A year of days", filter: TradeLeadStatsFilter{From: from, To: from.AddDate(1, 0, 0), Interval: TradeLeadStatsIntervalDay}},
+		{name: "Unknown interval", filter: TradeLeadStatsFilter{From: from, To: from.AddDate(0, 1, 0), Interval: "hour"}, wantField: "interval"},
+		{name: "Reversed range", filter: TradeLeadStatsFilter{From: from, To: from.AddDate(0, 0, -1), Interval: TradeLeadStatsIntervalDay}, wantField: "to"},
+		{name: "Too many buckets", filter: TradeLeadStatsFilter{From: from, To: from.AddDate(2, 0, 0), Interval: TradeLeadStatsIntervalDay}, wantField: "interval"},
+		{name: "Years of months", filter: TradeLeadStatsFilter{From: from, To: from.AddDate(10, 0, 0), Interval: TradeLeadStatsIntervalMonth}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateTradeLeadStatsFilter(v, tt.filter)
+			if tt.wantField == "" && !v.Valid() {
+				t.Errorf("unexpected errors: %v", v.Errors)
+			}
+			if _, ok := v.Errors[tt.wantField]; tt.wantField != "" && !ok {
+				t.Errorf("errors = %v, want one for %q", v.Errors, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestNewTradeLeadAnalytics(t *testing.T) {
+	from := time.Date(2026, time.January, 5, 12, 0, 0, 0, time.UTC)
+	rates := []*ExchangeRate{{BaseCurrency: "USD", QuoteCurrency: "KES", Rate: decimal.NewFromInt(100), RateDate: from}}
+	filter := TradeLeadStatsFilter{From: from, To: from.AddDate(0, 0, 14), Interval: TradeLeadStatsIntervalWeek, Converter: NewCurrencyConverter("USD", from, rates)}
+	counts := []tradeLeadStatusCount{
+		{TenantID: 2, Status: "new", Currency: "USD", Leads: 2, TotalValue: decimal.NewFromInt(200)},
+		{TenantID: 1, Status: "verified", Currency: "KES", Leads: 1, TotalValue: decimal.NewFromInt(50000)},
+		{TenantID: 1, Status: "closed", Currency: "USD", Leads: 1, TotalValue: decimal.NewFromInt(300)},
+	}
+	verifications := []tradeLeadVerificationTime{{TenantID: 1, VerifiedLeads: 2, SecondsToVerify: 7200}}
+	week := time.Date(2026, time.January, 12, 0, 0, 0, 0, time.UTC)
+	buckets := []tradeLeadBucketTotal{
+		{Start: week, Currency: "USD", Leads: 3, TotalValue: decimal.NewFromInt(500)},
+		{Start: week, Currency: "KES", Leads: 1, TotalValue: decimal.NewFromInt(50000)},
+	}
+
+	analytics, err := newTradeLeadAnalytics(filter, counts, verifications, buckets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analytics.TotalLeads != 4 || !analytics.TotalValue.Equal(decimal.NewFromInt(1000)) || analytics.ConversionRate != 0.5 ||
+		analytics.AverageSecondsToVerify == nil || *analytics.AverageSecondsToVerify != 3600 || analytics.Currency != "USD" {
+		t.Errorf("newTradeLeadAnalytics() = %+v", analytics)
+	}
+	wantStatuses := []int64{2, 1, 1}
+	for i, status := range analytics.ByStatus {
+		if status.Status != TradeLeadStatuses[i] || status.Leads != wantStatuses[i] {
+			t.Errorf("by status[%d] = %+v, want %d %s leads", i, status, wantStatuses[i], TradeLeadStatuses[i])
+		}
+	}
+	if len(analytics.ByTenant) != 2 || analytics.ByTenant[0].TenantID != 1 || analytics.ByTenant[0].ConversionRate != 1 ||
+		!analytics.ByTenant[0].TotalValue.Equal(decimal.NewFromInt(800)) || analytics.ByTenant[1].ConversionRate != 0 || analytics.ByTenant[1].AverageSecondsToVerify != nil {
+		t.Errorf("by tenant = %+v %+v", analytics.ByTenant[0], analytics.ByTenant[1])
+	}
+	// the range starts on a Monday afternoon and ends two weeks later, overlapping 3 weeks
+	if len(analytics.TimeSeries) != 3 || analytics.TimeSeries[0].Leads != 0 || analytics.TimeSeries[1].Leads != 4 || analytics.TimeSeries[2].Leads != 0 ||
+		!analytics.TimeSeries[1].TotalValue.Equal(decimal.NewFromInt(1000)) {
+		t.Errorf("time series = %+v %+v %+v", analytics.TimeSeries[0], analytics.TimeSeries[1], analytics.TimeSeries[2])
+	}
+
+	// a currency without a rate fails the analytics rather than adding up unlike units
+	filter.Converter = NewCurrencyConverter("EUR", from, rates)
+	if _, err := newTradeLeadAnalytics(filter, counts, verifications, buckets); !errors.Is(err, ErrExchangeRateNotFound) {
+		t.Errorf("newTradeLeadAnalytics() without a rate error = %v, want ErrExchangeRateNotFound", err)
+	}
+}
@@ -0,0 +1,216 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/database"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+type TradeLeadCommentModel struct {
+	DB      *database.Queries
+	Timeout time.Duration
+}
+
+const (
+	DefaultTradeLeadCommentDBContextTimeout = 3 * time.Second
+	// MaxTradeLeadCommentLength caps the length of a comment, in characters.
+	MaxTradeLeadCommentLength = 5000
+	// MaxTradeLeadCommentMentions caps the users a single comment can mention.
+	MaxTradeLeadCommentMentions = 20
+)
+
+// mentionRX matches an @mention of a user by their email address, e.g. "@ann@acme.test".
+// The mention must not follow a character of an email address, so plain addresses in the
+// text are not taken for mentions.
+var mentionRX = regexp.MustCompile(`(?:^|[^A-Za-z0-9._%+\-@])@([A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// TradeLeadComment is a comment on a trade lead. Internal comments are notes that are only
+// shown to admins. AuthorID is nil once the author's user has been deleted.
+type TradeLeadComment struct {
+	ID          int64     `json:"id"`
+	TradeLeadID int64     `json:"trade_lead_id"`
+	TenantID    int64     `json:"tenant_id"`
+	AuthorID    *int64    `json:"author_id"`
+	AuthorName  string    `json:"author_name"`
+	Body        string    `json:"body"`
+	Internal    bool      `json:"internal"`
+	Version     int32     `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ValidateTradeLeadComment validates the body of a comment.
+func ValidateTradeLeadComment(v *validator.Validator, comment *TradeLeadComment) {
+	v.Check(strings.TrimSpace(comment.Body) != "", "body", "must be provided")
+	v.Check(len([]rune(comment.Body)) <= MaxTradeLeadCommentLength, "body", fmt.Sprintf("must not be more than %d characters long", MaxTradeLeadCommentLength))
+	v.Check(len(TradeLeadCommentMentions(comment.Body)) <= MaxTradeLeadCommentMentions, "body", fmt.Sprintf("must not mention more than %d users", MaxTradeLeadCommentMentions))
+}
+
+// TradeLeadCommentMentions() returns the lowercased email addresses mentioned in a comment
+// body, once each and in order of appearance.
+func TradeLeadCommentMentions(body string) []string {
+	mentions := []string{}
+	for _, match := range mentionRX.FindAllStringSubmatch(body, -1) {
+		email := strings.ToLower(match[1])
+		if !slices.Contains(mentions, email) {
+			mentions = append(mentions, email)
+		}
+	}
+	return mentions
+}
+
+// CreateTradeLeadComment() adds a comment to a trade lead.
+func (m TradeLeadCommentModel) CreateTradeLeadComment(ctx context.Context, comment *TradeLeadComment) error {
+	ctx, span := startSpan(ctx, "TradeLeadCommentModel.CreateTradeLeadComment")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	newComment, err := m.DB.CreateTradeLeadComment(ctx, database.CreateTradeLeadCommentParams{
+		TradeLeadID: comment.TradeLeadID,
+		TenantID:    comment.TenantID,
+		AuthorID:    nullUserID(comment.AuthorID),
+		Body:        comment.Body,
+		Internal:    comment.Internal,
+	})
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "trade_lead_comments_trade_lead_id_fkey"):
+			return ErrGeneralRecordNotFound
+		default:
+			return err
+		}
+	}
+	comment.ID = newComment.ID
+	comment.Version = newComment.Version
+	comment.CreatedAt = newComment.CreatedAt
+	comment.UpdatedAt = newComment.UpdatedAt
+	return nil
+}
+
+// GetTradeLeadCommentByID() retrieves a comment of a trade lead.
+func (m TradeLeadCommentModel) GetTradeLeadCommentByID(ctx context.Context, tradeLeadID, id int64) (*TradeLeadComment, error) {
+	ctx, span := startSpan(ctx, "TradeLeadCommentModel.GetTradeLeadCommentByID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	comment, err := m.DB.GetTradeLeadCommentByID(ctx, database.GetTradeLeadCommentByIDParams{
+		ID:          id,
+		TradeLeadID: tradeLeadID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrGeneralRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return populateTradeLeadComment(database.GetTradeLeadCommentsByLeadIDRow{
+		ID:          comment.ID,
+		TradeLeadID: comment.TradeLeadID,
+		TenantID:    comment.TenantID,
+		AuthorID:    comment.AuthorID,
+		AuthorName:  comment.AuthorName,
+		Body:        comment.Body,
+		Internal:    comment.Internal,
+		Version:     comment.Version,
+		CreatedAt:   comment.CreatedAt,
+		UpdatedAt:   comment.UpdatedAt,
+	}), nil
+}
+
+// GetTradeLeadComments() lists the comments of a trade lead, oldest first unless sorted by
+// "-created_at". Internal notes are only listed when includeInternal is set.
+func (m TradeLeadCommentModel) GetTradeLeadComments(ctx context.Context, tradeLeadID int64, includeInternal bool, filters Filters) ([]*TradeLeadComment, Metadata, error) {
+	ctx, span := startSpan(ctx, "TradeLeadCommentModel.GetTradeLeadComments")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.GetTradeLeadCommentsByLeadID(ctx, database.GetTradeLeadCommentsByLeadIDParams{
+		TradeLeadID:     tradeLeadID,
+		IncludeInternal: includeInternal,
+		SortDirection:   filters.sortDirection(),
+		PageLimit:       filters.limitInt32(),
+		PageOffset:      filters.offsetInt32(),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	if len(rows) == 0 {
+		return nil, Metadata{}, ErrGeneralRecordNotFound
+	}
+	comments := []*TradeLeadComment{}
+	totalRows := 0
+	for _, row := range rows {
+		totalRows = int(row.TotalCount)
+		comments = append(comments, populateTradeLeadComment(row))
+	}
+	return comments, calculateMetadata(totalRows, filters.Page, filters.PageSize), nil
+}
+
+// UpdateTradeLeadComment() saves the edited body of a comment. The version must match the
+// stored comment, which makes concurrent edits fail with ErrGeneralEditConflict.
+func (m TradeLeadCommentModel) UpdateTradeLeadComment(ctx context.Context, comment *TradeLeadComment, version int32) error {
+	ctx, span := startSpan(ctx, "TradeLeadCommentModel.UpdateTradeLeadComment")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	updated, err := m.DB.UpdateTradeLeadComment(ctx, database.UpdateTradeLeadCommentParams{
+		ID:      comment.ID,
+		Version: version,
+		Body:    comment.Body,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrGeneralEditConflict
+		default:
+			return err
+		}
+	}
+	comment.Version = updated.Version
+	comment.UpdatedAt = updated.UpdatedAt
+	return nil
+}
+
+// DeleteTradeLeadComment() removes a comment of a trade lead.
+func (m TradeLeadCommentModel) DeleteTradeLeadComment(ctx context.Context, tradeLeadID, id int64) error {
+	ctx, span := startSpan(ctx, "TradeLeadCommentModel.DeleteTradeLeadComment")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.DeleteTradeLeadComment(ctx, database.DeleteTradeLeadCommentParams{
+		ID:          id,
+		TradeLeadID: tradeLeadID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrGeneralRecordNotFound
+	}
+	return nil
+}
+
+func populateTradeLeadComment(row database.GetTradeLeadCommentsByLeadIDRow) *TradeLeadComment {
+	return &TradeLeadComment{
+		ID:          row.ID,
+		TradeLeadID: row.TradeLeadID,
+		TenantID:    row.TenantID,
+		AuthorID:    leadAssigneeID(row.AuthorID),
+		AuthorName:  row.AuthorName,
+		Body:        row.Body,
+		Internal:    row.Internal,
+		Version:     row.Version,
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+	}
+}
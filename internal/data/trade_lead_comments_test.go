@@ -0,0 +1,56 @@
+package data
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+func TestTradeLeadCommentMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "No mentions", body: "Called the buyer, they want samples.", want: []string{}},
+		{name: "Mention", body: "@ann@tradehub.test can you follow up?", want: []string{"ann@tradehub.test"}},
+		{name: "Mentions in order and once each", body: "cc @Baraka@TradeHub.test, @ann@tradehub.test and @baraka@tradehub.test.", want: []string{"baraka@tradehub.test", "ann@tradehub.test"}},
+		{name: "Plain email address", body: "Reply to buyer@acme.test", want: []string{}},
+		{name: "Mention without a domain", body: "thanks @ann", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TradeLeadCommentMentions(tt.body); !slices.Equal(got, tt.want) {
+				t.Errorf("TradeLeadCommentMentions(%q) = %v, want %v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTradeLeadComment(t *testing.T) {
+	tooManyMentions := ""
+	for i := range MaxTradeLeadCommentMentions + 1 {
+		tooManyMentions += " @user" + strings.Repeat("x", i) + "@tradehub.test"
+	}
+	tests := []struct {
+		name      string
+		body      string
+		wantValid bool
+	}{
+		{name: "Valid comment", body: "Buyer confirmed the order volume.", wantValid: true},
+		{name: "Blank body", body: "  \n", wantValid: false},
+		{name: "Body too long", body: strings.Repeat("a", MaxTradeLeadCommentLength+1), wantValid: false},
+		{name: "Too many mentions", body: tooManyMentions, wantValid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateTradeLeadComment(v, &TradeLeadComment{Body: tt.body})
+			if v.Valid() != tt.wantValid {
+				t.Errorf("Valid() = %v, want %v: %v", v.Valid(), tt.wantValid, v.Errors)
+			}
+		})
+	}
+}
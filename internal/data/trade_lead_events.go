@@ -0,0 +1,83 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/database"
+)
+
+type TradeLeadEventModel struct {
+	DB      *database.Queries
+	Timeout time.Duration
+}
+
+const (
+	DefaultTradeLeadEventDBContextTimeout = 3 * time.Second
+	// TradeLeadEventsChannel is the NOTIFY channel every API instance listens on.
+	TradeLeadEventsChannel = "trade_lead_events"
+	// maxTradeLeadEventData keeps the NOTIFY payload below Postgres' limit of 8000 bytes,
+	// leaving room for the rest of the event.
+	maxTradeLeadEventData = 7500
+)
+
+// Define constants for the types of the live trade lead events.
+const (
+	TradeLeadEventCreated = "trade_lead.created"
+	TradeLeadEventUpdated = "trade_lead.updated"
+)
+
+// TradeLeadEvent is a change to a trade lead, streamed live to the users of its tenant. The
+// ID is assigned when it is published and is shared by every API instance.
+type TradeLeadEvent struct {
+	ID       int64           `json:"id"`
+	TenantID int64           `json:"tenant_id"`
+	Type     string          `json:"type"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// NewTradeLeadEvent() returns an event of the given type carrying the lead. Leads too large
+// for a notification, e.g. because of their custom fields, are cut down to their ID, status
+// and version, and clients fetch the rest.
+func NewTradeLeadEvent(eventType string, lead *TradeLead) (*TradeLeadEvent, error) {
+	payload, err := json.Marshal(map[string]any{"trade_lead": lead})
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > maxTradeLeadEventData {
+		payload, err = json.Marshal(map[string]any{"trade_lead": map[string]any{
+			"id":        lead.ID,
+			"tenant_id": lead.TenantID,
+			"status":    lead.Status,
+			"version":   lead.Version,
+		}})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &TradeLeadEvent{TenantID: lead.TenantID, Type: eventType, Data: payload}, nil
+}
+
+// PublishTradeLeadEvent() assigns the event its ID and sends it to TradeLeadEventsChannel.
+// Postgres only delivers the notification once the transaction commits, so events of
+// rolled back changes are never streamed.
+func (m TradeLeadEventModel) PublishTradeLeadEvent(ctx context.Context, event *TradeLeadEvent) error {
+	ctx, span := startSpan(ctx, "TradeLeadEventModel.PublishTradeLeadEvent")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	id, err := m.DB.NextTradeLeadEventID(ctx)
+	if err != nil {
+		return err
+	}
+	event.ID = id
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return m.DB.NotifyTradeLeadEvent(ctx, database.NotifyTradeLeadEventParams{
+		Channel: TradeLeadEventsChannel,
+		Payload: string(payload),
+	})
+}
@@ -0,0 +1,40 @@
+package data
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNewTradeLeadEvent(t *testing.T) {
+	lead := &TradeLead{ID: 7, TenantID: 3, Title: "Coffee beans", Status: "new", Value: decimal.NewFromInt(1200), Version: 2}
+	var decoded struct {
+		TradeLead TradeLead `json:"trade_lead"`
+	}
+	event, err := NewTradeLeadEvent(TradeLeadEventUpdated, lead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(event.Data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if event.TenantID != 3 || event.Type != TradeLeadEventUpdated || decoded.TradeLead.Title != "Coffee beans" {
+		t.Errorf("NewTradeLeadEvent() = %+v, data %s", event, event.Data)
+	}
+
+	// a lead too large for a notification only carries its identity
+	lead.CustomFields = map[string]any{"notes": strings.Repeat("x", 8000)}
+	event, err = NewTradeLeadEvent(TradeLeadEventUpdated, lead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded.TradeLead = TradeLead{}
+	if err := json.Unmarshal(event.Data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(event.Data) > maxTradeLeadEventData || decoded.TradeLead.ID != 7 || decoded.TradeLead.Version != 2 || decoded.TradeLead.Title != "" {
+		t.Errorf("NewTradeLeadEvent() of a large lead = %s", event.Data)
+	}
+}
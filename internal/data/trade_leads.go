@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"strings"
 	"time"
@@ -13,7 +14,8 @@ import (
 )
 
 type TradeLeadModel struct {
-	DB *database.Queries
+	DB      *database.Queries
+	Timeout time.Duration
 }
 
 const (
@@ -23,50 +25,148 @@ const (
 var (
 	ErrInvalidTenantReference = errors.New("invalid tenant reference")
 	ErrInvalidTradeLeadStatus = errors.New("invalid trade lead status")
+	// ErrInvalidTradeLeadAssignee is returned when a lead is assigned to a user that
+	// doesn't belong to the lead's tenant.
+	ErrInvalidTradeLeadAssignee = errors.New("invalid trade lead assignee")
 )
 
-// TradeLead represents a trade lead in the system.
+// TradeLead represents a trade lead in the system. AssignedTo is the ID of the tenant user
+// working the lead, or nil while it is unassigned.
 type TradeLead struct {
+	ID           int64           `json:"id"`
+	TenantID     int64           `json:"tenant_id"`
+	Title        string          `json:"title"`
+	Description  string          `json:"description"`
+	Status       string          `json:"status"`
+	Value        decimal.Decimal `json:"value"`
+	Currency     string          `json:"currency"`
+	AssignedTo   *int64          `json:"assigned_to"`
+	CustomFields map[string]any  `json:"custom_fields"`
+	Version      int32           `json:"version"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// TradeLeadHistory is a single recorded state of a trade lead.
+type TradeLeadHistory struct {
 	ID          int64           `json:"id"`
+	TradeLeadID int64           `json:"trade_lead_id"`
 	TenantID    int64           `json:"tenant_id"`
-	Title       string          `json:"title"`
-	Description string          `json:"description"`
 	Status      string          `json:"status"`
 	Value       decimal.Decimal `json:"value"`
 	Version     int32           `json:"version"`
-	CreatedAt   time.Time       `json:"created_at"`
-	UpdatedAt   time.Time       `json:"updated_at"`
+	ChangedAt   time.Time       `json:"changed_at"`
 }
+
+// TradeStats sums up trade leads, with their values converted to a single currency.
 type TradeStats struct {
 	TotalLeads         decimal.Decimal `json:"total_leads"`
 	TotalVerifiedValue decimal.Decimal `json:"total_verified_value"`
 	VerifiedLeads      decimal.Decimal `json:"verified_leads"`
 }
 
-// ValidateTradeLead validates the fields of a TradeLead.
-func ValidateTradeLead(v *validator.Validator, lead *TradeLead) {
+// Add() adds the figures of other to the stats.
+func (s *TradeStats) Add(other *TradeStats) {
+	s.TotalLeads = s.TotalLeads.Add(other.TotalLeads)
+	s.TotalVerifiedValue = s.TotalVerifiedValue.Add(other.TotalVerifiedValue)
+	s.VerifiedLeads = s.VerifiedLeads.Add(other.VerifiedLeads)
+}
+
+// TenantTradeStats holds the stats of a tenant within a hierarchy. Stats only covers the
+// tenant's own leads, Total rolls up the tenant and all of its sub-tenants.
+type TenantTradeStats struct {
+	TenantID int64               `json:"tenant_id"`
+	Name     string              `json:"name"`
+	Stats    TradeStats          `json:"stats"`
+	Total    TradeStats          `json:"total"`
+	Children []*TenantTradeStats `json:"children,omitempty"`
+}
+
+// RollUpTradeStats() builds the stats tree for a tenant tree, summing each tenant's own
+// stats with those of its sub-tenants. Tenants missing from stats have no leads.
+func RollUpTradeStats(tenant *Tenant, stats map[int64]*TradeStats) *TenantTradeStats {
+	node := &TenantTradeStats{
+		TenantID: tenant.ID,
+		Name:     tenant.Name,
+	}
+	if own, ok := stats[tenant.ID]; ok {
+		node.Stats = *own
+	}
+	node.Total = node.Stats
+	for _, child := range tenant.Children {
+		childNode := RollUpTradeStats(child, stats)
+		node.Total.Add(&childNode.Total)
+		node.Children = append(node.Children, childNode)
+	}
+	return node
+}
+
+// tradeStatsTotal holds the stats of the leads in a single currency, as the stores sum
+// them up, before conversion.
+type tradeStatsTotal struct {
+	Currency           string
+	TotalLeads         decimal.Decimal
+	VerifiedLeads      decimal.Decimal
+	TotalVerifiedValue decimal.Decimal
+}
+
+// newTradeStats() adds up the totals of each currency, converting the verified values.
+// It fails with ErrExchangeRateNotFound when a currency can't be converted.
+func newTradeStats(totals []tradeStatsTotal, converter *CurrencyConverter) (*TradeStats, error) {
+	stats := &TradeStats{}
+	for _, total := range totals {
+		value, err := converter.Convert(total.TotalVerifiedValue, total.Currency)
+		if err != nil {
+			return nil, err
+		}
+		stats.TotalLeads = stats.TotalLeads.Add(total.TotalLeads)
+		stats.VerifiedLeads = stats.VerifiedLeads.Add(total.VerifiedLeads)
+		stats.TotalVerifiedValue = stats.TotalVerifiedValue.Add(value)
+	}
+	stats.TotalVerifiedValue = stats.TotalVerifiedValue.Round(2)
+	return stats, nil
+}
+
+// ValidateTradeLead validates the fields of a TradeLead. Custom field values are checked
+// against the definitions of the lead's tenant.
+func ValidateTradeLead(v *validator.Validator, lead *TradeLead, definitions []*CustomField) {
 	// should validate tenant ID, title, description, and value
 	v.Check(lead.Title != "", "title", "must be provided")
 	v.Check(len(lead.Description) <= 1000, "description", "must not be more than 1000 characters long")
 	v.Check(lead.Value.GreaterThan(decimal.Zero), "value", "must be a non-negative or non-zero number")
+	v.Check(IsCurrency(lead.Currency), "currency", "must be an ISO 4217 currency code such as KES")
+	// validate the custom fields against the tenant's schema
+	validateCustomFieldValues(v, lead.CustomFields, definitions)
 }
 
 // CreateTradeLead() creates a new trade lead in the database.
-// we accept the tenant_id, and a *TradeLead struct as input.
-func (m TradeLeadModel) CreateTradeLead(tenantID int64, tenantLead *TradeLead) error {
-	ctx, cancel := contextGenerator(context.Background(), DefaultLeadManagerDBContextTimeout)
+// we accept the tenant_id, and a *TradeLead struct as input. The lead can be created
+// already assigned to a user of the tenant.
+func (m TradeLeadModel) CreateTradeLead(ctx context.Context, tenantID int64, tenantLead *TradeLead) error {
+	ctx, span := startSpan(ctx, "TradeLeadModel.CreateTradeLead")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
+	customFields, err := encodeCustomFields(tenantLead.CustomFields)
+	if err != nil {
+		return err
+	}
 	// create the trade lead in the database
 	newLead, err := m.DB.CreateTradeLead(ctx, database.CreateTradeLeadParams{
-		TenantID:    tenantID,
-		Title:       tenantLead.Title,
-		Description: sql.NullString{String: tenantLead.Description, Valid: true},
-		Value:       tenantLead.Value.String(),
+		TenantID:     tenantID,
+		Title:        tenantLead.Title,
+		Description:  sql.NullString{String: tenantLead.Description, Valid: true},
+		Value:        tenantLead.Value.String(),
+		CustomFields: customFields,
+		Currency:     tenantLead.Currency,
+		AssignedTo:   nullUserID(tenantLead.AssignedTo),
 	})
 	if err != nil {
 		switch {
 		case strings.Contains(err.Error(), "trade_leads_tenant_id_fkey"):
 			return ErrInvalidTenantReference
+		case strings.Contains(err.Error(), "trade_leads_assigned_to_fkey"):
+			return ErrInvalidTradeLeadAssignee
 		case strings.Contains(err.Error(), "trade_leads_status_check"):
 			return ErrInvalidTradeLeadStatus
 		default:
@@ -86,8 +186,10 @@ func (m TradeLeadModel) CreateTradeLead(tenantID int64, tenantLead *TradeLead) e
 }
 
 // GetTradeLeadByID() retrieves a trade lead by its ID from the database.
-func (m TradeLeadModel) GetTradeLeadByID(id int64) (*TradeLead, error) {
-	ctx, cancel := contextGenerator(context.Background(), DefaultLeadManagerDBContextTimeout)
+func (m TradeLeadModel) GetTradeLeadByID(ctx context.Context, id int64) (*TradeLead, error) {
+	ctx, span := startSpan(ctx, "TradeLeadModel.GetTradeLeadByID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 	// get trade lead by ID
 	lead, err := m.DB.GetTradeLeadByID(ctx, id)
@@ -106,16 +208,36 @@ func (m TradeLeadModel) GetTradeLeadByID(id int64) (*TradeLead, error) {
 }
 
 // GetAllLeadsByTenantID() retrieves all trade leads for a specific tenant ID from the database.
-// It supports both filtering and pagination.
-func (m TradeLeadModel) GetAllLeadsByTenantID(tenantID int64, name string, filters Filters) ([]*TradeLead, Metadata, error) {
-	ctx, cancel := contextGenerator(context.Background(), DefaultLeadManagerDBContextTimeout)
+// It supports filtering by assignee, name and custom fields, sorting and pagination. An
+// assigneeID of 0 returns the leads of every assignee, including unassigned ones.
+func (m TradeLeadModel) GetAllLeadsByTenantID(ctx context.Context, tenantID, assigneeID int64, name string, customFilters CustomFieldFilters, filters Filters) ([]*TradeLead, Metadata, error) {
+	return m.GetAllLeadsByTenantIDs(ctx, []int64{tenantID}, assigneeID, name, customFilters, filters)
+}
+
+// GetAllLeadsByTenantIDs() retrieves the trade leads of several tenants at once, such as
+// all tenants of a trading group. It supports the same filters as GetAllLeadsByTenantID().
+func (m TradeLeadModel) GetAllLeadsByTenantIDs(ctx context.Context, tenantIDs []int64, assigneeID int64, name string, customFilters CustomFieldFilters, filters Filters) ([]*TradeLead, Metadata, error) {
+	ctx, span := startSpan(ctx, "TradeLeadModel.GetAllLeadsByTenantIDs")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
-	// get all trade leads by tenant ID
-	leads, err := m.DB.GetAllLeadsByTenantID(ctx, database.GetAllLeadsByTenantIDParams{
-		TenantID: tenantID,
-		Column2:  name,
-		Limit:    filters.limitInt32(),
-		Offset:   filters.offsetInt32(),
+	customFields, err := encodeCustomFields(customFilters.Values)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	sort := leadSortFor(filters, customFilters.Definitions)
+	// get all trade leads by tenant IDs
+	leads, err := m.DB.GetAllLeadsByTenantIDs(ctx, database.GetAllLeadsByTenantIDsParams{
+		TenantIds:     tenantIDs,
+		AssignedTo:    assigneeID,
+		Name:          name,
+		CustomFields:  customFields,
+		SortColumn:    sort.column,
+		SortDirection: sort.direction,
+		SortNumeric:   sort.numeric,
+		SortKey:       sort.key,
+		PageLimit:     filters.limitInt32(),
+		PageOffset:    filters.offsetInt32(),
 	})
 	if err != nil {
 		switch {
@@ -142,15 +264,29 @@ func (m TradeLeadModel) GetAllLeadsByTenantID(tenantID int64, name string, filte
 	return leadRows, metadata, nil
 }
 
-// AdminGetAllTradeLeads() retrieves all trade leads from the database.
-func (m TradeLeadModel) AdminGetAllTradeLeads(name string, filters Filters) ([]*TradeLead, Metadata, error) {
-	ctx, cancel := contextGenerator(context.Background(), DefaultLeadManagerDBContextTimeout)
+// AdminGetAllTradeLeads() retrieves all trade leads from the database. A tenantID of 0
+// returns leads of every tenant; custom field filters only make sense for a single tenant.
+func (m TradeLeadModel) AdminGetAllTradeLeads(ctx context.Context, tenantID int64, name string, customFilters CustomFieldFilters, filters Filters) ([]*TradeLead, Metadata, error) {
+	ctx, span := startSpan(ctx, "TradeLeadModel.AdminGetAllTradeLeads")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
+	customFields, err := encodeCustomFields(customFilters.Values)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	sort := leadSortFor(filters, customFilters.Definitions)
 	// get all trade leads
 	leads, err := m.DB.AdminGetAllTradeLeads(ctx, database.AdminGetAllTradeLeadsParams{
-		Column1: name,
-		Limit:   filters.limitInt32(),
-		Offset:  filters.offsetInt32(),
+		TenantID:      tenantID,
+		Name:          name,
+		CustomFields:  customFields,
+		SortColumn:    sort.column,
+		SortDirection: sort.direction,
+		SortNumeric:   sort.numeric,
+		SortKey:       sort.key,
+		PageLimit:     filters.limitInt32(),
+		PageOffset:    filters.offsetInt32(),
 	})
 	if err != nil {
 		switch {
@@ -178,8 +314,10 @@ func (m TradeLeadModel) AdminGetAllTradeLeads(name string, filters Filters) ([]*
 }
 
 // AdminUpdateTradeLeadStatus() updates the status of a trade lead in the database.
-func (m TradeLeadModel) AdminUpdateTradeLeadStatus(leadID int64, version int32, lead *TradeLead) error {
-	ctx, cancel := contextGenerator(context.Background(), DefaultLeadManagerDBContextTimeout)
+func (m TradeLeadModel) AdminUpdateTradeLeadStatus(ctx context.Context, leadID int64, version int32, lead *TradeLead) error {
+	ctx, span := startSpan(ctx, "TradeLeadModel.AdminUpdateTradeLeadStatus")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 	// update the trade lead status in the database
 	updatedLead, err := m.DB.AdminUpdateTradeLeadStatus(ctx, database.AdminUpdateTradeLeadStatusParams{
@@ -204,66 +342,262 @@ func (m TradeLeadModel) AdminUpdateTradeLeadStatus(leadID int64, version int32,
 	return nil
 }
 
-// AdminGetTRadeLeadStats() retrieves statistics about trade leads from the database.
-func (m TradeLeadModel) AdminGetTradeLeadStats() (*TradeStats, error) {
-	ctx, cancel := contextGenerator(context.Background(), DefaultLeadManagerDBContextTimeout)
+// AssignTradeLead() assigns a trade lead to a user of its tenant, or unassigns it when
+// assigneeID is nil. The version must match the stored lead, otherwise
+// ErrGeneralRecordNotFound is returned.
+func (m TradeLeadModel) AssignTradeLead(ctx context.Context, leadID int64, version int32, assigneeID *int64, lead *TradeLead) error {
+	ctx, span := startSpan(ctx, "TradeLeadModel.AssignTradeLead")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
-	// get trade lead stats
-	stats, err := m.DB.AdminGetTRadeLeadStats(ctx)
+	assignedLead, err := m.DB.AssignTradeLead(ctx, database.AssignTradeLeadParams{
+		ID:         leadID,
+		Version:    version,
+		AssignedTo: nullUserID(assigneeID),
+	})
 	if err != nil {
 		switch {
+		case strings.Contains(err.Error(), "trade_leads_assigned_to_fkey"):
+			return ErrInvalidTradeLeadAssignee
 		case errors.Is(err, sql.ErrNoRows):
-			return nil, ErrGeneralRecordNotFound
+			return ErrGeneralRecordNotFound
+		default:
+			return err
+		}
+	}
+	lead.Version = assignedLead.Version
+	lead.AssignedTo = leadAssigneeID(assignedLead.AssignedTo)
+	lead.UpdatedAt = assignedLead.UpdatedAt
+	return nil
+}
+
+// GetNextTradeLeadAssignee() returns the ID of the user that the tenant's next lead is
+// assigned to when leads are assigned automatically. Activated users take turns in order
+// of their ID, starting after the assignee of the newest assigned lead. It returns
+// ErrGeneralRecordNotFound when the tenant has no activated users.
+func (m TradeLeadModel) GetNextTradeLeadAssignee(ctx context.Context, tenantID int64) (int64, error) {
+	ctx, span := startSpan(ctx, "TradeLeadModel.GetNextTradeLeadAssignee")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	userID, err := m.DB.GetNextTradeLeadAssignee(ctx, tenantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrGeneralRecordNotFound
 		default:
+			return 0, err
+		}
+	}
+	return userID, nil
+}
+
+// AdminGetTRadeLeadStats() retrieves statistics about trade leads from the database, with
+// the verified value converted to the converter's currency.
+func (m TradeLeadModel) AdminGetTradeLeadStats(ctx context.Context, converter *CurrencyConverter) (*TradeStats, error) {
+	ctx, span := startSpan(ctx, "TradeLeadModel.AdminGetTradeLeadStats")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	// get trade lead stats, one row per currency
+	rows, err := m.DB.AdminGetTRadeLeadStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	totals := make([]tradeStatsTotal, 0, len(rows))
+	for _, row := range rows {
+		totals = append(totals, tradeStatsTotal{
+			Currency:           row.Currency,
+			TotalLeads:         decimal.RequireFromString(row.TotalLeads),
+			VerifiedLeads:      decimal.RequireFromString(row.VerifiedLeads),
+			TotalVerifiedValue: decimal.RequireFromString(row.TotalVerifiedValue),
+		})
+	}
+	return newTradeStats(totals, converter)
+}
+
+// GetTradeLeadStatsByTenant() retrieves the stats of each of the given tenants' own leads,
+// keyed by tenant ID, with the verified values converted to the converter's currency.
+// Tenants without leads are left out.
+func (m TradeLeadModel) GetTradeLeadStatsByTenant(ctx context.Context, tenantIDs []int64, converter *CurrencyConverter) (map[int64]*TradeStats, error) {
+	ctx, span := startSpan(ctx, "TradeLeadModel.GetTradeLeadStatsByTenant")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.GetTradeLeadStatsByTenant(ctx, tenantIDs)
+	if err != nil {
+		return nil, err
+	}
+	totals := map[int64][]tradeStatsTotal{}
+	for _, row := range rows {
+		totals[row.TenantID] = append(totals[row.TenantID], tradeStatsTotal{
+			Currency:           row.Currency,
+			TotalLeads:         decimal.RequireFromString(row.TotalLeads),
+			VerifiedLeads:      decimal.RequireFromString(row.VerifiedLeads),
+			TotalVerifiedValue: decimal.RequireFromString(row.TotalVerifiedValue),
+		})
+	}
+	stats := make(map[int64]*TradeStats, len(totals))
+	for tenantID, tenantTotals := range totals {
+		stats[tenantID], err = newTradeStats(tenantTotals, converter)
+		if err != nil {
 			return nil, err
 		}
 	}
-	// Process the stats as needed
-	tradeStats := &TradeStats{
-		TotalLeads:         decimal.RequireFromString(stats.TotalLeads),
-		TotalVerifiedValue: decimal.RequireFromString(stats.TotalVerifiedValue),
-		VerifiedLeads:      decimal.RequireFromString(stats.VerifiedLeads),
+	return stats, nil
+}
+
+// GetAllTradeLeadsForExport() retrieves every trade lead of a tenant without pagination.
+func (m TradeLeadModel) GetAllTradeLeadsForExport(ctx context.Context, tenantID int64) ([]*TradeLead, error) {
+	ctx, span := startSpan(ctx, "TradeLeadModel.GetAllTradeLeadsForExport")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	leads, err := m.DB.GetAllTradeLeadsForExport(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	leadRows := []*TradeLead{}
+	for _, leadRow := range leads {
+		leadRows = append(leadRows, populateTradeLeads(leadRow))
+	}
+	return leadRows, nil
+}
+
+// GetTradeLeadHistoryByTenantID() retrieves the recorded history of every trade lead of a tenant.
+func (m TradeLeadModel) GetTradeLeadHistoryByTenantID(ctx context.Context, tenantID int64) ([]*TradeLeadHistory, error) {
+	ctx, span := startSpan(ctx, "TradeLeadModel.GetTradeLeadHistoryByTenantID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	history, err := m.DB.GetTradeLeadHistoryByTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	historyRows := []*TradeLeadHistory{}
+	for _, historyRow := range history {
+		historyRows = append(historyRows, &TradeLeadHistory{
+			ID:          historyRow.ID,
+			TradeLeadID: historyRow.TradeLeadID,
+			TenantID:    historyRow.TenantID,
+			Status:      historyRow.Status,
+			Value:       decimal.RequireFromString(historyRow.Value),
+			Version:     historyRow.Version,
+			ChangedAt:   historyRow.ChangedAt,
+		})
+	}
+	return historyRows, nil
+}
+
+// leadSort holds the ORDER BY arguments of the trade lead listing queries.
+type leadSort struct {
+	column    string
+	direction string
+	key       string
+	numeric   bool
+}
+
+// leadSortFor() translates the validated sort filter into the listing query arguments.
+// Sorting by "cf.<key>" sorts by a custom field, numerically for number fields.
+func leadSortFor(filters Filters, definitions []*CustomField) leadSort {
+	sort := leadSort{column: "created_at", direction: "DESC"}
+	if filters.Sort == "" {
+		return sort
+	}
+	sort.column = filters.sortColumn()
+	sort.direction = filters.sortDirection()
+	if key, ok := strings.CutPrefix(sort.column, CustomFieldQueryPrefix); ok {
+		sort.column = "custom_field"
+		sort.key = key
+		if def := FindCustomField(definitions, key); def != nil {
+			sort.numeric = def.Type == CustomFieldTypeNumber
+		}
+	}
+	return sort
+}
+
+// encodeCustomFields() marshals custom field values for a JSONB column, defaulting to {}.
+func encodeCustomFields(values map[string]any) (json.RawMessage, error) {
+	if len(values) == 0 {
+		return json.RawMessage("{}"), nil
+	}
+	return json.Marshal(values)
+}
+
+// decodeCustomFields() unmarshals a JSONB custom fields column. Malformed data yields an
+// empty map rather than failing the whole listing.
+func decodeCustomFields(raw json.RawMessage) map[string]any {
+	values := map[string]any{}
+	if len(raw) == 0 {
+		return values
+	}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return map[string]any{}
+	}
+	return values
+}
+
+// nullUserID() converts an optional user ID to its database representation.
+func nullUserID(id *int64) sql.NullInt64 {
+	if id == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *id, Valid: true}
+}
+
+func leadAssigneeID(assignedTo sql.NullInt64) *int64 {
+	if !assignedTo.Valid {
+		return nil
 	}
-	return tradeStats, nil
+	return &assignedTo.Int64
 }
 
 func populateTradeLeads(tradeLeadRow any) *TradeLead {
 	switch leadRow := tradeLeadRow.(type) {
 	case database.TradeLead:
 		return &TradeLead{
-			ID:          leadRow.ID,
-			TenantID:    leadRow.TenantID,
-			Title:       leadRow.Title,
-			Description: leadRow.Description.String,
-			Status:      leadRow.Status,
-			Value:       decimal.RequireFromString(leadRow.Value),
-			Version:     leadRow.Version,
-			CreatedAt:   leadRow.CreatedAt,
-			UpdatedAt:   leadRow.UpdatedAt,
+			ID:           leadRow.ID,
+			TenantID:     leadRow.TenantID,
+			Title:        leadRow.Title,
+			Description:  leadRow.Description.String,
+			Status:       leadRow.Status,
+			Value:        decimal.RequireFromString(leadRow.Value),
+			Currency:     leadRow.Currency,
+			AssignedTo:   leadAssigneeID(leadRow.AssignedTo),
+			CustomFields: decodeCustomFields(leadRow.CustomFields),
+			Version:      leadRow.Version,
+			CreatedAt:    leadRow.CreatedAt,
+			UpdatedAt:    leadRow.UpdatedAt,
 		}
-	case database.GetAllLeadsByTenantIDRow:
+	case database.GetAllLeadsByTenantIDsRow:
 		return &TradeLead{
-			ID:          leadRow.ID,
-			TenantID:    leadRow.TenantID,
-			Title:       leadRow.Title,
-			Description: leadRow.Description.String,
-			Status:      leadRow.Status,
-			Value:       decimal.RequireFromString(leadRow.Value),
-			Version:     leadRow.Version,
-			CreatedAt:   leadRow.CreatedAt,
-			UpdatedAt:   leadRow.UpdatedAt,
+			ID:           leadRow.ID,
+			TenantID:     leadRow.TenantID,
+			Title:        leadRow.Title,
+			Description:  leadRow.Description.String,
+			Status:       leadRow.Status,
+			Value:        decimal.RequireFromString(leadRow.Value),
+			Currency:     leadRow.Currency,
+			AssignedTo:   leadAssigneeID(leadRow.AssignedTo),
+			CustomFields: decodeCustomFields(leadRow.CustomFields),
+			Version:      leadRow.Version,
+			CreatedAt:    leadRow.CreatedAt,
+			UpdatedAt:    leadRow.UpdatedAt,
 		}
 	case database.AdminGetAllTradeLeadsRow:
 		return &TradeLead{
-			ID:          leadRow.ID,
-			TenantID:    leadRow.TenantID,
-			Title:       leadRow.Title,
-			Description: leadRow.Description.String,
-			Status:      leadRow.Status,
-			Value:       decimal.RequireFromString(leadRow.Value),
-			Version:     leadRow.Version,
-			CreatedAt:   leadRow.CreatedAt,
-			UpdatedAt:   leadRow.UpdatedAt,
+			ID:           leadRow.ID,
+			TenantID:     leadRow.TenantID,
+			Title:        leadRow.Title,
+			Description:  leadRow.Description.String,
+			Status:       leadRow.Status,
+			Value:        decimal.RequireFromString(leadRow.Value),
+			Currency:     leadRow.Currency,
+			AssignedTo:   leadAssigneeID(leadRow.AssignedTo),
+			CustomFields: decodeCustomFields(leadRow.CustomFields),
+			Version:      leadRow.Version,
+			CreatedAt:    leadRow.CreatedAt,
+			UpdatedAt:    leadRow.UpdatedAt,
 		}
 	default:
 		return nil // or handle the error as needed
@@ -14,7 +14,8 @@ import (
 )
 
 type UserModel struct {
-	DB *database.Queries
+	DB      *database.Queries
+	Timeout time.Duration
 }
 
 const (
@@ -73,12 +74,14 @@ func (p *password) Matches(plaintextPassword string) (bool, error) {
 // The user struct represents a user account in our application. It contains fields for
 // the user ID, created timestamp, name, email address, password hash, and activation data
 type User struct {
-	ID        int64     `json:"id"`
-	TenantID  int64     `json:"tenant_id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  password  `json:"-"`
-	Activated bool      `json:"-"`
+	ID        int64    `json:"id"`
+	TenantID  int64    `json:"tenant_id"`
+	Name      string   `json:"name"`
+	Email     string   `json:"email"`
+	Password  password `json:"-"`
+	Activated bool     `json:"-"`
+	// Locale is the language of the user's emails, empty uses the tenant's locale.
+	Locale    string    `json:"locale"`
 	Version   int32     `json:"-"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -103,11 +106,17 @@ func ValidatePasswordPlaintext(v *validator.Validator, password string) {
 	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
 	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
 }
+
+// ValidateLocale() checks an optional locale preference.
+func ValidateLocale(v *validator.Validator, locale string) {
+	v.Check(locale == "" || validator.Matches(locale, localeRX), "locale", "must be a locale such as en or fr-FR")
+}
 func ValidateUser(v *validator.Validator, user *User) {
 	// Call the standalone ValidateName() helper.
 	ValidateName(v, user.Name)
 	// Call the standalone ValidateEmail() helper.
 	ValidateEmail(v, user.Email)
+	ValidateLocale(v, user.Locale)
 	// If the plaintext password is not nil, call the standalone
 	// ValidatePasswordPlaintext() helper.
 	if user.Password.plaintext != nil {
@@ -124,9 +133,10 @@ func ValidateUser(v *validator.Validator, user *User) {
 // Insert() creates a new User and returns success on completion.
 // The function will also check for the uniqueness of the user email.
 // Note, this will only "Sign Up" our USER, not log them in.
-func (m UserModel) Insert(user *User) error {
-	// Create a new context with a 5 second timeout
-	ctx, cancel := contextGenerator(context.Background(), DefaultUserManagerDBContextTimeout)
+func (m UserModel) Insert(ctx context.Context, user *User) error {
+	ctx, span := startSpan(ctx, "UserModel.Insert")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 	createduser, err := m.DB.CreateUser(ctx, database.CreateUserParams{
 		TenantID:     user.TenantID,
@@ -134,6 +144,7 @@ func (m UserModel) Insert(user *User) error {
 		Email:        user.Email,
 		PasswordHash: user.Password.hash,
 		Activated:    user.Activated,
+		Locale:       user.Locale,
 	})
 
 	if err != nil {
@@ -158,10 +169,12 @@ func (m UserModel) Insert(user *User) error {
 // It calculates the sha256 hash of the provided plaintext token, and then queries
 // the database for a user with that token and scope. If found, it returns a User
 // struct populated with the user's data. If not found, it returns an error.
-func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
+func (m UserModel) GetForToken(ctx context.Context, tokenScope, tokenPlaintext string) (*User, error) {
 	// Calculate sha256 hash of plaintext
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
-	ctx, cancel := contextGenerator(context.Background(), DefaultUserManagerDBContextTimeout)
+	ctx, span := startSpan(ctx, "UserModel.GetForToken")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 	// get the user
 	user, err := m.DB.GetForToken(ctx, database.GetForTokenParams{
@@ -189,9 +202,10 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 // with the provided email, and returns a populated User struct if found. If no user
 // is found, it returns an ErrGeneralRecordNotFound error. If any other error occurs,
 // it returns that error.
-func (m UserModel) GetByEmail(email string) (*User, error) {
-	// Create a new context with a 5 second timeout
-	ctx, cancel := contextGenerator(context.Background(), DefaultUserManagerDBContextTimeout)
+func (m UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, span := startSpan(ctx, "UserModel.GetByEmail")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 	// get the user by email
 	user, err := m.DB.GetUserByEmail(ctx, email)
@@ -209,10 +223,12 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 	return populatedUser, nil
 }
 
-// UpdateUser() updates an existing user in the database.
-func (m UserModel) UpdateUser(user *User) error {
-	// Create a new context with a 5 second timeout
-	ctx, cancel := contextGenerator(context.Background(), DefaultUserManagerDBContextTimeout)
+// UpdateUser() updates an existing user in the database. The update only applies to the
+// version the user was read at, otherwise ErrGeneralEditConflict is returned.
+func (m UserModel) UpdateUser(ctx context.Context, user *User) error {
+	ctx, span := startSpan(ctx, "UserModel.UpdateUser")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
 	defer cancel()
 	// Update the user in the database
 	updatedUser, err := m.DB.UpdateUser(ctx, database.UpdateUserParams{
@@ -221,20 +237,42 @@ func (m UserModel) UpdateUser(user *User) error {
 		Email:        user.Email,
 		PasswordHash: user.Password.hash,
 		Activated:    user.Activated,
+		Locale:       user.Locale,
 		Version:      int32(user.Version),
 	})
 	if err != nil {
 		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrGeneralEditConflict
 		case strings.Contains(err.Error(), "users_email_key"):
 			return ErrDuplicateEmail
 		default:
 			return err
 		}
 	}
+	user.Version = updatedUser.Version
 	user.UpdatedAt = updatedUser.UpdatedAt
 	return nil
 }
 
+// GetAllUsersByTenantID() retrieves every user belonging to a tenant. Password hashes are
+// never selected, so the returned users can safely be handed out in exports.
+func (m UserModel) GetAllUsersByTenantID(ctx context.Context, tenantID int64) ([]*User, error) {
+	ctx, span := startSpan(ctx, "UserModel.GetAllUsersByTenantID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	users, err := m.DB.GetAllUsersByTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	tenantUsers := []*User{}
+	for _, user := range users {
+		tenantUsers = append(tenantUsers, populateUser(user))
+	}
+	return tenantUsers, nil
+}
+
 // populateUser() takes a userRow of type any and attempts to convert it to a User struct.
 // It checks the type of userRow, and if it is of type database.User, it creates a new
 // password struct instance with the user's password hash. It then returns a pointer to a
@@ -253,6 +291,19 @@ func populateUser(userRow any) *User {
 			Email:     user.Email,
 			Password:  userPassword,
 			Activated: user.Activated,
+			Locale:    user.Locale,
+			Version:   user.Version,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+		}
+	case database.GetAllUsersByTenantIDRow:
+		return &User{
+			ID:        user.ID,
+			TenantID:  user.TenantID,
+			Name:      user.Name,
+			Email:     user.Email,
+			Activated: user.Activated,
+			Locale:    user.Locale,
 			Version:   user.Version,
 			CreatedAt: user.CreatedAt,
 			UpdatedAt: user.UpdatedAt,
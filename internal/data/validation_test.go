@@ -21,6 +21,7 @@ func TestValidateTradeLead(t *testing.T) {
 				Title:       "Valid Trade Lead",
 				Description: "This is a valid trade lead description",
 				Value:       decimal.NewFromFloat(1000.50),
+				Currency:    "KES",
 				Status:      "new",
 			},
 			wantValid: true,
@@ -32,6 +33,7 @@ func TestValidateTradeLead(t *testing.T) {
 				Title:       "",
 				Description: "Valid description",
 				Value:       decimal.NewFromFloat(1000.50),
+				Currency:    "KES",
 				Status:      "new",
 			},
 			wantValid: false,
@@ -44,6 +46,7 @@ func TestValidateTradeLead(t *testing.T) {
 				Title:       "Valid Title",
 				Description: generateLongString(1001), // Over 1000 characters
 				Value:       decimal.NewFromFloat(1000.50),
+				Currency:    "KES",
 				Status:      "new",
 			},
 			wantValid: false,
@@ -56,11 +59,25 @@ func TestValidateTradeLead(t *testing.T) {
 				Title:       "Valid Title",
 				Description: "Valid description",
 				Value:       decimal.Zero,
+				Currency:    "KES",
 				Status:      "new",
 			},
 			wantValid: false,
 			wantError: "value",
 		},
+		{
+			name: "Unknown currency should fail",
+			lead: &TradeLead{
+				TenantID:    1,
+				Title:       "Valid Title",
+				Description: "Valid description",
+				Value:       decimal.NewFromFloat(1000.50),
+				Currency:    "kes",
+				Status:      "new",
+			},
+			wantValid: false,
+			wantError: "currency",
+		},
 		{
 			name: "Negative value should fail",
 			lead: &TradeLead{
@@ -68,6 +85,7 @@ func TestValidateTradeLead(t *testing.T) {
 				Title:       "Valid Title",
 				Description: "Valid description",
 				Value:       decimal.NewFromFloat(-100.00),
+				Currency:    "KES",
 				Status:      "new",
 			},
 			wantValid: false,
@@ -78,7 +96,7 @@ func TestValidateTradeLead(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			v := validator.New()
-			ValidateTradeLead(v, tt.lead)
+			ValidateTradeLead(v, tt.lead, nil)
 
 			if tt.wantValid && !v.Valid() {
 				t.Errorf("Expected valid trade lead, but got validation errors: %v", v.Errors)
@@ -193,11 +211,12 @@ func TestTradeLeadDecimalPrecision(t *testing.T) {
 		Title:       "High Value Trade",
 		Description: "Trade with precise monetary value",
 		Value:       decimal.RequireFromString("999999.99"), // Precise decimal
+		Currency:    "KES",
 		Status:      "new",
 	}
 
 	v := validator.New()
-	ValidateTradeLead(v, lead)
+	ValidateTradeLead(v, lead, nil)
 
 	if !v.Valid() {
 		t.Errorf("Valid high-precision decimal should pass validation, got errors: %v", v.Errors)
@@ -0,0 +1,463 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/database"
+	"github.com/Blue-Davinci/leadhub-service/internal/validator"
+)
+
+type WebhookModel struct {
+	DB      *database.Queries
+	Timeout time.Duration
+}
+
+const (
+	DefaultWebhookDBContextTimeout = 5 * time.Second
+	// MaxWebhookEndpointsPerTenant caps the endpoints a tenant can register.
+	MaxWebhookEndpointsPerTenant = 10
+	// webhookSecretPrefix makes webhook secrets recognisable, e.g. in secret scanners.
+	webhookSecretPrefix = "whsec_"
+)
+
+// Define constants for the event types webhook endpoints can subscribe to.
+const (
+	WebhookEventTradeLeadCreated       = "trade_lead.created"
+	WebhookEventTradeLeadStatusChanged = "trade_lead.status_changed"
+	WebhookEventTradeLeadAssigned      = "trade_lead.assigned"
+	WebhookEventUserCreated            = "user.created"
+	WebhookEventUserActivated          = "user.activated"
+)
+
+// WebhookEventTypes lists every event type webhook endpoints can subscribe to.
+var WebhookEventTypes = []string{
+	WebhookEventTradeLeadCreated,
+	WebhookEventTradeLeadStatusChanged,
+	WebhookEventTradeLeadAssigned,
+	WebhookEventUserCreated,
+	WebhookEventUserActivated,
+}
+
+// Define constants for the status of a webhook delivery. A pending delivery is waiting for
+// its first or next attempt, a failed one has run out of attempts.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookEndpoint is a URL of a tenant that is sent the events it subscribed to. The secret
+// signing the payloads is only returned by the API when the endpoint is created.
+type WebhookEndpoint struct {
+	ID                  int64     `json:"id"`
+	TenantID            int64     `json:"tenant_id"`
+	URL                 string    `json:"url"`
+	Description         string    `json:"description"`
+	Secret              string    `json:"-"`
+	EventTypes          []string  `json:"event_types"`
+	Enabled             bool      `json:"enabled"`
+	DisabledReason      string    `json:"disabled_reason,omitempty"`
+	ConsecutiveFailures int32     `json:"consecutive_failures"`
+	Version             int32     `json:"version"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is the record of an event sent to a webhook endpoint. The payload is the
+// exact JSON body sent, so a redelivery carries the same event ID.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	EndpointID     int64           `json:"endpoint_id"`
+	TenantID       int64           `json:"tenant_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	ResponseStatus int32           `json:"response_status,omitempty"`
+	ResponseBody   string          `json:"response_body,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DurationMS     int32           `json:"duration_ms"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// WebhookAttempt is the outcome of one delivery attempt of a webhook delivery. The response
+// status and body are empty when the endpoint could not be reached.
+type WebhookAttempt struct {
+	Status         string
+	ResponseStatus int32
+	ResponseBody   string
+	LastError      string
+	Duration       time.Duration
+}
+
+// ValidateWebhookEndpoint() validates the URL, description and event types of an endpoint.
+func ValidateWebhookEndpoint(v *validator.Validator, endpoint *WebhookEndpoint) {
+	v.Check(endpoint.URL != "", "url", "must be provided")
+	v.Check(len(endpoint.URL) <= 2048, "url", "must not be more than 2048 characters long")
+	if endpoint.URL != "" {
+		parsed, err := url.Parse(endpoint.URL)
+		v.Check(err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != "",
+			"url", "must be an absolute http or https URL")
+		v.Check(err != nil || parsed.User == nil, "url", "must not contain credentials")
+	}
+	v.Check(len(endpoint.Description) <= 200, "description", "must not be more than 200 characters long")
+	v.Check(len(endpoint.EventTypes) > 0, "event_types", "must contain at least one event type")
+	v.Check(validator.Unique(endpoint.EventTypes), "event_types", "must not contain duplicate values")
+	for _, eventType := range endpoint.EventTypes {
+		v.Check(validator.PermittedValue(eventType, WebhookEventTypes...), "event_types", fmt.Sprintf("%q is not a supported event type", eventType))
+	}
+}
+
+// ValidateWebhookDeliveryStatus() checks an optional status filter of the delivery log.
+func ValidateWebhookDeliveryStatus(v *validator.Validator, status string) {
+	v.Check(status == "" || validator.PermittedValue(status, WebhookDeliveryPending, WebhookDeliverySucceeded, WebhookDeliveryFailed),
+		"status", "must be one of pending, succeeded or failed")
+}
+
+// CreateWebhookEndpoint() creates an enabled webhook endpoint with a new random secret.
+func (m WebhookModel) CreateWebhookEndpoint(ctx context.Context, endpoint *WebhookEndpoint) error {
+	ctx, span := startSpan(ctx, "WebhookModel.CreateWebhookEndpoint")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return err
+	}
+	newEndpoint, err := m.DB.CreateWebhookEndpoint(ctx, database.CreateWebhookEndpointParams{
+		TenantID:    endpoint.TenantID,
+		Url:         endpoint.URL,
+		Description: endpoint.Description,
+		Secret:      secret,
+		EventTypes:  endpoint.EventTypes,
+	})
+	if err != nil {
+		return err
+	}
+	endpoint.ID = newEndpoint.ID
+	endpoint.Secret = secret
+	endpoint.Enabled = newEndpoint.Enabled
+	endpoint.ConsecutiveFailures = newEndpoint.ConsecutiveFailures
+	endpoint.Version = newEndpoint.Version
+	endpoint.CreatedAt = newEndpoint.CreatedAt
+	endpoint.UpdatedAt = newEndpoint.UpdatedAt
+	return nil
+}
+
+// GetWebhookEndpointByID() retrieves a webhook endpoint belonging to the tenant.
+func (m WebhookModel) GetWebhookEndpointByID(ctx context.Context, tenantID, endpointID int64) (*WebhookEndpoint, error) {
+	ctx, span := startSpan(ctx, "WebhookModel.GetWebhookEndpointByID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	endpoint, err := m.DB.GetWebhookEndpointByID(ctx, database.GetWebhookEndpointByIDParams{
+		ID:       endpointID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrGeneralRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return populateWebhookEndpoint(endpoint), nil
+}
+
+// GetWebhookEndpointsByTenantID() retrieves all webhook endpoints of a tenant.
+func (m WebhookModel) GetWebhookEndpointsByTenantID(ctx context.Context, tenantID int64) ([]*WebhookEndpoint, error) {
+	ctx, span := startSpan(ctx, "WebhookModel.GetWebhookEndpointsByTenantID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.GetWebhookEndpointsByTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := []*WebhookEndpoint{}
+	for _, row := range rows {
+		endpoints = append(endpoints, populateWebhookEndpoint(row))
+	}
+	return endpoints, nil
+}
+
+// GetSubscribedWebhookEndpoints() retrieves the enabled endpoints of a tenant that subscribed
+// to the event type.
+func (m WebhookModel) GetSubscribedWebhookEndpoints(ctx context.Context, tenantID int64, eventType string) ([]*WebhookEndpoint, error) {
+	ctx, span := startSpan(ctx, "WebhookModel.GetSubscribedWebhookEndpoints")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.GetSubscribedWebhookEndpoints(ctx, database.GetSubscribedWebhookEndpointsParams{
+		TenantID:  tenantID,
+		EventType: eventType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	endpoints := []*WebhookEndpoint{}
+	for _, row := range rows {
+		endpoints = append(endpoints, populateWebhookEndpoint(row))
+	}
+	return endpoints, nil
+}
+
+// UpdateWebhookEndpoint() updates the URL, description, event types and enabled flag of an
+// endpoint. Enabling a disabled endpoint clears its failures.
+func (m WebhookModel) UpdateWebhookEndpoint(ctx context.Context, endpoint *WebhookEndpoint, version int32) error {
+	ctx, span := startSpan(ctx, "WebhookModel.UpdateWebhookEndpoint")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	updated, err := m.DB.UpdateWebhookEndpoint(ctx, database.UpdateWebhookEndpointParams{
+		Url:         endpoint.URL,
+		Description: endpoint.Description,
+		EventTypes:  endpoint.EventTypes,
+		Enabled:     endpoint.Enabled,
+		ID:          endpoint.ID,
+		Version:     version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrGeneralEditConflict
+		default:
+			return err
+		}
+	}
+	endpoint.DisabledReason = updated.DisabledReason
+	endpoint.ConsecutiveFailures = updated.ConsecutiveFailures
+	endpoint.Version = updated.Version
+	endpoint.UpdatedAt = updated.UpdatedAt
+	return nil
+}
+
+// DeleteWebhookEndpoint() removes a webhook endpoint of the tenant with its deliveries.
+func (m WebhookModel) DeleteWebhookEndpoint(ctx context.Context, tenantID, endpointID int64) error {
+	ctx, span := startSpan(ctx, "WebhookModel.DeleteWebhookEndpoint")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.DeleteWebhookEndpoint(ctx, database.DeleteWebhookEndpointParams{
+		ID:       endpointID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrGeneralRecordNotFound
+	}
+	return nil
+}
+
+// RecordWebhookEndpointResult() counts a delivery that succeeded or failed for good towards
+// the endpoint's failures in a row. The failure making disableAfter failures in a row
+// disables the endpoint, a disableAfter of 0 never does. It reports whether the endpoint is
+// still enabled.
+func (m WebhookModel) RecordWebhookEndpointResult(ctx context.Context, endpointID int64, succeeded bool, disableAfter int32) (bool, error) {
+	ctx, span := startSpan(ctx, "WebhookModel.RecordWebhookEndpointResult")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	result, err := m.DB.RecordWebhookEndpointResult(ctx, database.RecordWebhookEndpointResultParams{
+		Succeeded:      succeeded,
+		DisableAfter:   disableAfter,
+		DisabledReason: webhookDisabledReason(disableAfter),
+		ID:             endpointID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return false, ErrGeneralRecordNotFound
+		default:
+			return false, err
+		}
+	}
+	return result.Enabled, nil
+}
+
+// CreateWebhookDelivery() stores a new pending delivery.
+func (m WebhookModel) CreateWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	ctx, span := startSpan(ctx, "WebhookModel.CreateWebhookDelivery")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	newDelivery, err := m.DB.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+		EndpointID: delivery.EndpointID,
+		TenantID:   delivery.TenantID,
+		EventID:    delivery.EventID,
+		EventType:  delivery.EventType,
+		Payload:    delivery.Payload,
+	})
+	if err != nil {
+		return err
+	}
+	delivery.ID = newDelivery.ID
+	delivery.Status = newDelivery.Status
+	delivery.Attempts = newDelivery.Attempts
+	delivery.CreatedAt = newDelivery.CreatedAt
+	delivery.UpdatedAt = newDelivery.UpdatedAt
+	return nil
+}
+
+// GetWebhookDeliveryByID() retrieves a webhook delivery by its ID.
+func (m WebhookModel) GetWebhookDeliveryByID(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	ctx, span := startSpan(ctx, "WebhookModel.GetWebhookDeliveryByID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	delivery, err := m.DB.GetWebhookDeliveryByID(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrGeneralRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return populateWebhookDelivery(delivery), nil
+}
+
+// GetWebhookDeliveriesByEndpointID() lists the deliveries of an endpoint, newest first. An
+// empty status doesn't filter.
+func (m WebhookModel) GetWebhookDeliveriesByEndpointID(ctx context.Context, endpointID int64, status string, filters Filters) ([]*WebhookDelivery, Metadata, error) {
+	ctx, span := startSpan(ctx, "WebhookModel.GetWebhookDeliveriesByEndpointID")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	rows, err := m.DB.GetWebhookDeliveriesByEndpointID(ctx, database.GetWebhookDeliveriesByEndpointIDParams{
+		EndpointID: endpointID,
+		Status:     status,
+		PageLimit:  filters.limitInt32(),
+		PageOffset: filters.offsetInt32(),
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	if len(rows) == 0 {
+		return nil, Metadata{}, ErrGeneralRecordNotFound
+	}
+	deliveries := []*WebhookDelivery{}
+	totalRows := 0
+	for _, row := range rows {
+		totalRows = int(row.TotalCount)
+		deliveries = append(deliveries, populateWebhookDelivery(database.WebhookDelivery{
+			ID:             row.ID,
+			EndpointID:     row.EndpointID,
+			TenantID:       row.TenantID,
+			EventID:        row.EventID,
+			EventType:      row.EventType,
+			Payload:        row.Payload,
+			Status:         row.Status,
+			Attempts:       row.Attempts,
+			ResponseStatus: row.ResponseStatus,
+			ResponseBody:   row.ResponseBody,
+			LastError:      row.LastError,
+			DurationMs:     row.DurationMs,
+			DeliveredAt:    row.DeliveredAt,
+			CreatedAt:      row.CreatedAt,
+			UpdatedAt:      row.UpdatedAt,
+		}))
+	}
+	return deliveries, calculateMetadata(totalRows, filters.Page, filters.PageSize), nil
+}
+
+// RecordWebhookDeliveryAttempt() counts a delivery attempt and stores its outcome.
+func (m WebhookModel) RecordWebhookDeliveryAttempt(ctx context.Context, id int64, attempt WebhookAttempt) error {
+	ctx, span := startSpan(ctx, "WebhookModel.RecordWebhookDeliveryAttempt")
+	defer span.End()
+	ctx, cancel := contextGenerator(ctx, m.Timeout)
+	defer cancel()
+	var deliveredAt sql.NullTime
+	if attempt.Status == WebhookDeliverySucceeded {
+		deliveredAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	rows, err := m.DB.RecordWebhookDeliveryAttempt(ctx, database.RecordWebhookDeliveryAttemptParams{
+		ID:             id,
+		Status:         attempt.Status,
+		ResponseStatus: attempt.ResponseStatus,
+		ResponseBody:   attempt.ResponseBody,
+		LastError:      attempt.LastError,
+		DurationMs:     attempt.durationMS(),
+		DeliveredAt:    deliveredAt,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrGeneralRecordNotFound
+	}
+	return nil
+}
+
+// durationMS() returns the duration of the attempt in whole milliseconds.
+func (a WebhookAttempt) durationMS() int32 {
+	return int32(min(a.Duration.Milliseconds(), int64(1<<31-1)))
+}
+
+// webhookDisabledReason() explains why an endpoint was disabled after its failures.
+func webhookDisabledReason(disableAfter int32) string {
+	return fmt.Sprintf("disabled after %d failed deliveries in a row", disableAfter)
+}
+
+// generateWebhookSecret() returns a random secret for signing webhook payloads.
+func generateWebhookSecret() (string, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + hex.EncodeToString(randomBytes), nil
+}
+
+func populateWebhookEndpoint(endpointRow database.WebhookEndpoint) *WebhookEndpoint {
+	return &WebhookEndpoint{
+		ID:                  endpointRow.ID,
+		TenantID:            endpointRow.TenantID,
+		URL:                 endpointRow.Url,
+		Description:         endpointRow.Description,
+		Secret:              endpointRow.Secret,
+		EventTypes:          endpointRow.EventTypes,
+		Enabled:             endpointRow.Enabled,
+		DisabledReason:      endpointRow.DisabledReason,
+		ConsecutiveFailures: endpointRow.ConsecutiveFailures,
+		Version:             endpointRow.Version,
+		CreatedAt:           endpointRow.CreatedAt,
+		UpdatedAt:           endpointRow.UpdatedAt,
+	}
+}
+
+func populateWebhookDelivery(deliveryRow database.WebhookDelivery) *WebhookDelivery {
+	delivery := &WebhookDelivery{
+		ID:             deliveryRow.ID,
+		EndpointID:     deliveryRow.EndpointID,
+		TenantID:       deliveryRow.TenantID,
+		EventID:        deliveryRow.EventID,
+		EventType:      deliveryRow.EventType,
+		Payload:        deliveryRow.Payload,
+		Status:         deliveryRow.Status,
+		Attempts:       deliveryRow.Attempts,
+		ResponseStatus: deliveryRow.ResponseStatus,
+		ResponseBody:   deliveryRow.ResponseBody,
+		LastError:      deliveryRow.LastError,
+		DurationMS:     deliveryRow.DurationMs,
+		CreatedAt:      deliveryRow.CreatedAt,
+		UpdatedAt:      deliveryRow.UpdatedAt,
+	}
+	if deliveryRow.DeliveredAt.Valid {
+		delivery.DeliveredAt = &deliveryRow.DeliveredAt.Time
+	}
+	return delivery
+}
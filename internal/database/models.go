@@ -6,6 +6,7 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
@@ -16,6 +17,49 @@ type ApiKey struct {
 	Scope  string
 }
 
+type EmailOutbox struct {
+	ID                int64
+	TenantID          int64
+	Recipient         string
+	Template          string
+	Locale            string
+	Data              json.RawMessage
+	Subject           string
+	Status            string
+	Attempts          int32
+	LastError         string
+	ProviderMessageID string
+	SentAt            sql.NullTime
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type ExchangeRate struct {
+	ID            int64
+	BaseCurrency  string
+	QuoteCurrency string
+	Rate          string
+	RateDate      time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type Job struct {
+	ID          int64
+	Kind        string
+	Payload     json.RawMessage
+	Status      string
+	Attempts    int32
+	MaxAttempts int32
+	RunAt       time.Time
+	LastError   string
+	LockedBy    string
+	LockedAt    sql.NullTime
+	CompletedAt sql.NullTime
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
 type Permission struct {
 	ID   int64
 	Code string
@@ -29,18 +73,99 @@ type Tenant struct {
 	Version      int32
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	ParentID     sql.NullInt64
 }
 
-type TradeLead struct {
+type TenantCustomField struct {
+	ID        int64
+	TenantID  int64
+	FieldKey  string
+	Label     string
+	FieldType string
+	Required  bool
+	Options   []string
+	Version   int32
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type TenantDomain struct {
+	ID                int64
+	TenantID          int64
+	Domain            string
+	VerificationToken string
+	Verified          bool
+	VerifiedAt        sql.NullTime
+	Version           int32
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type TenantExport struct {
 	ID          int64
 	TenantID    int64
-	Title       string
-	Description sql.NullString
+	RequestedBy int64
 	Status      string
-	Value       string
+	FileKey     string
+	FileSize    int64
+	Error       string
+	CompletedAt sql.NullTime
 	Version     int32
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	Currency    string
+}
+
+type TenantSetting struct {
+	TenantID        int64
+	DisplayName     string
+	LogoUrl         string
+	PrimaryColor    string
+	ReplyTo         string
+	DefaultCurrency string
+	Timezone        string
+	Locale          string
+	Version         int32
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	AutoAssignLeads bool
+}
+
+type TradeLead struct {
+	ID           int64
+	TenantID     int64
+	Title        string
+	Description  sql.NullString
+	Status       string
+	Value        string
+	Version      int32
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	CustomFields json.RawMessage
+	Currency     string
+	AssignedTo   sql.NullInt64
+}
+
+type TradeLeadComment struct {
+	ID          int64
+	TradeLeadID int64
+	TenantID    int64
+	AuthorID    sql.NullInt64
+	Body        string
+	Internal    bool
+	Version     int32
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type TradeLeadHistory struct {
+	ID          int64
+	TradeLeadID int64
+	TenantID    int64
+	Status      string
+	Value       string
+	Version     int32
+	ChangedAt   time.Time
 }
 
 type User struct {
@@ -53,9 +178,43 @@ type User struct {
 	Version      int32
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	Locale       string
 }
 
 type UsersPermission struct {
 	UserID       int64
 	PermissionID int64
 }
+
+type WebhookDelivery struct {
+	ID             int64
+	EndpointID     int64
+	TenantID       int64
+	EventID        string
+	EventType      string
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int32
+	ResponseStatus int32
+	ResponseBody   string
+	LastError      string
+	DurationMs     int32
+	DeliveredAt    sql.NullTime
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type WebhookEndpoint struct {
+	ID                  int64
+	TenantID            int64
+	Url                 string
+	Description         string
+	Secret              string
+	EventTypes          []string
+	Enabled             bool
+	DisabledReason      string
+	ConsecutiveFailures int32
+	Version             int32
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
@@ -9,6 +9,8 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 const adminGetAllTenants = `-- name: AdminGetAllTenants :many
@@ -19,9 +21,11 @@ SELECT count(*) OVER() AS total_count,
     description,
     version, 
     created_at, 
-    updated_at
+    updated_at,
+    parent_id
 FROM tenants
-WHERE ($1 = '' OR to_tsvector('simple', name) @@ plainto_tsquery('simple', $1))
+WHERE ($1 = '' AND parent_id IS NULL)
+   OR ($1 <> '' AND to_tsvector('simple', name) @@ plainto_tsquery('simple', $1))
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
 `
@@ -41,6 +45,7 @@ type AdminGetAllTenantsRow struct {
 	Version      int32
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	ParentID     sql.NullInt64
 }
 
 func (q *Queries) AdminGetAllTenants(ctx context.Context, arg AdminGetAllTenantsParams) ([]AdminGetAllTenantsRow, error) {
@@ -61,6 +66,7 @@ func (q *Queries) AdminGetAllTenants(ctx context.Context, arg AdminGetAllTenants
 			&i.Version,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ParentID,
 		); err != nil {
 			return nil, err
 		}
@@ -76,8 +82,8 @@ func (q *Queries) AdminGetAllTenants(ctx context.Context, arg AdminGetAllTenants
 }
 
 const createTenant = `-- name: CreateTenant :one
-INSERT INTO tenants (name, contact_email, description)
-VALUES ($1, $2, $3)
+INSERT INTO tenants (name, contact_email, description, parent_id)
+VALUES ($1, $2, $3, $4)
 RETURNING id, version, created_at, updated_at
 `
 
@@ -85,6 +91,7 @@ type CreateTenantParams struct {
 	Name         string
 	ContactEmail string
 	Description  sql.NullString
+	ParentID     sql.NullInt64
 }
 
 type CreateTenantRow struct {
@@ -95,7 +102,12 @@ type CreateTenantRow struct {
 }
 
 func (q *Queries) CreateTenant(ctx context.Context, arg CreateTenantParams) (CreateTenantRow, error) {
-	row := q.db.QueryRowContext(ctx, createTenant, arg.Name, arg.ContactEmail, arg.Description)
+	row := q.db.QueryRowContext(ctx, createTenant,
+		arg.Name,
+		arg.ContactEmail,
+		arg.Description,
+		arg.ParentID,
+	)
 	var i CreateTenantRow
 	err := row.Scan(
 		&i.ID,
@@ -114,7 +126,8 @@ SELECT
     description, 
     version,
     created_at, 
-    updated_at
+    updated_at,
+    parent_id
 FROM tenants
 WHERE id = $1
 `
@@ -130,16 +143,105 @@ func (q *Queries) GetTenantByID(ctx context.Context, id int64) (Tenant, error) {
 		&i.Version,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ParentID,
 	)
 	return i, err
 }
 
+const getTenantDescendantIDs = `-- name: GetTenantDescendantIDs :many
+WITH RECURSIVE tenant_tree AS (
+    SELECT id FROM tenants WHERE id = $1
+    UNION
+    SELECT t.id FROM tenants t
+    INNER JOIN tenant_tree tt ON t.parent_id = tt.id
+)
+SELECT id FROM tenant_tree
+ORDER BY id
+`
+
+func (q *Queries) GetTenantDescendantIDs(ctx context.Context, id int64) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getTenantDescendantIDs, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTenantSubtrees = `-- name: GetTenantSubtrees :many
+WITH RECURSIVE tenant_tree AS (
+    SELECT t.id FROM tenants t WHERE t.parent_id = ANY($1::bigint[])
+    UNION
+    SELECT t.id FROM tenants t
+    INNER JOIN tenant_tree tt ON t.parent_id = tt.id
+)
+SELECT 
+    t.id, 
+    t.name, 
+    t.contact_email, 
+    t.description, 
+    t.version,
+    t.created_at, 
+    t.updated_at,
+    t.parent_id
+FROM tenants t
+INNER JOIN tenant_tree tt ON t.id = tt.id
+ORDER BY t.created_at DESC
+`
+
+func (q *Queries) GetTenantSubtrees(ctx context.Context, rootIds []int64) ([]Tenant, error) {
+	rows, err := q.db.QueryContext(ctx, getTenantSubtrees, pq.Array(rootIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Tenant
+	for rows.Next() {
+		var i Tenant
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.ContactEmail,
+			&i.Description,
+			&i.Version,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateTenant = `-- name: UpdateTenant :one
 UPDATE tenants
 SET 
     name = $2,
     contact_email = $3,
-    description = $4
+    description = $4,
+    parent_id = $6
 WHERE id = $1 AND version = $5
 RETURNING version, updated_at
 `
@@ -150,6 +252,7 @@ type UpdateTenantParams struct {
 	ContactEmail string
 	Description  sql.NullString
 	Version      int32
+	ParentID     sql.NullInt64
 }
 
 type UpdateTenantRow struct {
@@ -164,6 +267,7 @@ func (q *Queries) UpdateTenant(ctx context.Context, arg UpdateTenantParams) (Upd
 		arg.ContactEmail,
 		arg.Description,
 		arg.Version,
+		arg.ParentID,
 	)
 	var i UpdateTenantRow
 	err := row.Scan(&i.Version, &i.UpdatedAt)
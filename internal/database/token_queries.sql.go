@@ -35,7 +35,8 @@ SELECT
     users.activated, 
     users.version, 
     users.created_at, 
-    users.updated_at
+    users.updated_at,
+    users.locale
 FROM users
 INNER JOIN api_keys
 ON users.id = api_keys.user_id
@@ -63,6 +64,7 @@ func (q *Queries) GetForToken(ctx context.Context, arg GetForTokenParams) (User,
 		&i.Version,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Locale,
 	)
 	return i, err
 }
@@ -8,7 +8,10 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 const adminGetAllTradeLeads = `-- name: AdminGetAllTradeLeads :many
@@ -22,34 +25,69 @@ SELECT
   value, 
   version,
   created_at, 
-  updated_at
+  updated_at,
+  custom_fields,
+  currency,
+  assigned_to
 FROM trade_leads
-WHERE ($1 = '' OR to_tsvector('simple', title) @@ plainto_tsquery('simple', $1))
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+WHERE ($1::bigint = 0 OR tenant_id = $1::bigint)
+  AND ($2::text = '' OR to_tsvector('simple', title) @@ plainto_tsquery('simple', $2::text))
+  AND custom_fields @> $3::jsonb
+ORDER BY
+  CASE WHEN $4::text = 'title' AND $5::text = 'ASC' THEN title END ASC,
+  CASE WHEN $4::text = 'title' AND $5::text = 'DESC' THEN title END DESC,
+  CASE WHEN $4::text = 'value' AND $5::text = 'ASC' THEN value END ASC,
+  CASE WHEN $4::text = 'value' AND $5::text = 'DESC' THEN value END DESC,
+  CASE WHEN $4::text = 'created_at' AND $5::text = 'ASC' THEN created_at END ASC,
+  CASE WHEN $4::text = 'custom_field' AND $6::boolean AND $5::text = 'ASC' AND jsonb_typeof(custom_fields->$7::text) = 'number' THEN (custom_fields->>$7::text)::numeric END ASC,
+  CASE WHEN $4::text = 'custom_field' AND $6::boolean AND $5::text = 'DESC' AND jsonb_typeof(custom_fields->$7::text) = 'number' THEN (custom_fields->>$7::text)::numeric END DESC,
+  CASE WHEN $4::text = 'custom_field' AND NOT $6::boolean AND $5::text = 'ASC' THEN custom_fields->>$7::text END ASC,
+  CASE WHEN $4::text = 'custom_field' AND NOT $6::boolean AND $5::text = 'DESC' THEN custom_fields->>$7::text END DESC,
+  created_at DESC,
+  id DESC
+LIMIT $8 OFFSET $9
 `
 
 type AdminGetAllTradeLeadsParams struct {
-	Column1 interface{}
-	Limit   int32
-	Offset  int32
+	TenantID      int64
+	Name          string
+	CustomFields  json.RawMessage
+	SortColumn    string
+	SortDirection string
+	SortNumeric   bool
+	SortKey       string
+	PageLimit     int32
+	PageOffset    int32
 }
 
 type AdminGetAllTradeLeadsRow struct {
-	TotalCount  int64
-	ID          int64
-	TenantID    int64
-	Title       string
-	Description sql.NullString
-	Status      string
-	Value       string
-	Version     int32
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	TotalCount   int64
+	ID           int64
+	TenantID     int64
+	Title        string
+	Description  sql.NullString
+	Status       string
+	Value        string
+	Version      int32
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	CustomFields json.RawMessage
+	Currency     string
+	AssignedTo   sql.NullInt64
 }
 
 func (q *Queries) AdminGetAllTradeLeads(ctx context.Context, arg AdminGetAllTradeLeadsParams) ([]AdminGetAllTradeLeadsRow, error) {
-	rows, err := q.db.QueryContext(ctx, adminGetAllTradeLeads, arg.Column1, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, adminGetAllTradeLeads,
+		arg.TenantID,
+		arg.Name,
+		arg.CustomFields,
+		arg.SortColumn,
+		arg.SortDirection,
+		arg.SortNumeric,
+		arg.SortKey,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -68,6 +106,9 @@ func (q *Queries) AdminGetAllTradeLeads(ctx context.Context, arg AdminGetAllTrad
 			&i.Version,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.CustomFields,
+			&i.Currency,
+			&i.AssignedTo,
 		); err != nil {
 			return nil, err
 		}
@@ -82,25 +123,50 @@ func (q *Queries) AdminGetAllTradeLeads(ctx context.Context, arg AdminGetAllTrad
 	return items, nil
 }
 
-const adminGetTRadeLeadStats = `-- name: AdminGetTRadeLeadStats :one
+const adminGetTRadeLeadStats = `-- name: AdminGetTRadeLeadStats :many
 SELECT 
+  currency,
   COUNT(*)::text AS total_leads,
   COUNT(*) FILTER (WHERE status = 'verified')::text AS verified_leads,
   COALESCE(SUM(value) FILTER (WHERE status = 'verified'), 0)::text AS total_verified_value
 FROM trade_leads
+GROUP BY currency
+ORDER BY currency
 `
 
 type AdminGetTRadeLeadStatsRow struct {
+	Currency           string
 	TotalLeads         string
 	VerifiedLeads      string
 	TotalVerifiedValue string
 }
 
-func (q *Queries) AdminGetTRadeLeadStats(ctx context.Context) (AdminGetTRadeLeadStatsRow, error) {
-	row := q.db.QueryRowContext(ctx, adminGetTRadeLeadStats)
-	var i AdminGetTRadeLeadStatsRow
-	err := row.Scan(&i.TotalLeads, &i.VerifiedLeads, &i.TotalVerifiedValue)
-	return i, err
+func (q *Queries) AdminGetTRadeLeadStats(ctx context.Context) ([]AdminGetTRadeLeadStatsRow, error) {
+	rows, err := q.db.QueryContext(ctx, adminGetTRadeLeadStats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AdminGetTRadeLeadStatsRow
+	for rows.Next() {
+		var i AdminGetTRadeLeadStatsRow
+		if err := rows.Scan(
+			&i.Currency,
+			&i.TotalLeads,
+			&i.VerifiedLeads,
+			&i.TotalVerifiedValue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const adminUpdateTradeLeadStatus = `-- name: AdminUpdateTradeLeadStatus :one
@@ -129,23 +195,56 @@ func (q *Queries) AdminUpdateTradeLeadStatus(ctx context.Context, arg AdminUpdat
 	return i, err
 }
 
+const assignTradeLead = `-- name: AssignTradeLead :one
+UPDATE trade_leads
+SET
+  assigned_to = $3
+WHERE id = $1 AND version = $2
+RETURNING version, assigned_to, updated_at
+`
+
+type AssignTradeLeadParams struct {
+	ID         int64
+	Version    int32
+	AssignedTo sql.NullInt64
+}
+
+type AssignTradeLeadRow struct {
+	Version    int32
+	AssignedTo sql.NullInt64
+	UpdatedAt  time.Time
+}
+
+func (q *Queries) AssignTradeLead(ctx context.Context, arg AssignTradeLeadParams) (AssignTradeLeadRow, error) {
+	row := q.db.QueryRowContext(ctx, assignTradeLead, arg.ID, arg.Version, arg.AssignedTo)
+	var i AssignTradeLeadRow
+	err := row.Scan(&i.Version, &i.AssignedTo, &i.UpdatedAt)
+	return i, err
+}
+
 const createTradeLead = `-- name: CreateTradeLead :one
 INSERT INTO trade_leads (
   tenant_id,
   title,
   description,
-  value
+  value,
+  custom_fields,
+  currency,
+  assigned_to
 ) VALUES (
-  $1, $2, $3, $4
+  $1, $2, $3, $4, $5, $6, $7
 )
 RETURNING id,tenant_id, status, version, created_at, updated_at
 `
 
 type CreateTradeLeadParams struct {
-	TenantID    int64
-	Title       string
-	Description sql.NullString
-	Value       string
+	TenantID     int64
+	Title        string
+	Description  sql.NullString
+	Value        string
+	CustomFields json.RawMessage
+	Currency     string
+	AssignedTo   sql.NullInt64
 }
 
 type CreateTradeLeadRow struct {
@@ -163,6 +262,9 @@ func (q *Queries) CreateTradeLead(ctx context.Context, arg CreateTradeLeadParams
 		arg.Title,
 		arg.Description,
 		arg.Value,
+		arg.CustomFields,
+		arg.Currency,
+		arg.AssignedTo,
 	)
 	var i CreateTradeLeadRow
 	err := row.Scan(
@@ -176,7 +278,7 @@ func (q *Queries) CreateTradeLead(ctx context.Context, arg CreateTradeLeadParams
 	return i, err
 }
 
-const getAllLeadsByTenantID = `-- name: GetAllLeadsByTenantID :many
+const getAllLeadsByTenantIDs = `-- name: GetAllLeadsByTenantIDs :many
 SELECT 
   COUNT(*) OVER() AS total_count,
   id, 
@@ -187,48 +289,79 @@ SELECT
   value, 
   version,
   created_at, 
-  updated_at
+  updated_at,
+  custom_fields,
+  currency,
+  assigned_to
 FROM trade_leads
-WHERE tenant_id = $1
-  AND ($2 = '' OR to_tsvector('simple', title) @@ plainto_tsquery('simple', $2))
-ORDER BY created_at DESC
-LIMIT $3 OFFSET $4
+WHERE tenant_id = ANY($1::bigint[])
+  AND ($2::bigint = 0 OR assigned_to = $2::bigint)
+  AND ($3::text = '' OR to_tsvector('simple', title) @@ plainto_tsquery('simple', $3::text))
+  AND custom_fields @> $4::jsonb
+ORDER BY
+  CASE WHEN $5::text = 'title' AND $6::text = 'ASC' THEN title END ASC,
+  CASE WHEN $5::text = 'title' AND $6::text = 'DESC' THEN title END DESC,
+  CASE WHEN $5::text = 'value' AND $6::text = 'ASC' THEN value END ASC,
+  CASE WHEN $5::text = 'value' AND $6::text = 'DESC' THEN value END DESC,
+  CASE WHEN $5::text = 'created_at' AND $6::text = 'ASC' THEN created_at END ASC,
+  CASE WHEN $5::text = 'custom_field' AND $7::boolean AND $6::text = 'ASC' AND jsonb_typeof(custom_fields->$8::text) = 'number' THEN (custom_fields->>$8::text)::numeric END ASC,
+  CASE WHEN $5::text = 'custom_field' AND $7::boolean AND $6::text = 'DESC' AND jsonb_typeof(custom_fields->$8::text) = 'number' THEN (custom_fields->>$8::text)::numeric END DESC,
+  CASE WHEN $5::text = 'custom_field' AND NOT $7::boolean AND $6::text = 'ASC' THEN custom_fields->>$8::text END ASC,
+  CASE WHEN $5::text = 'custom_field' AND NOT $7::boolean AND $6::text = 'DESC' THEN custom_fields->>$8::text END DESC,
+  created_at DESC,
+  id DESC
+LIMIT $9 OFFSET $10
 `
 
-type GetAllLeadsByTenantIDParams struct {
-	TenantID int64
-	Column2  interface{}
-	Limit    int32
-	Offset   int32
+type GetAllLeadsByTenantIDsParams struct {
+	TenantIds     []int64
+	AssignedTo    int64
+	Name          string
+	CustomFields  json.RawMessage
+	SortColumn    string
+	SortDirection string
+	SortNumeric   bool
+	SortKey       string
+	PageLimit     int32
+	PageOffset    int32
 }
 
-type GetAllLeadsByTenantIDRow struct {
-	TotalCount  int64
-	ID          int64
-	TenantID    int64
-	Title       string
-	Description sql.NullString
-	Status      string
-	Value       string
-	Version     int32
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+type GetAllLeadsByTenantIDsRow struct {
+	TotalCount   int64
+	ID           int64
+	TenantID     int64
+	Title        string
+	Description  sql.NullString
+	Status       string
+	Value        string
+	Version      int32
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	CustomFields json.RawMessage
+	Currency     string
+	AssignedTo   sql.NullInt64
 }
 
-func (q *Queries) GetAllLeadsByTenantID(ctx context.Context, arg GetAllLeadsByTenantIDParams) ([]GetAllLeadsByTenantIDRow, error) {
-	rows, err := q.db.QueryContext(ctx, getAllLeadsByTenantID,
-		arg.TenantID,
-		arg.Column2,
-		arg.Limit,
-		arg.Offset,
+func (q *Queries) GetAllLeadsByTenantIDs(ctx context.Context, arg GetAllLeadsByTenantIDsParams) ([]GetAllLeadsByTenantIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllLeadsByTenantIDs,
+		pq.Array(arg.TenantIds),
+		arg.AssignedTo,
+		arg.Name,
+		arg.CustomFields,
+		arg.SortColumn,
+		arg.SortDirection,
+		arg.SortNumeric,
+		arg.SortKey,
+		arg.PageLimit,
+		arg.PageOffset,
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetAllLeadsByTenantIDRow
+	var items []GetAllLeadsByTenantIDsRow
 	for rows.Next() {
-		var i GetAllLeadsByTenantIDRow
+		var i GetAllLeadsByTenantIDsRow
 		if err := rows.Scan(
 			&i.TotalCount,
 			&i.ID,
@@ -240,6 +373,9 @@ func (q *Queries) GetAllLeadsByTenantID(ctx context.Context, arg GetAllLeadsByTe
 			&i.Version,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.CustomFields,
+			&i.Currency,
+			&i.AssignedTo,
 		); err != nil {
 			return nil, err
 		}
@@ -254,6 +390,84 @@ func (q *Queries) GetAllLeadsByTenantID(ctx context.Context, arg GetAllLeadsByTe
 	return items, nil
 }
 
+const getAllTradeLeadsForExport = `-- name: GetAllTradeLeadsForExport :many
+SELECT 
+  id, 
+  tenant_id, 
+  title, 
+  description, 
+  status, 
+  value, 
+  version,
+  created_at, 
+  updated_at,
+  custom_fields,
+  currency,
+  assigned_to
+FROM trade_leads
+WHERE tenant_id = $1
+ORDER BY id
+`
+
+func (q *Queries) GetAllTradeLeadsForExport(ctx context.Context, tenantID int64) ([]TradeLead, error) {
+	rows, err := q.db.QueryContext(ctx, getAllTradeLeadsForExport, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TradeLead
+	for rows.Next() {
+		var i TradeLead
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Value,
+			&i.Version,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CustomFields,
+			&i.Currency,
+			&i.AssignedTo,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNextTradeLeadAssignee = `-- name: GetNextTradeLeadAssignee :one
+SELECT u.id
+FROM users u
+WHERE u.tenant_id = $1 AND u.activated
+ORDER BY
+  u.id <= COALESCE((
+    SELECT l.assigned_to
+    FROM trade_leads l
+    WHERE l.tenant_id = $1 AND l.assigned_to IS NOT NULL
+    ORDER BY l.created_at DESC, l.id DESC
+    LIMIT 1
+  ), 0),
+  u.id
+LIMIT 1
+`
+
+func (q *Queries) GetNextTradeLeadAssignee(ctx context.Context, tenantID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getNextTradeLeadAssignee, tenantID)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
 const getTradeLeadByID = `-- name: GetTradeLeadByID :one
 SELECT 
   id, 
@@ -264,7 +478,10 @@ SELECT
   value, 
   version,
   created_at, 
-  updated_at
+  updated_at,
+  custom_fields,
+  currency,
+  assigned_to
 FROM trade_leads
 WHERE id = $1
 `
@@ -282,6 +499,276 @@ func (q *Queries) GetTradeLeadByID(ctx context.Context, id int64) (TradeLead, er
 		&i.Version,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CustomFields,
+		&i.Currency,
+		&i.AssignedTo,
 	)
 	return i, err
 }
+
+const getTradeLeadHistoryByTenantID = `-- name: GetTradeLeadHistoryByTenantID :many
+SELECT 
+  id, 
+  trade_lead_id, 
+  tenant_id, 
+  status, 
+  value, 
+  version, 
+  changed_at
+FROM trade_lead_history
+WHERE tenant_id = $1
+ORDER BY trade_lead_id, id
+`
+
+func (q *Queries) GetTradeLeadHistoryByTenantID(ctx context.Context, tenantID int64) ([]TradeLeadHistory, error) {
+	rows, err := q.db.QueryContext(ctx, getTradeLeadHistoryByTenantID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TradeLeadHistory
+	for rows.Next() {
+		var i TradeLeadHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.TradeLeadID,
+			&i.TenantID,
+			&i.Status,
+			&i.Value,
+			&i.Version,
+			&i.ChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTradeLeadStatsByTenant = `-- name: GetTradeLeadStatsByTenant :many
+SELECT 
+  tenant_id,
+  currency,
+  COUNT(*)::text AS total_leads,
+  COUNT(*) FILTER (WHERE status = 'verified')::text AS verified_leads,
+  COALESCE(SUM(value) FILTER (WHERE status = 'verified'), 0)::text AS total_verified_value
+FROM trade_leads
+WHERE tenant_id = ANY($1::bigint[])
+GROUP BY tenant_id, currency
+ORDER BY tenant_id, currency
+`
+
+type GetTradeLeadStatsByTenantRow struct {
+	TenantID           int64
+	Currency           string
+	TotalLeads         string
+	VerifiedLeads      string
+	TotalVerifiedValue string
+}
+
+func (q *Queries) GetTradeLeadStatsByTenant(ctx context.Context, tenantIds []int64) ([]GetTradeLeadStatsByTenantRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTradeLeadStatsByTenant, pq.Array(tenantIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTradeLeadStatsByTenantRow
+	for rows.Next() {
+		var i GetTradeLeadStatsByTenantRow
+		if err := rows.Scan(
+			&i.TenantID,
+			&i.Currency,
+			&i.TotalLeads,
+			&i.VerifiedLeads,
+			&i.TotalVerifiedValue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTradeLeadStatusCounts = `-- name: GetTradeLeadStatusCounts :many
+SELECT
+  tenant_id,
+  status,
+  currency,
+  COUNT(*)::bigint AS leads,
+  COALESCE(SUM(value), 0)::text AS total_value
+FROM trade_leads
+WHERE (cardinality($1::bigint[]) = 0 OR tenant_id = ANY($1::bigint[]))
+  AND created_at >= $2
+  AND created_at < $3
+GROUP BY tenant_id, status, currency
+ORDER BY tenant_id, status, currency
+`
+
+type GetTradeLeadStatusCountsParams struct {
+	TenantIds   []int64
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+type GetTradeLeadStatusCountsRow struct {
+	TenantID   int64
+	Status     string
+	Currency   string
+	Leads      int64
+	TotalValue string
+}
+
+func (q *Queries) GetTradeLeadStatusCounts(ctx context.Context, arg GetTradeLeadStatusCountsParams) ([]GetTradeLeadStatusCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTradeLeadStatusCounts, pq.Array(arg.TenantIds), arg.CreatedFrom, arg.CreatedTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTradeLeadStatusCountsRow
+	for rows.Next() {
+		var i GetTradeLeadStatusCountsRow
+		if err := rows.Scan(
+			&i.TenantID,
+			&i.Status,
+			&i.Currency,
+			&i.Leads,
+			&i.TotalValue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTradeLeadTimeSeries = `-- name: GetTradeLeadTimeSeries :many
+SELECT
+  date_trunc($1::text, created_at, 'UTC')::timestamptz AS bucket_start,
+  currency,
+  COUNT(*)::bigint AS leads,
+  COALESCE(SUM(value), 0)::text AS total_value
+FROM trade_leads
+WHERE (cardinality($2::bigint[]) = 0 OR tenant_id = ANY($2::bigint[]))
+  AND created_at >= $3
+  AND created_at < $4
+GROUP BY bucket_start, currency
+ORDER BY bucket_start, currency
+`
+
+type GetTradeLeadTimeSeriesParams struct {
+	Bucket      string
+	TenantIds   []int64
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+type GetTradeLeadTimeSeriesRow struct {
+	BucketStart time.Time
+	Currency    string
+	Leads       int64
+	TotalValue  string
+}
+
+func (q *Queries) GetTradeLeadTimeSeries(ctx context.Context, arg GetTradeLeadTimeSeriesParams) ([]GetTradeLeadTimeSeriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTradeLeadTimeSeries,
+		arg.Bucket,
+		pq.Array(arg.TenantIds),
+		arg.CreatedFrom,
+		arg.CreatedTo,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTradeLeadTimeSeriesRow
+	for rows.Next() {
+		var i GetTradeLeadTimeSeriesRow
+		if err := rows.Scan(
+			&i.BucketStart,
+			&i.Currency,
+			&i.Leads,
+			&i.TotalValue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTradeLeadVerificationTimes = `-- name: GetTradeLeadVerificationTimes :many
+SELECT
+  l.tenant_id,
+  COUNT(*)::bigint AS verified_leads,
+  COALESCE(SUM(EXTRACT(EPOCH FROM (v.verified_at - l.created_at))), 0)::float8 AS seconds_to_verify
+FROM trade_leads l
+JOIN LATERAL (
+  SELECT MIN(h.changed_at) AS verified_at
+  FROM trade_lead_history h
+  WHERE h.trade_lead_id = l.id AND h.status = 'verified'
+) v ON v.verified_at IS NOT NULL
+WHERE (cardinality($1::bigint[]) = 0 OR l.tenant_id = ANY($1::bigint[]))
+  AND l.created_at >= $2
+  AND l.created_at < $3
+GROUP BY l.tenant_id
+ORDER BY l.tenant_id
+`
+
+type GetTradeLeadVerificationTimesParams struct {
+	TenantIds   []int64
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+type GetTradeLeadVerificationTimesRow struct {
+	TenantID        int64
+	VerifiedLeads   int64
+	SecondsToVerify float64
+}
+
+func (q *Queries) GetTradeLeadVerificationTimes(ctx context.Context, arg GetTradeLeadVerificationTimesParams) ([]GetTradeLeadVerificationTimesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTradeLeadVerificationTimes, pq.Array(arg.TenantIds), arg.CreatedFrom, arg.CreatedTo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTradeLeadVerificationTimesRow
+	for rows.Next() {
+		var i GetTradeLeadVerificationTimesRow
+		if err := rows.Scan(&i.TenantID, &i.VerifiedLeads, &i.SecondsToVerify); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -11,8 +11,8 @@ import (
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (tenant_id, name, email, password_hash, activated)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO users (tenant_id, name, email, password_hash, activated, locale)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING id, created_at, version
 `
 
@@ -22,6 +22,7 @@ type CreateUserParams struct {
 	Email        string
 	PasswordHash []byte
 	Activated    bool
+	Locale       string
 }
 
 type CreateUserRow struct {
@@ -37,14 +38,67 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		arg.Email,
 		arg.PasswordHash,
 		arg.Activated,
+		arg.Locale,
 	)
 	var i CreateUserRow
 	err := row.Scan(&i.ID, &i.CreatedAt, &i.Version)
 	return i, err
 }
 
+const getAllUsersByTenantID = `-- name: GetAllUsersByTenantID :many
+SELECT id, tenant_id, name, email, activated, version, created_at, updated_at, locale
+FROM users
+WHERE tenant_id = $1
+ORDER BY id
+`
+
+type GetAllUsersByTenantIDRow struct {
+	ID        int64
+	TenantID  int64
+	Name      string
+	Email     string
+	Activated bool
+	Version   int32
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Locale    string
+}
+
+func (q *Queries) GetAllUsersByTenantID(ctx context.Context, tenantID int64) ([]GetAllUsersByTenantIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllUsersByTenantID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllUsersByTenantIDRow
+	for rows.Next() {
+		var i GetAllUsersByTenantIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.Name,
+			&i.Email,
+			&i.Activated,
+			&i.Version,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Locale,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, tenant_id, name, email, password_hash, activated, version, created_at, updated_at
+SELECT id, tenant_id, name, email, password_hash, activated, version, created_at, updated_at, locale
 FROM users WHERE email = $1
 `
 
@@ -61,6 +115,7 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Version,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Locale,
 	)
 	return i, err
 }
@@ -71,8 +126,9 @@ SET
     name = $1, 
     email = $2, 
     password_hash = $3, 
-    activated = $4
-WHERE id = $5 AND version = $6
+    activated = $4,
+    locale = $5
+WHERE id = $6 AND version = $7
 RETURNING version, updated_at
 `
 
@@ -81,6 +137,7 @@ type UpdateUserParams struct {
 	Email        string
 	PasswordHash []byte
 	Activated    bool
+	Locale       string
 	ID           int64
 	Version      int32
 }
@@ -96,6 +153,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateU
 		arg.Email,
 		arg.PasswordHash,
 		arg.Activated,
+		arg.Locale,
 		arg.ID,
 		arg.Version,
 	)
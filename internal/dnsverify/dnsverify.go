@@ -0,0 +1,70 @@
+package dnsverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	// RecordPrefix is prepended to a domain to get the name of the TXT record holding
+	// the verification token, e.g. _leadhub-verification.example.com
+	RecordPrefix = "_leadhub-verification."
+	// ValuePrefix is prepended to the token to get the expected TXT record value.
+	ValuePrefix = "leadhub-verification="
+)
+
+var (
+	ErrRecordNotFound = errors.New("verification TXT record not found")
+)
+
+// Resolver looks up TXT records. *net.Resolver satisfies it, tests can provide a fake.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Verifier checks domain ownership through a DNS TXT record.
+type Verifier struct {
+	resolver Resolver
+}
+
+// New() returns a Verifier using the given resolver. A nil resolver uses the system one.
+func New(resolver Resolver) *Verifier {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &Verifier{resolver: resolver}
+}
+
+// RecordName() returns the name of the TXT record that has to be created for domain.
+func RecordName(domain string) string {
+	return RecordPrefix + domain
+}
+
+// RecordValue() returns the value the TXT record has to hold for token.
+func RecordValue(token string) string {
+	return ValuePrefix + token
+}
+
+// Verify() returns nil if the domain has a TXT record holding the token, and
+// ErrRecordNotFound if it does not. Lookup failures other than a missing record are
+// returned as they are.
+func (v *Verifier) Verify(ctx context.Context, domain, token string) error {
+	records, err := v.resolver.LookupTXT(ctx, RecordName(domain))
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return ErrRecordNotFound
+		}
+		return fmt.Errorf("looking up %s: %w", RecordName(domain), err)
+	}
+	expected := RecordValue(token)
+	for _, record := range records {
+		if strings.TrimSpace(record) == expected {
+			return nil
+		}
+	}
+	return ErrRecordNotFound
+}
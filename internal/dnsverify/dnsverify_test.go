@@ -0,0 +1,47 @@
+package dnsverify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// fakeResolver serves TXT records from a map.
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, ok := f[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestVerify(t *testing.T) {
+	resolver := fakeResolver{
+		RecordName("tradehub.co.ke"): {"v=spf1 -all", RecordValue("abc123")},
+		RecordName("other.com"):      {RecordValue("something-else")},
+	}
+	verifier := New(resolver)
+
+	tests := []struct {
+		name    string
+		domain  string
+		token   string
+		wantErr error
+	}{
+		{name: "Matching record", domain: "tradehub.co.ke", token: "abc123"},
+		{name: "Wrong token", domain: "other.com", token: "abc123", wantErr: ErrRecordNotFound},
+		{name: "Missing record", domain: "missing.com", token: "abc123", wantErr: ErrRecordNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifier.Verify(context.Background(), tt.domain, tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
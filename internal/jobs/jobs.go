@@ -0,0 +1,357 @@
+// Package jobs runs the background jobs stored through data.JobStore. A pool of workers
+// claims due jobs, runs the handler registered for their kind and retries failed jobs with
+// an exponential backoff until they run out of attempts and are moved to the dead state.
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/Blue-Davinci/leadhub-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultWorkers      = 2
+	DefaultPollInterval = time.Second
+	DefaultJobTimeout   = time.Minute
+	DefaultLockTimeout  = 5 * time.Minute
+	DefaultBaseBackoff  = 10 * time.Second
+	DefaultMaxBackoff   = time.Hour
+)
+
+// Define constants for the outcome of a job run, as reported to the Observer.
+const (
+	OutcomeCompleted = "completed"
+	OutcomeRetried   = "retried"
+	OutcomeDead      = "dead"
+)
+
+// tracer creates a span for every job run.
+var tracer = tracing.Tracer("github.com/Blue-Davinci/leadhub-service/internal/jobs")
+
+// Handler processes a job. A returned error retries the job, unless it is Permanent.
+type Handler func(ctx context.Context, job *data.Job) error
+
+// Typed() returns a Handler that decodes the job payload into T with Decode() before
+// calling fn.
+func Typed[T any](fn func(ctx context.Context, payload T) error) Handler {
+	return func(ctx context.Context, job *data.Job) error {
+		var payload T
+		if err := Decode(job, &payload); err != nil {
+			return err
+		}
+		return fn(ctx, payload)
+	}
+}
+
+// Decode() decodes the job payload into v. Numbers decoded into interface values are
+// json.Number, so e.g. IDs keep their formatting. A payload that can't be decoded fails
+// the job permanently.
+func Decode(job *data.Job, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(job.Payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return Permanent(fmt.Errorf("decode %s payload: %w", job.Kind, err))
+	}
+	return nil
+}
+
+// LastAttempt() reports whether the claimed job is on its last attempt, so a failure moves
+// it to the dead state.
+func LastAttempt(job *data.Job) bool {
+	return job.Attempts >= job.MaxAttempts
+}
+
+// permanentError marks a failure that retrying will not fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent() wraps err so the job is moved to the dead state instead of being retried.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent() reports whether err was wrapped by Permanent().
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+// Observer is told about every job run, *metrics.Metrics satisfies it.
+type Observer interface {
+	ObserveJob(kind, outcome string, duration time.Duration)
+}
+
+// Config configures a Queue. Zero values use the defaults above.
+type Config struct {
+	// Workers is the number of jobs run at the same time.
+	Workers int
+	// PollInterval is how long an idle worker waits before looking for due jobs again.
+	PollInterval time.Duration
+	// JobTimeout bounds a single run of a job.
+	JobTimeout time.Duration
+	// LockTimeout is how long a job may stay running before it is assumed that its worker
+	// crashed and it is handed to another one. It has to be longer than JobTimeout.
+	LockTimeout time.Duration
+	// BaseBackoff is the delay before the first retry, it doubles with every attempt up to
+	// MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Observer    Observer
+}
+
+// Queue is a pool of workers running the jobs of a JobStore.
+type Queue struct {
+	store    data.JobStore
+	logger   *zap.Logger
+	config   Config
+	workerID string
+	handlers map[string]Handler
+
+	// wake is signalled by Notify() to have an idle worker look for jobs right away.
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	// jobCtx is the parent of every job run, cancelled when the drain times out.
+	jobCtx    context.Context
+	cancelJob context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// New() returns a Queue running the jobs of store. Handlers have to be registered before
+// Start() is called.
+func New(store data.JobStore, logger *zap.Logger, config Config) *Queue {
+	if config.Workers <= 0 {
+		config.Workers = DefaultWorkers
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.JobTimeout <= 0 {
+		config.JobTimeout = DefaultJobTimeout
+	}
+	if config.LockTimeout <= 0 {
+		config.LockTimeout = DefaultLockTimeout
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = DefaultBaseBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	jobCtx, cancelJob := context.WithCancel(context.Background())
+	return &Queue{
+		store:     store,
+		logger:    logger,
+		config:    config,
+		workerID:  newWorkerID(),
+		handlers:  map[string]Handler{},
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		jobCtx:    jobCtx,
+		cancelJob: cancelJob,
+	}
+}
+
+// Register() sets the handler for jobs of the given kind.
+func (q *Queue) Register(kind string, handler Handler) {
+	q.handlers[kind] = handler
+}
+
+// Start() starts the workers and the reaper requeueing the jobs of crashed workers.
+func (q *Queue) Start() {
+	q.logger.Info("starting job workers", zap.String("worker_id", q.workerID), zap.Int("workers", q.config.Workers))
+	for range q.config.Workers {
+		q.wg.Add(1)
+		go q.work()
+	}
+	q.wg.Add(1)
+	go q.reap()
+}
+
+// Notify() wakes an idle worker, so a job enqueued by this process runs without waiting
+// for the next poll. It is safe to call on a nil Queue.
+func (q *Queue) Notify() {
+	if q == nil {
+		return
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Shutdown() stops claiming new jobs and waits for the running ones to finish. Once ctx is
+// done the running jobs are cancelled, they are retried later.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.stopOnce.Do(func() { close(q.stop) })
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		q.cancelJob()
+		return nil
+	case <-ctx.Done():
+		q.cancelJob()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// work() claims and runs jobs one at a time until the queue is stopped.
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+		jobs, err := q.store.ClaimJobs(q.jobCtx, q.workerID, 1)
+		if err != nil {
+			q.logger.Error("failed to claim jobs", zap.Error(err))
+		}
+		if len(jobs) == 0 {
+			if !q.idle() {
+				return
+			}
+			continue
+		}
+		q.process(jobs[0])
+	}
+}
+
+// idle() waits for the poll interval or a Notify(). It returns false once the queue stops.
+func (q *Queue) idle() bool {
+	timer := time.NewTimer(q.config.PollInterval)
+	defer timer.Stop()
+	select {
+	case <-q.stop:
+		return false
+	case <-q.wake:
+	case <-timer.C:
+	}
+	return true
+}
+
+// reap() periodically hands the jobs of crashed workers back to the queue.
+func (q *Queue) reap() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(q.config.LockTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		}
+		requeued, err := q.store.RequeueStaleJobs(q.jobCtx, time.Now().Add(-q.config.LockTimeout))
+		if err != nil {
+			q.logger.Error("failed to requeue stale jobs", zap.Error(err))
+			continue
+		}
+		if requeued > 0 {
+			q.logger.Warn("requeued jobs of unresponsive workers", zap.Int64("jobs", requeued))
+		}
+	}
+}
+
+// process() runs a claimed job and records its outcome.
+func (q *Queue) process(job *data.Job) {
+	ctx, span := tracer.Start(q.jobCtx, "jobs.process")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("job.kind", job.Kind),
+		attribute.Int64("job.id", job.ID),
+		attribute.Int("job.attempt", int(job.Attempts)),
+	)
+	logger := q.logger.With(zap.Int64("job_id", job.ID), zap.String("kind", job.Kind), zap.Int32("attempt", job.Attempts))
+
+	start := time.Now()
+	err := q.run(ctx, job)
+	duration := time.Since(start)
+	// a job cut short by the shutdown still has to be released
+	storeCtx := context.WithoutCancel(ctx)
+
+	var outcome string
+	switch {
+	case err == nil:
+		outcome = OutcomeCompleted
+		err = q.store.CompleteJob(storeCtx, job.ID, q.workerID)
+	case IsPermanent(err) || LastAttempt(job):
+		outcome = OutcomeDead
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.Error("job failed for good, moved to the dead state", zap.Error(err))
+		err = q.store.BuryJob(storeCtx, job.ID, q.workerID, err.Error())
+	default:
+		outcome = OutcomeRetried
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		retryAt := time.Now().Add(q.backoff(job.Attempts))
+		logger.Warn("job failed, retrying", zap.Error(err), zap.Time("retry_at", retryAt))
+		err = q.store.RetryJob(storeCtx, job.ID, q.workerID, retryAt, err.Error())
+	}
+	if err != nil {
+		logger.Error("failed to record the job outcome", zap.String("outcome", outcome), zap.Error(err))
+	}
+	if q.config.Observer != nil {
+		q.config.Observer.ObserveJob(job.Kind, outcome, duration)
+	}
+}
+
+// run() calls the job's handler, bounded by the job timeout. A panic fails the job.
+func (q *Queue) run(ctx context.Context, job *data.Job) (err error) {
+	handler, ok := q.handlers[job.Kind]
+	if !ok {
+		// another version of the service may know the kind, so it is retried
+		return fmt.Errorf("no handler registered for job kind %q", job.Kind)
+	}
+	ctx, cancel := context.WithTimeout(ctx, q.config.JobTimeout)
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// backoff() returns the delay before retrying a job that failed its given attempt.
+func (q *Queue) backoff(attempt int32) time.Duration {
+	delay := q.config.BaseBackoff
+	for i := int32(1); i < attempt && delay < q.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, q.config.MaxBackoff)
+}
+
+// newWorkerID() returns an ID for the workers of this process, used to lock jobs.
+func newWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "leadhub"
+	}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(b))
+}
@@ -0,0 +1,286 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"go.uber.org/zap"
+)
+
+// recorder is an Observer remembering the outcome of every run.
+type recorder struct {
+	mu       sync.Mutex
+	outcomes []string
+}
+
+func (r *recorder) ObserveJob(kind, outcome string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outcomes = append(r.outcomes, outcome)
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.outcomes...)
+}
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+// newTestQueue() returns a fast polling queue over an in-memory job store, shut down at
+// the end of the test.
+func newTestQueue(t *testing.T, config Config) (*Queue, data.JobStore, *recorder) {
+	t.Helper()
+	store := data.NewMemoryModels().Jobs
+	observer := &recorder{}
+	if config.PollInterval == 0 {
+		config.PollInterval = 5 * time.Millisecond
+	}
+	if config.BaseBackoff == 0 {
+		config.BaseBackoff = time.Millisecond
+	}
+	config.Observer = observer
+	queue := New(store, zap.NewNop(), config)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		queue.Shutdown(ctx)
+	})
+	return queue, store, observer
+}
+
+func enqueue(t *testing.T, store data.JobStore, kind string, payload any, maxAttempts int32) *data.Job {
+	t.Helper()
+	job, err := data.NewJob(kind, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.MaxAttempts = maxAttempts
+	if err := store.EnqueueJob(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+// waitForStatus() waits until the job reaches the status and returns it.
+func waitForStatus(t *testing.T, store data.JobStore, id int64, status string) *data.Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := store.GetJobByID(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d is %s after %d attempts, want %s", id, job.Status, job.Attempts, status)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+func TestQueueRunsTypedHandlers(t *testing.T) {
+	queue, store, observer := newTestQueue(t, Config{})
+	got := make(chan string, 1)
+	queue.Register("greet", Typed(func(ctx context.Context, payload greeting) error {
+		got <- payload.Name
+		return nil
+	}))
+	queue.Start()
+
+	job := enqueue(t, store, "greet", greeting{Name: "Ann"}, 3)
+	queue.Notify()
+	if name := <-got; name != "Ann" {
+		t.Errorf("handler got %q, want %q", name, "Ann")
+	}
+	done := waitForStatus(t, store, job.ID, data.JobStatusCompleted)
+	if done.Attempts != 1 || done.CompletedAt == nil {
+		t.Errorf("completed job = %+v", done)
+	}
+	if outcomes := observer.get(); len(outcomes) != 1 || outcomes[0] != OutcomeCompleted {
+		t.Errorf("outcomes = %v, want [%s]", outcomes, OutcomeCompleted)
+	}
+}
+
+func TestTypedKeepsNumbers(t *testing.T) {
+	var got map[string]any
+	handler := Typed(func(ctx context.Context, payload map[string]any) error {
+		got = payload
+		return nil
+	})
+	if err := handler(context.Background(), &data.Job{Payload: []byte(`{"userID": 1234567}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(got["userID"]); s != "1234567" {
+		t.Errorf("userID prints as %q, want %q", s, "1234567")
+	}
+}
+
+func TestQueueRetries(t *testing.T) {
+	t.Run("Until the job succeeds", func(t *testing.T) {
+		queue, store, observer := newTestQueue(t, Config{})
+		var runs atomic.Int32
+		queue.Register("flaky", func(ctx context.Context, job *data.Job) error {
+			if runs.Add(1) < 3 {
+				return errors.New("smtp unavailable")
+			}
+			return nil
+		})
+		queue.Start()
+		job := enqueue(t, store, "flaky", nil, 5)
+		done := waitForStatus(t, store, job.ID, data.JobStatusCompleted)
+		if done.Attempts != 3 {
+			t.Errorf("attempts = %d, want 3", done.Attempts)
+		}
+		want := []string{OutcomeRetried, OutcomeRetried, OutcomeCompleted}
+		if outcomes := observer.get(); len(outcomes) != len(want) || outcomes[0] != want[0] || outcomes[2] != want[2] {
+			t.Errorf("outcomes = %v, want %v", outcomes, want)
+		}
+	})
+
+	t.Run("Dead after the last attempt", func(t *testing.T) {
+		queue, store, _ := newTestQueue(t, Config{})
+		queue.Register("broken", func(ctx context.Context, job *data.Job) error {
+			return errors.New("smtp unavailable")
+		})
+		queue.Start()
+		job := enqueue(t, store, "broken", nil, 2)
+		dead := waitForStatus(t, store, job.ID, data.JobStatusDead)
+		if dead.Attempts != 2 || dead.LastError != "smtp unavailable" {
+			t.Errorf("dead job = %+v", dead)
+		}
+	})
+
+	t.Run("Permanent errors are not retried", func(t *testing.T) {
+		queue, store, _ := newTestQueue(t, Config{})
+		queue.Register("invalid", func(ctx context.Context, job *data.Job) error {
+			return Permanent(errors.New("unknown template"))
+		})
+		queue.Register("greet", Typed(func(ctx context.Context, payload greeting) error { return nil }))
+		queue.Start()
+		invalid := enqueue(t, store, "invalid", nil, 5)
+		undecodable := enqueue(t, store, "greet", []int{1}, 5)
+		for _, id := range []int64{invalid.ID, undecodable.ID} {
+			if dead := waitForStatus(t, store, id, data.JobStatusDead); dead.Attempts != 1 {
+				t.Errorf("job %d attempts = %d, want 1", id, dead.Attempts)
+			}
+		}
+	})
+
+	t.Run("Panics and unknown kinds are retried", func(t *testing.T) {
+		queue, store, _ := newTestQueue(t, Config{})
+		queue.Register("panics", func(ctx context.Context, job *data.Job) error { panic("boom") })
+		queue.Start()
+		panics := enqueue(t, store, "panics", nil, 2)
+		unknown := enqueue(t, store, "unknown", nil, 2)
+		if dead := waitForStatus(t, store, panics.ID, data.JobStatusDead); dead.Attempts != 2 || dead.LastError != "job panicked: boom" {
+			t.Errorf("panicking job = %+v", dead)
+		}
+		if dead := waitForStatus(t, store, unknown.ID, data.JobStatusDead); dead.Attempts != 2 {
+			t.Errorf("unknown job = %+v", dead)
+		}
+	})
+}
+
+func TestQueueDelayedJobs(t *testing.T) {
+	queue, store, _ := newTestQueue(t, Config{})
+	ran := make(chan time.Time, 1)
+	queue.Register("later", func(ctx context.Context, job *data.Job) error {
+		ran <- time.Now()
+		return nil
+	})
+	queue.Start()
+	job, _ := data.NewJob("later", nil)
+	job.RunAt = time.Now().Add(50 * time.Millisecond)
+	if err := store.EnqueueJob(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+	if at := <-ran; at.Before(job.RunAt) {
+		t.Errorf("job ran at %v, before its run_at %v", at, job.RunAt)
+	}
+}
+
+func TestQueueShutdown(t *testing.T) {
+	t.Run("Drains running jobs", func(t *testing.T) {
+		queue, store, _ := newTestQueue(t, Config{})
+		started := make(chan struct{})
+		queue.Register("slow", func(ctx context.Context, job *data.Job) error {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		})
+		queue.Start()
+		job := enqueue(t, store, "slow", nil, 3)
+		<-started
+		if err := queue.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown() error = %v", err)
+		}
+		got, _ := store.GetJobByID(context.Background(), job.ID)
+		if got.Status != data.JobStatusCompleted {
+			t.Errorf("job status after shutdown = %s, want %s", got.Status, data.JobStatusCompleted)
+		}
+	})
+
+	t.Run("Cancels jobs outliving the grace period", func(t *testing.T) {
+		queue, store, _ := newTestQueue(t, Config{BaseBackoff: time.Hour})
+		started := make(chan struct{})
+		queue.Register("stuck", func(ctx context.Context, job *data.Job) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		queue.Start()
+		job := enqueue(t, store, "stuck", nil, 3)
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := queue.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+		// the job is released for another instance to retry
+		got, _ := store.GetJobByID(context.Background(), job.ID)
+		if got.Status != data.JobStatusPending || got.LockedBy != "" {
+			t.Errorf("job after shutdown = %+v, want it pending", got)
+		}
+	})
+}
+
+func TestQueueRequeuesStaleJobs(t *testing.T) {
+	queue, store, _ := newTestQueue(t, Config{LockTimeout: 20 * time.Millisecond})
+	queue.Register("orphan", func(ctx context.Context, job *data.Job) error { return nil })
+	job := enqueue(t, store, "orphan", nil, 3)
+	// a worker of another process claims the job and crashes
+	if _, err := store.ClaimJobs(context.Background(), "crashed-worker", 1); err != nil {
+		t.Fatal(err)
+	}
+	queue.Start()
+	done := waitForStatus(t, store, job.ID, data.JobStatusCompleted)
+	if done.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", done.Attempts)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	queue := New(nil, zap.NewNop(), Config{BaseBackoff: 10 * time.Second, MaxBackoff: time.Minute})
+	for attempt, want := range map[int32]time.Duration{
+		1: 10 * time.Second,
+		2: 20 * time.Second,
+		3: 40 * time.Second,
+		4: time.Minute,
+		9: time.Minute,
+	} {
+		if got := queue.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultLocale is the language of the templates at the root of the templates directory.
+const DefaultLocale = "en"
+
+// SupportedLocales lists the languages emails are written in. Every locale other than
+// DefaultLocale has its own directory of templates, e.g. templates/fr/user_welcome.tmpl.
+var SupportedLocales = []string{DefaultLocale, "fr", "sw"}
+
+// ResolveLocale() returns the first supported language among the candidates, e.g. the
+// user's and then the tenant's locale. Regional variants such as fr-FR use the templates
+// of their language. DefaultLocale is returned when no candidate is supported.
+func ResolveLocale(candidates ...string) string {
+	for _, candidate := range candidates {
+		if IsSupportedLocale(candidate) {
+			return language(candidate)
+		}
+	}
+	return DefaultLocale
+}
+
+// IsSupportedLocale() reports whether emails can be written in the language of locale,
+// e.g. "fr" or "fr-CA".
+func IsSupportedLocale(locale string) bool {
+	return slices.Contains(SupportedLocales, language(locale))
+}
+
+// language() returns the language part of a locale, e.g. "fr" for "fr-CA".
+func language(locale string) string {
+	language, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(locale)), "-")
+	return language
+}
+
+// templatePath() returns the path of a template file in the given locale, falling back to
+// the default template when the locale has no translation of it.
+func templatePath(templateFile, locale string) string {
+	locale = ResolveLocale(locale)
+	if locale != DefaultLocale {
+		localized := path.Join("templates", locale, templateFile)
+		if _, err := fs.Stat(templateFS, localized); err == nil {
+			return localized
+		}
+	}
+	return path.Join("templates", templateFile)
+}
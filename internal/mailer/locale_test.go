@@ -0,0 +1,117 @@
+package mailer
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+)
+
+// templateData holds every key used by the templates.
+var templateData = map[string]any{
+	"userID":          12,
+	"userName":        "Amina",
+	"activationURL":   "https://example.com/activate?token=abc",
+	"activationToken": "abc",
+	"loginURL":        "https://example.com/login",
+	"tenantName":      "TradeHub KE",
+	"downloadURL":     "https://example.com/exports/1/download",
+	"expiresAt":       "Mon, 02 Jan 2026 15:04:05 EAT",
+	"leadTitle":       "200 bags of AA coffee",
+	"leadValue":       "2500000.00 KES",
+	"assignedBy":      "Brian",
+	"authorName":      "Brian",
+	"commentBody":     "@amina@tradehub.test please send the quote",
+}
+
+func TestResolveLocale(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		want       string
+	}{
+		{name: "Supported", candidates: []string{"fr"}, want: "fr"},
+		{name: "Regional variant", candidates: []string{"fr-CA"}, want: "fr"},
+		{name: "Case insensitive", candidates: []string{"SW-ke"}, want: "sw"},
+		{name: "First supported candidate", candidates: []string{"", "de", "sw", "fr"}, want: "sw"},
+		{name: "Unsupported", candidates: []string{"de-DE"}, want: DefaultLocale},
+		{name: "None", want: DefaultLocale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveLocale(tt.candidates...); got != tt.want {
+				t.Errorf("ResolveLocale(%q) = %q, want %q", tt.candidates, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTemplatesRenderInEveryLocale makes sure every template is translated to every
+// supported locale and renders all three parts.
+func TestTemplatesRenderInEveryLocale(t *testing.T) {
+	templates, err := fs.Glob(templateFS, "templates/*.tmpl")
+	if err != nil || len(templates) == 0 {
+		t.Fatalf("no templates found: %v", err)
+	}
+	for _, template := range templates {
+		templateFile := path.Base(template)
+		defaultSubject, _, _, err := render(templateFile, DefaultLocale, templateData, Branding{})
+		if err != nil {
+			t.Fatalf("render(%s) error = %v", templateFile, err)
+		}
+		for _, locale := range SupportedLocales {
+			t.Run(locale+"/"+templateFile, func(t *testing.T) {
+				if locale != DefaultLocale {
+					if _, err := fs.Stat(templateFS, path.Join("templates", locale, templateFile)); err != nil {
+						t.Fatalf("missing translation: %v", err)
+					}
+				}
+				subject, plainBody, htmlBody, err := render(templateFile, locale, templateData, Branding{})
+				if err != nil {
+					t.Fatalf("render() error = %v", err)
+				}
+				for part, body := range map[string]string{"subject": subject, "plainBody": plainBody, "htmlBody": htmlBody} {
+					if strings.TrimSpace(body) == "" {
+						t.Errorf("%s is empty", part)
+					}
+					if strings.Contains(body, "<no value>") {
+						t.Errorf("%s uses data the template is not given", part)
+					}
+				}
+				if locale != DefaultLocale && subject == defaultSubject {
+					t.Errorf("subject %q is not translated", subject)
+				}
+			})
+		}
+	}
+}
+
+func TestLocalizedTemplatesHaveADefault(t *testing.T) {
+	localized, err := fs.Glob(templateFS, "templates/*/*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, template := range localized {
+		if _, err := fs.Stat(templateFS, path.Join("templates", path.Base(template))); err != nil {
+			t.Errorf("%s has no default template to fall back to", template)
+		}
+	}
+}
+
+func TestTemplatePathFallback(t *testing.T) {
+	tests := []struct {
+		templateFile string
+		locale       string
+		want         string
+	}{
+		{templateFile: "user_welcome.tmpl", locale: "fr-FR", want: "templates/fr/user_welcome.tmpl"},
+		{templateFile: "user_welcome.tmpl", locale: "de", want: "templates/user_welcome.tmpl"},
+		{templateFile: "user_welcome.tmpl", locale: "", want: "templates/user_welcome.tmpl"},
+		{templateFile: "untranslated.tmpl", locale: "sw", want: "templates/untranslated.tmpl"},
+	}
+	for _, tt := range tests {
+		if got := templatePath(tt.templateFile, tt.locale); got != tt.want {
+			t.Errorf("templatePath(%q, %q) = %q, want %q", tt.templateFile, tt.locale, got, tt.want)
+		}
+	}
+}
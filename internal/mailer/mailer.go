@@ -2,11 +2,22 @@ package mailer
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
 	"embed"
-	"html/template"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	netmail "net/mail"
+	"strings"
+	"text/template"
 	"time"
 
-	"github.com/go-mail/mail/v2"
+	"github.com/Blue-Davinci/leadhub-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // Below we declare a new variable with the type embed.FS (embedded file system) to hold
@@ -15,78 +26,210 @@ import (
 //go:embed "templates/*"
 var templateFS embed.FS
 
-// Define a Mailer struct which contains a mail.Dialer instance (used to connect to a
-// SMTP server) and the sender information for your emails
+// tracer creates a span for every email.
+var tracer = tracing.Tracer("github.com/Blue-Davinci/leadhub-service/internal/mailer")
+
+var (
+	// ErrRenderTemplate wraps failures to render an email. Unlike a failed delivery,
+	// sending the same email again will not fix it.
+	ErrRenderTemplate = errors.New("failed to render email template")
+	// ErrUnknownTemplate is returned when previewing a template file that doesn't exist.
+	ErrUnknownTemplate = errors.New("unknown email template")
+)
+
+// Define a Mailer struct which contains the Transport delivering the rendered emails and
+// the sender information for your emails.
 type Mailer struct {
-	dialer *mail.Dialer
-	sender string
+	transport Transport
+	sender    string
+}
+
+// Branding describes how an email should look and who it appears to come from. Every
+// template can reach it through the "brand" function, e.g. {{brand.Name}}.
+type Branding struct {
+	Name         string
+	LogoURL      string
+	PrimaryColor string
+	ReplyTo      string
+}
+
+// DefaultBranding() returns the LeadHub branding, used for tenants that have not
+// configured their own.
+func DefaultBranding() Branding {
+	return Branding{
+		Name:         "LeadHub",
+		LogoURL:      "https://i.ibb.co/5hCHs54H/lead-hub-high-resolution-logo-modified.png",
+		PrimaryColor: "#555555",
+	}
+}
+
+// withDefaults() fills any unset branding field from the default LeadHub branding.
+func (b Branding) withDefaults() Branding {
+	defaults := DefaultBranding()
+	if b.Name == "" {
+		b.Name = defaults.Name
+	}
+	if b.LogoURL == "" {
+		b.LogoURL = defaults.LogoURL
+	}
+	if b.PrimaryColor == "" {
+		b.PrimaryColor = defaults.PrimaryColor
+	}
+	return b
 }
 
-func New(host string, port int, username, password, sender string) Mailer {
-	// Initialize a new mail.Dialer instance with the given SMTP server settings. We
-	// also configure this to use a 5-second timeout whenever we send an email.
-	dialer := mail.NewDialer(host, port, username, password)
-	dialer.Timeout = 5 * time.Second
-	// Return a Mailer instance containing the dialer and sender information.
+// New() returns a Mailer delivering its emails through transport.
+func New(transport Transport, sender string) Mailer {
 	return Mailer{
-		dialer: dialer,
-		sender: sender,
+		transport: transport,
+		sender:    sender,
+	}
+}
+
+// Define a Send() method on the Mailer type. This takes the context carrying the
+// caller's trace, the recipient email address, the locale the email is written in, the
+// name of the file containing the templates, any dynamic data for the templates as an
+// any parameter and the branding of the tenant the email is sent on behalf of. Send()
+// makes a single delivery attempt, retrying is left to the job queue sending the email.
+// It returns the rendered email, holding the subject and Message-ID, also when the
+// delivery fails.
+func (m Mailer) Send(ctx context.Context, recipient, locale, templateFile string, data any, branding Branding) (*Email, error) {
+	ctx, span := tracer.Start(ctx, "mailer.Send")
+	defer span.End()
+	locale = ResolveLocale(locale)
+	span.SetAttributes(attribute.String("email.template", templateFile), attribute.String("email.locale", locale))
+	email, err := m.compose(recipient, locale, templateFile, data, branding)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "render failed")
+		return nil, err
+	}
+	email.MessageID, err = newMessageID(m.sender)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("email.message_id", email.MessageID))
+	err = m.transport.Deliver(ctx, email)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return email, err
+	}
+	return email, nil
+}
+
+// Preview() renders an email the way Send() does, but returns it instead of delivering it.
+// It fails with ErrUnknownTemplate for a template file not listed by Templates().
+func (m Mailer) Preview(recipient, locale, templateFile string, data any, branding Branding) (*Email, error) {
+	if !templateExists(templateFile) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTemplate, templateFile)
 	}
+	return m.compose(recipient, ResolveLocale(locale), templateFile, data, branding)
 }
 
-// Define a Send() method on the Mailer type. This takes the recipient email address
-// as the first parameter, the name of the file containing the templates, and any
-// dynamic data for the templates as an any parameter.
-func (m Mailer) Send(recipient, templateFile string, data any) error {
-	// Use the ParseFS() method to parse the required template file from the embedded
-	// file system.
-	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
+// compose() renders the templates of an email and sets its sender.
+func (m Mailer) compose(recipient, locale, templateFile string, data any, branding Branding) (*Email, error) {
+	subject, plainBody, htmlBody, err := render(templateFile, locale, data, branding)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("%w: %w", ErrRenderTemplate, err)
+	}
+	email := &Email{
+		To:        recipient,
+		Subject:   subject,
+		Template:  templateFile,
+		Locale:    locale,
+		PlainBody: plainBody,
+		HTMLBody:  htmlBody,
+		SentAt:    time.Now(),
+	}
+	m.setSender(email, branding.withDefaults())
+	return email, nil
+}
+
+// newMessageID() returns a unique Message-ID in the domain of the sender address, which
+// identifies the email with the mail provider and in the recipient's mailbox.
+func newMessageID(sender string) (string, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	domain := "leadhub.localhost"
+	if address, err := netmail.ParseAddress(sender); err == nil {
+		if _, host, ok := strings.Cut(address.Address, "@"); ok {
+			domain = host
+		}
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(random), domain), nil
+}
+
+// setSender() sets the From and Reply-To addresses. Mail is always sent from the
+// configured sender address, but shows the tenant's display name and replies go to the
+// tenant.
+func (m Mailer) setSender(email *Email, branding Branding) {
+	address, err := netmail.ParseAddress(m.sender)
+	if err != nil {
+		// not a parsable address, leave it to the SMTP server to complain
+		email.From = m.sender
+	} else {
+		email.From = (&netmail.Address{Name: branding.Name, Address: address.Address}).String()
+	}
+	email.ReplyTo = branding.ReplyTo
+}
+
+// render() executes the subject, plainBody and htmlBody templates of a template file in
+// the given locale with the given data and branding.
+func render(templateFile, locale string, data any, branding Branding) (string, string, string, error) {
+	// Parse the required template file, in the recipient's language where translated,
+	// from the embedded file system.
+	tmpl, err := parseTemplate(templateFS, templatePath(templateFile, locale), branding)
+	if err != nil {
+		return "", "", "", err
 	}
 	// Execute the named template "subject", passing in the dynamic data and storing the
 	// result in a bytes.Buffer variable.
 	subject := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(subject, "subject", data)
+	err = tmpl.text.ExecuteTemplate(subject, "subject", data)
 	if err != nil {
-		return err
+		return "", "", "", err
 	}
 	// Follow the same pattern to execute the "plainBody" template and store the result
 	// in the plainBody variable.
 	plainBody := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(plainBody, "plainBody", data)
+	err = tmpl.text.ExecuteTemplate(plainBody, "plainBody", data)
 	if err != nil {
-		return err
+		return "", "", "", err
 	}
-	// And likewise with the "htmlBody" template.
+	// And likewise with the "htmlBody" template, the only one that is HTML escaped.
 	htmlBody := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
+	err = tmpl.html.ExecuteTemplate(htmlBody, "htmlBody", data)
 	if err != nil {
-		return err
-	}
-	// Use the mail.NewMessage() function to initialize a new mail.Message instance.
-	// Then we use the SetHeader() method to set the email recipient, sender and subject
-	// headers, the SetBody() method to set the plain-text body, and the AddAlternative()
-	// method to set the HTML body. It's important to note that AddAlternative() should
-	// always be called *after* SetBody().
-	msg := mail.NewMessage()
-	msg.SetHeader("To", recipient)
-	msg.SetHeader("From", m.sender)
-	msg.SetHeader("Subject", subject.String())
-	msg.SetBody("text/plain", plainBody.String())
-	msg.AddAlternative("text/html", htmlBody.String())
-	// Call the DialAndSend() method on the dialer, passing in the message to send. This
-	// opens a connection to the SMTP server, sends the message, then closes the
-	// connection. If there is a timeout, it will return a "dial tcp: i/o timeout"
-	// error.
-	for i := 1; i <= 3; i++ {
-		err = m.dialer.DialAndSend(msg)
-		// If everything worked, return nil.
-		if nil == err {
-			return nil
-		}
-		// If it didn't work, sleep for a short time and retry.
-		time.Sleep(500 * time.Millisecond)
+		return "", "", "", err
+	}
+	return subject.String(), plainBody.String(), htmlBody.String(), nil
+}
+
+// emailTemplate is a template file parsed twice: the subject and the plain text body are
+// rendered as text, so names like "Smith & O'Neil" reach the recipient as written, and
+// only the HTML body is escaped.
+type emailTemplate struct {
+	text *template.Template
+	html *htmltemplate.Template
+}
+
+// parseTemplate() parses a template file of fsys. The branding, with the defaults filled
+// in, is exposed to the templates via the "brand" function.
+func parseTemplate(fsys fs.FS, templatePath string, branding Branding) (*emailTemplate, error) {
+	branding = branding.withDefaults()
+	funcs := map[string]any{
+		"brand": func() Branding { return branding },
+	}
+	text, err := template.New("email").Funcs(funcs).ParseFS(fsys, templatePath)
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.New("email").Funcs(funcs).ParseFS(fsys, templatePath)
+	if err != nil {
+		return nil, err
 	}
-	return err
+	return &emailTemplate{text: text, html: html}, nil
 }
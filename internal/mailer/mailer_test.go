@@ -0,0 +1,102 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderWithBranding(t *testing.T) {
+	branding := Branding{
+		Name:         "TradeHub KE",
+		LogoURL:      "https://tradehub.co.ke/logo.png",
+		PrimaryColor: "#1a73e8",
+	}
+	data := map[string]any{
+		"activationURL":   "https://example.com/activate?token=abc",
+		"activationToken": "abc",
+		"userID":          1,
+	}
+	subject, plainBody, htmlBody, err := render("user_welcome.tmpl", DefaultLocale, data, branding)
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	if subject != "Welcome to TradeHub KE!" {
+		t.Errorf("subject = %q, want %q", subject, "Welcome to TradeHub KE!")
+	}
+	if !strings.Contains(plainBody, "The TradeHub KE Team") {
+		t.Errorf("plain body does not contain the tenant name: %q", plainBody)
+	}
+	for _, want := range []string{branding.LogoURL, branding.PrimaryColor, "Welcome to TradeHub KE"} {
+		if !strings.Contains(htmlBody, want) {
+			t.Errorf("html body does not contain %q", want)
+		}
+	}
+}
+
+func TestRenderEscapesOnlyTheHTMLBody(t *testing.T) {
+	data := map[string]any{"userName": "Jane", "loginURL": "https://example.com/login"}
+	subject, plainBody, htmlBody, err := render("user_succesful_activation.tmpl", DefaultLocale, data, Branding{Name: "Smith & O'Neil"})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	if !strings.Contains(subject, "Smith & O'Neil") {
+		t.Errorf("subject = %q, want the name as written", subject)
+	}
+	if !strings.Contains(plainBody, "The Smith & O'Neil Team") {
+		t.Errorf("plain body = %q, want the name as written", plainBody)
+	}
+	if !strings.Contains(htmlBody, "Smith &amp; O&#39;Neil") || strings.Contains(htmlBody, "Smith & O'Neil") {
+		t.Error("html body does not escape the name")
+	}
+}
+
+func TestRenderMentionAsWritten(t *testing.T) {
+	data := map[string]any{
+		"userName":    "Amina",
+		"authorName":  "Ann O'Brien",
+		"leadTitle":   "Coffee",
+		"commentBody": "@amina@tradehub.test Smith & O'Neil confirmed the volumes",
+		"loginURL":    "https://example.com/login",
+	}
+	subject, plainBody, htmlBody, err := render("trade_lead_mention.tmpl", DefaultLocale, data, Branding{})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	if subject != "Ann O'Brien mentioned you on LeadHub" {
+		t.Errorf("subject = %q, want %q", subject, "Ann O'Brien mentioned you on LeadHub")
+	}
+	for _, want := range []string{"Ann O'Brien mentioned you", "Smith & O'Neil confirmed the volumes"} {
+		if !strings.Contains(plainBody, want) {
+			t.Errorf("plain body does not contain %q: %q", want, plainBody)
+		}
+	}
+	for _, want := range []string{"Ann O&#39;Brien", "Smith &amp; O&#39;Neil"} {
+		if !strings.Contains(htmlBody, want) {
+			t.Errorf("html body does not contain %q", want)
+		}
+	}
+}
+
+func TestRenderDefaultBranding(t *testing.T) {
+	data := map[string]any{"userName": "Jane", "loginURL": "https://example.com/login"}
+	subject, _, htmlBody, err := render("user_succesful_activation.tmpl", DefaultLocale, data, Branding{})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+	if !strings.Contains(subject, "LeadHub") {
+		t.Errorf("subject = %q, want the default LeadHub branding", subject)
+	}
+	if !strings.Contains(htmlBody, DefaultBranding().LogoURL) {
+		t.Error("html body does not contain the default logo")
+	}
+}
+
+func TestSendUnknownTemplate(t *testing.T) {
+	m := New(NewMemoryTransport(1), "LeadHub <no-reply@leadhub.test>")
+	_, err := m.Send(context.Background(), "ann@acme.test", "", "missing.tmpl", nil, Branding{})
+	if !errors.Is(err, ErrRenderTemplate) {
+		t.Errorf("Send() error = %v, want %v", err, ErrRenderTemplate)
+	}
+}
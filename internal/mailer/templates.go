@@ -0,0 +1,116 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// templateBlocks are the templates every email template file has to define.
+var templateBlocks = []string{"subject", "plainBody", "htmlBody"}
+
+// TemplateInfo describes an email template file and the locales it is written in.
+type TemplateInfo struct {
+	Name    string   `json:"name"`
+	Locales []string `json:"locales"`
+}
+
+// Templates() lists the email template files, sorted by name.
+func Templates() ([]TemplateInfo, error) {
+	files, err := fs.Glob(templateFS, "templates/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	templates := []TemplateInfo{}
+	for _, file := range files {
+		info := TemplateInfo{Name: path.Base(file), Locales: []string{DefaultLocale}}
+		for _, locale := range SupportedLocales {
+			if locale == DefaultLocale {
+				continue
+			}
+			if _, err := fs.Stat(templateFS, path.Join("templates", locale, info.Name)); err == nil {
+				info.Locales = append(info.Locales, locale)
+			}
+		}
+		templates = append(templates, info)
+	}
+	return templates, nil
+}
+
+// templateExists() reports whether templateFile names a default template file.
+func templateExists(templateFile string) bool {
+	if templateFile != path.Base(templateFile) || !strings.HasSuffix(templateFile, ".tmpl") {
+		return false
+	}
+	_, err := fs.Stat(templateFS, path.Join("templates", templateFile))
+	return err == nil
+}
+
+// ValidateTemplates() parses every template file in every locale and checks that it
+// defines the subject, plainBody and htmlBody templates. It is run at startup, so a broken
+// template stops the service instead of failing every email rendered from it.
+func ValidateTemplates() error {
+	return validateTemplates(templateFS)
+}
+
+// validateTemplates() validates the template files of fsys, laid out like the embedded
+// templates directory. All problems are returned, joined.
+func validateTemplates(fsys fs.FS) error {
+	defaults, err := fs.Glob(fsys, "templates/*.tmpl")
+	if err != nil {
+		return err
+	}
+	if len(defaults) == 0 {
+		return errors.New("no email templates found")
+	}
+	localized, err := fs.Glob(fsys, "templates/*/*.tmpl")
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, file := range localized {
+		locale := path.Base(path.Dir(file))
+		if locale == DefaultLocale || !slices.Contains(SupportedLocales, locale) {
+			errs = append(errs, fmt.Errorf("%s: %q is not a supported locale", file, locale))
+		}
+		if !slices.Contains(defaults, path.Join("templates", path.Base(file))) {
+			errs = append(errs, fmt.Errorf("%s: no default template to fall back to", file))
+		}
+	}
+	for _, file := range append(defaults, localized...) {
+		tmpl, err := parseTemplate(fsys, file, Branding{})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			continue
+		}
+		for _, block := range templateBlocks {
+			if tmpl.text.Lookup(block) == nil {
+				errs = append(errs, fmt.Errorf("%s: missing the %q template", file, block))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SampleData() returns example values for the data of every template, used to preview
+// them.
+func SampleData() map[string]any {
+	return map[string]any{
+		"userID":          1042,
+		"userName":        "Amina Wanjiru",
+		"activationURL":   "https://leadhub.example/activate?token=Y3QMGX3PJ3WLRL2YRTQGQ6KRHU",
+		"activationToken": "Y3QMGX3PJ3WLRL2YRTQGQ6KRHU",
+		"loginURL":        "https://leadhub.example/login",
+		"tenantName":      "TradeHub KE",
+		"downloadURL":     "https://leadhub.example/v1/exports/7/download?expires=1767366245&signature=sample",
+		"expiresAt":       "Fri, 02 Jan 2026 18:04:05 EAT",
+		"leadTitle":       "200 bags of AA coffee",
+		"leadValue":       "2500000.00 KES",
+		"assignedBy":      "Brian Otieno",
+		"authorName":      "Brian Otieno",
+		"commentBody":     "@amina@tradehub.example the buyer confirmed the volumes, can you send the quote?",
+	}
+}
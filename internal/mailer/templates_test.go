@@ -0,0 +1,109 @@
+package mailer
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestValidateTemplates(t *testing.T) {
+	if err := ValidateTemplates(); err != nil {
+		t.Fatalf("embedded templates are invalid: %v", err)
+	}
+
+	const complete = `{{define "subject"}}Hi{{end}}{{define "plainBody"}}Hi{{end}}{{define "htmlBody"}}<p>Hi</p>{{end}}`
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{name: "No templates", files: map[string]string{}, want: "no email templates found"},
+		{
+			name:  "Missing block",
+			files: map[string]string{"templates/a.tmpl": `{{define "subject"}}Hi{{end}}{{define "plainBody"}}Hi{{end}}`},
+			want:  `templates/a.tmpl: missing the "htmlBody" template`,
+		},
+		{
+			name:  "Missing block in a translation",
+			files: map[string]string{"templates/a.tmpl": complete, "templates/fr/a.tmpl": `{{define "subject"}}Salut{{end}}`},
+			want:  `templates/fr/a.tmpl: missing the "plainBody" template`,
+		},
+		{
+			name:  "Syntax error",
+			files: map[string]string{"templates/a.tmpl": `{{define "subject"}}{{.userName}{{end}}`},
+			want:  "templates/a.tmpl",
+		},
+		{
+			name:  "Unknown function",
+			files: map[string]string{"templates/a.tmpl": `{{define "subject"}}{{tenant.Name}}{{end}}`},
+			want:  `function "tenant" not defined`,
+		},
+		{
+			name:  "Unsupported locale",
+			files: map[string]string{"templates/a.tmpl": complete, "templates/de/a.tmpl": complete},
+			want:  `"de" is not a supported locale`,
+		},
+		{
+			name:  "Translation without a default",
+			files: map[string]string{"templates/a.tmpl": complete, "templates/fr/b.tmpl": complete},
+			want:  "templates/fr/b.tmpl: no default template to fall back to",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, content := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(content)}
+			}
+			err := validateTemplates(fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("validateTemplates() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	templates, err := Templates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, template := range templates {
+		names = append(names, template.Name)
+		if !slices.Equal(template.Locales, SupportedLocales) {
+			t.Errorf("%s locales = %v, want %v", template.Name, template.Locales, SupportedLocales)
+		}
+	}
+	if !slices.Contains(names, "user_welcome.tmpl") || !slices.IsSorted(names) {
+		t.Errorf("Templates() = %v", names)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	transport := NewMemoryTransport(1)
+	m := New(transport, "LeadHub <no-reply@leadhub.test>")
+	email, err := m.Preview("ann@acme.test", "sw-KE", "user_welcome.tmpl", SampleData(), Branding{Name: "TradeHub KE"})
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if email.Locale != "sw" || email.From != `"TradeHub KE" <no-reply@leadhub.test>` || email.MessageID != "" {
+		t.Errorf("Preview() = %+v", email)
+	}
+	for part, body := range map[string]string{"subject": email.Subject, "plainBody": email.PlainBody, "htmlBody": email.HTMLBody} {
+		if strings.TrimSpace(body) == "" || strings.Contains(body, "<no value>") {
+			t.Errorf("%s is not rendered with the sample data: %q", part, body)
+		}
+	}
+	if emails := transport.Emails(); len(emails) != 0 {
+		t.Errorf("Preview() delivered %d emails", len(emails))
+	}
+
+	for _, templateFile := range []string{"missing.tmpl", "../mailer.go", "fr/user_welcome.tmpl", "templates"} {
+		if _, err := m.Preview("", "", templateFile, nil, Branding{}); !errors.Is(err, ErrUnknownTemplate) {
+			t.Errorf("Preview(%q) error = %v, want %v", templateFile, err, ErrUnknownTemplate)
+		}
+	}
+}
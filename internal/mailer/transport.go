@@ -0,0 +1,253 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-mail/mail/v2"
+)
+
+// Define constants for the supported transports.
+const (
+	TransportSMTP   = "smtp"
+	TransportFile   = "file"
+	TransportLog    = "log"
+	TransportMemory = "memory"
+)
+
+// DefaultMemoryCapacity is how many emails the memory transport keeps, older ones are
+// dropped.
+const DefaultMemoryCapacity = 100
+
+var ErrUnknownTransport = errors.New("unknown mail transport")
+
+// Email is a rendered email, ready to be delivered by a Transport.
+type Email struct {
+	From      string    `json:"from"`
+	ReplyTo   string    `json:"reply_to,omitempty"`
+	To        string    `json:"to"`
+	Subject   string    `json:"subject"`
+	Template  string    `json:"template"`
+	Locale    string    `json:"locale"`
+	MessageID string    `json:"message_id,omitempty"`
+	PlainBody string    `json:"plain_body"`
+	HTMLBody  string    `json:"html_body"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// message() converts the email to a MIME message with a plain-text and an HTML part.
+func (e *Email) message() *mail.Message {
+	// It's important to note that AddAlternative() should always be called *after*
+	// SetBody().
+	msg := mail.NewMessage()
+	msg.SetHeader("From", e.From)
+	msg.SetHeader("To", e.To)
+	if e.ReplyTo != "" {
+		msg.SetHeader("Reply-To", e.ReplyTo)
+	}
+	msg.SetHeader("Subject", e.Subject)
+	if e.Locale != "" {
+		msg.SetHeader("Content-Language", e.Locale)
+	}
+	if e.MessageID != "" {
+		msg.SetHeader("Message-ID", e.MessageID)
+	}
+	msg.SetDateHeader("Date", e.SentAt)
+	msg.SetBody("text/plain", e.PlainBody)
+	msg.AddAlternative("text/html", e.HTMLBody)
+	return msg
+}
+
+// Transport delivers rendered emails. Deliver() makes a single attempt, retrying is left
+// to the caller.
+type Transport interface {
+	Deliver(ctx context.Context, email *Email) error
+}
+
+// TransportConfig selects and configures a Transport.
+type TransportConfig struct {
+	// Transport is one of TransportSMTP, TransportFile, TransportLog or TransportMemory.
+	Transport string
+	// SMTP server settings of the smtp transport.
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// Dir is the maildir the file transport writes to.
+	Dir string
+	// Writer receives the emails of the log transport, os.Stdout when nil.
+	Writer io.Writer
+}
+
+// NewTransport() returns the configured Transport.
+func NewTransport(cfg TransportConfig) (Transport, error) {
+	switch cfg.Transport {
+	case TransportSMTP, "":
+		return NewSMTPTransport(cfg.Host, cfg.Port, cfg.Username, cfg.Password), nil
+	case TransportFile:
+		return NewFileTransport(cfg.Dir)
+	case TransportLog:
+		writer := cfg.Writer
+		if writer == nil {
+			writer = os.Stdout
+		}
+		return NewLogTransport(writer), nil
+	case TransportMemory:
+		return NewMemoryTransport(DefaultMemoryCapacity), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTransport, cfg.Transport)
+	}
+}
+
+// SMTPTransport delivers emails through an SMTP server.
+type SMTPTransport struct {
+	dialer *mail.Dialer
+}
+
+// NewSMTPTransport() returns a Transport sending through the given SMTP server with a
+// 5-second timeout.
+func NewSMTPTransport(host string, port int, username, password string) *SMTPTransport {
+	dialer := mail.NewDialer(host, port, username, password)
+	dialer.Timeout = 5 * time.Second
+	return &SMTPTransport{dialer: dialer}
+}
+
+// Deliver() opens a connection to the SMTP server, sends the message, then closes the
+// connection. If there is a timeout, it will return a "dial tcp: i/o timeout" error.
+func (t *SMTPTransport) Deliver(ctx context.Context, email *Email) error {
+	return t.dialer.DialAndSend(email.message())
+}
+
+// FileTransport writes every email as an .eml file into a maildir, so it can be opened
+// with any mail client.
+type FileTransport struct {
+	dir string
+}
+
+// NewFileTransport() creates the tmp, new and cur directories of the maildir at dir if
+// they don't exist and returns a FileTransport writing to it.
+func NewFileTransport(dir string) (*FileTransport, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o750); err != nil {
+			return nil, err
+		}
+	}
+	return &FileTransport{dir: dir}, nil
+}
+
+// Deliver() writes the email to the tmp directory and then moves it to new, so readers
+// never observe a partially written email.
+func (t *FileTransport) Deliver(ctx context.Context, email *Email) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d.%s.eml", email.SentAt.UnixNano(), hex.EncodeToString(suffix))
+	tmpPath := filepath.Join(t.dir, "tmp", name)
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return err
+	}
+	_, err = email.message().WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(t.dir, "new", name))
+}
+
+// LogTransport prints the headers and plain-text body of every email instead of sending
+// it.
+type LogTransport struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewLogTransport() returns a LogTransport printing to w.
+func NewLogTransport(w io.Writer) *LogTransport {
+	return &LogTransport{writer: w}
+}
+
+// Deliver() prints the email.
+func (t *LogTransport) Deliver(ctx context.Context, email *Email) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := fmt.Fprintf(t.writer, "----- email %s -----\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n----- end of email -----\n",
+		email.Template, email.From, email.To, email.Subject, email.PlainBody)
+	return err
+}
+
+// CapturedEmail is an email kept by the MemoryTransport.
+type CapturedEmail struct {
+	ID int64 `json:"id"`
+	Email
+}
+
+// MemoryTransport keeps the last emails in memory instead of sending them, for tests and
+// local development.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	capacity int
+	nextID   int64
+	emails   []CapturedEmail
+}
+
+// NewMemoryTransport() returns a MemoryTransport keeping up to capacity emails.
+func NewMemoryTransport(capacity int) *MemoryTransport {
+	if capacity <= 0 {
+		capacity = DefaultMemoryCapacity
+	}
+	return &MemoryTransport{capacity: capacity}
+}
+
+// Deliver() captures the email, dropping the oldest one when full.
+func (t *MemoryTransport) Deliver(ctx context.Context, email *Email) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	if len(t.emails) == t.capacity {
+		t.emails = slices.Delete(t.emails, 0, 1)
+	}
+	t.emails = append(t.emails, CapturedEmail{ID: t.nextID, Email: *email})
+	return nil
+}
+
+// Emails() returns the captured emails, newest first.
+func (t *MemoryTransport) Emails() []CapturedEmail {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	emails := slices.Clone(t.emails)
+	slices.Reverse(emails)
+	return emails
+}
+
+// Email() returns the captured email with the given ID.
+func (t *MemoryTransport) Email(id int64) (CapturedEmail, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, email := range t.emails {
+		if email.ID == id {
+			return email, true
+		}
+	}
+	return CapturedEmail{}, false
+}
+
+// Reset() drops all captured emails.
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.emails = nil
+}
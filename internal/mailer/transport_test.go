@@ -0,0 +1,118 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var welcomeData = map[string]any{
+	"activationURL":   "https://example.com/activate?token=abc",
+	"activationToken": "abc",
+}
+
+func TestSendThroughMemoryTransport(t *testing.T) {
+	transport := NewMemoryTransport(2)
+	m := New(transport, "LeadHub <no-reply@leadhub.test>")
+	branding := Branding{Name: "TradeHub KE", ReplyTo: "support@tradehub.co.ke"}
+	for _, recipient := range []string{"ann@acme.test", "bob@acme.test", "cy@acme.test"} {
+		if _, err := m.Send(context.Background(), recipient, "", "user_welcome.tmpl", welcomeData, branding); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	// the oldest email was dropped, the newest comes first
+	emails := transport.Emails()
+	if len(emails) != 2 || emails[0].To != "cy@acme.test" || emails[1].To != "bob@acme.test" {
+		t.Fatalf("Emails() = %+v", emails)
+	}
+	email, ok := transport.Email(emails[0].ID)
+	if !ok {
+		t.Fatalf("Email(%d) not found", emails[0].ID)
+	}
+	if email.From != `"TradeHub KE" <no-reply@leadhub.test>` || email.ReplyTo != branding.ReplyTo {
+		t.Errorf("From = %q, Reply-To = %q", email.From, email.ReplyTo)
+	}
+	if email.Subject != "Welcome to TradeHub KE!" || email.Template != "user_welcome.tmpl" {
+		t.Errorf("Subject = %q, Template = %q", email.Subject, email.Template)
+	}
+	if !strings.Contains(email.PlainBody, "abc") || !strings.Contains(email.HTMLBody, "<html") {
+		t.Errorf("bodies were not rendered: %q", email.PlainBody)
+	}
+	if _, ok := transport.Email(emails[0].ID - 2); ok {
+		t.Error("Email() found a dropped email")
+	}
+	transport.Reset()
+	if emails := transport.Emails(); len(emails) != 0 {
+		t.Errorf("Emails() after Reset() = %+v", emails)
+	}
+}
+
+func TestFileTransport(t *testing.T) {
+	dir := t.TempDir()
+	transport, err := NewTransport(TransportConfig{Transport: TransportFile, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(transport, "no-reply@leadhub.test")
+	sent, err := m.Send(context.Background(), "ann@acme.test", "", "user_welcome.tmpl", welcomeData, Branding{})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "new", "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("maildir holds %d emails, want 1", len(files))
+	}
+	if tmp, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(tmp) != 0 {
+		t.Errorf("tmp directory was not cleaned up: %v", tmp)
+	}
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("written email is not a valid message: %v", err)
+	}
+	if got := msg.Header.Get("To"); got != "ann@acme.test" {
+		t.Errorf("To = %q", got)
+	}
+	if got := msg.Header.Get("Subject"); got != "Welcome to LeadHub!" {
+		t.Errorf("Subject = %q", got)
+	}
+	if got := msg.Header.Get("Message-Id"); got != sent.MessageID || !strings.HasSuffix(got, "@leadhub.test>") {
+		t.Errorf("Message-ID = %q, want %q", got, sent.MessageID)
+	}
+	if !strings.HasPrefix(msg.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Errorf("Content-Type = %q", msg.Header.Get("Content-Type"))
+	}
+}
+
+func TestLogTransport(t *testing.T) {
+	var output bytes.Buffer
+	transport, err := NewTransport(TransportConfig{Transport: TransportLog, Writer: &output})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(transport, "no-reply@leadhub.test")
+	if _, err := m.Send(context.Background(), "ann@acme.test", "", "user_welcome.tmpl", welcomeData, Branding{}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	for _, want := range []string{"To: ann@acme.test", "Subject: Welcome to LeadHub!", welcomeData["activationURL"].(string)} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("log output does not contain %q:\n%s", want, output.String())
+		}
+	}
+}
+
+func TestUnknownTransport(t *testing.T) {
+	if _, err := NewTransport(TransportConfig{Transport: "carrier-pigeon"}); !errors.Is(err, ErrUnknownTransport) {
+		t.Errorf("NewTransport() error = %v, want %v", err, ErrUnknownTransport)
+	}
+}
@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes the name of every metric exported by the service.
+const Namespace = "leadhub"
+
+// UnmatchedRoute is the route label used for requests that did not match any route,
+// so that random paths cannot blow up the cardinality of the request metrics.
+const UnmatchedRoute = "unmatched"
+
+// Metrics holds the Prometheus collectors of the service together with the registry
+// they are registered on. All methods are safe to call on a nil *Metrics, which makes
+// instrumentation optional in tests.
+type Metrics struct {
+	registry             *prometheus.Registry
+	requestDuration      *prometheus.HistogramVec
+	requestsInFlight     prometheus.Gauge
+	emailsSent           *prometheus.CounterVec
+	backgroundGoroutines prometheus.Gauge
+	rateLimitRejections  prometheus.Counter
+	jobsProcessed        *prometheus.CounterVec
+	jobDuration          *prometheus.HistogramVec
+}
+
+// New() creates the service metrics on a fresh registry. The Go runtime and process
+// collectors are always registered, the connection pool statistics only when db is
+// not nil.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of HTTP requests by route pattern, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		requestsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests currently being served.",
+		}),
+		emailsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "mailer",
+			Name:      "emails_total",
+			Help:      "Number of emails the mailer tried to send by template and result.",
+		}, []string{"template", "result"}),
+		backgroundGoroutines: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "background_goroutines",
+			Help:      "Number of background tasks currently running.",
+		}),
+		rateLimitRejections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "rate_limit_rejections_total",
+			Help:      "Number of requests rejected by the rate limiter.",
+		}),
+		jobsProcessed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "jobs",
+			Name:      "processed_total",
+			Help:      "Number of background job runs by kind and outcome (completed|retried|dead).",
+		}, []string{"kind", "outcome"}),
+		jobDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "jobs",
+			Name:      "duration_seconds",
+			Help:      "Duration of background job runs by kind.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"kind"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestDuration,
+		m.requestsInFlight,
+		m.emailsSent,
+		m.backgroundGoroutines,
+		m.rateLimitRejections,
+		m.jobsProcessed,
+		m.jobDuration,
+	)
+	if db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, Namespace))
+	}
+	return m
+}
+
+// Handler() returns the HTTP handler serving the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Registry() exposes the underlying registry, mainly for tests.
+func (m *Metrics) Registry() *prometheus.Registry {
+	if m == nil {
+		return nil
+	}
+	return m.registry
+}
+
+// RequestStarted() increments the in-flight gauge. It must be paired with ObserveRequest().
+func (m *Metrics) RequestStarted() {
+	if m == nil {
+		return
+	}
+	m.requestsInFlight.Inc()
+}
+
+// ObserveRequest() records a finished request and decrements the in-flight gauge. An
+// empty route is recorded as UnmatchedRoute.
+func (m *Metrics) ObserveRequest(route, method string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.requestsInFlight.Dec()
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	m.requestDuration.WithLabelValues(route, method, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// ObserveEmail() records the outcome of sending an email with the given template.
+func (m *Metrics) ObserveEmail(template string, err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.emailsSent.WithLabelValues(template, result).Inc()
+}
+
+// BackgroundStarted() increments the number of running background tasks.
+func (m *Metrics) BackgroundStarted() {
+	if m == nil {
+		return
+	}
+	m.backgroundGoroutines.Inc()
+}
+
+// BackgroundFinished() decrements the number of running background tasks.
+func (m *Metrics) BackgroundFinished() {
+	if m == nil {
+		return
+	}
+	m.backgroundGoroutines.Dec()
+}
+
+// RateLimitRejected() counts a request rejected by the rate limiter.
+func (m *Metrics) RateLimitRejected() {
+	if m == nil {
+		return
+	}
+	m.rateLimitRejections.Inc()
+}
+
+// ObserveJob() records a background job run of the given kind and its outcome.
+func (m *Metrics) ObserveJob(kind, outcome string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.jobsProcessed.WithLabelValues(kind, outcome).Inc()
+	m.jobDuration.WithLabelValues(kind).Observe(duration.Seconds())
+}
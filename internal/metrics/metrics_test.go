@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsRecording(t *testing.T) {
+	m := New(nil)
+
+	m.RequestStarted()
+	m.RequestStarted()
+	m.ObserveRequest("/v1/trade_leads/", http.MethodGet, http.StatusOK, 20*time.Millisecond)
+	if got := testutil.ToFloat64(m.requestsInFlight); got != 1 {
+		t.Errorf("requests in flight = %v, want 1", got)
+	}
+	m.ObserveRequest("", http.MethodGet, http.StatusNotFound, time.Millisecond)
+	if got := testutil.CollectAndCount(m.requestDuration); got != 2 {
+		t.Errorf("request duration series = %d, want 2", got)
+	}
+
+	m.ObserveEmail("user_welcome.tmpl", nil)
+	m.ObserveEmail("user_welcome.tmpl", errors.New("dial tcp: i/o timeout"))
+	m.ObserveEmail("user_welcome.tmpl", nil)
+	if got := testutil.ToFloat64(m.emailsSent.WithLabelValues("user_welcome.tmpl", "success")); got != 2 {
+		t.Errorf("successful emails = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.emailsSent.WithLabelValues("user_welcome.tmpl", "failure")); got != 1 {
+		t.Errorf("failed emails = %v, want 1", got)
+	}
+
+	m.BackgroundStarted()
+	m.BackgroundStarted()
+	m.BackgroundFinished()
+	if got := testutil.ToFloat64(m.backgroundGoroutines); got != 1 {
+		t.Errorf("background goroutines = %v, want 1", got)
+	}
+
+	m.RateLimitRejected()
+	if got := testutil.ToFloat64(m.rateLimitRejections); got != 1 {
+		t.Errorf("rate limit rejections = %v, want 1", got)
+	}
+
+	m.ObserveJob("email.send", "retried", time.Second)
+	m.ObserveJob("email.send", "completed", time.Second)
+	if got := testutil.ToFloat64(m.jobsProcessed.WithLabelValues("email.send", "retried")); got != 1 {
+		t.Errorf("retried jobs = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.jobDuration); got != 1 {
+		t.Errorf("job duration series = %d, want 1", got)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	m := New(nil)
+	m.RequestStarted()
+	m.ObserveRequest("", http.MethodGet, http.StatusNotFound, time.Millisecond)
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	body, _ := io.ReadAll(rr.Body)
+	for _, want := range []string{
+		"leadhub_http_request_duration_seconds_bucket",
+		`route="unmatched"`,
+		"leadhub_http_requests_in_flight",
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output does not contain %q", want)
+		}
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	// none of these may panic
+	m.RequestStarted()
+	m.ObserveRequest("/", http.MethodGet, http.StatusOK, time.Millisecond)
+	m.ObserveEmail("user_welcome.tmpl", nil)
+	m.BackgroundStarted()
+	m.BackgroundFinished()
+	m.RateLimitRejected()
+	m.ObserveJob("email.send", "completed", time.Second)
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/metrics", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
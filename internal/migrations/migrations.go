@@ -0,0 +1,104 @@
+// Package migrations applies the embedded goose migrations to the database. It keeps the
+// same goose_db_version table as scripts/database/migrate.sh, so both can be used on the
+// same database.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/sql/schema"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+const (
+	// LockID is the key of the Postgres advisory lock held while migrations run, so
+	// replicas starting at the same time apply them one after the other.
+	LockID int64 = 5183914247
+	// DefaultLockTimeout is how long to wait for another instance to finish migrating.
+	DefaultLockTimeout = 5 * time.Minute
+	// lockRetryPeriod is how often the advisory lock is retried.
+	lockRetryPeriod = time.Second
+)
+
+var (
+	ErrNoMigrationsApplied = errors.New("no migrations have been applied")
+)
+
+// Migrator runs the embedded migrations against a Postgres database.
+type Migrator struct {
+	provider *goose.Provider
+}
+
+// New() returns a Migrator for the migrations embedded in the binary. Every operation
+// holds the advisory lock, waiting up to lockTimeout for it, a zero lockTimeout uses
+// DefaultLockTimeout.
+func New(db *sql.DB, lockTimeout time.Duration) (*Migrator, error) {
+	return newMigrator(db, schema.FS, lockTimeout)
+}
+
+func newMigrator(db *sql.DB, fsys fs.FS, lockTimeout time.Duration) (*Migrator, error) {
+	if lockTimeout <= 0 {
+		lockTimeout = DefaultLockTimeout
+	}
+	attempts := uint64(lockTimeout / lockRetryPeriod)
+	if attempts < 1 {
+		attempts = 1
+	}
+	locker, err := lock.NewPostgresSessionLocker(
+		lock.WithLockID(LockID),
+		lock.WithLockTimeout(uint64(lockRetryPeriod/time.Second), attempts),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, fsys,
+		goose.WithSessionLocker(locker),
+		goose.WithDisableGlobalRegistry(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{provider: provider}, nil
+}
+
+// Sources() returns the embedded migrations ordered by version.
+func (m *Migrator) Sources() []*goose.Source {
+	return m.provider.ListSources()
+}
+
+// Up() applies all pending migrations. Nothing is returned when the database is up to date.
+func (m *Migrator) Up(ctx context.Context) ([]*goose.MigrationResult, error) {
+	return m.provider.Up(ctx)
+}
+
+// Down() rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) (*goose.MigrationResult, error) {
+	result, err := m.provider.Down(ctx)
+	if errors.Is(err, goose.ErrNoNextVersion) {
+		return nil, ErrNoMigrationsApplied
+	}
+	return result, err
+}
+
+// Redo() rolls back the most recently applied migration and applies it again.
+func (m *Migrator) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+	down, err := m.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	up, err := m.provider.ApplyVersion(ctx, down.Source.Version, true)
+	if err != nil {
+		return []*goose.MigrationResult{down}, err
+	}
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+// Status() returns the state of every embedded migration.
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.provider.Status(ctx)
+}
@@ -0,0 +1,155 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/sql/schema"
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	files, err := fs.Glob(schema.FS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no migrations are embedded")
+	}
+	// the provider only reads the embedded files, nothing connects to this database
+	db, err := sql.Open("postgres", "postgres://localhost/leadhub?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrator, err := New(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources := migrator.Sources()
+	if len(sources) != len(files) {
+		t.Fatalf("got %d migrations, want one for each of the %d embedded files", len(sources), len(files))
+	}
+	for i, source := range sources {
+		if want := int64(i + 1); source.Version != want {
+			t.Errorf("migration %s has version %d, want %d", source.Path, source.Version, want)
+		}
+		if source.Type != goose.TypeSQL {
+			t.Errorf("migration %s has type %s, want %s", source.Path, source.Type, goose.TypeSQL)
+		}
+		contents, err := fs.ReadFile(schema.FS, path.Base(source.Path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, annotation := range []string{"-- +goose Up", "-- +goose Down"} {
+			if !strings.Contains(string(contents), annotation) {
+				t.Errorf("migration %s is missing %q", source.Path, annotation)
+			}
+		}
+	}
+}
+
+func TestLockTimeout(t *testing.T) {
+	db, err := sql.Open("postgres", "postgres://localhost/leadhub?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, timeout := range []time.Duration{-time.Second, 0, 100 * time.Millisecond, time.Minute} {
+		if _, err := New(db, timeout); err != nil {
+			t.Errorf("New() with a %v lock timeout error = %v", timeout, err)
+		}
+	}
+}
+
+// TestVersionTriggers checks that update_updated_at_column(), which bumps the version of
+// every updated row, is only used on tables that have a version column. Any other table
+// has to use update_updated_at_only_column(), or all of its updates fail.
+func TestVersionTriggers(t *testing.T) {
+	files, err := fs.Glob(schema.FS, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTableRX := regexp.MustCompile(`(?is)CREATE TABLE(?: IF NOT EXISTS)?\s+(\w+)\s*\((.*?)\n\s*\);`)
+	addVersionRX := regexp.MustCompile(`(?i)ALTER TABLE\s+(\w+)\s+ADD COLUMN(?: IF NOT EXISTS)?\s+version\b`)
+	triggerRX := regexp.MustCompile(`(?i)BEFORE UPDATE ON\s+(\w+)\s+FOR EACH ROW\s+EXECUTE FUNCTION update_updated_at_column\(\)`)
+	versioned := map[string]bool{}
+	for _, file := range files {
+		contents, err := fs.ReadFile(schema.FS, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, _, _ := strings.Cut(string(contents), "-- +goose Down")
+		for _, match := range createTableRX.FindAllStringSubmatch(up, -1) {
+			versioned[match[1]] = regexp.MustCompile(`(?im)^\s*version\s`).MatchString(match[2])
+		}
+		for _, match := range addVersionRX.FindAllStringSubmatch(up, -1) {
+			versioned[match[1]] = true
+		}
+		for _, match := range triggerRX.FindAllStringSubmatch(up, -1) {
+			if !versioned[match[1]] {
+				t.Errorf("migration %s bumps the version of %s, which has no version column", file, match[1])
+			}
+		}
+	}
+}
+
+// TestConcurrentUp runs against a database set through LEADHUB_TEST_DB_DSN. Several
+// migrators start at once, as replicas would, and all of them have to succeed.
+func TestConcurrentUp(t *testing.T) {
+	dsn := os.Getenv("LEADHUB_TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("LEADHUB_TEST_DB_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			migrator, err := New(db, 0)
+			if err == nil {
+				_, err = migrator.Up(context.Background())
+			}
+			if err != nil {
+				errs <- fmt.Errorf("replica %d: %w", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	migrator, err := New(db, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.State != goose.StateApplied {
+			t.Errorf("migration %s is %s, want %s", status.Source.Path, status.State, goose.StateApplied)
+		}
+	}
+}
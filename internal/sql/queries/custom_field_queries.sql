@@ -0,0 +1,48 @@
+-- name: CreateCustomField :one
+INSERT INTO tenant_custom_fields (tenant_id, field_key, label, field_type, required, options)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, version, created_at, updated_at;
+
+-- name: GetCustomFieldsByTenantID :many
+SELECT 
+    id, 
+    tenant_id, 
+    field_key, 
+    label, 
+    field_type, 
+    required, 
+    options, 
+    version, 
+    created_at, 
+    updated_at
+FROM tenant_custom_fields
+WHERE tenant_id = $1
+ORDER BY id;
+
+-- name: GetCustomFieldByID :one
+SELECT 
+    id, 
+    tenant_id, 
+    field_key, 
+    label, 
+    field_type, 
+    required, 
+    options, 
+    version, 
+    created_at, 
+    updated_at
+FROM tenant_custom_fields
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: UpdateCustomField :one
+UPDATE tenant_custom_fields
+SET 
+    label = $3,
+    required = $4,
+    options = $5
+WHERE id = $1 AND version = $2
+RETURNING version, updated_at;
+
+-- name: DeleteCustomField :execrows
+DELETE FROM tenant_custom_fields
+WHERE id = $1 AND tenant_id = $2;
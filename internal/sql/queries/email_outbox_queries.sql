@@ -0,0 +1,65 @@
+-- name: CreateOutboxEmail :one
+INSERT INTO email_outbox (tenant_id, recipient, template, locale, data)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, status, attempts, created_at, updated_at;
+
+-- name: GetOutboxEmailByID :one
+SELECT 
+    id, 
+    tenant_id, 
+    recipient, 
+    template, 
+    locale, 
+    data, 
+    subject, 
+    status, 
+    attempts, 
+    last_error, 
+    provider_message_id, 
+    sent_at, 
+    created_at, 
+    updated_at
+FROM email_outbox
+WHERE id = $1;
+
+-- name: AdminGetAllOutboxEmails :many
+SELECT 
+    COUNT(*) OVER() AS total_count,
+    id, 
+    tenant_id, 
+    recipient, 
+    template, 
+    locale, 
+    data, 
+    subject, 
+    status, 
+    attempts, 
+    last_error, 
+    provider_message_id, 
+    sent_at, 
+    created_at, 
+    updated_at
+FROM email_outbox
+WHERE (sqlc.arg(tenant_id)::bigint = 0 OR tenant_id = sqlc.arg(tenant_id)::bigint)
+  AND (sqlc.arg(status)::text = '' OR status = sqlc.arg(status)::text)
+  AND (sqlc.arg(recipient)::text = '' OR lower(recipient) = lower(sqlc.arg(recipient)::text))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: RecordOutboxEmailAttempt :execrows
+UPDATE email_outbox
+SET 
+    status = $2,
+    attempts = attempts + 1,
+    subject = $3,
+    last_error = $4,
+    provider_message_id = $5,
+    sent_at = $6
+WHERE id = $1;
+
+-- name: RequeueOutboxEmail :execrows
+UPDATE email_outbox
+SET 
+    status = 'queued',
+    last_error = ''
+WHERE id = $1 AND status = 'failed';
@@ -0,0 +1,19 @@
+-- name: UpsertExchangeRate :one
+INSERT INTO exchange_rates (base_currency, quote_currency, rate, rate_date)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (base_currency, quote_currency, rate_date) DO UPDATE
+SET rate = EXCLUDED.rate
+RETURNING id, created_at, updated_at;
+
+-- name: GetExchangeRatesOnDate :many
+SELECT DISTINCT ON (base_currency, quote_currency)
+    id,
+    base_currency,
+    quote_currency,
+    rate,
+    rate_date,
+    created_at,
+    updated_at
+FROM exchange_rates
+WHERE rate_date <= $1
+ORDER BY base_currency, quote_currency, rate_date DESC;
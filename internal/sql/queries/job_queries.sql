@@ -0,0 +1,91 @@
+-- name: EnqueueJob :one
+INSERT INTO jobs (kind, payload, max_attempts, run_at)
+VALUES ($1, $2, $3, $4)
+RETURNING id, status, attempts, created_at, updated_at;
+
+-- name: ClaimJobs :many
+UPDATE jobs
+SET 
+    status = 'running',
+    attempts = attempts + 1,
+    locked_by = $1,
+    locked_at = now()
+WHERE id IN (
+    SELECT id
+    FROM jobs
+    WHERE status = 'pending' AND run_at <= now()
+    ORDER BY run_at, id
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING 
+    id, 
+    kind, 
+    payload, 
+    status, 
+    attempts, 
+    max_attempts, 
+    run_at, 
+    last_error, 
+    locked_by, 
+    locked_at, 
+    completed_at, 
+    created_at, 
+    updated_at;
+
+-- name: CompleteJob :execrows
+UPDATE jobs
+SET 
+    status = 'completed',
+    last_error = '',
+    locked_by = '',
+    locked_at = NULL,
+    completed_at = now()
+WHERE id = $1 AND status = 'running' AND locked_by = $2;
+
+-- name: RetryJob :execrows
+UPDATE jobs
+SET 
+    status = 'pending',
+    run_at = $3,
+    last_error = $4,
+    locked_by = '',
+    locked_at = NULL
+WHERE id = $1 AND status = 'running' AND locked_by = $2;
+
+-- name: BuryJob :execrows
+UPDATE jobs
+SET 
+    status = 'dead',
+    last_error = $3,
+    locked_by = '',
+    locked_at = NULL,
+    completed_at = now()
+WHERE id = $1 AND status = 'running' AND locked_by = $2;
+
+-- name: RequeueStaleJobs :execrows
+UPDATE jobs
+SET 
+    status = 'pending',
+    last_error = 'worker lock expired',
+    locked_by = '',
+    locked_at = NULL
+WHERE status = 'running' AND locked_at < $1;
+
+-- name: GetJobByID :one
+SELECT 
+    id, 
+    kind, 
+    payload, 
+    status, 
+    attempts, 
+    max_attempts, 
+    run_at, 
+    last_error, 
+    locked_by, 
+    locked_at, 
+    completed_at, 
+    created_at, 
+    updated_at
+FROM jobs
+WHERE id = $1;
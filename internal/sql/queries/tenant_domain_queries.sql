@@ -0,0 +1,64 @@
+-- name: CreateTenantDomain :one
+INSERT INTO tenant_domains (tenant_id, domain, verification_token)
+VALUES ($1, $2, $3)
+RETURNING id, verified, version, created_at, updated_at;
+
+-- name: GetTenantDomainsByTenantID :many
+SELECT 
+    id, 
+    tenant_id, 
+    domain, 
+    verification_token, 
+    verified, 
+    verified_at, 
+    version, 
+    created_at, 
+    updated_at
+FROM tenant_domains
+WHERE tenant_id = $1
+ORDER BY id;
+
+-- name: GetTenantDomainByID :one
+SELECT 
+    id, 
+    tenant_id, 
+    domain, 
+    verification_token, 
+    verified, 
+    verified_at, 
+    version, 
+    created_at, 
+    updated_at
+FROM tenant_domains
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: GetVerifiedTenantDomainByDomain :one
+SELECT 
+    id, 
+    tenant_id, 
+    domain, 
+    verification_token, 
+    verified, 
+    verified_at, 
+    version, 
+    created_at, 
+    updated_at
+FROM tenant_domains
+WHERE domain = $1 AND verified;
+
+-- name: TenantHasVerifiedDomains :one
+SELECT EXISTS (
+    SELECT 1 FROM tenant_domains WHERE tenant_id = $1 AND verified
+);
+
+-- name: VerifyTenantDomain :one
+UPDATE tenant_domains
+SET 
+    verified = true,
+    verified_at = now()
+WHERE id = $1 AND version = $2
+RETURNING verified, verified_at, version, updated_at;
+
+-- name: DeleteTenantDomain :execrows
+DELETE FROM tenant_domains
+WHERE id = $1 AND tenant_id = $2;
@@ -0,0 +1,32 @@
+-- name: CreateTenantExport :one
+INSERT INTO tenant_exports (tenant_id, requested_by, currency)
+VALUES ($1, $2, $3)
+RETURNING id, status, version, created_at, updated_at;
+
+-- name: GetTenantExportByID :one
+SELECT 
+    id, 
+    tenant_id, 
+    requested_by, 
+    status, 
+    file_key, 
+    file_size, 
+    error, 
+    completed_at, 
+    version, 
+    created_at, 
+    updated_at,
+    currency
+FROM tenant_exports
+WHERE id = $1;
+
+-- name: UpdateTenantExport :one
+UPDATE tenant_exports
+SET 
+    status = $3,
+    file_key = $4,
+    file_size = $5,
+    error = $6,
+    completed_at = $7
+WHERE id = $1 AND version = $2
+RETURNING version, updated_at;
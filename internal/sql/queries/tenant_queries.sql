@@ -1,6 +1,6 @@
 -- name: CreateTenant :one
-INSERT INTO tenants (name, contact_email, description)
-VALUES ($1, $2, $3)
+INSERT INTO tenants (name, contact_email, description, parent_id)
+VALUES ($1, $2, $3, $4)
 RETURNING id, version, created_at, updated_at;
 
 -- name: GetTenantByID :one
@@ -11,7 +11,8 @@ SELECT
     description, 
     version,
     created_at, 
-    updated_at
+    updated_at,
+    parent_id
 FROM tenants
 WHERE id = $1;
 
@@ -23,9 +24,11 @@ SELECT count(*) OVER() AS total_count,
     description,
     version, 
     created_at, 
-    updated_at
+    updated_at,
+    parent_id
 FROM tenants
-WHERE ($1 = '' OR to_tsvector('simple', name) @@ plainto_tsquery('simple', $1))
+WHERE ($1 = '' AND parent_id IS NULL)
+   OR ($1 <> '' AND to_tsvector('simple', name) @@ plainto_tsquery('simple', $1))
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
@@ -34,6 +37,37 @@ UPDATE tenants
 SET 
     name = $2,
     contact_email = $3,
-    description = $4
+    description = $4,
+    parent_id = $6
 WHERE id = $1 AND version = $5
-RETURNING version, updated_at;
\ No newline at end of file
+RETURNING version, updated_at;
+
+-- name: GetTenantDescendantIDs :many
+WITH RECURSIVE tenant_tree AS (
+    SELECT id FROM tenants WHERE id = $1
+    UNION
+    SELECT t.id FROM tenants t
+    INNER JOIN tenant_tree tt ON t.parent_id = tt.id
+)
+SELECT id FROM tenant_tree
+ORDER BY id;
+
+-- name: GetTenantSubtrees :many
+WITH RECURSIVE tenant_tree AS (
+    SELECT t.id FROM tenants t WHERE t.parent_id = ANY(sqlc.arg(root_ids)::bigint[])
+    UNION
+    SELECT t.id FROM tenants t
+    INNER JOIN tenant_tree tt ON t.parent_id = tt.id
+)
+SELECT 
+    t.id, 
+    t.name, 
+    t.contact_email, 
+    t.description, 
+    t.version,
+    t.created_at, 
+    t.updated_at,
+    t.parent_id
+FROM tenants t
+INNER JOIN tenant_tree tt ON t.id = tt.id
+ORDER BY t.created_at DESC;
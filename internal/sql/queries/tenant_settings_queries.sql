@@ -0,0 +1,36 @@
+-- name: GetTenantSettingsByTenantID :one
+SELECT 
+    tenant_id, 
+    display_name, 
+    logo_url, 
+    primary_color, 
+    reply_to, 
+    default_currency, 
+    timezone, 
+    locale, 
+    version, 
+    created_at, 
+    updated_at,
+    auto_assign_leads
+FROM tenant_settings
+WHERE tenant_id = $1;
+
+-- name: InsertTenantSettings :one
+INSERT INTO tenant_settings (tenant_id, display_name, logo_url, primary_color, reply_to, default_currency, timezone, locale, auto_assign_leads)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (tenant_id) DO NOTHING
+RETURNING version, created_at, updated_at;
+
+-- name: UpdateTenantSettings :one
+UPDATE tenant_settings
+SET
+    display_name = $2,
+    logo_url = $3,
+    primary_color = $4,
+    reply_to = $5,
+    default_currency = $6,
+    timezone = $7,
+    locale = $8,
+    auto_assign_leads = $9
+WHERE tenant_id = $1 AND version = $10
+RETURNING version, created_at, updated_at;
@@ -17,7 +17,8 @@ SELECT
     users.activated, 
     users.version, 
     users.created_at, 
-    users.updated_at
+    users.updated_at,
+    users.locale
 FROM users
 INNER JOIN api_keys
 ON users.id = api_keys.user_id
@@ -0,0 +1,55 @@
+-- name: CreateTradeLeadComment :one
+INSERT INTO trade_lead_comments (trade_lead_id, tenant_id, author_id, body, internal)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, version, created_at, updated_at;
+
+-- name: GetTradeLeadCommentByID :one
+SELECT
+  c.id,
+  c.trade_lead_id,
+  c.tenant_id,
+  c.author_id,
+  COALESCE(u.name, '')::text AS author_name,
+  c.body,
+  c.internal,
+  c.version,
+  c.created_at,
+  c.updated_at
+FROM trade_lead_comments c
+LEFT JOIN users u ON u.id = c.author_id
+WHERE c.id = $1 AND c.trade_lead_id = $2;
+
+-- name: GetTradeLeadCommentsByLeadID :many
+SELECT
+  COUNT(*) OVER() AS total_count,
+  c.id,
+  c.trade_lead_id,
+  c.tenant_id,
+  c.author_id,
+  COALESCE(u.name, '')::text AS author_name,
+  c.body,
+  c.internal,
+  c.version,
+  c.created_at,
+  c.updated_at
+FROM trade_lead_comments c
+LEFT JOIN users u ON u.id = c.author_id
+WHERE c.trade_lead_id = sqlc.arg(trade_lead_id)
+  AND (sqlc.arg(include_internal)::boolean OR NOT c.internal)
+ORDER BY
+  CASE WHEN sqlc.arg(sort_direction)::text = 'ASC' THEN c.created_at END ASC,
+  CASE WHEN sqlc.arg(sort_direction)::text = 'DESC' THEN c.created_at END DESC,
+  CASE WHEN sqlc.arg(sort_direction)::text = 'ASC' THEN c.id END ASC,
+  c.id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: UpdateTradeLeadComment :one
+UPDATE trade_lead_comments
+SET
+  body = $3
+WHERE id = $1 AND version = $2
+RETURNING version, updated_at;
+
+-- name: DeleteTradeLeadComment :execrows
+DELETE FROM trade_lead_comments
+WHERE id = $1 AND trade_lead_id = $2;
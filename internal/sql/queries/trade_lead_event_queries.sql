@@ -0,0 +1,5 @@
+-- name: NextTradeLeadEventID :one
+SELECT nextval('trade_lead_event_id_seq')::bigint;
+
+-- name: NotifyTradeLeadEvent :exec
+SELECT pg_notify(sqlc.arg(channel)::text, sqlc.arg(payload)::text);
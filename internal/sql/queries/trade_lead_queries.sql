@@ -1,4 +1,4 @@
--- name: GetAllLeadsByTenantID :many
+-- name: GetAllLeadsByTenantIDs :many
 SELECT 
   COUNT(*) OVER() AS total_count,
   id, 
@@ -9,12 +9,28 @@ SELECT
   value, 
   version,
   created_at, 
-  updated_at
+  updated_at,
+  custom_fields,
+  currency,
+  assigned_to
 FROM trade_leads
-WHERE tenant_id = $1
-  AND ($2 = '' OR to_tsvector('simple', title) @@ plainto_tsquery('simple', $2))
-ORDER BY created_at DESC
-LIMIT $3 OFFSET $4;
+WHERE tenant_id = ANY(sqlc.arg(tenant_ids)::bigint[])
+  AND (sqlc.arg(assigned_to)::bigint = 0 OR assigned_to = sqlc.arg(assigned_to)::bigint)
+  AND (sqlc.arg(name)::text = '' OR to_tsvector('simple', title) @@ plainto_tsquery('simple', sqlc.arg(name)::text))
+  AND custom_fields @> sqlc.arg(custom_fields)::jsonb
+ORDER BY
+  CASE WHEN sqlc.arg(sort_column)::text = 'title' AND sqlc.arg(sort_direction)::text = 'ASC' THEN title END ASC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'title' AND sqlc.arg(sort_direction)::text = 'DESC' THEN title END DESC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'value' AND sqlc.arg(sort_direction)::text = 'ASC' THEN value END ASC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'value' AND sqlc.arg(sort_direction)::text = 'DESC' THEN value END DESC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'created_at' AND sqlc.arg(sort_direction)::text = 'ASC' THEN created_at END ASC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'custom_field' AND sqlc.arg(sort_numeric)::boolean AND sqlc.arg(sort_direction)::text = 'ASC' AND jsonb_typeof(custom_fields->sqlc.arg(sort_key)::text) = 'number' THEN (custom_fields->>sqlc.arg(sort_key)::text)::numeric END ASC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'custom_field' AND sqlc.arg(sort_numeric)::boolean AND sqlc.arg(sort_direction)::text = 'DESC' AND jsonb_typeof(custom_fields->sqlc.arg(sort_key)::text) = 'number' THEN (custom_fields->>sqlc.arg(sort_key)::text)::numeric END DESC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'custom_field' AND NOT sqlc.arg(sort_numeric)::boolean AND sqlc.arg(sort_direction)::text = 'ASC' THEN custom_fields->>sqlc.arg(sort_key)::text END ASC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'custom_field' AND NOT sqlc.arg(sort_numeric)::boolean AND sqlc.arg(sort_direction)::text = 'DESC' THEN custom_fields->>sqlc.arg(sort_key)::text END DESC,
+  created_at DESC,
+  id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
 
 -- name: GetTradeLeadByID :one
 SELECT 
@@ -26,7 +42,10 @@ SELECT
   value, 
   version,
   created_at, 
-  updated_at
+  updated_at,
+  custom_fields,
+  currency,
+  assigned_to
 FROM trade_leads
 WHERE id = $1;
 
@@ -41,11 +60,27 @@ SELECT
   value, 
   version,
   created_at, 
-  updated_at
+  updated_at,
+  custom_fields,
+  currency,
+  assigned_to
 FROM trade_leads
-WHERE ($1 = '' OR to_tsvector('simple', title) @@ plainto_tsquery('simple', $1))
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE (sqlc.arg(tenant_id)::bigint = 0 OR tenant_id = sqlc.arg(tenant_id)::bigint)
+  AND (sqlc.arg(name)::text = '' OR to_tsvector('simple', title) @@ plainto_tsquery('simple', sqlc.arg(name)::text))
+  AND custom_fields @> sqlc.arg(custom_fields)::jsonb
+ORDER BY
+  CASE WHEN sqlc.arg(sort_column)::text = 'title' AND sqlc.arg(sort_direction)::text = 'ASC' THEN title END ASC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'title' AND sqlc.arg(sort_direction)::text = 'DESC' THEN title END DESC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'value' AND sqlc.arg(sort_direction)::text = 'ASC' THEN value END ASC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'value' AND sqlc.arg(sort_direction)::text = 'DESC' THEN value END DESC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'created_at' AND sqlc.arg(sort_direction)::text = 'ASC' THEN created_at END ASC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'custom_field' AND sqlc.arg(sort_numeric)::boolean AND sqlc.arg(sort_direction)::text = 'ASC' AND jsonb_typeof(custom_fields->sqlc.arg(sort_key)::text) = 'number' THEN (custom_fields->>sqlc.arg(sort_key)::text)::numeric END ASC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'custom_field' AND sqlc.arg(sort_numeric)::boolean AND sqlc.arg(sort_direction)::text = 'DESC' AND jsonb_typeof(custom_fields->sqlc.arg(sort_key)::text) = 'number' THEN (custom_fields->>sqlc.arg(sort_key)::text)::numeric END DESC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'custom_field' AND NOT sqlc.arg(sort_numeric)::boolean AND sqlc.arg(sort_direction)::text = 'ASC' THEN custom_fields->>sqlc.arg(sort_key)::text END ASC,
+  CASE WHEN sqlc.arg(sort_column)::text = 'custom_field' AND NOT sqlc.arg(sort_numeric)::boolean AND sqlc.arg(sort_direction)::text = 'DESC' THEN custom_fields->>sqlc.arg(sort_key)::text END DESC,
+  created_at DESC,
+  id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
 
 
 -- name: CreateTradeLead :one
@@ -53,9 +88,12 @@ INSERT INTO trade_leads (
   tenant_id,
   title,
   description,
-  value
+  value,
+  custom_fields,
+  currency,
+  assigned_to
 ) VALUES (
-  $1, $2, $3, $4
+  $1, $2, $3, $4, $5, $6, $7
 )
 RETURNING id,tenant_id, status, version, created_at, updated_at;
 
@@ -66,10 +104,122 @@ SET
 WHERE id = $1 AND version = $2
 RETURNING version, status, updated_at;
 
+-- name: AssignTradeLead :one
+UPDATE trade_leads
+SET
+  assigned_to = $3
+WHERE id = $1 AND version = $2
+RETURNING version, assigned_to, updated_at;
+
+-- name: GetNextTradeLeadAssignee :one
+SELECT u.id
+FROM users u
+WHERE u.tenant_id = $1 AND u.activated
+ORDER BY
+  u.id <= COALESCE((
+    SELECT l.assigned_to
+    FROM trade_leads l
+    WHERE l.tenant_id = $1 AND l.assigned_to IS NOT NULL
+    ORDER BY l.created_at DESC, l.id DESC
+    LIMIT 1
+  ), 0),
+  u.id
+LIMIT 1;
+
 
--- name: AdminGetTRadeLeadStats :one
+-- name: AdminGetTRadeLeadStats :many
 SELECT 
+  currency,
   COUNT(*)::text AS total_leads,
   COUNT(*) FILTER (WHERE status = 'verified')::text AS verified_leads,
   COALESCE(SUM(value) FILTER (WHERE status = 'verified'), 0)::text AS total_verified_value
-FROM trade_leads;
+FROM trade_leads
+GROUP BY currency
+ORDER BY currency;
+
+-- name: GetTradeLeadStatsByTenant :many
+SELECT 
+  tenant_id,
+  currency,
+  COUNT(*)::text AS total_leads,
+  COUNT(*) FILTER (WHERE status = 'verified')::text AS verified_leads,
+  COALESCE(SUM(value) FILTER (WHERE status = 'verified'), 0)::text AS total_verified_value
+FROM trade_leads
+WHERE tenant_id = ANY(sqlc.arg(tenant_ids)::bigint[])
+GROUP BY tenant_id, currency
+ORDER BY tenant_id, currency;
+
+-- name: GetAllTradeLeadsForExport :many
+SELECT 
+  id, 
+  tenant_id, 
+  title, 
+  description, 
+  status, 
+  value, 
+  version,
+  created_at, 
+  updated_at,
+  custom_fields,
+  currency,
+  assigned_to
+FROM trade_leads
+WHERE tenant_id = $1
+ORDER BY id;
+
+-- name: GetTradeLeadHistoryByTenantID :many
+SELECT 
+  id, 
+  trade_lead_id, 
+  tenant_id, 
+  status, 
+  value, 
+  version, 
+  changed_at
+FROM trade_lead_history
+WHERE tenant_id = $1
+ORDER BY trade_lead_id, id;
+
+-- name: GetTradeLeadStatusCounts :many
+SELECT
+  tenant_id,
+  status,
+  currency,
+  COUNT(*)::bigint AS leads,
+  COALESCE(SUM(value), 0)::text AS total_value
+FROM trade_leads
+WHERE (cardinality(sqlc.arg(tenant_ids)::bigint[]) = 0 OR tenant_id = ANY(sqlc.arg(tenant_ids)::bigint[]))
+  AND created_at >= sqlc.arg(created_from)
+  AND created_at < sqlc.arg(created_to)
+GROUP BY tenant_id, status, currency
+ORDER BY tenant_id, status, currency;
+
+-- name: GetTradeLeadVerificationTimes :many
+SELECT
+  l.tenant_id,
+  COUNT(*)::bigint AS verified_leads,
+  COALESCE(SUM(EXTRACT(EPOCH FROM (v.verified_at - l.created_at))), 0)::float8 AS seconds_to_verify
+FROM trade_leads l
+JOIN LATERAL (
+  SELECT MIN(h.changed_at) AS verified_at
+  FROM trade_lead_history h
+  WHERE h.trade_lead_id = l.id AND h.status = 'verified'
+) v ON v.verified_at IS NOT NULL
+WHERE (cardinality(sqlc.arg(tenant_ids)::bigint[]) = 0 OR l.tenant_id = ANY(sqlc.arg(tenant_ids)::bigint[]))
+  AND l.created_at >= sqlc.arg(created_from)
+  AND l.created_at < sqlc.arg(created_to)
+GROUP BY l.tenant_id
+ORDER BY l.tenant_id;
+
+-- name: GetTradeLeadTimeSeries :many
+SELECT
+  date_trunc(sqlc.arg(bucket)::text, created_at, 'UTC')::timestamptz AS bucket_start,
+  currency,
+  COUNT(*)::bigint AS leads,
+  COALESCE(SUM(value), 0)::text AS total_value
+FROM trade_leads
+WHERE (cardinality(sqlc.arg(tenant_ids)::bigint[]) = 0 OR tenant_id = ANY(sqlc.arg(tenant_ids)::bigint[]))
+  AND created_at >= sqlc.arg(created_from)
+  AND created_at < sqlc.arg(created_to)
+GROUP BY bucket_start, currency
+ORDER BY bucket_start, currency;
@@ -1,10 +1,10 @@
 -- name: CreateUser :one
-INSERT INTO users (tenant_id, name, email, password_hash, activated)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO users (tenant_id, name, email, password_hash, activated, locale)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING id, created_at, version;
 
 -- name: GetUserByEmail :one
-SELECT id, tenant_id, name, email, password_hash, activated, version, created_at, updated_at
+SELECT id, tenant_id, name, email, password_hash, activated, version, created_at, updated_at, locale
 FROM users WHERE email = $1;
 
 -- name: UpdateUser :one
@@ -13,6 +13,13 @@ SET
     name = $1, 
     email = $2, 
     password_hash = $3, 
-    activated = $4
-WHERE id = $5 AND version = $6
-RETURNING version, updated_at;
\ No newline at end of file
+    activated = $4,
+    locale = $5
+WHERE id = $6 AND version = $7
+RETURNING version, updated_at;
+
+-- name: GetAllUsersByTenantID :many
+SELECT id, tenant_id, name, email, activated, version, created_at, updated_at, locale
+FROM users
+WHERE tenant_id = $1
+ORDER BY id;
@@ -0,0 +1,153 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (tenant_id, url, description, secret, event_types)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, enabled, consecutive_failures, version, created_at, updated_at;
+
+-- name: GetWebhookEndpointByID :one
+SELECT 
+    id, 
+    tenant_id, 
+    url, 
+    description, 
+    secret, 
+    event_types, 
+    enabled, 
+    disabled_reason, 
+    consecutive_failures, 
+    version, 
+    created_at, 
+    updated_at
+FROM webhook_endpoints
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: GetWebhookEndpointsByTenantID :many
+SELECT 
+    id, 
+    tenant_id, 
+    url, 
+    description, 
+    secret, 
+    event_types, 
+    enabled, 
+    disabled_reason, 
+    consecutive_failures, 
+    version, 
+    created_at, 
+    updated_at
+FROM webhook_endpoints
+WHERE tenant_id = $1
+ORDER BY id;
+
+-- name: GetSubscribedWebhookEndpoints :many
+SELECT 
+    id, 
+    tenant_id, 
+    url, 
+    description, 
+    secret, 
+    event_types, 
+    enabled, 
+    disabled_reason, 
+    consecutive_failures, 
+    version, 
+    created_at, 
+    updated_at
+FROM webhook_endpoints
+WHERE tenant_id = sqlc.arg(tenant_id) AND enabled AND sqlc.arg(event_type)::text = ANY(event_types)
+ORDER BY id;
+
+-- name: UpdateWebhookEndpoint :one
+UPDATE webhook_endpoints
+SET 
+    url = sqlc.arg(url),
+    description = sqlc.arg(description),
+    event_types = sqlc.arg(event_types),
+    enabled = sqlc.arg(enabled)::boolean,
+    disabled_reason = CASE WHEN sqlc.arg(enabled)::boolean THEN '' ELSE disabled_reason END,
+    consecutive_failures = CASE WHEN sqlc.arg(enabled)::boolean AND NOT enabled THEN 0 ELSE consecutive_failures END,
+    version = version + 1
+WHERE id = sqlc.arg(id) AND version = sqlc.arg(version)
+RETURNING disabled_reason, consecutive_failures, version, updated_at;
+
+-- name: DeleteWebhookEndpoint :execrows
+DELETE FROM webhook_endpoints
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: RecordWebhookEndpointResult :one
+UPDATE webhook_endpoints
+SET 
+    consecutive_failures = CASE WHEN sqlc.arg(succeeded)::boolean THEN 0 ELSE consecutive_failures + 1 END,
+    enabled = enabled AND (sqlc.arg(succeeded)::boolean OR sqlc.arg(disable_after)::int = 0 OR consecutive_failures + 1 < sqlc.arg(disable_after)::int),
+    disabled_reason = CASE
+        WHEN enabled AND NOT sqlc.arg(succeeded)::boolean AND sqlc.arg(disable_after)::int > 0 AND consecutive_failures + 1 >= sqlc.arg(disable_after)::int
+        THEN sqlc.arg(disabled_reason)::text
+        ELSE disabled_reason
+    END,
+    version = CASE
+        WHEN enabled AND NOT sqlc.arg(succeeded)::boolean AND sqlc.arg(disable_after)::int > 0 AND consecutive_failures + 1 >= sqlc.arg(disable_after)::int
+        THEN version + 1
+        ELSE version
+    END
+WHERE id = sqlc.arg(id)
+RETURNING enabled, consecutive_failures;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (endpoint_id, tenant_id, event_id, event_type, payload)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, status, attempts, created_at, updated_at;
+
+-- name: GetWebhookDeliveryByID :one
+SELECT 
+    id, 
+    endpoint_id, 
+    tenant_id, 
+    event_id, 
+    event_type, 
+    payload, 
+    status, 
+    attempts, 
+    response_status, 
+    response_body, 
+    last_error, 
+    duration_ms, 
+    delivered_at, 
+    created_at, 
+    updated_at
+FROM webhook_deliveries
+WHERE id = $1;
+
+-- name: GetWebhookDeliveriesByEndpointID :many
+SELECT 
+    COUNT(*) OVER() AS total_count,
+    id, 
+    endpoint_id, 
+    tenant_id, 
+    event_id, 
+    event_type, 
+    payload, 
+    status, 
+    attempts, 
+    response_status, 
+    response_body, 
+    last_error, 
+    duration_ms, 
+    delivered_at, 
+    created_at, 
+    updated_at
+FROM webhook_deliveries
+WHERE endpoint_id = sqlc.arg(endpoint_id)
+  AND (sqlc.arg(status)::text = '' OR status = sqlc.arg(status)::text)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: RecordWebhookDeliveryAttempt :execrows
+UPDATE webhook_deliveries
+SET 
+    status = $2,
+    attempts = attempts + 1,
+    response_status = $3,
+    response_body = $4,
+    last_error = $5,
+    duration_ms = $6,
+    delivered_at = $7
+WHERE id = $1;
@@ -0,0 +1,45 @@
+-- +goose Up
+CREATE TABLE trade_lead_history (
+    id BIGSERIAL PRIMARY KEY,
+    trade_lead_id BIGINT NOT NULL REFERENCES trade_leads(id) ON DELETE CASCADE,
+    tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    value NUMERIC(18, 2) NOT NULL,
+    version INT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_trade_lead_history_trade_lead_id ON trade_lead_history(trade_lead_id);
+CREATE INDEX idx_trade_lead_history_tenant_id ON trade_lead_history(tenant_id);
+
+-- +goose StatementBegin
+-- Function to record every insert and status/value change of a trade lead
+CREATE OR REPLACE FUNCTION record_trade_lead_history()
+RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.status IS DISTINCT FROM OLD.status OR NEW.value IS DISTINCT FROM OLD.value THEN
+        INSERT INTO trade_lead_history (trade_lead_id, tenant_id, status, value, version)
+        VALUES (NEW.id, NEW.tenant_id, NEW.status, NEW.value, NEW.version);
+    END IF;
+    RETURN NEW;
+END;
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER record_trade_lead_history
+AFTER INSERT OR UPDATE ON trade_leads
+FOR EACH ROW
+EXECUTE FUNCTION record_trade_lead_history();
+
+-- Backfill the current state of existing leads
+INSERT INTO trade_lead_history (trade_lead_id, tenant_id, status, value, version, changed_at)
+SELECT id, tenant_id, status, value, version, updated_at FROM trade_leads;
+
+-- +goose Down
+DROP TRIGGER IF EXISTS record_trade_lead_history ON trade_leads;
+DROP FUNCTION IF EXISTS record_trade_lead_history();
+DROP INDEX IF EXISTS idx_trade_lead_history_tenant_id;
+DROP INDEX IF EXISTS idx_trade_lead_history_trade_lead_id;
+DROP TABLE IF EXISTS trade_lead_history;
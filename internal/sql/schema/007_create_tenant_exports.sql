@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE tenant_exports (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    requested_by BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('pending', 'running', 'completed', 'failed')) DEFAULT 'pending',
+    file_key TEXT NOT NULL DEFAULT '',
+    file_size BIGINT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    completed_at TIMESTAMPTZ,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose StatementBegin
+-- Function to automatically update updated_at inherited from tenants
+CREATE TRIGGER update_tenant_exports_updated_at
+BEFORE UPDATE ON tenant_exports
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+CREATE INDEX idx_tenant_exports_tenant_id ON tenant_exports(tenant_id);
+
+-- Tenant owners hold this permission, scoped to their own tenant.
+INSERT INTO permissions (code)
+VALUES
+('tenant:admin');
+
+-- +goose Down
+DELETE FROM permissions WHERE code = 'tenant:admin';
+DROP INDEX IF EXISTS idx_tenant_exports_tenant_id;
+DROP TRIGGER IF EXISTS update_tenant_exports_updated_at ON tenant_exports;
+DROP TABLE IF EXISTS tenant_exports;
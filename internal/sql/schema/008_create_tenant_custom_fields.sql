@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE tenant_custom_fields (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    field_key TEXT NOT NULL,
+    label TEXT NOT NULL,
+    field_type TEXT NOT NULL CHECK (field_type IN ('text', 'number', 'date', 'enum', 'boolean')),
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    options TEXT[] NOT NULL DEFAULT '{}',
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT tenant_custom_fields_tenant_key UNIQUE (tenant_id, field_key)
+);
+
+-- +goose StatementBegin
+-- Function to automatically update updated_at inherited from tenants
+CREATE TRIGGER update_tenant_custom_fields_updated_at
+BEFORE UPDATE ON tenant_custom_fields
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- Custom field values are stored alongside the lead
+ALTER TABLE trade_leads ADD COLUMN custom_fields JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX idx_trade_leads_custom_fields ON trade_leads USING GIN (custom_fields);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_trade_leads_custom_fields;
+ALTER TABLE trade_leads DROP COLUMN IF EXISTS custom_fields;
+DROP TRIGGER IF EXISTS update_tenant_custom_fields_updated_at ON tenant_custom_fields;
+DROP TABLE IF EXISTS tenant_custom_fields;
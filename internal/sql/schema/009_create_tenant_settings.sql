@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE tenant_settings (
+    tenant_id BIGINT PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    display_name TEXT NOT NULL DEFAULT '',
+    logo_url TEXT NOT NULL DEFAULT '',
+    primary_color TEXT NOT NULL DEFAULT '',
+    reply_to TEXT NOT NULL DEFAULT '',
+    default_currency CHAR(3) NOT NULL DEFAULT 'USD',
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    locale TEXT NOT NULL DEFAULT 'en',
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose StatementBegin
+-- Function to automatically update updated_at inherited from tenants
+CREATE TRIGGER update_tenant_settings_updated_at
+BEFORE UPDATE ON tenant_settings
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- Existing tenants keep their name as the display name
+INSERT INTO tenant_settings (tenant_id, display_name)
+SELECT id, name FROM tenants;
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_tenant_settings_updated_at ON tenant_settings;
+DROP TABLE IF EXISTS tenant_settings;
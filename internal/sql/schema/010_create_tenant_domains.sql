@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE tenant_domains (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    domain TEXT NOT NULL,
+    verification_token TEXT NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT false,
+    verified_at TIMESTAMPTZ,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT tenant_domains_tenant_domain_key UNIQUE (tenant_id, domain)
+);
+
+-- +goose StatementBegin
+-- Function to automatically update updated_at inherited from tenants
+CREATE TRIGGER update_tenant_domains_updated_at
+BEFORE UPDATE ON tenant_domains
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+-- Several tenants may claim a domain, but only one of them can verify it.
+CREATE UNIQUE INDEX tenant_domains_verified_domain_key ON tenant_domains(domain) WHERE verified;
+CREATE INDEX idx_tenant_domains_tenant_id ON tenant_domains(tenant_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_tenant_domains_tenant_id;
+DROP INDEX IF EXISTS tenant_domains_verified_domain_key;
+DROP TRIGGER IF EXISTS update_tenant_domains_updated_at ON tenant_domains;
+DROP TABLE IF EXISTS tenant_domains;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Tenants can belong to a parent organization (trading group). Deleting a parent turns
+-- its sub-tenants into top level tenants.
+ALTER TABLE tenants
+ADD COLUMN parent_id BIGINT REFERENCES tenants(id) ON DELETE SET NULL,
+ADD CONSTRAINT tenants_parent_not_self CHECK (parent_id <> id);
+
+CREATE INDEX idx_tenants_parent_id ON tenants (parent_id);
+
+-- Group users read leads and stats across their tenant and all of its sub-tenants.
+INSERT INTO permissions (code)
+VALUES
+('tenant:group');
+
+-- +goose Down
+DELETE FROM permissions WHERE code = 'tenant:group';
+DROP INDEX IF EXISTS idx_tenants_parent_id;
+ALTER TABLE tenants
+DROP CONSTRAINT IF EXISTS tenants_parent_not_self,
+DROP COLUMN IF EXISTS parent_id;
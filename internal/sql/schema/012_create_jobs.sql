@@ -0,0 +1,49 @@
+-- +goose Up
+-- Durable background jobs. Workers claim due jobs with FOR UPDATE SKIP LOCKED, failed
+-- jobs are retried with a backoff until max_attempts and then kept as 'dead'.
+CREATE TABLE jobs (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+    status TEXT NOT NULL CHECK (status IN ('pending', 'running', 'completed', 'dead')) DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5 CHECK (max_attempts > 0),
+    run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT NOT NULL DEFAULT '',
+    locked_by TEXT NOT NULL DEFAULT '',
+    locked_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose StatementBegin
+-- Like update_updated_at_column(), for tables without a version column
+CREATE OR REPLACE FUNCTION update_updated_at_only_column()
+RETURNS TRIGGER
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER update_jobs_updated_at
+BEFORE UPDATE ON jobs
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_only_column();
+-- +goose StatementEnd
+
+-- workers only ever look for due pending jobs and stale running ones
+CREATE INDEX idx_jobs_pending_run_at ON jobs (run_at) WHERE status = 'pending';
+CREATE INDEX idx_jobs_running_locked_at ON jobs (locked_at) WHERE status = 'running';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_jobs_running_locked_at;
+DROP INDEX IF EXISTS idx_jobs_pending_run_at;
+DROP TRIGGER IF EXISTS update_jobs_updated_at ON jobs;
+DROP TABLE IF EXISTS jobs;
+DROP FUNCTION IF EXISTS update_updated_at_only_column();
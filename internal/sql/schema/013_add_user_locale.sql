@@ -0,0 +1,9 @@
+-- +goose Up
+-- The language a user's emails are written in, e.g. "fr" or "fr-FR". Empty falls back to
+-- the locale of the user's tenant.
+ALTER TABLE users
+ADD COLUMN locale TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN IF EXISTS locale;
@@ -0,0 +1,36 @@
+-- +goose Up
+-- Every email the API sends, written in the same transaction as the change that triggers
+-- it. The email.send job records each delivery attempt here, failed emails can be re-sent
+-- by an admin.
+CREATE TABLE email_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    recipient TEXT NOT NULL,
+    template TEXT NOT NULL,
+    locale TEXT NOT NULL DEFAULT '',
+    data JSONB NOT NULL DEFAULT '{}'::jsonb,
+    subject TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL CHECK (status IN ('queued', 'sent', 'failed')) DEFAULT 'queued',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    provider_message_id TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER update_email_outbox_updated_at
+BEFORE UPDATE ON email_outbox
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_only_column();
+-- +goose StatementEnd
+
+CREATE INDEX idx_email_outbox_tenant_id ON email_outbox (tenant_id, created_at DESC);
+CREATE INDEX idx_email_outbox_status ON email_outbox (status, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_email_outbox_status;
+DROP INDEX IF EXISTS idx_email_outbox_tenant_id;
+DROP TRIGGER IF EXISTS update_email_outbox_updated_at ON email_outbox;
+DROP TABLE IF EXISTS email_outbox;
@@ -0,0 +1,64 @@
+-- +goose Up
+-- Endpoints a tenant registers to be notified of events, e.g. trade_lead.created. Every
+-- payload is signed with the endpoint's secret. An endpoint is disabled once enough of
+-- its deliveries in a row have failed for good.
+CREATE TABLE webhook_endpoints (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    secret TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    disabled_reason TEXT NOT NULL DEFAULT '',
+    consecutive_failures INT NOT NULL DEFAULT 0,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER update_webhook_endpoints_updated_at
+BEFORE UPDATE ON webhook_endpoints
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+CREATE INDEX idx_webhook_endpoints_tenant_id ON webhook_endpoints (tenant_id);
+
+-- The log of every event sent to an endpoint, written in the same transaction as the
+-- change the event reports. The webhook.deliver job records each attempt here.
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    endpoint_id BIGINT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('pending', 'succeeded', 'failed')) DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    response_status INT NOT NULL DEFAULT 0,
+    response_body TEXT NOT NULL DEFAULT '',
+    last_error TEXT NOT NULL DEFAULT '',
+    duration_ms INT NOT NULL DEFAULT 0,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER update_webhook_deliveries_updated_at
+BEFORE UPDATE ON webhook_deliveries
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_only_column();
+-- +goose StatementEnd
+
+CREATE INDEX idx_webhook_deliveries_endpoint_id ON webhook_deliveries (endpoint_id, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_webhook_deliveries_endpoint_id;
+DROP TRIGGER IF EXISTS update_webhook_deliveries_updated_at ON webhook_deliveries;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP INDEX IF EXISTS idx_webhook_endpoints_tenant_id;
+DROP TRIGGER IF EXISTS update_webhook_endpoints_updated_at ON webhook_endpoints;
+DROP TABLE IF EXISTS webhook_endpoints;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- IDs of the live trade lead events sent over NOTIFY. They come from one sequence, so every
+-- API instance hands out the same IDs and a stream can resume on any of them.
+CREATE SEQUENCE trade_lead_event_id_seq;
+
+-- +goose Down
+DROP SEQUENCE IF EXISTS trade_lead_event_id_seq;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- The ISO 4217 currency of a lead's value. Existing leads take the default currency of
+-- their tenant.
+ALTER TABLE trade_leads
+ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+-- The backfill is not an edit of the leads, so it must not bump their version or
+-- updated_at. Status and value are unchanged, so no history is recorded either.
+ALTER TABLE trade_leads DISABLE TRIGGER update_trade_lead_updated_at;
+
+UPDATE trade_leads l
+SET currency = s.default_currency
+FROM tenant_settings s
+WHERE s.tenant_id = l.tenant_id;
+
+ALTER TABLE trade_leads ENABLE TRIGGER update_trade_lead_updated_at;
+
+-- The currency an export reports lead values in, next to their own. Empty when no
+-- conversion was requested.
+ALTER TABLE tenant_exports
+ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE tenant_exports
+DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE trade_leads
+DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- Dated exchange rates: on rate_date, 1 unit of base_currency was worth rate units of
+-- quote_currency. Conversions use the latest rate on or before the date asked for.
+CREATE TABLE exchange_rates (
+    id BIGSERIAL PRIMARY KEY,
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate NUMERIC(24, 10) NOT NULL CHECK (rate > 0),
+    rate_date DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (base_currency, quote_currency, rate_date),
+    CHECK (base_currency <> quote_currency)
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER update_exchange_rates_updated_at
+BEFORE UPDATE ON exchange_rates
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_only_column();
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_exchange_rates_updated_at ON exchange_rates;
+DROP TABLE IF EXISTS exchange_rates;
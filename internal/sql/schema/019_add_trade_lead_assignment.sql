@@ -0,0 +1,30 @@
+-- +goose Up
+-- A lead can only be assigned to a user of its own tenant, which the foreign key enforces
+-- by referencing the user together with the tenant. Deleting the user leaves the lead
+-- unassigned.
+ALTER TABLE users
+ADD CONSTRAINT users_id_tenant_id_key UNIQUE (id, tenant_id);
+
+ALTER TABLE trade_leads
+ADD COLUMN assigned_to BIGINT,
+ADD CONSTRAINT trade_leads_assigned_to_fkey FOREIGN KEY (assigned_to, tenant_id)
+    REFERENCES users (id, tenant_id) ON DELETE SET NULL (assigned_to);
+
+CREATE INDEX idx_trade_leads_assigned_to ON trade_leads(assigned_to);
+
+-- Whether new leads are assigned to the tenant's users in turn.
+ALTER TABLE tenant_settings
+ADD COLUMN auto_assign_leads BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE tenant_settings
+DROP COLUMN IF EXISTS auto_assign_leads;
+
+DROP INDEX IF EXISTS idx_trade_leads_assigned_to;
+
+ALTER TABLE trade_leads
+DROP CONSTRAINT IF EXISTS trade_leads_assigned_to_fkey,
+DROP COLUMN IF EXISTS assigned_to;
+
+ALTER TABLE users
+DROP CONSTRAINT IF EXISTS users_id_tenant_id_key;
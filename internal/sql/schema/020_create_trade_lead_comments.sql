@@ -0,0 +1,30 @@
+-- +goose Up
+-- Comments on trade leads. Internal notes are only shown to admins, e.g. the remarks of
+-- a lead's verification. Comments of a deleted user are kept without their author.
+CREATE TABLE trade_lead_comments (
+    id BIGSERIAL PRIMARY KEY,
+    trade_lead_id BIGINT NOT NULL REFERENCES trade_leads(id) ON DELETE CASCADE,
+    tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    author_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    internal BOOLEAN NOT NULL DEFAULT false,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER update_trade_lead_comments_updated_at
+BEFORE UPDATE ON trade_lead_comments
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
+
+CREATE INDEX idx_trade_lead_comments_trade_lead_id ON trade_lead_comments (trade_lead_id, created_at);
+CREATE INDEX idx_trade_lead_comments_tenant_id ON trade_lead_comments (tenant_id);
+
+-- +goose Down
+DROP TRIGGER IF EXISTS update_trade_lead_comments_updated_at ON trade_lead_comments;
+DROP INDEX IF EXISTS idx_trade_lead_comments_tenant_id;
+DROP INDEX IF EXISTS idx_trade_lead_comments_trade_lead_id;
+DROP TABLE IF EXISTS trade_lead_comments;
@@ -0,0 +1,10 @@
+// Package schema embeds the goose migration files so the binary can apply them without
+// the source tree. The .sql files stay the primary source for sqlc and the scripts.
+package schema
+
+import "embed"
+
+// FS holds the migration files, named NNN_description.sql.
+//
+//go:embed *.sql
+var FS embed.FS
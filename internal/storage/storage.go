@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrObjectNotFound = errors.New("storage object not found")
+	ErrInvalidKey     = errors.New("invalid storage key")
+)
+
+// Storage is the interface every storage backend has to satisfy. Keys are
+// slash separated paths such as "exports/1/12.zip".
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalStorage is a Storage backend that keeps objects on the local disk
+// underneath a single root directory.
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage() creates the root directory if it doesn't exist and returns
+// a LocalStorage rooted at it.
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	err := os.MkdirAll(root, 0o750)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+// Put() writes the contents of r to the object at key, returning the number of bytes
+// written. The data is first written to a temporary file and then renamed so readers
+// never observe a partially written object.
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	fullPath, err := s.resolve(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o750); err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	// make sure we never leave the temporary file behind
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, contextReader{ctx: ctx, r: r})
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+// Open() returns a reader for the object at key. The caller must close it.
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	fullPath, err := s.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(fullPath) // #nosec G304 -- the path is resolved beneath the storage root
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// Delete() removes the object at key. Deleting a missing object is not an error.
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	fullPath, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(fullPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// resolve() maps a key to a path on disk, refusing anything that would escape the root.
+func (s *LocalStorage) resolve(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(strings.TrimPrefix(cleaned, "/"))), nil
+}
+
+// contextReader stops a copy as soon as the context is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	n, err := s.Put(ctx, "exports/1/1.zip", strings.NewReader("archive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len("archive")) {
+		t.Errorf("expected %d bytes written, got %d", len("archive"), n)
+	}
+	rc, err := s.Open(ctx, "exports/1/1.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(content) != "archive" {
+		t.Errorf("unexpected content %q", content)
+	}
+
+	if err := s.Delete(ctx, "exports/1/1.zip"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(ctx, "exports/1/1.zip"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("expected ErrObjectNotFound after delete, got %v", err)
+	}
+	if _, err := s.Put(ctx, "../escape", strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for path traversal, got %v", err)
+	}
+}
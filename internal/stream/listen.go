@@ -0,0 +1,64 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	// pingInterval is how long the listener waits for a notification before checking that
+	// its connection is still alive.
+	pingInterval = 90 * time.Second
+)
+
+// Listen() listens on data.TradeLeadEventsChannel with its own connection to the database
+// at dsn and publishes every event to the broker until ctx is done. The connection is
+// re-established when it drops, as notifications sent in the meantime are lost the broker
+// is reset.
+func Listen(ctx context.Context, dsn string, broker *Broker, logger *zap.Logger) error {
+	listener := pq.NewListener(dsn, minReconnectInterval, maxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			logger.Warn("lost the trade lead event listener connection", zap.Error(err))
+		case pq.ListenerEventReconnected:
+			logger.Info("reconnected the trade lead event listener")
+		case pq.ListenerEventConnectionAttemptFailed:
+			logger.Warn("failed to connect the trade lead event listener", zap.Error(err))
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(data.TradeLeadEventsChannel); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// a nil notification follows a reconnect
+			if notification == nil {
+				broker.Reset()
+				continue
+			}
+			var event data.TradeLeadEvent
+			if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+				logger.Error("failed to decode a trade lead event", zap.Error(err))
+				continue
+			}
+			broker.Publish(&event)
+		case <-time.After(pingInterval):
+			go func() {
+				if err := listener.Ping(); err != nil {
+					logger.Warn("the trade lead event listener connection is down", zap.Error(err))
+				}
+			}()
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package stream fans the live trade lead events out to the Server-Sent Events streams of
+// their tenant. Events reach the Broker of every API instance through Postgres
+// LISTEN/NOTIFY, see Listen(). The Broker keeps the last events of every tenant, so a
+// client reconnecting with the ID of the last event it saw misses nothing.
+package stream
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+)
+
+const (
+	// DefaultBufferSize is how many recent events are kept per tenant for resuming streams.
+	DefaultBufferSize = 100
+	// DefaultMaxStreamsPerTenant caps the streams a tenant can have open on one instance.
+	DefaultMaxStreamsPerTenant = 20
+	// subscriberBuffer is how many events a stream can fall behind before it is dropped.
+	subscriberBuffer = 32
+)
+
+var (
+	ErrTooManyStreams = errors.New("too many open streams for this tenant")
+	ErrBrokerClosed   = errors.New("the event broker is shutting down")
+)
+
+// Config configures a Broker. Zero values use the defaults above.
+type Config struct {
+	BufferSize          int
+	MaxStreamsPerTenant int
+}
+
+// Broker hands the published events to the subscriptions of their tenant.
+type Broker struct {
+	config Config
+
+	mu            sync.Mutex
+	closed        bool
+	buffers       map[int64][]*data.TradeLeadEvent
+	subscriptions map[int64]map[*Subscription]struct{}
+}
+
+// NewBroker() returns an empty Broker.
+func NewBroker(config Config) *Broker {
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultBufferSize
+	}
+	if config.MaxStreamsPerTenant <= 0 {
+		config.MaxStreamsPerTenant = DefaultMaxStreamsPerTenant
+	}
+	return &Broker{
+		config:        config,
+		buffers:       map[int64][]*data.TradeLeadEvent{},
+		subscriptions: map[int64]map[*Subscription]struct{}{},
+	}
+}
+
+// Subscription receives the events of one tenant.
+type Subscription struct {
+	// Events delivers the events published after Subscribe(). It is closed when the
+	// subscription falls behind, the broker loses events or shuts down, and the client
+	// should reconnect.
+	Events <-chan *data.TradeLeadEvent
+	// Replay holds the buffered events published after the last event ID passed to
+	// Subscribe(), oldest first.
+	Replay []*data.TradeLeadEvent
+	// Resumed is false when the last event ID is no longer buffered, the client may have
+	// missed events and has to reload its data.
+	Resumed bool
+
+	broker   *Broker
+	tenantID int64
+	events   chan *data.TradeLeadEvent
+	closed   bool
+}
+
+// Subscribe() opens a subscription to the events of a tenant. A lastEventID of 0 starts with
+// the next event. The subscription has to be closed with Close().
+func (b *Broker) Subscribe(tenantID, lastEventID int64) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBrokerClosed
+	}
+	if len(b.subscriptions[tenantID]) >= b.config.MaxStreamsPerTenant {
+		return nil, ErrTooManyStreams
+	}
+	events := make(chan *data.TradeLeadEvent, subscriberBuffer)
+	sub := &Subscription{Events: events, Resumed: true, broker: b, tenantID: tenantID, events: events}
+	if lastEventID != 0 {
+		// events arrive in commit order, which can differ from the order of their IDs, so
+		// the replay starts after the position of the last event rather than its ID
+		buffer := b.buffers[tenantID]
+		i := slices.IndexFunc(buffer, func(event *data.TradeLeadEvent) bool { return event.ID == lastEventID })
+		if i >= 0 {
+			sub.Replay = slices.Clone(buffer[i+1:])
+		} else {
+			sub.Resumed = false
+		}
+	}
+	if b.subscriptions[tenantID] == nil {
+		b.subscriptions[tenantID] = map[*Subscription]struct{}{}
+	}
+	b.subscriptions[tenantID][sub] = struct{}{}
+	return sub, nil
+}
+
+// Close() ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// Publish() buffers the event and hands it to the subscriptions of its tenant. A
+// subscription whose channel is full is closed rather than blocking the others, its client
+// reconnects and catches up from the buffer.
+func (b *Broker) Publish(event *data.TradeLeadEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	buffer := append(b.buffers[event.TenantID], event)
+	if len(buffer) > b.config.BufferSize {
+		buffer = slices.Delete(buffer, 0, len(buffer)-b.config.BufferSize)
+	}
+	b.buffers[event.TenantID] = buffer
+	for sub := range b.subscriptions[event.TenantID] {
+		select {
+		case sub.events <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Reset() drops the buffered events and closes every subscription. It is called when
+// events may have been lost, e.g. while the connection to Postgres was down, so clients
+// reconnect and reload their data instead of silently missing changes.
+func (b *Broker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clear(b.buffers)
+	b.removeAll()
+}
+
+// Close() closes every subscription and refuses new ones, it is called when the server
+// shuts down so open streams don't hold up the shutdown.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.removeAll()
+}
+
+// Streams() returns the number of open subscriptions of a tenant.
+func (b *Broker) Streams(tenantID int64) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscriptions[tenantID])
+}
+
+// remove() closes a subscription, the caller holds the lock.
+func (b *Broker) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.events)
+	delete(b.subscriptions[sub.tenantID], sub)
+	if len(b.subscriptions[sub.tenantID]) == 0 {
+		delete(b.subscriptions, sub.tenantID)
+	}
+}
+
+// removeAll() closes every subscription, the caller holds the lock.
+func (b *Broker) removeAll() {
+	for _, subs := range b.subscriptions {
+		for sub := range subs {
+			b.remove(sub)
+		}
+	}
+}
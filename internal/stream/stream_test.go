@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Blue-Davinci/leadhub-service/internal/data"
+)
+
+func event(id, tenantID int64) *data.TradeLeadEvent {
+	return &data.TradeLeadEvent{ID: id, TenantID: tenantID, Type: data.TradeLeadEventCreated, Data: []byte(`{}`)}
+}
+
+// ids() returns the IDs of the events, or of the events waiting on a channel.
+func ids(events []*data.TradeLeadEvent, ch <-chan *data.TradeLeadEvent) []int64 {
+	var ids []int64
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return ids
+			}
+			ids = append(ids, event.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestBrokerPublish(t *testing.T) {
+	broker := NewBroker(Config{})
+	sub, err := broker.Subscribe(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	other, err := broker.Subscribe(2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	broker.Publish(event(1, 1))
+	broker.Publish(event(2, 2))
+	broker.Publish(event(3, 1))
+	if got := ids(nil, sub.Events); !slices.Equal(got, []int64{1, 3}) {
+		t.Errorf("tenant 1 received %v, want [1 3]", got)
+	}
+	if got := ids(nil, other.Events); !slices.Equal(got, []int64{2}) {
+		t.Errorf("tenant 2 received %v, want [2]", got)
+	}
+}
+
+func TestBrokerResume(t *testing.T) {
+	broker := NewBroker(Config{BufferSize: 3})
+	// events arrive in commit order, 5 committed before 4
+	for _, id := range []int64{1, 2, 3, 5, 4} {
+		broker.Publish(event(id, 1))
+	}
+
+	tests := []struct {
+		name        string
+		lastEventID int64
+		wantReplay  []int64
+		wantResumed bool
+	}{
+		{name: "Live", lastEventID: 0, wantResumed: true},
+		{name: "Buffered", lastEventID: 3, wantReplay: []int64{5, 4}, wantResumed: true},
+		{name: "Out of order", lastEventID: 5, wantReplay: []int64{4}, wantResumed: true},
+		{name: "Up to date", lastEventID: 4, wantResumed: true},
+		{name: "Evicted", lastEventID: 2, wantResumed: false},
+		{name: "Unknown", lastEventID: 99, wantResumed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := broker.Subscribe(1, tt.lastEventID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sub.Close()
+			if got := ids(sub.Replay, nil); !slices.Equal(got, tt.wantReplay) || sub.Resumed != tt.wantResumed {
+				t.Errorf("Subscribe() replay = %v, resumed %t, want %v, %t", got, sub.Resumed, tt.wantReplay, tt.wantResumed)
+			}
+		})
+	}
+}
+
+func TestBrokerLimits(t *testing.T) {
+	broker := NewBroker(Config{MaxStreamsPerTenant: 2})
+	first, _ := broker.Subscribe(1, 0)
+	second, _ := broker.Subscribe(1, 0)
+	if _, err := broker.Subscribe(1, 0); !errors.Is(err, ErrTooManyStreams) {
+		t.Errorf("third stream error = %v, want %v", err, ErrTooManyStreams)
+	}
+	if sub, err := broker.Subscribe(2, 0); err != nil {
+		t.Errorf("another tenant's stream error = %v", err)
+	} else {
+		sub.Close()
+	}
+	first.Close()
+	first.Close()
+	if broker.Streams(1) != 1 {
+		t.Errorf("Streams() = %d after closing one, want 1", broker.Streams(1))
+	}
+
+	// a stream that falls behind is closed instead of blocking the others
+	fast, _ := broker.Subscribe(1, 0)
+	for id := range int64(subscriberBuffer + 1) {
+		broker.Publish(event(id+1, 1))
+		<-fast.Events
+	}
+	if got := ids(nil, second.Events); len(got) != subscriberBuffer {
+		t.Errorf("the lagging stream received %d events, want %d", len(got), subscriberBuffer)
+	}
+	if _, ok := <-second.Events; ok {
+		t.Error("the lagging stream is still open")
+	}
+	if broker.Streams(1) != 1 {
+		t.Errorf("Streams() = %d, want only the stream keeping up", broker.Streams(1))
+	}
+
+	// a reset closes the streams, and nothing can be resumed
+	broker.Reset()
+	if _, ok := <-fast.Events; ok {
+		t.Error("the stream is still open after Reset()")
+	}
+	if sub, _ := broker.Subscribe(1, subscriberBuffer); sub.Resumed {
+		t.Error("a stream resumed after Reset()")
+	}
+
+	broker.Close()
+	if _, err := broker.Subscribe(1, 0); !errors.Is(err, ErrBrokerClosed) {
+		t.Errorf("Subscribe() after Close() error = %v, want %v", err, ErrBrokerClosed)
+	}
+}
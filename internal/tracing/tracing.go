@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Define constants for the supported span exporters.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// DefaultOTLPEndpoint is the traces endpoint of an OpenTelemetry collector on the local host.
+const DefaultOTLPEndpoint = "http://localhost:4318/v1/traces"
+
+var ErrUnknownExporter = errors.New("unknown tracing exporter")
+
+// Config holds the tracing settings of the service.
+type Config struct {
+	// Exporter is one of ExporterNone, ExporterStdout or ExporterOTLP.
+	Exporter string
+	// Endpoint is the OTLP/HTTP traces endpoint, e.g. http://localhost:4318/v1/traces.
+	Endpoint string
+	// SampleRatio is the fraction of new traces that are sampled. Traces started by an
+	// upstream service follow the caller's sampling decision.
+	SampleRatio    float64
+	ServiceName    string
+	ServiceVersion string
+	Environment    string
+	// Writer receives the spans of the stdout exporter, os.Stdout when nil.
+	Writer io.Writer
+}
+
+// Setup() installs the global tracer provider and the W3C trace context propagator. The
+// returned function flushes any buffered spans and must be called on shutdown. With the
+// "none" exporter a no-op provider is installed, spans are still propagated but never
+// recorded.
+func Setup(cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		options := []stdouttrace.Option{}
+		if cfg.Writer != nil {
+			options = append(options, stdouttrace.WithWriter(cfg.Writer))
+		}
+		stdoutExporter, err := stdouttrace.New(options...)
+		if err != nil {
+			return nil, err
+		}
+		exporter = stdoutExporter
+	case ExporterOTLP:
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = DefaultOTLPEndpoint
+		}
+		// the exporter only logs an endpoint it cannot parse, so reject it here
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid otlp endpoint %q", endpoint)
+		}
+		otlpExporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+		if err != nil {
+			return nil, err
+		}
+		exporter = otlpExporter
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownExporter, cfg.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(cfg.ServiceVersion),
+		semconv.DeploymentEnvironmentNameKey.String(cfg.Environment),
+	))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer() returns a named tracer from the global provider.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
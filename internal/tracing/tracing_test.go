@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		wantErr  error
+		anyError bool
+	}{
+		{name: "Default", cfg: Config{}},
+		{name: "None", cfg: Config{Exporter: ExporterNone}},
+		{name: "Stdout", cfg: Config{Exporter: ExporterStdout, Writer: io.Discard, SampleRatio: 1}},
+		{name: "OTLP", cfg: Config{Exporter: ExporterOTLP, Endpoint: "http://collector:4318/v1/traces", SampleRatio: 1}},
+		{name: "Invalid OTLP endpoint", cfg: Config{Exporter: ExporterOTLP, Endpoint: "collector:4318"}, anyError: true},
+		{name: "Unknown exporter", cfg: Config{Exporter: "zipkin"}, wantErr: ErrUnknownExporter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Setup(tt.cfg)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Setup() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.anyError:
+				if err == nil {
+					t.Fatal("Setup() error = nil, want an error")
+				}
+			default:
+				if err != nil {
+					t.Fatalf("Setup() error = %v", err)
+				}
+				if err := shutdown(context.Background()); err != nil {
+					t.Errorf("shutdown() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	shutdown, err := Setup(Config{Exporter: ExporterStdout, Writer: buf, SampleRatio: 1, ServiceName: "leadhub-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := Tracer("test").Start(context.Background(), "TradeLeadModel.GetTradeLeadByID")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "TradeLeadModel.GetTradeLeadByID") {
+		t.Errorf("stdout exporter output does not contain the span: %s", buf.String())
+	}
+}
@@ -0,0 +1,227 @@
+// Package webhooks builds, signs and sends the events LeadHub delivers to the webhook
+// endpoints of tenants. Every request carries an X-LeadHub-Signature header of the form
+// "t=<unix timestamp>,v1=<hex HMAC-SHA256>", the HMAC of "<timestamp>.<body>" keyed with
+// the endpoint's secret. Receivers can check it with Verify().
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+	// DefaultMaxAttempts is how often a delivery is attempted before it fails for good.
+	DefaultMaxAttempts = 8
+	// DefaultDisableAfter is how many deliveries in a row have to fail for good before
+	// their endpoint is disabled.
+	DefaultDisableAfter = 5
+	// DefaultTolerance is how old a signature Verify() accepts.
+	DefaultTolerance = 5 * time.Minute
+	// maxResponseBody caps how much of a response body is kept in the delivery log.
+	maxResponseBody = 1024
+	userAgent       = "LeadHub-Webhooks/1.0"
+)
+
+// Define constants for the headers sent with every event.
+const (
+	SignatureHeader = "X-LeadHub-Signature"
+	EventHeader     = "X-LeadHub-Event"
+	DeliveryHeader  = "X-LeadHub-Delivery"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrExpiredSignature = errors.New("webhook signature timestamp is outside the tolerance")
+	ErrForbiddenAddress = errors.New("webhook URL resolves to a private or loopback address")
+	ErrUnexpectedStatus = errors.New("webhook endpoint responded with a non-2xx status")
+)
+
+// Event is the JSON body sent to webhook endpoints.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	TenantID  int64     `json:"tenant_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// NewEvent() returns an event of the given type with a new random ID. The ID stays the same
+// across retries and redeliveries, so receivers can use it to drop duplicates.
+func NewEvent(eventType string, tenantID int64, data any) (*Event, error) {
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, err
+	}
+	return &Event{
+		ID:        "evt_" + hex.EncodeToString(randomBytes),
+		Type:      eventType,
+		TenantID:  tenantID,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}, nil
+}
+
+// Sign() returns the signature header value of a body sent at the given time.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + computeSignature(secret, unix, body)
+}
+
+// Verify() checks a signature header against the body and rejects signatures older or
+// newer than the tolerance, which keeps captured requests from being replayed.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	return verify(secret, header, body, tolerance, time.Now())
+}
+
+func verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var unix string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return ErrExpiredSignature
+	}
+	expected := computeSignature(secret, unix, body)
+	// a receiver rotating secrets may be sent several signatures
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func computeSignature(secret, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Config configures a Client.
+type Config struct {
+	// Timeout bounds a whole request, a zero value uses DefaultTimeout.
+	Timeout time.Duration
+	// AllowPrivateNetworks lets endpoints resolve to loopback, private and link-local
+	// addresses. It is off in production, so tenants can't reach internal services.
+	AllowPrivateNetworks bool
+}
+
+// Client sends signed events to webhook endpoints.
+type Client struct {
+	http *http.Client
+}
+
+// NewClient() returns a Client. Redirects are not followed, a redirect counts as a failed
+// delivery.
+func NewClient(config Config) *Client {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivateNetworks {
+		// checked on the resolved address of every connection, so a DNS name can't be
+		// pointed at an internal address after the URL was validated
+		dialer.Control = denyPrivateAddresses
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Client{http: &http.Client{
+		Timeout:   config.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// Request is an event to send to an endpoint.
+type Request struct {
+	URL        string
+	Secret     string
+	DeliveryID int64
+	EventType  string
+	Body       []byte
+}
+
+// Response is what an endpoint answered, with the body cut to the first KiB.
+type Response struct {
+	StatusCode int
+	Body       string
+	Duration   time.Duration
+}
+
+// Send() posts the signed event to the endpoint. Any response other than a 2xx is an
+// error wrapping ErrUnexpectedStatus, returned together with the response. A connection
+// refused because of the address wraps ErrForbiddenAddress.
+func (c *Client) Send(ctx context.Context, request Request) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, request.URL, bytes.NewReader(request.Body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, request.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(request.DeliveryID, 10))
+	req.Header.Set(SignatureHeader, Sign(request.Secret, time.Now(), request.Body))
+	start := time.Now()
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseBody))
+	response := &Response{StatusCode: res.StatusCode, Body: strings.ToValidUTF8(string(body), ""), Duration: time.Since(start)}
+	if err != nil {
+		return response, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return response, fmt.Errorf("%w: %s", ErrUnexpectedStatus, res.Status)
+	}
+	return response, nil
+}
+
+// denyPrivateAddresses() is a net.Dialer Control function refusing connections to
+// addresses that aren't publicly routable.
+func denyPrivateAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
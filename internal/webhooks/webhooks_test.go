@@ -0,0 +1,104 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":"evt_1","type":"trade_lead.created"}`)
+	now := time.Unix(1767366245, 0)
+	header := Sign(secret, now, body)
+	if !strings.HasPrefix(header, "t=1767366245,v1=") {
+		t.Fatalf("Sign() = %q", header)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{name: "Valid", secret: secret, header: header, body: body, now: now.Add(time.Minute)},
+		{name: "Rotated secret", secret: secret, header: header + ",v1=" + strings.Repeat("0", 64), body: body, now: now},
+		{name: "Tampered body", secret: secret, header: header, body: []byte(`{"id":"evt_2"}`), now: now, want: ErrInvalidSignature},
+		{name: "Wrong secret", secret: "whsec_other", header: header, body: body, now: now, want: ErrInvalidSignature},
+		{name: "Replayed", secret: secret, header: header, body: body, now: now.Add(DefaultTolerance + time.Second), want: ErrExpiredSignature},
+		{name: "From the future", secret: secret, header: header, body: body, now: now.Add(-DefaultTolerance - time.Second), want: ErrExpiredSignature},
+		{name: "Missing timestamp", secret: secret, header: strings.Split(header, ",")[1], body: body, now: now, want: ErrInvalidSignature},
+		{name: "Malformed", secret: secret, header: "garbage", body: body, now: now, want: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verify(tt.secret, tt.header, tt.body, DefaultTolerance, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientSend(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":"evt_1","type":"user.activated"}`)
+	var received *http.Request
+	var receivedBody []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, strings.Repeat("x", 2*maxResponseBody))
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer receiver.Close()
+	client := NewClient(Config{AllowPrivateNetworks: true})
+	send := func(path string) (*Response, error) {
+		return client.Send(context.Background(), Request{URL: receiver.URL + path, Secret: secret, DeliveryID: 7, EventType: "user.activated", Body: body})
+	}
+
+	response, err := send("/ok")
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if response.StatusCode != http.StatusNoContent || string(receivedBody) != string(body) {
+		t.Errorf("Send() = %+v, received %s", response, receivedBody)
+	}
+	if err := Verify(secret, received.Header.Get(SignatureHeader), receivedBody, DefaultTolerance); err != nil {
+		t.Errorf("the received signature does not verify: %v", err)
+	}
+	for header, want := range map[string]string{EventHeader: "user.activated", DeliveryHeader: "7", "Content-Type": "application/json"} {
+		if got := received.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	response, err = send("/fail")
+	if !errors.Is(err, ErrUnexpectedStatus) || response == nil || response.StatusCode != http.StatusInternalServerError || len(response.Body) != maxResponseBody {
+		t.Errorf("Send() to a failing endpoint = %+v, %v", response, err)
+	}
+	response, err = send("/redirect")
+	if !errors.Is(err, ErrUnexpectedStatus) || response.StatusCode != http.StatusFound {
+		t.Errorf("Send() to a redirect = %+v, %v, want the redirect not to be followed", response, err)
+	}
+
+	// the receiver listens on the loopback interface
+	_, err = NewClient(Config{}).Send(context.Background(), Request{URL: receiver.URL, Secret: secret, Body: body})
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Send() to a loopback address error = %v, want %v", err, ErrForbiddenAddress)
+	}
+}
@@ -3,6 +3,8 @@
 # Add permissions to a user in development
 # Usage: ./scripts/add-user-permissions.sh <email> <permission1> [permission2] ...
 # Example: ./scripts/add-user-permissions.sh user@example.com admin:read admin:write
+# The binary does the same through the data models:
+#   leadhub-api admin permission grant -email=user@example.com admin:read admin:write
 
 set -e
 